@@ -36,3 +36,19 @@ func (c *Client) UseTarget(name string) *Client {
 
 	return &Client{Client: *newClient}
 }
+
+// ReadYourWrites returns a new client whose reads wait until its previous writes are committed. As database queries
+// are executed by the dqlite leader, reads already observe committed writes, see types.ConsistencyTokenHeader.
+func (c *Client) ReadYourWrites() *Client {
+	newClient := c.Client.ReadYourWrites()
+
+	return &Client{Client: *newClient}
+}
+
+// IfMatch returns a new client whose writes only apply if the resource they modify has the given version, on
+// endpoints that support conditional writes. Otherwise they fail with Precondition Failed (412).
+func (c *Client) IfMatch(version string) *Client {
+	newClient := c.Client.IfMatch(version)
+
+	return &Client{Client: *newClient}
+}
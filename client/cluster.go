@@ -2,8 +2,13 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/http"
 	"sync"
+
+	"github.com/canonical/lxd/shared/api"
 )
 
 // Cluster is a list of clients belonging to a cluster.
@@ -18,7 +23,13 @@ func (c Cluster) SelectRandom() Client {
 func (c Cluster) Query(ctx context.Context, concurrent bool, query func(context.Context, *Client) error) error {
 	if !concurrent {
 		for _, client := range c {
-			err := query(ctx, &client)
+			// Stop early if the query was cancelled.
+			err := ctx.Err()
+			if err != nil {
+				return err
+			}
+
+			err = query(ctx, &client)
 			if err != nil {
 				return err
 			}
@@ -54,3 +65,54 @@ func (c Cluster) Query(ctx context.Context, concurrent bool, query func(context.
 
 	return nil
 }
+
+// Failover executes the given query on one member of the cluster at a time, in order, until it succeeds. The query is
+// only retried on the next member if the previous one could not be reached or was unavailable, so it must be
+// idempotent. Any other error is returned immediately. At most maxAttempts members are tried, or all of them if
+// maxAttempts is not positive.
+func (c Cluster) Failover(ctx context.Context, maxAttempts int, query func(context.Context, *Client) error) error {
+	if len(c) == 0 {
+		return fmt.Errorf("No cluster members to query")
+	}
+
+	if maxAttempts <= 0 || maxAttempts > len(c) {
+		maxAttempts = len(c)
+	}
+
+	var errs []error
+	for _, client := range c[:maxAttempts] {
+		// Stop early if the query was cancelled.
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = query(ctx, &client)
+		if err == nil {
+			return nil
+		}
+
+		if !failoverAllowed(err) {
+			return err
+		}
+
+		errs = append(errs, fmt.Errorf("%q: %w", client.URL().URL.Host, err))
+	}
+
+	return fmt.Errorf("Failed to query any of %d cluster members: %w", len(errs), errors.Join(errs...))
+}
+
+// failoverAllowed returns whether a query that failed with the given error may be retried on another cluster member,
+// because the member could not be reached or was temporarily unable to handle it.
+func failoverAllowed(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	code, ok := api.StatusErrorMatch(err)
+	if !ok {
+		return true
+	}
+
+	return code == http.StatusServiceUnavailable || code == http.StatusBadGateway || code == http.StatusGatewayTimeout
+}
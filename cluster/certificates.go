@@ -0,0 +1,211 @@
+package cluster
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// GetAdditionalCertificates returns the additional certificates that are accepted for each cluster member, keyed by
+// member name, for the cluster members that have any.
+func GetAdditionalCertificates(ctx context.Context, tx *sql.Tx) (map[string][]types.X509Certificate, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name, additional_certificates FROM internal_cluster_members WHERE additional_certificates != ''")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get additional certificates of cluster members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	certificates := map[string][]types.X509Certificate{}
+	for rows.Next() {
+		var name, certs string
+		err := rows.Scan(&name, &certs)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan additional certificates of cluster member: %w", err)
+		}
+
+		certificates[name], err = parseCertificates(certs)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse additional certificates of cluster member %q: %w", name, err)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get additional certificates of cluster members: %w", err)
+	}
+
+	return certificates, nil
+}
+
+// AddAdditionalCertificate records another certificate that is accepted for the cluster member with the given name,
+// such as its new certificate while it is being rotated. It does nothing if the certificate is already accepted.
+func AddAdditionalCertificate(ctx context.Context, tx *sql.Tx, memberName string, cert types.X509Certificate) error {
+	if cert.Certificate == nil {
+		return fmt.Errorf("Failed to add certificate to cluster member %q. Found empty certificate", memberName)
+	}
+
+	certificate, additional, err := getCertificates(ctx, tx, memberName)
+	if err != nil {
+		return err
+	}
+
+	if findCertificate(append([]types.X509Certificate{certificate}, additional...), shared.CertFingerprint(cert.Certificate)) >= 0 {
+		return nil
+	}
+
+	return setCertificates(ctx, tx, memberName, certificate, append(additional, cert))
+}
+
+// PromoteCertificate records the additional certificate with the given fingerprint as the certificate of the cluster
+// member with the given name. Its previous certificate is kept as an additional certificate until it is retired with
+// RetireCertificate. It returns the resulting certificate and additional certificates of the cluster member.
+func PromoteCertificate(ctx context.Context, tx *sql.Tx, memberName string, fingerprint string) (types.X509Certificate, []types.X509Certificate, error) {
+	certificate, additional, err := getCertificates(ctx, tx, memberName)
+	if err != nil {
+		return types.X509Certificate{}, nil, err
+	}
+
+	if shared.CertFingerprint(certificate.Certificate) == fingerprint {
+		return certificate, additional, nil
+	}
+
+	i := findCertificate(additional, fingerprint)
+	if i < 0 {
+		return types.X509Certificate{}, nil, api.StatusErrorf(http.StatusNotFound, "Cluster member %q has no additional certificate with fingerprint %q", memberName, fingerprint)
+	}
+
+	certificate, additional[i] = additional[i], certificate
+
+	err = setCertificates(ctx, tx, memberName, certificate, additional)
+	if err != nil {
+		return types.X509Certificate{}, nil, err
+	}
+
+	return certificate, additional, nil
+}
+
+// RetireCertificate removes the additional certificate with the given fingerprint from the cluster member with the
+// given name, so that it is no longer accepted. The certificate of the cluster member can't be retired, another must
+// be promoted with PromoteCertificate first. It returns the resulting certificate and additional certificates of the
+// cluster member.
+func RetireCertificate(ctx context.Context, tx *sql.Tx, memberName string, fingerprint string) (types.X509Certificate, []types.X509Certificate, error) {
+	certificate, additional, err := getCertificates(ctx, tx, memberName)
+	if err != nil {
+		return types.X509Certificate{}, nil, err
+	}
+
+	if shared.CertFingerprint(certificate.Certificate) == fingerprint {
+		return types.X509Certificate{}, nil, api.StatusErrorf(http.StatusBadRequest, "Cannot retire the certificate of cluster member %q, another certificate must be promoted first", memberName)
+	}
+
+	i := findCertificate(additional, fingerprint)
+	if i < 0 {
+		return types.X509Certificate{}, nil, api.StatusErrorf(http.StatusNotFound, "Cluster member %q has no additional certificate with fingerprint %q", memberName, fingerprint)
+	}
+
+	additional = append(additional[:i], additional[i+1:]...)
+
+	err = setCertificates(ctx, tx, memberName, certificate, additional)
+	if err != nil {
+		return types.X509Certificate{}, nil, err
+	}
+
+	return certificate, additional, nil
+}
+
+// getCertificates returns the certificate and additional certificates of the cluster member with the given name.
+func getCertificates(ctx context.Context, tx *sql.Tx, memberName string) (types.X509Certificate, []types.X509Certificate, error) {
+	var certStr, additionalStr string
+	err := tx.QueryRowContext(ctx, "SELECT certificate, additional_certificates FROM internal_cluster_members WHERE name = ?", memberName).Scan(&certStr, &additionalStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.X509Certificate{}, nil, api.StatusErrorf(http.StatusNotFound, "InternalClusterMember not found")
+	}
+
+	if err != nil {
+		return types.X509Certificate{}, nil, fmt.Errorf("Failed to get certificates of cluster member %q: %w", memberName, err)
+	}
+
+	certificate, err := types.ParseX509Certificate(certStr)
+	if err != nil {
+		return types.X509Certificate{}, nil, fmt.Errorf("Failed to parse certificate of cluster member %q: %w", memberName, err)
+	}
+
+	additional, err := parseCertificates(additionalStr)
+	if err != nil {
+		return types.X509Certificate{}, nil, fmt.Errorf("Failed to parse additional certificates of cluster member %q: %w", memberName, err)
+	}
+
+	return *certificate, additional, nil
+}
+
+// setCertificates sets the certificate and additional certificates of the cluster member with the given name.
+func setCertificates(ctx context.Context, tx *sql.Tx, memberName string, certificate types.X509Certificate, additional []types.X509Certificate) error {
+	var additionalStr strings.Builder
+	for _, cert := range additional {
+		additionalStr.WriteString(cert.String())
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE internal_cluster_members SET certificate = ?, additional_certificates = ? WHERE name = ?", certificate.String(), additionalStr.String(), memberName)
+	if err != nil {
+		return fmt.Errorf("Failed to update certificates of cluster member %q: %w", memberName, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "InternalClusterMember not found")
+	}
+
+	return nil
+}
+
+// parseCertificates parses each certificate of the given concatenated PEM encoded certificates.
+func parseCertificates(certStr string) ([]types.X509Certificate, error) {
+	var certs []types.X509Certificate
+	rest := []byte(certStr)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, types.X509Certificate{Certificate: cert})
+	}
+
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, fmt.Errorf("Failed to decode certificate")
+	}
+
+	return certs, nil
+}
+
+// findCertificate returns the index of the certificate with the given fingerprint, or -1 if there is none.
+func findCertificate(certs []types.X509Certificate, fingerprint string) int {
+	for i, cert := range certs {
+		if cert.Certificate != nil && shared.CertFingerprint(cert.Certificate) == fingerprint {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetConfig returns the cluster-wide configuration as a map of keys to values.
+func GetConfig(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT key, value FROM internal_config")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get cluster configuration: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	config := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		err := rows.Scan(&key, &value)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan cluster configuration: %w", err)
+		}
+
+		config[key] = value
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get cluster configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// UpdateConfig sets the given keys of the cluster-wide configuration. Keys with an empty value are removed.
+func UpdateConfig(ctx context.Context, tx *sql.Tx, config map[string]string) error {
+	for key, value := range config {
+		if value == "" {
+			_, err := tx.ExecContext(ctx, "DELETE FROM internal_config WHERE key = ?", key)
+			if err != nil {
+				return fmt.Errorf("Failed to delete cluster configuration key %q: %w", key, err)
+			}
+
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, "INSERT INTO internal_config (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", key, value)
+		if err != nil {
+			return fmt.Errorf("Failed to set cluster configuration key %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// GetDqliteAddresses returns the address at which each cluster member accepts dqlite connections, keyed by member
+// name, for the cluster members that accept them at an address other than their API address.
+func GetDqliteAddresses(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name, dqlite_address FROM internal_cluster_members WHERE dqlite_address != ''")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dqlite addresses of cluster members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	addresses := map[string]string{}
+	for rows.Next() {
+		var name, address string
+		err := rows.Scan(&name, &address)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan dqlite address of cluster member: %w", err)
+		}
+
+		addresses[name] = address
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dqlite addresses of cluster members: %w", err)
+	}
+
+	return addresses, nil
+}
+
+// SetDqliteAddress sets the address at which the cluster member with the given name accepts dqlite connections. An
+// empty address means it accepts them at its API address.
+func SetDqliteAddress(ctx context.Context, tx *sql.Tx, memberName string, address string) error {
+	result, err := tx.ExecContext(ctx, "UPDATE internal_cluster_members SET dqlite_address = ? WHERE name = ?", address, memberName)
+	if err != nil {
+		return fmt.Errorf("Failed to update dqlite address of cluster member %q: %w", memberName, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "InternalClusterMember not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// GetDrainingClusterMembers returns the names of the cluster members that are draining ahead of their removal.
+func GetDrainingClusterMembers(ctx context.Context, tx *sql.Tx) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM internal_cluster_members WHERE draining = 1")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get draining cluster members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	draining := map[string]bool{}
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan draining cluster member: %w", err)
+		}
+
+		draining[name] = true
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get draining cluster members: %w", err)
+	}
+
+	return draining, nil
+}
+
+// SetClusterMemberDraining sets whether the cluster member with the given name is draining ahead of its removal.
+func SetClusterMemberDraining(ctx context.Context, tx *sql.Tx, memberName string, draining bool) error {
+	result, err := tx.ExecContext(ctx, "UPDATE internal_cluster_members SET draining = ? WHERE name = ?", draining, memberName)
+	if err != nil {
+		return fmt.Errorf("Failed to update draining status of cluster member %q: %w", memberName, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "InternalClusterMember not found")
+	}
+
+	return nil
+}
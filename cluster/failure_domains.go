@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// FailureDomain is the placement of a cluster member, used by dqlite to spread voters across failure domains.
+type FailureDomain struct {
+	// Code identifies the failure domain, such as a rack or availability zone.
+	Code uint64
+
+	// Tags are free-form labels describing the placement of the cluster member.
+	Tags map[string]string
+}
+
+// GetFailureDomains returns the failure domain of each cluster member that has recorded one, keyed by member name.
+func GetFailureDomains(ctx context.Context, tx *sql.Tx) (map[string]FailureDomain, error) {
+	stmt := `
+SELECT internal_cluster_members.name, internal_cluster_member_failure_domains.failure_domain, internal_cluster_member_failure_domains.tags
+  FROM internal_cluster_member_failure_domains
+  JOIN internal_cluster_members ON internal_cluster_members.id = internal_cluster_member_failure_domains.member_id
+`
+	rows, err := tx.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get failure domains of cluster members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	domains := map[string]FailureDomain{}
+	for rows.Next() {
+		var name, tags string
+		var domain FailureDomain
+		err := rows.Scan(&name, &domain.Code, &tags)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan failure domain of cluster member: %w", err)
+		}
+
+		err = json.Unmarshal([]byte(tags), &domain.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse tags of cluster member %q: %w", name, err)
+		}
+
+		domains[name] = domain
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get failure domains of cluster members: %w", err)
+	}
+
+	return domains, nil
+}
+
+// SetFailureDomain replaces the failure domain of the cluster member with the given name.
+func SetFailureDomain(ctx context.Context, tx *sql.Tx, memberName string, domain FailureDomain) error {
+	memberID, err := GetInternalClusterMemberID(ctx, tx, memberName)
+	if err != nil {
+		return err
+	}
+
+	tags := domain.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("Failed to encode tags of cluster member %q: %w", memberName, err)
+	}
+
+	stmt := `
+INSERT INTO internal_cluster_member_failure_domains (member_id, failure_domain, tags) VALUES (?, ?, ?)
+  ON CONFLICT(member_id) DO UPDATE SET failure_domain = excluded.failure_domain, tags = excluded.tags
+`
+	_, err = tx.ExecContext(ctx, stmt, memberID, domain.Code, string(tagsJSON))
+	if err != nil {
+		return fmt.Errorf("Failed to set failure domain of cluster member %q: %w", memberName, err)
+	}
+
+	return nil
+}
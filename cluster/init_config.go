@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetInitConfig returns the configuration that the cluster member with the given name was bootstrapped or joined with.
+func GetInitConfig(ctx context.Context, tx *sql.Tx, memberName string) (map[string]string, error) {
+	stmt := `
+SELECT internal_init_config.key, internal_init_config.value
+  FROM internal_init_config
+  JOIN internal_cluster_members ON internal_cluster_members.id = internal_init_config.member_id
+  WHERE internal_cluster_members.name = ?
+`
+	rows, err := tx.QueryContext(ctx, stmt, memberName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get init configuration of cluster member %q: %w", memberName, err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	config := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		err := rows.Scan(&key, &value)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan init configuration of cluster member %q: %w", memberName, err)
+		}
+
+		config[key] = value
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get init configuration of cluster member %q: %w", memberName, err)
+	}
+
+	return config, nil
+}
+
+// SetInitConfig replaces the init configuration of the cluster member with the given name.
+func SetInitConfig(ctx context.Context, tx *sql.Tx, memberName string, config map[string]string) error {
+	memberID, err := GetInternalClusterMemberID(ctx, tx, memberName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM internal_init_config WHERE member_id = ?", memberID)
+	if err != nil {
+		return fmt.Errorf("Failed to clear init configuration of cluster member %q: %w", memberName, err)
+	}
+
+	for key, value := range config {
+		_, err := tx.ExecContext(ctx, "INSERT INTO internal_init_config (member_id, key, value) VALUES (?, ?, ?)", memberID, key, value)
+		if err != nil {
+			return fmt.Errorf("Failed to set init configuration key %q of cluster member %q: %w", key, memberName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// GetReplicaOnlyClusterMembers returns the names of the cluster members that only ever hold the dqlite spare role.
+func GetReplicaOnlyClusterMembers(ctx context.Context, tx *sql.Tx) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM internal_cluster_members WHERE replica_only = 1")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get replica-only cluster members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	replicas := map[string]bool{}
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan replica-only cluster member: %w", err)
+		}
+
+		replicas[name] = true
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get replica-only cluster members: %w", err)
+	}
+
+	return replicas, nil
+}
+
+// SetClusterMemberReplicaOnly sets whether the cluster member with the given name only ever holds the dqlite spare role.
+func SetClusterMemberReplicaOnly(ctx context.Context, tx *sql.Tx, memberName string, replicaOnly bool) error {
+	result, err := tx.ExecContext(ctx, "UPDATE internal_cluster_members SET replica_only = ? WHERE name = ?", replicaOnly, memberName)
+	if err != nil {
+		return fmt.Errorf("Failed to update replica-only status of cluster member %q: %w", memberName, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "InternalClusterMember not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// GetRequestedRoles returns the dqlite role requested for each cluster member by an operator, keyed by member name.
+// Cluster members without a requested role are omitted.
+func GetRequestedRoles(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name, requested_role FROM internal_cluster_members WHERE requested_role != ''")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get requested roles of cluster members: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	roles := map[string]string{}
+	for rows.Next() {
+		var name, role string
+		err := rows.Scan(&name, &role)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan requested role of cluster member: %w", err)
+		}
+
+		roles[name] = role
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get requested roles of cluster members: %w", err)
+	}
+
+	return roles, nil
+}
+
+// SetRequestedRole records the dqlite role requested for the cluster member with the given name by an operator.
+func SetRequestedRole(ctx context.Context, tx *sql.Tx, memberName string, role string) error {
+	result, err := tx.ExecContext(ctx, "UPDATE internal_cluster_members SET requested_role = ? WHERE name = ?", role, memberName)
+	if err != nil {
+		return fmt.Errorf("Failed to update requested role of cluster member %q: %w", memberName, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch affected rows: %w", err)
+	}
+
+	if n != 1 {
+		return api.StatusErrorf(http.StatusNotFound, "InternalClusterMember not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// HasSchemaExtensionTables returns whether the tables tracking the versions of named schema extensions exist yet.
+// They are introduced by an internal schema update, so may be missing while the cluster is being upgraded.
+func HasSchemaExtensionTables(ctx context.Context, tx *sql.Tx) (bool, error) {
+	counts, err := query.SelectIntegers(ctx, tx, "SELECT count(name) FROM sqlite_master WHERE type = 'table' AND name = 'internal_cluster_member_schema_extensions'")
+	if err != nil {
+		return false, err
+	}
+
+	return len(counts) == 1 && counts[0] == 1, nil
+}
+
+// UpdateClusterMemberSchemaExtensions records the versions of named schema extensions supported by the cluster member
+// with the given address, replacing any previously recorded versions.
+// This helper is non-generated to work before generated statements are loaded, as we update the schema.
+func UpdateClusterMemberSchemaExtensions(ctx context.Context, tx *sql.Tx, versions map[string]uint64, address string) error {
+	ids, err := query.SelectIntegers(ctx, tx, "SELECT id FROM internal_cluster_members WHERE address = ?", address)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) != 1 {
+		return fmt.Errorf("Found %d cluster members with address %q instead of 1", len(ids), address)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM internal_cluster_member_schema_extensions WHERE member_id = ?", ids[0])
+	if err != nil {
+		return err
+	}
+
+	for name, version := range versions {
+		_, err = tx.ExecContext(ctx, "INSERT INTO internal_cluster_member_schema_extensions (member_id, name, version) VALUES (?, ?, ?)", ids[0], name, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetClusterMemberSchemaExtensions returns the versions of named schema extensions supported by each cluster member
+// that is not pending. Extensions missing from a cluster member's versions are not supported by it.
+// This helper is non-generated to work before generated statements are loaded, as we update the schema.
+func GetClusterMemberSchemaExtensions(ctx context.Context, tx *sql.Tx) ([]map[string]uint64, error) {
+	stmt := `
+SELECT internal_cluster_members.id, internal_cluster_member_schema_extensions.name, internal_cluster_member_schema_extensions.version
+  FROM internal_cluster_members
+  LEFT JOIN internal_cluster_member_schema_extensions ON internal_cluster_member_schema_extensions.member_id = internal_cluster_members.id
+  WHERE NOT internal_cluster_members.role='pending'
+`
+
+	memberVersions := map[int64]map[string]uint64{}
+	dest := func(scan func(dest ...any) error) error {
+		var id int64
+		var name sql.NullString
+		var version sql.NullInt64
+		err := scan(&id, &name, &version)
+		if err != nil {
+			return err
+		}
+
+		if memberVersions[id] == nil {
+			memberVersions[id] = map[string]uint64{}
+		}
+
+		if name.Valid {
+			memberVersions[id][name.String] = uint64(version.Int64)
+		}
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]map[string]uint64, 0, len(memberVersions))
+	for _, versions := range memberVersions {
+		results = append(results, versions)
+	}
+
+	return results, nil
+}
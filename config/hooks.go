@@ -1,23 +1,39 @@
 package config
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 // Hooks holds customizable functions that can be called at varying points by the daemon to.
 // integrate with other tools.
 type Hooks struct {
 	// PreBootstrap is run before the daemon is initialized and bootstrapped.
-	PreBootstrap func(s *state.State, initConfig map[string]string) error
+	// The context is cancelled once BootstrapHookTimeout has elapsed.
+	PreBootstrap func(ctx context.Context, s *state.State, initConfig map[string]string) error
 
 	// PostBootstrap is run after the daemon is initialized and bootstrapped.
-	PostBootstrap func(s *state.State, initConfig map[string]string) error
+	// The context is cancelled once BootstrapHookTimeout has elapsed.
+	PostBootstrap func(ctx context.Context, s *state.State, initConfig map[string]string) error
+
+	// BootstrapHookTimeout limits how long each of the PreBootstrap and PostBootstrap hooks may run.
+	// If a hook has not returned by the deadline, bootstrapping fails with a timeout error. If 0, hooks are not limited.
+	BootstrapHookTimeout time.Duration
 
 	// OnStart is run after the daemon is started.
 	OnStart func(s *state.State) error
 
+	// OnDatabaseReady is run after the database has been bootstrapped, joined, or re-established with the cluster,
+	// and is open for queries. It is not run on systems that have not yet bootstrapped or joined a cluster.
+	OnDatabaseReady func(ctx context.Context, s *state.State) error
+
 	// PostJoin is run after the daemon is initialized, joined the cluster and existing members triggered
 	// their 'OnNewMember' hooks.
+	// If it returns ErrRetryHook, it is run again after the requested delay rather than failing the join.
 	PostJoin func(s *state.State, initConfig map[string]string) error
 
 	// PreJoin is run after the daemon is initialized and joined the cluster but before existing members triggered
@@ -33,6 +49,70 @@ type Hooks struct {
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat func(s *state.State) error
 
+	// HeartbeatPayload is run on each cluster member as it takes part in a heartbeat round, and returns a small opaque
+	// payload, such as the load of the member, that is passed to the other cluster members along with the heartbeats.
+	// Payloads larger than MaxHeartbeatPayload bytes, or not returned within a couple of seconds so that the heartbeat
+	// is not delayed, are dropped. Delivery is best-effort: payloads reach the other members up to a heartbeat round
+	// late, and are lost if a heartbeat fails or leadership changes, so they must not be relied on for data that has to
+	// be delivered.
+	HeartbeatPayload func(s *state.State) ([]byte, error)
+
+	// OnHeartbeatPayloads is run on each cluster member when it receives the latest heartbeat payloads of the cluster
+	// members, keyed by member name. Members without a payload are omitted. It runs in the background, and if its
+	// previous run has not returned, the payloads are skipped. See HeartbeatPayload.
+	OnHeartbeatPayloads func(s *state.State, payloads map[string][]byte) error
+
+	// PreNewMember is run on the dqlite leader when a new cluster member asks to join with a valid join token, before
+	// the member is added to the database, the trust store, or dqlite. Returning an error rejects the join, and the
+	// error is returned to the joining member. The join token is not consumed, so the member can try again.
+	PreNewMember func(ctx context.Context, s *state.State, candidate types.ClusterMemberLocal) error
+
 	// OnNewMember is run on each peer after a new cluster member has joined and executed their 'PreJoin' hook.
+	// If it returns ErrRetryHook, the joining member runs it again after the requested delay rather than skipping it.
 	OnNewMember func(s *state.State) error
+
+	// PreShutdown is run when the daemon begins shutting down, before its listeners and database are stopped, so that
+	// the application can hand off any work that only runs on one cluster member. If this member is the dqlite leader,
+	// leadership has already been transferred to another voter. It is skipped if the shutdown is forced.
+	PreShutdown func(ctx context.Context, s *state.State) error
+
+	// OnLeaderChange is run on a cluster member when it becomes the dqlite leader, or stops being the leader.
+	// Leadership is checked periodically, so short-lived changes may be missed.
+	OnLeaderChange func(ctx context.Context, s *state.State, isLeader bool) error
+
+	// OnLowDisk is run on a cluster member when the free space in its database directory drops below the configured
+	// threshold, with the number of bytes still free, so that the application can shed load before database writes
+	// start failing. It is run again only after the free space has recovered and dropped again.
+	OnLowDisk func(ctx context.Context, s *state.State, free uint64) error
+
+	// OnConfigChange is run on each cluster member after the cluster-wide configuration has been changed with
+	// state.SetClusterConfig. It receives the full configuration from before and after the change.
+	OnConfigChange func(ctx context.Context, s *state.State, oldConfig map[string]string, newConfig map[string]string) error
+}
+
+// MaxHeartbeatPayload is the largest heartbeat payload, in bytes, that is passed to other cluster members.
+const MaxHeartbeatPayload = 4096
+
+// ErrRetryHook can be returned by the PostJoin and OnNewMember hooks to indicate a transient failure. Rather than
+// failing the join, the hook is run again once After has elapsed, up to a limited number of times.
+type ErrRetryHook struct {
+	// After is how long to wait before running the hook again.
+	After time.Duration
+
+	// Err is the transient error that caused the hook to fail, if any.
+	Err error
+}
+
+// Error implements error.
+func (e ErrRetryHook) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("Hook requested to be retried after %s", e.After)
+	}
+
+	return fmt.Sprintf("Hook requested to be retried after %s: %v", e.After, e.Err)
+}
+
+// Unwrap returns the transient error that caused the hook to fail.
+func (e ErrRetryHook) Unwrap() error {
+	return e.Err
 }
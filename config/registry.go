@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// KeyType is the type of value held by a cluster-wide configuration key.
+type KeyType string
+
+const (
+	// KeyTypeString accepts any value.
+	KeyTypeString KeyType = "string"
+
+	// KeyTypeBool accepts values that can be parsed with strconv.ParseBool.
+	KeyTypeBool KeyType = "bool"
+
+	// KeyTypeInt accepts values that can be parsed as a 64-bit integer.
+	KeyTypeInt KeyType = "int"
+
+	// KeyTypeDuration accepts values that can be parsed with time.ParseDuration.
+	KeyTypeDuration KeyType = "duration"
+)
+
+// Key declares a key of the cluster-wide configuration.
+type Key struct {
+	// Type is the type of the value. If empty, KeyTypeString is assumed.
+	Type KeyType
+
+	// Default is returned for the key if it has not been set.
+	Default string
+
+	// Validate is run on any non-empty value, after it has been checked against the Type.
+	Validate func(value string) error
+
+	// Description describes the purpose of the key.
+	Description string
+}
+
+// Registry holds the schema of the cluster-wide configuration. Keys are declared by the application and its
+// extensions before the daemon starts, and are enforced by state.GetConfig and state.SetConfig. Values of declared keys
+// are also validated by the dqlite leader before they are persisted. Undeclared keys can still be set with
+// state.SetClusterConfig.
+type Registry struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+// NewRegistry returns an empty configuration registry.
+func NewRegistry() *Registry {
+	return &Registry{keys: map[string]Key{}}
+}
+
+// Register declares the given key. It fails if the key is already declared, or if its default value is invalid.
+func (r *Registry) Register(name string, key Key) error {
+	if name == "" {
+		return fmt.Errorf("Configuration key name cannot be empty")
+	}
+
+	if key.Type == "" {
+		key.Type = KeyTypeString
+	}
+
+	err := validateType(key.Type, key.Default)
+	if err != nil {
+		return fmt.Errorf("Invalid default value for configuration key %q: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.keys[name]
+	if ok {
+		return fmt.Errorf("Configuration key %q is already registered", name)
+	}
+
+	r.keys[name] = key
+
+	return nil
+}
+
+// Keys returns the names of all declared keys, in sorted order.
+func (r *Registry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.keys))
+	for name := range r.keys {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Registered returns whether the key has been declared.
+func (r *Registry) Registered(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.keys[name]
+
+	return ok
+}
+
+// Default returns the default value of the key, or a Not Found error if the key has not been declared.
+func (r *Registry) Default(name string) (string, error) {
+	r.mu.RLock()
+	key, ok := r.keys[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", api.StatusErrorf(http.StatusNotFound, "Unknown configuration key %q", name)
+	}
+
+	return key.Default, nil
+}
+
+// Validate checks the value against the schema of the key. An empty value, which resets the key to its default,
+// is always valid. It returns a Not Found error if the key has not been declared, and a Bad Request error if the
+// value is invalid.
+func (r *Registry) Validate(name string, value string) error {
+	r.mu.RLock()
+	key, ok := r.keys[name]
+	r.mu.RUnlock()
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "Unknown configuration key %q", name)
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	err := validateType(key.Type, value)
+	if err == nil && key.Validate != nil {
+		err = key.Validate(value)
+	}
+
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid value %q for configuration key %q: %v", value, name, err)
+	}
+
+	return nil
+}
+
+// validateType checks that a non-empty value can be parsed as the given type.
+func validateType(keyType KeyType, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var err error
+	switch keyType {
+	case KeyTypeString:
+	case KeyTypeBool:
+		_, err = strconv.ParseBool(value)
+	case KeyTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case KeyTypeDuration:
+		_, err = time.ParseDuration(value)
+	default:
+		return fmt.Errorf("Unknown key type %q", keyType)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Expected a value of type %s", keyType)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/suite"
+)
+
+type registrySuite struct {
+	suite.Suite
+}
+
+func TestRegistrySuite(t *testing.T) {
+	suite.Run(t, new(registrySuite))
+}
+
+// Ensures keys are only registered with a name, a known type, and a default value of that type, and only once.
+func (s *registrySuite) Test_register() {
+	tests := []struct {
+		name      string
+		key       string
+		value     Key
+		expectErr bool
+	}{
+		{
+			name:  "String key without a type",
+			key:   "user.name",
+			value: Key{Default: "anything"},
+		},
+		{
+			name:  "Bool key",
+			key:   "user.enabled",
+			value: Key{Type: KeyTypeBool, Default: "true"},
+		},
+		{
+			name:  "Int key",
+			key:   "user.count",
+			value: Key{Type: KeyTypeInt, Default: "-42"},
+		},
+		{
+			name:  "Duration key",
+			key:   "user.interval",
+			value: Key{Type: KeyTypeDuration, Default: "1m30s"},
+		},
+		{
+			name:  "Key without a default",
+			key:   "user.optional",
+			value: Key{Type: KeyTypeInt},
+		},
+		{
+			name:      "Key without a name",
+			key:       "",
+			value:     Key{},
+			expectErr: true,
+		},
+		{
+			name:      "Key of an unknown type",
+			key:       "user.unknown",
+			value:     Key{Type: "float", Default: "1.5"},
+			expectErr: true,
+		},
+		{
+			name:      "Bool key with an invalid default",
+			key:       "user.invalid-bool",
+			value:     Key{Type: KeyTypeBool, Default: "maybe"},
+			expectErr: true,
+		},
+		{
+			name:      "Int key with an invalid default",
+			key:       "user.invalid-int",
+			value:     Key{Type: KeyTypeInt, Default: "1.5"},
+			expectErr: true,
+		},
+		{
+			name:      "Duration key with an invalid default",
+			key:       "user.invalid-duration",
+			value:     Key{Type: KeyTypeDuration, Default: "5"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		r := NewRegistry()
+		err := r.Register(test.key, test.value)
+		if test.expectErr {
+			s.Error(err)
+			s.False(r.Registered(test.key))
+			continue
+		}
+
+		s.NoError(err)
+		s.True(r.Registered(test.key))
+
+		value, err := r.Default(test.key)
+		s.NoError(err)
+		s.Equal(test.value.Default, value)
+
+		// A key can only be registered once.
+		s.Error(r.Register(test.key, test.value))
+	}
+}
+
+// Ensures values are validated against the type and validator of their key, and unknown keys are not found.
+func (s *registrySuite) Test_validate() {
+	r := NewRegistry()
+	s.Require().NoError(r.Register("user.enabled", Key{Type: KeyTypeBool, Default: "false"}))
+	s.Require().NoError(r.Register("user.interval", Key{Type: KeyTypeDuration}))
+	s.Require().NoError(r.Register("user.port", Key{
+		Type: KeyTypeInt,
+		Validate: func(value string) error {
+			if value == "0" {
+				return fmt.Errorf("Port cannot be 0")
+			}
+
+			return nil
+		},
+	}))
+
+	tests := []struct {
+		name   string
+		key    string
+		value  string
+		status int
+	}{
+		{
+			name:  "Valid bool",
+			key:   "user.enabled",
+			value: "true",
+		},
+		{
+			name:   "Invalid bool",
+			key:    "user.enabled",
+			value:  "yes please",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:  "Valid duration",
+			key:   "user.interval",
+			value: "10s",
+		},
+		{
+			name:   "Invalid duration",
+			key:    "user.interval",
+			value:  "10",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:  "Value accepted by the validator",
+			key:   "user.port",
+			value: "8443",
+		},
+		{
+			name:   "Value rejected by the validator",
+			key:    "user.port",
+			value:  "0",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Value of the wrong type is not passed to the validator",
+			key:    "user.port",
+			value:  "port",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:  "Empty value resets the key",
+			key:   "user.port",
+			value: "",
+		},
+		{
+			name:   "Unknown key",
+			key:    "user.unknown",
+			value:  "value",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		err := r.Validate(test.key, test.value)
+		if test.status == 0 {
+			s.NoError(err)
+		} else {
+			s.True(api.StatusErrorCheck(err, test.status), "Unexpected error: %v", err)
+		}
+	}
+
+	_, err := r.Default("user.unknown")
+	s.True(api.StatusErrorCheck(err, http.StatusNotFound))
+
+	s.Equal([]string{"user.enabled", "user.interval", "user.port"}, r.Keys())
+}
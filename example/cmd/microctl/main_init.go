@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/canonical/microcluster/microcluster"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 type cmdInit struct {
@@ -17,6 +19,7 @@ type cmdInit struct {
 	flagBootstrap bool
 	flagToken     string
 	flagConfig    []string
+	flagDryRun    bool
 }
 
 func (c *cmdInit) command() *cobra.Command {
@@ -25,13 +28,17 @@ func (c *cmdInit) command() *cobra.Command {
 		Short: "Initialize the network endpoint and create or join a new cluster",
 		RunE:  c.run,
 		Example: `  microctl init member1 127.0.0.1:8443 --bootstrap
-    microctl init member1 127.0.0.1:8443 --token <token>`,
+    microctl init member1 eth1:8443 --bootstrap
+    microctl init member1 127.0.0.1:8443 --token <token>
+    microctl init member1 127.0.0.1:8443 --token <token> --dry-run`,
 	}
 
 	cmd.Flags().BoolVar(&c.flagBootstrap, "bootstrap", false, "Configure a new cluster with this daemon")
 	cmd.Flags().StringVar(&c.flagToken, "token", "", "Join a cluster with a join token")
 	cmd.Flags().StringSliceVar(&c.flagConfig, "config", nil, "Extra configuration to be applied during bootstrap")
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, "Check compatibility with the cluster without joining it")
 	cmd.MarkFlagsMutuallyExclusive("bootstrap", "token")
+	cmd.MarkFlagsMutuallyExclusive("bootstrap", "dry-run")
 
 	return cmd
 }
@@ -63,9 +70,45 @@ func (c *cmdInit) run(cmd *cobra.Command, args []string) error {
 		return m.NewCluster(ctx, args[0], args[1], conf)
 	}
 
+	if c.flagToken != "" && c.flagDryRun {
+		return c.checkJoin(ctx, m)
+	}
+
 	if c.flagToken != "" {
-		return m.JoinCluster(ctx, args[0], args[1], c.flagToken, conf)
+		err := m.JoinCluster(ctx, args[0], args[1], c.flagToken, conf)
+
+		// Print the schema version this node needs if the cluster is ahead.
+		var mismatch types.SchemaVersionMismatch
+		if errors.As(err, &mismatch) && mismatch.Behind() {
+			return fmt.Errorf("This node needs schema version (internal %d, external %d) and API extensions %v to join the cluster, please upgrade: %w", mismatch.ClusterSchemaInternal, mismatch.ClusterSchemaExternal, mismatch.MissingExtensions, err)
+		}
+
+		return err
 	}
 
 	return fmt.Errorf("Option must be one of bootstrap or token")
 }
+
+// checkJoin prints whether this daemon is compatible with each cluster member in the join token.
+func (c *cmdInit) checkJoin(ctx context.Context, m *microcluster.MicroCluster) error {
+	report, err := m.CheckJoin(ctx, c.flagToken)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range report.Members {
+		if !member.Reachable {
+			fmt.Printf("%s: unreachable: %s\n", member.Address, member.Error)
+		} else if member.Mismatch != nil {
+			fmt.Printf("%s: incompatible: %s\n", member.Address, member.Mismatch.Error())
+		} else {
+			fmt.Printf("%s: compatible\n", member.Address)
+		}
+	}
+
+	if !report.Compatible {
+		return fmt.Errorf("This node cannot join the cluster")
+	}
+
+	return nil
+}
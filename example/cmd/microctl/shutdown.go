@@ -8,6 +8,8 @@ import (
 
 type cmdShutdown struct {
 	common *CmdControl
+
+	flagForce bool
 }
 
 func (c *cmdShutdown) command() *cobra.Command {
@@ -17,6 +19,8 @@ func (c *cmdShutdown) command() *cobra.Command {
 		RunE:  c.run,
 	}
 
+	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, "Shut down without handing off leadership or running the pre-shutdown hook")
+
 	return cmd
 }
 
@@ -39,7 +43,7 @@ func (c *cmdShutdown) run(cmd *cobra.Command, args []string) error {
 	go func() {
 		defer close(chResult)
 
-		err := client.ShutdownDaemon(cmd.Context())
+		err := client.ShutdownDaemon(cmd.Context(), c.flagForce)
 		if err != nil {
 			chResult <- err
 			return
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/canonical/lxd/shared/logger"
@@ -75,7 +76,7 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 	// exampleHooks are some example post-action hooks that can be run by MicroCluster.
 	exampleHooks := &config.Hooks{
 		// PostBootstrap is run after the daemon is initialized and bootstrapped.
-		PostBootstrap: func(s *state.State, initConfig map[string]string) error {
+		PostBootstrap: func(ctx context.Context, s *state.State, initConfig map[string]string) error {
 			logCtx := logger.Ctx{}
 			for k, v := range initConfig {
 				logCtx[k] = v
@@ -110,7 +111,7 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
-		PreBootstrap: func(s *state.State, initConfig map[string]string) error {
+		PreBootstrap: func(ctx context.Context, s *state.State, initConfig map[string]string) error {
 			logCtx := logger.Ctx{}
 			for k, v := range initConfig {
 				logCtx[k] = v
@@ -129,6 +130,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
+		// OnDatabaseReady is run after the database is open and ready for queries.
+		OnDatabaseReady: func(ctx context.Context, s *state.State) error {
+			logger.Info("This is a hook that runs after the database is bootstrapped, joined, or re-established with the cluster")
+
+			return nil
+		},
+
 		// PostJoin is run after the daemon is initialized and joins a cluster.
 		PostJoin: func(s *state.State, initConfig map[string]string) error {
 			logCtx := logger.Ctx{}
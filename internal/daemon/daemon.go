@@ -2,15 +2,27 @@ package daemon
 
 import (
 	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/db/schema"
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
@@ -19,15 +31,22 @@ import (
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/renameio"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/config"
 	"github.com/canonical/microcluster/internal/db"
+	"github.com/canonical/microcluster/internal/db/update"
 	"github.com/canonical/microcluster/internal/endpoints"
+	"github.com/canonical/microcluster/internal/events"
 	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/internal/logging"
+	"github.com/canonical/microcluster/internal/operations"
 	internalREST "github.com/canonical/microcluster/internal/rest"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	"github.com/canonical/microcluster/internal/rest/resources"
@@ -45,6 +64,7 @@ type Daemon struct {
 
 	address api.URL // Listen Address.
 	name    string  // Name of the cluster member.
+	iface   string  // Network interface from which the listen address is resolved, if any.
 
 	os         *sys.OS
 	serverCert *shared.CertInfo
@@ -53,56 +73,140 @@ type Daemon struct {
 	clusterCert *shared.CertInfo
 
 	endpoints *endpoints.Endpoints
+	ctlSocket *endpoints.Socket // The control socket, also tracked by endpoints.
 	db        *db.DB
 
 	fsWatcher  *sys.Watcher
 	trustStore *trust.Store
 
-	hooks config.Hooks // Hooks to be called upon various daemon actions.
+	hooks           config.Hooks // Hooks to be called upon various daemon actions.
+	registeredHooks []string     // Names of the hooks set by the application, before defaults are applied.
 
 	ReadyChan      chan struct{}      // Closed when the daemon is fully ready.
+	runtimeErrors  chan error         // Receives non-fatal errors encountered while the daemon is running.
 	shutdownCtx    context.Context    // Cancelled when shutdown starts.
 	shutdownDoneCh chan error         // Receives the result of state.Stop() when exit() is called and tells the daemon to end.
 	shutdownCancel context.CancelFunc // Cancels the shutdownCtx to indicate shutdown starting.
+	forceShutdown  atomic.Bool        // Whether shutdown skips handing off leadership and the PreShutdown hook.
 
 	Extensions extensions.Extensions // Extensions supported at runtime by the daemon.
 
+	operations *operations.Operations // Long-running operations on this cluster member.
+	draining   atomic.Bool            // Whether this cluster member is draining ahead of its removal.
+	syncing    atomic.Bool            // Whether this cluster member is catching up with the leader after starting or joining.
+	syncedCh   chan struct{}          // Closed once this cluster member has finished catching up with the leader.
+	syncedOnce sync.Once              // Guards closing syncedCh.
+
+	workers        sync.WaitGroup // Background workers registered through the state, joined on shutdown.
+	workersMu      sync.Mutex     // Guards registration of workers against shutdown.
+	workersStopped bool           // Set once shutdown has started waiting for workers.
+
+	clientPool *internalClient.Pool // Cached clients to other cluster members.
+
+	extensionCache *state.ExtensionCache // Cached API extensions of other cluster members.
+
 	// stop is a sync.Once which wraps the daemon's stop sequence. Each call will block until the first one completes.
 	stop func() error
 
 	extensionServers []rest.Server
+
+	memberName      string // Name of the cluster member to use before it is bootstrapped or joins a cluster.
+	maxRequestBytes int64  // Default maximum size of request bodies for all servers.
+
+	dialFunc func(ctx context.Context, address string) (net.Conn, error) // Optional dialer for dqlite connections.
+
+	dqliteAddress string // Optional address for dqlite connections between cluster members, separate from the API.
+
+	dqliteLog dqliteClient.LogFunc // Optional log function for dqlite's own log messages.
+
+	rateLimiter *internalREST.RateLimiter // Optional rate limiter for the public API.
+
+	endpointLimits   map[string]chan struct{} // Semaphores of endpoints with MaxConcurrent, shared by every listener.
+	endpointLimitsMu sync.Mutex
+
+	responseHeaders map[string]string // Static headers added to every response, or removed if empty.
+
+	compressionThreshold int // Response size in bytes above which responses are compressed, or negative to disable.
+
+	events *events.Bus // Distributes events emitted by the daemon to subscribers of its event stream.
+
+	serverTimeouts types.ServerTimeouts // Timeouts of the HTTP servers of every listener.
+
+	verifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error // Extra check of client certificates.
+
+	networkDisabled bool // Whether the daemon serves its API over the control socket only.
+
+	replicaOnly bool // Whether the daemon only ever holds the dqlite spare role and rejects writes.
+
+	recoveryMode bool // Whether the daemon serves the control socket without starting dqlite, to recover from quorum loss.
+
+	asyncPostJoin bool // Whether the PostJoin hook runs as a background operation, rather than before the join completes.
+
+	syncTimeout             time.Duration // Longest a newly joined member reports itself as syncing, or 0 to not do so.
+	rejectReadsWhileSyncing bool          // Whether reads over the network are rejected while syncing.
+
+	configRegistry *config.Registry // Schema of the cluster-wide configuration.
+
+	trustAudit func(event types.TrustAuditEvent) // Optional hook for changes to the trust store.
+
+	schemaExtensions update.SchemaExtensions // Named schema extensions, applied after the external schema updates.
+
+	allowRemoteSQL bool // Whether SQL queries are allowed from other cluster members, and not just the unix socket.
+
+	failureDomain cluster.FailureDomain // Placement of this cluster member, used to spread dqlite voters.
+
+	databaseDir string // Optional directory for the database, in place of one within the state directory.
+
+	controlSocket string // Optional address of the control socket, in place of the socket file in the state directory.
+
+	targetVoters   int // Target number of dqlite voters, or zero for the default.
+	targetStandBys int // Target number of dqlite stand-bys, or negative for the default.
+
+	snapshotThreshold uint64 // Raft log entries after which dqlite takes a snapshot, or zero for the default.
+	snapshotTrailing  uint64 // Raft log entries dqlite retains after a snapshot, or zero for the default.
+
+	clockSkewThreshold time.Duration // Clock difference with other members above which a warning is logged.
+
+	heartbeatHistoryDepth int // Number of recent heartbeat outcomes kept for each cluster member.
+
+	tracerProvider trace.TracerProvider // Optional OpenTelemetry tracer provider for spans of API requests.
+
+	bootstrapClusterCert *shared.CertInfo // Optional pre-generated cluster certificate to bootstrap with.
+
+	daemonConfigJSON bool // Whether the daemon configuration file is written as JSON rather than YAML.
+
+	lowDiskThreshold  int64         // Free space in the database directory below which it is low, or zero for the default.
+	diskCheckInterval time.Duration // How often the free space is checked, or zero for the default.
+	lowDisk           atomic.Bool   // Whether the free space in the database directory is below the threshold.
+
+	log logger.Logger // Logger for the daemon, its endpoints, and the heartbeat.
 }
 
+// runtimeErrorsBuffer is the number of runtime errors kept until they are received. Further errors are dropped.
+const runtimeErrorsBuffer = 16
+
 // NewDaemon initializes the Daemon context and channels.
-func NewDaemon(project string) *Daemon {
+// If log is nil, the daemon logs through the package-global logger.
+func NewDaemon(project string, log logger.Logger) *Daemon {
+	if log == nil {
+		log = logging.Global()
+	}
+
 	d := &Daemon{
 		shutdownDoneCh: make(chan error),
 		ReadyChan:      make(chan struct{}),
+		syncedCh:       make(chan struct{}),
+		runtimeErrors:  make(chan error, runtimeErrorsBuffer),
 		project:        project,
+		operations:     operations.NewOperations(),
+		clientPool:     internalClient.NewPool(),
+		extensionCache: state.NewExtensionCache(),
+		events:         events.NewBus(),
+		log:            log,
+		targetStandBys: -1,
 	}
 
-	d.stop = sync.OnceValue(func() error {
-		if d.shutdownCancel != nil {
-			d.shutdownCancel()
-		}
-
-		var dqliteErr error
-		if d.db != nil {
-			dqliteErr = d.db.Stop()
-			if dqliteErr != nil {
-				logger.Error("Failed shutting down database", logger.Ctx{"error": dqliteErr})
-			}
-		}
-
-		if d.endpoints != nil {
-			err := d.endpoints.Down()
-			if err != nil {
-				return err
-			}
-		}
-
-		return dqliteErr
-	})
+	d.stop = sync.OnceValue(d.shutdown)
 
 	return d
 }
@@ -126,18 +230,23 @@ func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, so
 		return fmt.Errorf("Failed to find state directory: %w", err)
 	}
 
-	d.os, err = sys.DefaultOS(stateDir, socketGroup, true)
+	d.os, err = sys.DefaultOS(stateDir, d.databaseDir, d.controlSocket, socketGroup, true)
 	if err != nil {
 		return fmt.Errorf("Failed to initialize directory structure: %w", err)
 	}
 
+	err = d.os.CheckDatabaseLocation()
+	if err != nil {
+		return err
+	}
+
 	// Clean up the daemon state on an error during init.
 	reverter := revert.New()
 	defer reverter.Fail()
 	reverter.Add(func() {
 		err := d.stop()
 		if err != nil {
-			logger.Error("Failed to cleanly stop the daemon", logger.Ctx{"error": err})
+			d.log.Error("Failed to cleanly stop the daemon", logger.Ctx{"error": err})
 		}
 	})
 
@@ -148,11 +257,21 @@ func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, so
 		return fmt.Errorf("Daemon failed to start: %w", err)
 	}
 
+	d.watchDiskSpace()
+
 	err = d.hooks.OnStart(d.State())
 	if err != nil {
 		return fmt.Errorf("Failed to run post-start hook: %w", err)
 	}
 
+	// Only report the daemon as ready once it has caught up with the leader, if it restarted as a cluster member.
+	if d.syncing.Load() {
+		select {
+		case <-d.syncedCh:
+		case <-ctx.Done():
+		}
+	}
+
 	close(d.ReadyChan)
 
 	reverter.Success()
@@ -167,13 +286,304 @@ func (d *Daemon) Run(ctx context.Context, listenPort string, stateDir string, so
 	}
 }
 
+// SetDialFunc sets the function used to connect to other cluster members for dqlite traffic, in place of a direct
+// TCP connection to their address. It must be called before Run.
+func (d *Daemon) SetDialFunc(dial func(ctx context.Context, address string) (net.Conn, error)) {
+	d.dialFunc = dial
+}
+
+// SetDqliteAddress sets a dedicated address at which this cluster member accepts dqlite connections from other members,
+// so that database replication can be kept on a separate network from the API. Once set, dqlite connections are no
+// longer accepted at the API address. The address is shared with the other cluster members, which connect to it for
+// dqlite traffic. It must be called before Run.
+func (d *Daemon) SetDqliteAddress(address string) {
+	d.dqliteAddress = address
+}
+
+// SetDqliteLogFunc sets the function that receives dqlite's own log messages, such as those about raft elections and
+// snapshots, in place of dqlite's default of logging errors only. It must be called before Run.
+func (d *Daemon) SetDqliteLogFunc(log dqliteClient.LogFunc) {
+	d.dqliteLog = log
+}
+
+// SetMemberName sets the name of the cluster member to use before it is bootstrapped or joins a cluster. It is ignored
+// once the member is initialized. If empty, the hostname is used, or a name derived from the server certificate if
+// there is no hostname. It must be called before Run.
+func (d *Daemon) SetMemberName(name string) {
+	d.memberName = name
+}
+
+// SetMaxRequestBytes sets the default maximum size of request bodies for all servers, unless overridden by a server
+// or endpoint. Zero uses a default of 32MiB, and a negative value disables the limit. It must be called before Run.
+func (d *Daemon) SetMaxRequestBytes(maxRequestBytes int64) {
+	d.maxRequestBytes = maxRequestBytes
+}
+
+// SetRateLimits enables rate limiting of requests to the public API over the network. It must be called before Run.
+func (d *Daemon) SetRateLimits(limits types.RateLimits) {
+	if !limits.Enabled() {
+		d.rateLimiter = nil
+		return
+	}
+
+	d.rateLimiter = internalREST.NewRateLimiter(limits)
+}
+
+// SetSchemaExtensions sets the named schema extensions, whose versions are tracked separately from the schema
+// updates given to Run. It must be called before Run.
+func (d *Daemon) SetSchemaExtensions(schemaExtensions update.SchemaExtensions) {
+	d.schemaExtensions = schemaExtensions
+}
+
+// SetNetworkDisabled sets whether the daemon serves its API over the control socket only, without any network
+// listeners. Such a daemon can only bootstrap a single-member cluster, as other members could not reach it.
+// It must be called before Run.
+func (d *Daemon) SetNetworkDisabled(disabled bool) {
+	d.networkDisabled = disabled
+}
+
+// SetReplicaOnly sets whether the daemon joins the cluster as a read-only replica. Such a member only ever holds the
+// dqlite spare role, so it never votes or stands by, and it rejects writes to the public API. It can't bootstrap a
+// cluster, and can't start unless enough other cluster members can hold the targeted voter and stand-by roles.
+// It must be called before Run.
+func (d *Daemon) SetReplicaOnly(replicaOnly bool) {
+	d.replicaOnly = replicaOnly
+}
+
+// SetConfigRegistry sets the schema of the cluster-wide configuration, enforced by state.GetConfig and state.SetConfig.
+// It must be called before Run.
+func (d *Daemon) SetConfigRegistry(registry *config.Registry) {
+	d.configRegistry = registry
+}
+
+// SetRecoveryMode sets whether an initialized daemon starts without dqlite, serving only the control socket, so that
+// the database can be recovered with state.RecoverFromQuorumLoss. It must be called before Run.
+func (d *Daemon) SetRecoveryMode(recoveryMode bool) {
+	d.recoveryMode = recoveryMode
+}
+
+// SetAsyncPostJoin sets whether the PostJoin hook runs in the background as a registered operation, so that a join
+// completes as soon as the member is part of the cluster. The progress and result of the hook can then be observed
+// through the operations API, in the operation described by types.OperationPostJoin. It must be called before Run.
+func (d *Daemon) SetAsyncPostJoin(async bool) {
+	d.asyncPostJoin = async
+}
+
+// SetSyncGate sets how long at most a cluster member reports itself as syncing, rather than ready, while it catches up
+// with the leader after joining the cluster or restarting. The member is considered caught up once its dqlite node has
+// finished joining and taking on its role, and it has then received a heartbeat from the leader. As database queries
+// are executed by the dqlite leader, the lag of the local replica is not measured. The leader is always caught up.
+// Until then, ReadyChan is not closed when restarting. If rejectReads is true, reads from the public API over the
+// network are also rejected while syncing. A timeout of zero disables the gate. It must be called before Run.
+func (d *Daemon) SetSyncGate(timeout time.Duration, rejectReads bool) {
+	d.syncTimeout = timeout
+	d.rejectReadsWhileSyncing = rejectReads
+}
+
+// SetResponseHeaders sets static headers, such as Strict-Transport-Security, to add to every API response.
+// Headers set by endpoint handlers take precedence. A header with an empty value is instead removed from every
+// response. It must be called before Run.
+func (d *Daemon) SetResponseHeaders(headers map[string]string) {
+	d.responseHeaders = headers
+}
+
+// SetCompressionThreshold sets the size in bytes above which API responses are compressed with gzip for clients that
+// accept it. Zero uses the default of 1KiB, and a negative value disables compression. It must be called before Run.
+func (d *Daemon) SetCompressionThreshold(threshold int) {
+	d.compressionThreshold = threshold
+}
+
+// SetServerTimeouts sets the timeouts of the HTTP servers of the unix socket and every network listener. Zero fields use
+// the defaults, and negative fields disable the timeout. It must be called before Run.
+func (d *Daemon) SetServerTimeouts(timeouts types.ServerTimeouts) {
+	d.serverTimeouts = timeouts
+}
+
+// SetVerifyPeerCertificate sets a function to enforce extra policy on the certificates presented by clients of the
+// core API and extension server listeners, such as requiring a custom extension. It is installed as the
+// VerifyPeerCertificate function of their TLS configuration, so it runs after Go's own checks of the handshake, and an
+// error aborts the handshake with a TLS alert. Client certificates are requested but not verified against any CA, so
+// verifiedChains is always nil, and the function must parse and check rawCerts itself. Clients that present no
+// certificate are not checked, and whether a certificate is trusted by the cluster is still checked for each request.
+// It must be called before Run.
+func (d *Daemon) SetVerifyPeerCertificate(verify func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error) {
+	d.verifyPeerCertificate = verify
+}
+
+// SetDatabaseDir sets the directory in which the database is stored, in place of the default directory within the
+// state directory. It must be an existing, writable directory. An existing database is not moved, so the daemon refuses
+// to start if the default directory still holds one. It must be called before Run.
+func (d *Daemon) SetDatabaseDir(dir string) {
+	d.databaseDir = dir
+}
+
+// SetControlSocket sets the address of the control socket, in place of the control.socket file in the state
+// directory. Addresses starting with "@" are bound in the Linux abstract namespace, which leaves no stale socket file
+// behind. As they have no file permissions, the peer credentials of each connection are checked instead, allowing
+// root, the user of the daemon, and members of the socket group. It must be called before Run.
+func (d *Daemon) SetControlSocket(address string) {
+	d.controlSocket = address
+}
+
+// SetFailureDomain sets the failure domain of this cluster member, such as its rack or availability zone, along with
+// free-form tags describing its placement. dqlite prefers to spread voters across distinct failure domains, so that
+// the loss of a single domain does not lose quorum. It must be called before Run.
+func (d *Daemon) SetFailureDomain(code uint64, tags map[string]string) {
+	d.failureDomain = cluster.FailureDomain{Code: code, Tags: tags}
+}
+
+// DefaultLowDiskThreshold and DefaultDiskCheckInterval are used if no disk space threshold or interval is configured.
+const (
+	DefaultLowDiskThreshold  = 256 * 1024 * 1024
+	DefaultDiskCheckInterval = time.Minute
+)
+
+// SetDiskSpaceCheck sets the number of free bytes in the database directory below which the cluster member is low on
+// disk space, and how often it is checked. While it is low, a warning is logged and the member reports itself as
+// unavailable to health checks. Zero values use the defaults, and a negative threshold disables the check.
+// It must be called before Run.
+func (d *Daemon) SetDiskSpaceCheck(threshold int64, interval time.Duration) {
+	d.lowDiskThreshold = threshold
+	d.diskCheckInterval = interval
+}
+
+// SetDaemonConfigJSON sets whether the daemon configuration in the state directory is written as daemon.json rather
+// than daemon.yaml. Either file is read if present, and an existing file in the other format is converted the next
+// time the configuration is written. It must be called before Run.
+func (d *Daemon) SetDaemonConfigJSON(enabled bool) {
+	d.daemonConfigJSON = enabled
+}
+
+// SetBootstrapClusterCert sets a pre-generated cluster keypair, such as one signed by an external CA, to bootstrap the
+// cluster with instead of generating a self-signed one. The certificate must be usable for server authentication and
+// include the CA that signed it, which joining members receive along with the keypair. It is ignored unless the
+// daemon bootstraps a new cluster. It must be called before Run.
+func (d *Daemon) SetBootstrapClusterCert(cert *shared.CertInfo) {
+	d.bootstrapClusterCert = cert
+}
+
+// SetTracerProvider sets the OpenTelemetry tracer provider used to start a span for each API request, continuing any
+// trace propagated by the caller. Requests to other cluster members made with the request context are child spans.
+// If unset, requests are not traced. It must be called before Run.
+func (d *Daemon) SetTracerProvider(provider trace.TracerProvider) {
+	d.tracerProvider = provider
+}
+
+// SetClockSkewThreshold sets the difference between the clocks of this cluster member and another, above which a
+// warning is logged when the leader sends a heartbeat. Zero uses the default of 5 seconds, and a negative value
+// disables the warning. It must be called before Run.
+func (d *Daemon) SetClockSkewThreshold(threshold time.Duration) {
+	d.clockSkewThreshold = threshold
+}
+
+// SetHeartbeatHistoryDepth sets the number of recent heartbeat outcomes that the leader keeps in memory for each
+// cluster member. Zero uses the default of 64, and a negative value disables the history. It must be called before Run.
+func (d *Daemon) SetHeartbeatHistoryDepth(depth int) {
+	d.heartbeatHistoryDepth = depth
+}
+
+// SetTargetRoles sets the number of dqlite voters and stand-bys that automatic role assignment targets. A number of
+// voters of zero, or a negative number of stand-bys, uses the default of 3. The number of voters must be odd. All
+// cluster members must use the same values.
+// It must be called before Run.
+func (d *Daemon) SetTargetRoles(voters int, standBys int) {
+	d.targetVoters = voters
+	d.targetStandBys = standBys
+}
+
+// SetSnapshotParams sets the number of raft log entries after which dqlite takes a snapshot, and the number of entries
+// it retains after a snapshot, with zero using dqlite's default for either. See db.DB.SetSnapshotParams for the
+// trade-offs. It must be called before Run.
+func (d *Daemon) SetSnapshotParams(threshold uint64, trailing uint64) {
+	d.snapshotThreshold = threshold
+	d.snapshotTrailing = trailing
+}
+
+// SetAllowRemoteSQL sets whether SQL queries against the database are allowed from other cluster members.
+// By default, they are only allowed over the unix socket. It must be called before Run.
+func (d *Daemon) SetAllowRemoteSQL(allow bool) {
+	d.allowRemoteSQL = allow
+}
+
+// SetTrustAuditHook sets a hook that receives an event whenever a remote is added to or removed from the trust store.
+// It must be called before Run.
+func (d *Daemon) SetTrustAuditHook(audit func(event types.TrustAuditEvent)) {
+	d.trustAudit = audit
+}
+
+// SetErrorChannel replaces the channel returned by Errors. It must be called before Run.
+func (d *Daemon) SetErrorChannel(errors chan error) {
+	if errors != nil {
+		d.runtimeErrors = errors
+	}
+}
+
+// Errors returns a channel that receives the non-fatal errors, of type types.RuntimeError, that the daemon encounters
+// while running, such as an API listener stopping or a heartbeat round failing. The daemon keeps running after these
+// errors, unlike fatal errors which are returned by Run. Errors are dropped while the channel is full.
+func (d *Daemon) Errors() <-chan error {
+	return d.runtimeErrors
+}
+
+// errorHandler returns a function that sends errors from the given source to the Errors channel without blocking.
+func (d *Daemon) errorHandler(source types.RuntimeErrorSource) func(err error) {
+	return func(err error) {
+		d.events.Publish(types.Event{
+			Type:     types.EventError,
+			Time:     time.Now(),
+			Member:   d.Name(),
+			Message:  err.Error(),
+			Metadata: map[string]string{"source": string(source)},
+		})
+
+		select {
+		case d.runtimeErrors <- types.RuntimeError{Source: source, Err: err}:
+		default:
+			d.log.Warn("Dropped runtime error as the error channel is full", logger.Ctx{"source": source, "error": err})
+		}
+	}
+}
+
+// defaultName returns the name of the cluster member to use if none is given, which is the hostname of the system. If
+// the hostname can't be determined, such as in a minimal container, a name derived from the fingerprint of the server
+// certificate is used instead, so that it stays the same across restarts.
+func (d *Daemon) defaultName() (string, error) {
+	hostname, err := os.Hostname()
+	if err == nil && hostname != "" {
+		return hostname, nil
+	} else if err == nil {
+		err = fmt.Errorf("Hostname is empty")
+	}
+
+	if d.serverCert == nil {
+		return "", fmt.Errorf("Failed to determine hostname, and no server certificate exists to derive a name from: %w", err)
+	}
+
+	name := "member-" + d.serverCert.Fingerprint()[:12]
+	d.log.Warn("Failed to determine hostname, using a generated cluster member name instead", logger.Ctx{"name": name, "error": err})
+
+	return name, nil
+}
+
+// WaitReady blocks until the daemon is ready, or the given context is done.
+func (d *Daemon) WaitReady(ctx context.Context) error {
+	select {
+	case <-d.ReadyChan:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Timed out waiting for the daemon to be ready: %w", ctx.Err())
+	}
+}
+
 func (d *Daemon) init(listenPort string, schemaExtensions []schema.Update, apiExtensions []string, hooks *config.Hooks) error {
 	d.applyHooks(hooks)
 
 	var err error
-	d.name, err = os.Hostname()
-	if err != nil {
-		return fmt.Errorf("Failed to assign default system name: %w", err)
+	if d.memberName != "" {
+		err = trust.ValidateName(d.memberName)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Initialize the extensions registry with the internal extensions.
@@ -188,17 +598,57 @@ func (d *Daemon) init(listenPort string, schemaExtensions []schema.Update, apiEx
 		return err
 	}
 
+	err = generateServerCert(d.os.StateDir)
+	if err != nil {
+		return fmt.Errorf("Failed to generate server certificate: %w", err)
+	}
+
 	d.serverCert, err = util.LoadServerCert(d.os.StateDir)
 	if err != nil {
 		return err
 	}
 
+	d.name = d.memberName
+	if d.name == "" {
+		d.name, err = d.defaultName()
+		if err != nil {
+			return fmt.Errorf("Failed to assign default system name: %w", err)
+		}
+	}
+
 	err = d.initStore()
 	if err != nil {
 		return fmt.Errorf("Failed to initialize trust store: %w", err)
 	}
 
+	d.setStateHooks()
+
 	d.db = db.NewDB(d.shutdownCtx, d.serverCert, d.ClusterCert, d.os)
+	d.db.SetErrorHandler(d.errorHandler(types.RuntimeErrorHeartbeat))
+
+	if d.networkDisabled && listenPort != "" {
+		return fmt.Errorf("Cannot set a listen port while the network API is disabled")
+	}
+
+	if d.dqliteAddress != "" {
+		if d.networkDisabled {
+			return fmt.Errorf("Cannot set a dqlite address while the network API is disabled")
+		}
+
+		addrPort, err := types.ParseAddrPort(d.dqliteAddress)
+		if err != nil {
+			return fmt.Errorf("Invalid dqlite address %q: %w", d.dqliteAddress, err)
+		}
+
+		// Other cluster members connect to the address, so it can't be a wildcard address.
+		if addrPort.Addr().IsUnspecified() || addrPort.Port() == 0 {
+			return fmt.Errorf("Dqlite address %q must have a specific IP address and port", d.dqliteAddress)
+		}
+
+		d.db.SetDqliteAddress(addrPort.String())
+	}
+
+	d.db.SetDialAddressFunc(d.dqliteDialAddress)
 
 	listenAddr := api.NewURL()
 	if listenPort != "" {
@@ -210,25 +660,36 @@ func (d *Daemon) init(listenPort string, schemaExtensions []schema.Update, apiEx
 		return err
 	}
 
-	serverEndpoints := []rest.Resources{
-		resources.UnixEndpoints,
-		resources.InternalEndpoints,
-		resources.PublicEndpoints,
+	// Servers can only restrict their advertised extensions to those that are registered.
+	for _, server := range d.extensionServers {
+		for _, extension := range server.AdvertisedExtensions {
+			if !d.Extensions.HasExtension(extension) {
+				return fmt.Errorf("Server advertises unregistered API extension %q", extension)
+			}
+		}
 	}
 
+	unixServers := []rest.Server{{
+		Resources: []rest.Resources{
+			resources.UnixEndpoints,
+			resources.InternalEndpoints,
+			resources.PublicEndpoints,
+		},
+	}}
+
 	for _, server := range d.extensionServers {
-		if server.ServeUnix {
-			serverEndpoints = append(serverEndpoints, server.Resources...)
+		if server.ServeUnix || server.UnixOnly {
+			unixServers = append(unixServers, server)
 		}
 	}
 
-	err = d.startUnixServer(serverEndpoints)
+	err = d.startUnixServer(unixServers)
 	if err != nil {
 		return err
 	}
 
 	if listenPort != "" {
-		serverEndpoints = []rest.Resources{resources.PublicEndpoints}
+		serverEndpoints := []rest.Resources{resources.PublicEndpoints}
 		err = d.addCoreServers(true, *listenAddr, d.ServerCert(), serverEndpoints)
 		if err != nil {
 			return err
@@ -236,12 +697,35 @@ func (d *Daemon) init(listenPort string, schemaExtensions []schema.Update, apiEx
 	}
 
 	// Add extension servers before post-join hook.
-	err = d.addExtensionServers(true, d.ServerCert(), listenAddr.URL.Host)
+	if !d.networkDisabled {
+		err = d.addExtensionServers(true, d.ServerCert(), listenAddr.URL.Host)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.db.SetSchema(schemaExtensions, d.Extensions, d.schemaExtensions...)
+	d.db.SetFailureDomain(d.failureDomain.Code)
+	err = d.db.SetTargetRoles(d.targetVoters, d.targetStandBys)
+	if err != nil {
+		return err
+	}
+
+	err = d.db.SetSnapshotParams(d.snapshotThreshold, d.snapshotTrailing)
 	if err != nil {
 		return err
 	}
 
-	d.db.SetSchema(schemaExtensions, d.Extensions)
+	if d.dialFunc != nil {
+		d.db.SetDialFunc(d.dialFunc)
+	}
+
+	if d.dqliteLog != nil {
+		d.db.SetLogFunc(d.dqliteLog)
+	}
+
+	d.db.SetClockSkewThreshold(d.clockSkewThreshold)
+	d.db.SetHeartbeatHistoryDepth(d.heartbeatHistoryDepth)
 
 	err = d.reloadIfBootstrapped()
 	if err != nil {
@@ -259,8 +743,15 @@ func (d *Daemon) init(listenPort string, schemaExtensions []schema.Update, apiEx
 func (d *Daemon) applyHooks(hooks *config.Hooks) {
 	// Apply a no-op hooks for any missing hooks.
 	noOpHook := func(s *state.State) error { return nil }
+	noOpCtxHook := func(ctx context.Context, s *state.State) error { return nil }
 	noOpRemoveHook := func(s *state.State, force bool) error { return nil }
 	noOpInitHook := func(s *state.State, initConfig map[string]string) error { return nil }
+	noOpBootstrapHook := func(ctx context.Context, s *state.State, initConfig map[string]string) error { return nil }
+	noOpLeaderHook := func(ctx context.Context, s *state.State, isLeader bool) error { return nil }
+	noOpDiskHook := func(ctx context.Context, s *state.State, free uint64) error { return nil }
+	noOpConfigHook := func(ctx context.Context, s *state.State, oldConfig map[string]string, newConfig map[string]string) error {
+		return nil
+	}
 
 	if hooks == nil {
 		d.hooks = config.Hooks{}
@@ -268,12 +759,14 @@ func (d *Daemon) applyHooks(hooks *config.Hooks) {
 		d.hooks = *hooks
 	}
 
+	d.registeredHooks = registeredHooks(d.hooks)
+
 	if d.hooks.PreBootstrap == nil {
-		d.hooks.PreBootstrap = noOpInitHook
+		d.hooks.PreBootstrap = noOpBootstrapHook
 	}
 
 	if d.hooks.PostBootstrap == nil {
-		d.hooks.PostBootstrap = noOpInitHook
+		d.hooks.PostBootstrap = noOpBootstrapHook
 	}
 
 	if d.hooks.PostJoin == nil {
@@ -288,10 +781,26 @@ func (d *Daemon) applyHooks(hooks *config.Hooks) {
 		d.hooks.OnStart = noOpHook
 	}
 
+	if d.hooks.OnDatabaseReady == nil {
+		d.hooks.OnDatabaseReady = noOpCtxHook
+	}
+
 	if d.hooks.OnHeartbeat == nil {
 		d.hooks.OnHeartbeat = noOpHook
 	}
 
+	if d.hooks.HeartbeatPayload == nil {
+		d.hooks.HeartbeatPayload = func(s *state.State) ([]byte, error) { return nil, nil }
+	}
+
+	if d.hooks.OnHeartbeatPayloads == nil {
+		d.hooks.OnHeartbeatPayloads = func(s *state.State, payloads map[string][]byte) error { return nil }
+	}
+
+	if d.hooks.PreNewMember == nil {
+		d.hooks.PreNewMember = func(ctx context.Context, s *state.State, candidate types.ClusterMemberLocal) error { return nil }
+	}
+
 	if d.hooks.OnNewMember == nil {
 		d.hooks.OnNewMember = noOpHook
 	}
@@ -303,23 +812,155 @@ func (d *Daemon) applyHooks(hooks *config.Hooks) {
 	if d.hooks.PostRemove == nil {
 		d.hooks.PostRemove = noOpRemoveHook
 	}
+
+	if d.hooks.OnConfigChange == nil {
+		d.hooks.OnConfigChange = noOpConfigHook
+	}
+
+	if d.hooks.OnLeaderChange == nil {
+		d.hooks.OnLeaderChange = noOpLeaderHook
+	}
+
+	if d.hooks.PreShutdown == nil {
+		d.hooks.PreShutdown = noOpCtxHook
+	}
+
+	if d.hooks.OnLowDisk == nil {
+		d.hooks.OnLowDisk = noOpDiskHook
+	}
+
+	d.publishHookEvents()
+}
+
+// registeredHooks returns the names of the hooks that are set, sorted by name.
+func registeredHooks(hooks config.Hooks) []string {
+	set := map[internalTypes.HookType]bool{
+		internalTypes.PreBootstrap:        hooks.PreBootstrap != nil,
+		internalTypes.PostBootstrap:       hooks.PostBootstrap != nil,
+		internalTypes.PreJoin:             hooks.PreJoin != nil,
+		internalTypes.PostJoin:            hooks.PostJoin != nil,
+		internalTypes.OnStart:             hooks.OnStart != nil,
+		internalTypes.OnDatabaseReady:     hooks.OnDatabaseReady != nil,
+		internalTypes.OnHeartbeat:         hooks.OnHeartbeat != nil,
+		internalTypes.HeartbeatPayload:    hooks.HeartbeatPayload != nil,
+		internalTypes.OnHeartbeatPayloads: hooks.OnHeartbeatPayloads != nil,
+		internalTypes.PreNewMember:        hooks.PreNewMember != nil,
+		internalTypes.OnNewMember:         hooks.OnNewMember != nil,
+		internalTypes.PreRemove:           hooks.PreRemove != nil,
+		internalTypes.PostRemove:          hooks.PostRemove != nil,
+		internalTypes.OnConfigChange:      hooks.OnConfigChange != nil,
+		internalTypes.OnLeaderChange:      hooks.OnLeaderChange != nil,
+		internalTypes.PreShutdown:         hooks.PreShutdown != nil,
+		internalTypes.OnLowDisk:           hooks.OnLowDisk != nil,
+	}
+
+	names := make([]string, 0, len(set))
+	for name, ok := range set {
+		if ok {
+			names = append(names, string(name))
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// publishHookEvents wraps each hook so that an event is published whenever it has run.
+func (d *Daemon) publishHookEvents() {
+	hooks := d.hooks
+
+	d.hooks.PreBootstrap = func(ctx context.Context, s *state.State, initConfig map[string]string) error {
+		return d.hookEvent(s, internalTypes.PreBootstrap, hooks.PreBootstrap(ctx, s, initConfig))
+	}
+
+	d.hooks.PostBootstrap = func(ctx context.Context, s *state.State, initConfig map[string]string) error {
+		return d.hookEvent(s, internalTypes.PostBootstrap, hooks.PostBootstrap(ctx, s, initConfig))
+	}
+
+	d.hooks.PostJoin = func(s *state.State, initConfig map[string]string) error {
+		return d.hookEvent(s, internalTypes.PostJoin, hooks.PostJoin(s, initConfig))
+	}
+
+	d.hooks.PreJoin = func(s *state.State, initConfig map[string]string) error {
+		return d.hookEvent(s, internalTypes.PreJoin, hooks.PreJoin(s, initConfig))
+	}
+
+	d.hooks.OnStart = func(s *state.State) error {
+		return d.hookEvent(s, internalTypes.OnStart, hooks.OnStart(s))
+	}
+
+	d.hooks.OnDatabaseReady = func(ctx context.Context, s *state.State) error {
+		return d.hookEvent(s, internalTypes.OnDatabaseReady, hooks.OnDatabaseReady(ctx, s))
+	}
+
+	d.hooks.OnHeartbeat = func(s *state.State) error {
+		return d.hookEvent(s, internalTypes.OnHeartbeat, hooks.OnHeartbeat(s))
+	}
+
+	d.hooks.PreNewMember = func(ctx context.Context, s *state.State, candidate types.ClusterMemberLocal) error {
+		return d.hookEvent(s, internalTypes.PreNewMember, hooks.PreNewMember(ctx, s, candidate))
+	}
+
+	d.hooks.OnNewMember = func(s *state.State) error {
+		return d.hookEvent(s, internalTypes.OnNewMember, hooks.OnNewMember(s))
+	}
+
+	d.hooks.PreRemove = func(s *state.State, force bool) error {
+		return d.hookEvent(s, internalTypes.PreRemove, hooks.PreRemove(s, force))
+	}
+
+	d.hooks.PostRemove = func(s *state.State, force bool) error {
+		return d.hookEvent(s, internalTypes.PostRemove, hooks.PostRemove(s, force))
+	}
+
+	d.hooks.OnConfigChange = func(ctx context.Context, s *state.State, oldConfig map[string]string, newConfig map[string]string) error {
+		return d.hookEvent(s, internalTypes.OnConfigChange, hooks.OnConfigChange(ctx, s, oldConfig, newConfig))
+	}
+
+	d.hooks.OnLeaderChange = func(ctx context.Context, s *state.State, isLeader bool) error {
+		return d.hookEvent(s, internalTypes.OnLeaderChange, hooks.OnLeaderChange(ctx, s, isLeader))
+	}
+
+	d.hooks.PreShutdown = func(ctx context.Context, s *state.State) error {
+		return d.hookEvent(s, internalTypes.PreShutdown, hooks.PreShutdown(ctx, s))
+	}
+
+	d.hooks.OnLowDisk = func(ctx context.Context, s *state.State, free uint64) error {
+		return d.hookEvent(s, internalTypes.OnLowDisk, hooks.OnLowDisk(ctx, s, free))
+	}
+}
+
+// hookEvent publishes an event for the hook having run, and returns its error.
+func (d *Daemon) hookEvent(s *state.State, hook internalTypes.HookType, err error) error {
+	metadata := map[string]string{"hook": string(hook)}
+	message := fmt.Sprintf("Ran %s hook", hook)
+	if err != nil {
+		metadata["error"] = err.Error()
+		message = fmt.Sprintf("Failed to run %s hook: %v", hook, err)
+	}
+
+	s.PublishEvent(types.EventHook, message, metadata)
+
+	return err
 }
 
 func (d *Daemon) reloadIfBootstrapped() error {
+	// The dqlite node info is kept alongside the database, which may be outside the state directory.
 	_, err := os.Stat(filepath.Join(d.os.DatabaseDir, "info.yaml"))
 	if err != nil {
 		if os.IsNotExist(err) {
-			logger.Warn("microcluster database is uninitialized")
+			d.log.Warn("microcluster database is uninitialized")
 			return nil
 		}
 
 		return err
 	}
 
-	_, err = os.Stat(filepath.Join(d.os.StateDir, "daemon.yaml"))
+	_, _, err = d.findDaemonConfig()
 	if err != nil {
 		if os.IsNotExist(err) {
-			logger.Warn("microcluster daemon config is missing")
+			d.log.Warn("microcluster daemon config is missing")
 			return nil
 		}
 
@@ -328,10 +969,29 @@ func (d *Daemon) reloadIfBootstrapped() error {
 
 	err = d.setDaemonConfig(nil)
 	if err != nil {
-		return fmt.Errorf("Failed to retrieve daemon configuration yaml: %w", err)
+		return fmt.Errorf("Failed to retrieve daemon configuration: %w", err)
 	}
 
-	err = d.StartAPI(false, nil, nil)
+	// Renaming an initialized member must be done cluster-wide, so ignore any name given at startup.
+	if d.memberName != "" && d.memberName != d.name {
+		d.log.Warn("Ignoring member name for already initialized cluster member", logger.Ctx{"name": d.name, "ignored": d.memberName})
+	}
+
+	if d.recoveryMode {
+		d.log.Warn("Starting in recovery mode, the database will not be started")
+		return nil
+	}
+
+	loaded, err := d.db.LoadRecoveryTarball()
+	if err != nil {
+		return fmt.Errorf("Failed to load recovery tarball: %w", err)
+	}
+
+	if loaded {
+		d.log.Warn("Replaced the database with the recovered database of another cluster member")
+	}
+
+	err = d.StartAPI(false, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -351,10 +1011,51 @@ func (d *Daemon) initStore() error {
 		return err
 	}
 
+	if d.trustAudit != nil {
+		d.trustStore.Remotes().SetAuditHook(d.trustAudit)
+	}
+
+	d.trustStore.SetErrorHandler(d.errorHandler(types.RuntimeErrorTrustStore))
+
+	// Drop cached clients to cluster members that have been removed from the truststore.
+	d.trustStore.SetUpdateHandler(func(remotes *trust.Remotes) {
+		hosts := []string{}
+		for _, addr := range remotes.Addresses() {
+			hosts = append(hosts, addr.String())
+		}
+
+		d.clientPool.Prune(hosts)
+	})
+
 	return nil
 }
 
-func (d *Daemon) initServer(resources ...rest.Resources) *http.Server {
+// endpointLimit returns the semaphore limiting the number of requests handled at once by the endpoint, or nil if it
+// sets no MaxConcurrent. The same semaphore is returned each time for the endpoint, so that its limit is shared by its
+// aliases and every listener it is served on.
+func (d *Daemon) endpointLimit(prefix types.EndpointPrefix, e rest.Endpoint) chan struct{} {
+	if e.MaxConcurrent <= 0 {
+		return nil
+	}
+
+	d.endpointLimitsMu.Lock()
+	defer d.endpointLimitsMu.Unlock()
+
+	if d.endpointLimits == nil {
+		d.endpointLimits = map[string]chan struct{}{}
+	}
+
+	key := filepath.Join("/", string(prefix), e.Path)
+	inFlight, ok := d.endpointLimits[key]
+	if !ok {
+		inFlight = make(chan struct{}, e.MaxConcurrent)
+		d.endpointLimits[key] = inFlight
+	}
+
+	return inFlight
+}
+
+func (d *Daemon) initServer(servers ...rest.Server) *http.Server {
 	/* Setup the web server */
 	mux := mux.NewRouter()
 	mux.StrictSlash(false)
@@ -362,16 +1063,45 @@ func (d *Daemon) initServer(resources ...rest.Resources) *http.Server {
 	mux.UseEncodedPath()
 
 	state := d.State()
-	for _, endpoints := range resources {
-		for _, e := range endpoints.Endpoints {
-			internalREST.HandleEndpoint(state, mux, string(endpoints.PathPrefix), e)
+	for _, server := range servers {
+		maxRequestBytes := server.MaxRequestBytes
+		if maxRequestBytes == 0 {
+			maxRequestBytes = d.maxRequestBytes
+		}
+
+		if maxRequestBytes == 0 {
+			maxRequestBytes = internalREST.DefaultMaxRequestBytes
+		}
+
+		for _, endpoints := range server.Resources {
+			middleware := server.Middleware
+			if d.rateLimiter != nil && endpoints.PathPrefix == internalTypes.PublicEndpoint {
+				middleware = append([]func(http.Handler) http.Handler{d.rateLimiter.Middleware(state)}, middleware...)
+			}
+
+			if d.tracerProvider != nil {
+				middleware = append([]func(http.Handler) http.Handler{internalREST.Tracing(d.tracerProvider.Tracer(internalTypes.TracerName))}, middleware...)
+			}
+
+			for _, e := range endpoints.Endpoints {
+				if e.MaxRequestBytes == 0 {
+					e.MaxRequestBytes = maxRequestBytes
+				}
+
+				if e.Authenticator == nil {
+					e.Authenticator = server.Authenticator
+				}
+
+				inFlight := d.endpointLimit(endpoints.PathPrefix, e)
+				internalREST.HandleEndpoint(state, mux, string(endpoints.PathPrefix), e, inFlight, middleware...)
 
-			for _, alias := range e.Aliases {
-				ae := e
-				ae.Name = alias.Name
-				ae.Path = alias.Path
+				for _, alias := range e.Aliases {
+					ae := e
+					ae.Name = alias.Name
+					ae.Path = alias.Path
 
-				internalREST.HandleEndpoint(state, mux, string(endpoints.PathPrefix), ae)
+					internalREST.HandleEndpoint(state, mux, string(endpoints.PathPrefix), ae, inFlight, middleware...)
+				}
 			}
 		}
 	}
@@ -380,28 +1110,82 @@ func (d *Daemon) initServer(resources ...rest.Resources) *http.Server {
 		w.Header().Set("Content-Type", "application/json")
 		err := response.SyncResponse(true, []string{"/1.0"}).Render(w)
 		if err != nil {
-			logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			d.log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
 		}
 	})
 
 	mux.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("Sending top level 404", logger.Ctx{"url": r.URL})
+		d.log.Info("Sending top level 404", logger.Ctx{"url": r.URL})
 		w.Header().Set("Content-Type", "application/json")
 		err := response.NotFound(nil).Render(w)
 		if err != nil {
-			logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			d.log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
 		}
 	})
 
-	return &http.Server{
-		Handler:     mux,
+	var handler http.Handler = mux
+	if d.compressionThreshold >= 0 {
+		threshold := d.compressionThreshold
+		if threshold == 0 {
+			threshold = internalREST.DefaultCompressionThreshold
+		}
+
+		handler = internalREST.Compress(threshold)(handler)
+	}
+
+	if len(d.responseHeaders) > 0 {
+		handler = internalREST.ResponseHeaders(d.responseHeaders)(handler)
+	}
+
+	server := &http.Server{
+		Handler:     handler,
 		ConnContext: request.SaveConnectionInContext,
 	}
+
+	d.serverTimeouts.Apply(server)
+
+	return server
+}
+
+// runBootstrapHook runs the given bootstrap hook, with a context that is cancelled after the configured hook timeout.
+// If the hook does not return by then, an error is returned without waiting for it any further.
+func (d *Daemon) runBootstrapHook(name string, hook func(ctx context.Context, s *state.State, initConfig map[string]string) error, initConfig map[string]string) error {
+	if d.hooks.BootstrapHookTimeout <= 0 {
+		return hook(d.shutdownCtx, d.State(), initConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(d.shutdownCtx, d.hooks.BootstrapHookTimeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hook(ctx, d.State(), initConfig)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		if d.shutdownCtx.Err() != nil {
+			return fmt.Errorf("Daemon is shutting down: %w", d.shutdownCtx.Err())
+		}
+
+		d.log.Error("Hook exceeded its timeout", logger.Ctx{"hook": name, "timeout": d.hooks.BootstrapHookTimeout})
+
+		return fmt.Errorf("Hook %q did not complete within %s: %w", name, d.hooks.BootstrapHookTimeout, ctx.Err())
+	}
 }
 
 // StartAPI starts up the admin and consumer APIs, and generates a cluster cert
 // if we are bootstrapping the first node.
 func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfig *trust.Location, joinAddresses ...string) error {
+	if bootstrap && d.bootstrapClusterCert != nil {
+		err := validateClusterCert(d.bootstrapClusterCert)
+		if err != nil {
+			return fmt.Errorf("Invalid cluster certificate: %w", err)
+		}
+	}
+
 	if newConfig != nil {
 		err := d.setDaemonConfig(newConfig)
 		if err != nil {
@@ -410,7 +1194,7 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 	}
 
 	if bootstrap {
-		err := d.hooks.PreBootstrap(d.State(), initConfig)
+		err := d.runBootstrapHook(string(internalTypes.PreBootstrap), d.hooks.PreBootstrap, initConfig)
 		if err != nil {
 			return fmt.Errorf("Failed to run pre-bootstrap hook before starting the API: %w", err)
 		}
@@ -420,6 +1204,17 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		return fmt.Errorf("Cannot start network API without valid daemon configuration")
 	}
 
+	if d.networkDisabled && len(joinAddresses) > 0 {
+		return fmt.Errorf("Cannot join a cluster while the network API is disabled, as other cluster members could not reach this member")
+	}
+
+	// The address of an existing cluster member is recorded by all other members, so it can't be updated here.
+	initialized := !bootstrap && newConfig == nil && len(joinAddresses) == 0
+	err := d.resolveInterfaceAddress(initialized)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve listen address from network interface: %w", err)
+	}
+
 	serverCert, err := d.serverCert.PublicKeyX509()
 	if err != nil {
 		return fmt.Errorf("Failed to parse server certificate when bootstrapping API: %w", err)
@@ -431,17 +1226,32 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 	}
 
 	localNode := trust.Remote{
-		Location:    trust.Location{Name: d.name, Address: addrPort},
-		Certificate: types.X509Certificate{Certificate: serverCert},
+		Location:      trust.Location{Name: d.name, Address: addrPort},
+		Certificate:   types.X509Certificate{Certificate: serverCert},
+		DqliteAddress: d.db.DqliteAddress(),
 	}
 
 	if bootstrap {
-		err = d.trustStore.Remotes().Add(d.os.TrustDir, localNode)
+		err = d.trustStore.Remotes().Add(d.os.TrustDir, "", localNode)
 		if err != nil {
 			return fmt.Errorf("Failed to initialize local remote entry: %w", err)
 		}
 	}
 
+	if bootstrap && d.bootstrapClusterCert != nil {
+		// Use the pre-generated cluster certificate, so that it is loaded rather than generated.
+		err = writeClusterCert(d.os.StateDir, d.bootstrapClusterCert)
+		if err != nil {
+			return fmt.Errorf("Failed to write cluster certificate: %w", err)
+		}
+	} else if bootstrap {
+		// Generate the cluster certificate, as it does not exist yet when bootstrapping.
+		_, err = util.LoadClusterCert(d.os.StateDir)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = d.ReloadClusterCert()
 	if err != nil {
 		return err
@@ -453,21 +1263,36 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		return err
 	}
 
-	err = d.endpoints.Down(endpoints.EndpointNetwork)
+	err = d.validateDqliteAddress()
 	if err != nil {
 		return err
 	}
 
-	serverEndpoints := []rest.Resources{resources.InternalEndpoints, resources.PublicEndpoints}
-	err = d.addCoreServers(false, d.address, d.ClusterCert(), serverEndpoints)
-	if err != nil {
-		return err
-	}
+	// Without the network API, the address only identifies this member to dqlite and is not listened on.
+	if !d.networkDisabled {
+		err = d.endpoints.Down(endpoints.EndpointNetwork, endpoints.EndpointCore, endpoints.EndpointDqlite)
+		if err != nil {
+			return err
+		}
 
-	// Add extension servers before post-join hook.
-	err = d.addExtensionServers(false, d.ClusterCert(), d.address.URL.Host)
-	if err != nil {
-		return err
+		serverEndpoints := []rest.Resources{resources.InternalEndpoints, resources.PublicEndpoints}
+		err = d.addCoreServers(false, d.address, d.ClusterCert(), serverEndpoints)
+		if err != nil {
+			return err
+		}
+
+		if d.db.DqliteAddress() != "" {
+			err = d.addDqliteServer(d.ClusterCert())
+			if err != nil {
+				return err
+			}
+		}
+
+		// Add extension servers before post-join hook.
+		err = d.addExtensionServers(false, d.ClusterCert(), d.address.URL.Host)
+		if err != nil {
+			return err
+		}
 	}
 
 	// If bootstrapping the first node, just open the database and create an entry for ourselves.
@@ -492,44 +1317,126 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 			return err
 		}
 
-		err = d.hooks.PostBootstrap(d.State(), initConfig)
+		err = d.recordInitConfig(initConfig)
+		if err != nil {
+			return err
+		}
+
+		err = d.recordFailureDomain()
+		if err != nil {
+			return err
+		}
+
+		err = d.recordDqliteAddress()
+		if err != nil {
+			return err
+		}
+
+		err = d.hooks.OnDatabaseReady(d.shutdownCtx, d.State())
+		if err != nil {
+			return fmt.Errorf("Failed to run database ready hook: %w", err)
+		}
+
+		d.watchLeader()
+
+		err = d.runBootstrapHook(string(internalTypes.PostBootstrap), d.hooks.PostBootstrap, initConfig)
 		if err != nil {
 			return fmt.Errorf("Failed to run post-bootstrap actions: %w", err)
 		}
 
+		err = d.recordInitInfo(types.InitBootstrap, nil)
+		if err != nil {
+			return err
+		}
+
 		// Return as we have completed the bootstrap process.
 		return nil
 	}
 
+	// The replica of a member that joins or restarts may be behind the leader, so report it as syncing until it has
+	// caught up.
+	started := time.Now()
+	if d.syncTimeout > 0 {
+		d.syncing.Store(true)
+	}
+
 	if len(joinAddresses) != 0 {
 		err = d.db.Join(d.Extensions, d.project, d.address, joinAddresses...)
 		if err != nil {
+			d.syncing.Store(false)
+
 			return fmt.Errorf("Failed to join cluster: %w", err)
 		}
 	} else {
 		err = d.db.StartWithCluster(d.Extensions, d.project, d.address, d.trustStore.Remotes().Addresses())
 		if err != nil {
+			d.syncing.Store(false)
+
 			return fmt.Errorf("Failed to re-establish cluster connection: %w", err)
 		}
 	}
 
+	if d.syncTimeout > 0 {
+		d.registerWorker(func(ctx context.Context) { d.awaitSync(ctx, started) })
+	}
+
+	if len(joinAddresses) != 0 {
+		err = d.recordInitConfig(initConfig)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = d.trustStore.Refresh()
 	if err != nil {
 		return err
 	}
 
+	// Record the failure domain every time, as it may have been changed since the last start.
+	err = d.recordFailureDomain()
+	if err != nil {
+		return err
+	}
+
+	err = d.recordReplicaOnly()
+	if err != nil {
+		return err
+	}
+
+	// Record the dqlite address every time, as it may have been changed since the last start.
+	err = d.recordDqliteAddress()
+	if err != nil {
+		return err
+	}
+
+	err = d.restoreDraining()
+	if err != nil {
+		return err
+	}
+
+	err = d.hooks.OnDatabaseReady(d.shutdownCtx, d.State())
+	if err != nil {
+		return fmt.Errorf("Failed to run database ready hook: %w", err)
+	}
+
+	d.watchLeader()
+
+	if d.replicaOnly {
+		d.keepSpareRole()
+	}
+
 	// Get a client for every other cluster member in the newly refreshed local store.
 	publicKey, err := d.ClusterCert().PublicKeyX509()
 	if err != nil {
 		return err
 	}
 
-	cluster, err := d.trustStore.Remotes().Cluster(false, d.ServerCert(), publicKey)
+	cluster, err := d.trustStore.Remotes().Cluster(d.clientPool, false, d.ServerCert(), publicKey)
 	if err != nil {
 		return err
 	}
 
-	localMemberInfo := internalTypes.ClusterMemberLocal{Name: localNode.Name, Address: localNode.Address, Certificate: localNode.Certificate}
+	localMemberInfo := internalTypes.ClusterMemberLocal{Name: localNode.Name, Address: localNode.Address, Certificate: localNode.Certificate, DqliteAddress: localNode.DqliteAddress}
 	if len(joinAddresses) > 0 {
 		err = d.hooks.PreJoin(d.State(), initConfig)
 		if err != nil {
@@ -538,23 +1445,34 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 	}
 
 	if len(joinAddresses) > 0 {
-		var lastErr error
-		var clusterConfirmation bool
-		err = cluster.Query(d.shutdownCtx, true, func(ctx context.Context, c *client.Client) error {
-			// No need to send a request to ourselves.
-			if d.address.URL.Host == c.URL().URL.Host {
+		err = d.operations.Run(d.shutdownCtx, "Confirming new cluster member with existing members", func(ctx context.Context) error {
+			var lastErr error
+			var clusterConfirmation bool
+			err := cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+				// No need to send a request to ourselves.
+				if d.address.URL.Host == c.URL().URL.Host {
+					return nil
+				}
+
+				// At this point the joiner is only trusted on the node that was leader at the time,
+				// so find it and have it instruct all dqlite members to trust this system now that it is functional.
+				if !clusterConfirmation {
+					err := internalClient.AddTrustStoreEntry(ctx, &c.Client, localMemberInfo)
+					if err != nil {
+						lastErr = err
+					} else {
+						clusterConfirmation = true
+					}
+				}
+
 				return nil
+			})
+			if err != nil {
+				return err
 			}
 
-			// At this point the joiner is only trusted on the node that was leader at the time,
-			// so find it and have it instruct all dqlite members to trust this system now that it is functional.
 			if !clusterConfirmation {
-				err := internalClient.AddTrustStoreEntry(ctx, &c.Client, localMemberInfo)
-				if err != nil {
-					lastErr = err
-				} else {
-					clusterConfirmation = true
-				}
+				return fmt.Errorf("Failed to confirm new member %q on any existing system (%d): %w", localMemberInfo.Name, len(cluster)-1, lastErr)
 			}
 
 			return nil
@@ -562,142 +1480,595 @@ func (d *Daemon) StartAPI(bootstrap bool, initConfig map[string]string, newConfi
 		if err != nil {
 			return err
 		}
-
-		if !clusterConfirmation {
-			return fmt.Errorf("Failed to confirm new member %q on any existing system (%d): %w", localMemberInfo.Name, len(cluster)-1, lastErr)
-		}
 	}
 
 	// Tell the other nodes that this system is up.
 	remotes := d.trustStore.Remotes()
-	err = cluster.Query(d.shutdownCtx, true, func(ctx context.Context, c *client.Client) error {
-		c.SetClusterNotification()
-
-		// No need to send a request to ourselves.
-		if d.address.URL.Host == c.URL().URL.Host {
-			return nil
-		}
+	err = d.operations.Run(d.shutdownCtx, "Notifying cluster members of upgrade", func(ctx context.Context) error {
+		return cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+			c.SetClusterNotification()
 
-		// Send notification about this node's dqlite version to all other cluster members.
-		err = d.sendUpgradeNotification(ctx, c)
-		if err != nil {
-			return err
-		}
+			// No need to send a request to ourselves.
+			if d.address.URL.Host == c.URL().URL.Host {
+				return nil
+			}
 
-		// If this was a join request, instruct all peers to run their OnNewMember hook.
-		if len(joinAddresses) > 0 {
-			addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+			// Send notification about this node's dqlite version to all other cluster members.
+			err = d.sendUpgradeNotification(ctx, c)
 			if err != nil {
 				return err
 			}
 
-			remote := remotes.RemoteByAddress(addrPort)
-			if remote == nil {
-				return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
-			}
+			// If this was a join request, instruct all peers to run their OnNewMember hook.
+			if len(joinAddresses) > 0 {
+				addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+				if err != nil {
+					return err
+				}
 
-			// Run the OnNewMember hook, and skip errors on any nodes that are still in the process of joining.
-			err = internalClient.RunNewMemberHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookNewMemberOptions{Name: localMemberInfo.Name})
-			if err != nil && !api.StatusErrorCheck(err, http.StatusServiceUnavailable) {
-				return err
+				remote := remotes.RemoteByAddress(addrPort)
+				if remote == nil {
+					return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
+				}
+
+				// Run the OnNewMember hook, and retry it on any nodes that asked to run it again later.
+				// Skip errors on any nodes that are still in the process of joining.
+				err = d.retryHook(ctx, string(internalTypes.OnNewMember), func() error {
+					err := internalClient.RunNewMemberHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookNewMemberOptions{Name: localMemberInfo.Name})
+					after, ok := internalClient.RetryAfter(err)
+					if ok {
+						return config.ErrRetryHook{After: after, Err: err}
+					}
+
+					if err != nil && !api.StatusErrorCheck(err, http.StatusServiceUnavailable) {
+						return err
+					}
+
+					return nil
+				})
+				if err != nil {
+					return err
+				}
 			}
-		}
 
-		return nil
+			return nil
+		})
 	})
 	if err != nil {
 		return err
 	}
 
 	if len(joinAddresses) > 0 {
-		return d.hooks.PostJoin(d.State(), initConfig)
-	}
+		err = d.runPostJoinHook(initConfig)
+		if err != nil {
+			return err
+		}
 
-	return nil
-}
+		joinAddrPorts, err := types.ParseAddrPorts(joinAddresses)
+		if err != nil {
+			return err
+		}
 
-// startUnixServer starts up the core unix listener with the given resources.
-func (d *Daemon) startUnixServer(serverEndpoints []rest.Resources) error {
-	ctlServer := d.initServer(serverEndpoints...)
-	ctl := endpoints.NewSocket(d.shutdownCtx, ctlServer, d.os.ControlSocket(), d.os.SocketGroup)
-	d.endpoints = endpoints.NewEndpoints(d.shutdownCtx, ctl)
+		return d.recordInitInfo(types.InitJoin, joinAddrPorts)
+	}
 
-	return d.endpoints.Up()
+	return nil
 }
 
-// addCoreServers initializes the default resources with the default address and certificate.
-// If the default address and certificate may be applied to any extension servers, those will be started as well.
-func (d *Daemon) addCoreServers(preInit bool, defaultURL api.URL, defaultCert *shared.CertInfo, defaultResources []rest.Resources) error {
-	serverEndpoints := []rest.Resources{}
-	serverEndpoints = append(serverEndpoints, defaultResources...)
-
-	// Append all extension servers whose address is empty or matches the default URL.
-	for _, s := range d.extensionServers {
-		// If the server is not available prior to initialization, then skip it if we are before initialization.
-		if !s.PreInit && preInit {
-			continue
-		}
+// runPostJoinHook runs the PostJoin hook, retrying it if requested. If the hook is asynchronous, it is started as a
+// background operation and any failure is only reported through the operations API and the event stream.
+func (d *Daemon) runPostJoinHook(initConfig map[string]string) error {
+	postJoin := func(ctx context.Context) error {
+		return d.retryHook(ctx, string(internalTypes.PostJoin), func() error {
+			return d.hooks.PostJoin(d.State(), initConfig)
+		})
+	}
 
-		// If the Server resources are not part of the core API, then skip it.
-		if !s.CoreAPI {
-			continue
-		}
+	if !d.asyncPostJoin {
+		return postJoin(d.shutdownCtx)
+	}
 
-		serverEndpoints = append(serverEndpoints, s.Resources...)
+	op, err := d.operations.Start(d.shutdownCtx, types.OperationPostJoin, postJoin)
+	if err != nil {
+		return err
 	}
 
-	server := d.initServer(serverEndpoints...)
-	network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, defaultURL, defaultCert)
+	logger.Info("Running post-join hook in the background", logger.Ctx{"operation": op.ID()})
 
-	return d.endpoints.Add(network)
+	return nil
 }
 
-// addExtensionServers initialises a new *endpoints.Network for each extension server and adds it to the Daemon endpoints.
-// Only servers with a defined address will be started.
-// If a server lacks a certificate, the fallbackCert will be used instead.
-func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo, coreAddress string) error {
-	var networks []endpoints.Endpoint
-	for _, extensionServer := range d.extensionServers {
-		// Skip any core API servers.
-		if extensionServer.CoreAPI {
-			continue
-		}
+// awaitSync waits for this cluster member to catch up with the leader after joining the cluster or restarting at the
+// given time, and then stops reporting it as syncing. If it has not caught up by the sync timeout, it is reported as
+// ready anyway.
+func (d *Daemon) awaitSync(ctx context.Context, started time.Time) {
+	defer func() {
+		d.syncing.Store(false)
+		d.syncedOnce.Do(func() { close(d.syncedCh) })
+	}()
 
-		// If we are before initialization, only start the servers who have `PreInit` set.
-		if !extensionServer.PreInit && preInit {
-			continue
-		}
+	ctx, cancel := context.WithTimeout(ctx, d.syncTimeout)
+	defer cancel()
 
-		// If the server has no defined address, then do not start it as it should have already started with the core servers.
-		if extensionServer.Address == (types.AddrPort{}) {
-			continue
-		}
+	err := d.db.AwaitReady(ctx)
 
-		// If the server address matches the core address, then it should have already started.
-		if extensionServer.Address.String() == coreAddress {
-			continue
-		}
+	s := d.State()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
 
-		// If there is no certificate defined, apply the default certificate for the core server.
-		cert := extensionServer.Certificate
-		if cert == nil {
-			cert = fallbackCert
+	for err == nil && !d.synced(ctx, s, started) {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ticker.C:
 		}
+	}
 
-		server := d.initServer(extensionServer.Resources...)
-		url := api.NewURL().Scheme(extensionServer.Protocol).Host(extensionServer.Address.String())
-		network := endpoints.NewNetwork(d.shutdownCtx, endpoints.EndpointNetwork, server, *url, cert)
-		networks = append(networks, network)
+	if err != nil {
+		d.log.Warn("Cluster member did not finish syncing in time, serving requests anyway", logger.Ctx{"timeout": d.syncTimeout, "err": err})
+		return
 	}
 
-	if len(networks) > 0 {
-		err := d.endpoints.Add(networks...)
-		if err != nil {
-			return err
-		}
+	d.log.Info("Cluster member finished syncing", logger.Ctx{"duration": time.Since(started)})
+}
+
+// synced returns whether this cluster member has caught up with the leader since the given time. The leader is always
+// caught up. Other members have caught up once they have received a heartbeat from the leader after the given time.
+func (d *Daemon) synced(ctx context.Context, s *state.State, since time.Time) bool {
+	isLeader, err := s.IsLeader(ctx)
+	if err == nil && isLeader {
+		return true
 	}
 
-	return nil
+	// The leader only sends heartbeats to members recorded in its database, so receiving one also confirms this member
+	// is known to the rest of the cluster.
+	received, ok := d.db.HeartbeatReceived()
+
+	return ok && received.After(since)
+}
+
+// hookRetryLimit is the number of times a hook that returned config.ErrRetryHook is run again before failing.
+const hookRetryLimit = 5
+
+// maxHookRetryDelay is the longest delay before a hook that returned config.ErrRetryHook is run again.
+const maxHookRetryDelay = time.Minute
+
+// retryHook runs the given hook, and runs it again after the requested delay each time it returns config.ErrRetryHook,
+// up to hookRetryLimit times. The last error is returned if the hook still fails, or if the context is cancelled.
+func (d *Daemon) retryHook(ctx context.Context, name string, hook func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := hook()
+
+		var retry config.ErrRetryHook
+		if err == nil || !errors.As(err, &retry) || attempt > hookRetryLimit {
+			return err
+		}
+
+		delay := min(retry.After, maxHookRetryDelay)
+		d.log.Warn("Retrying hook after transient failure", logger.Ctx{"hook": name, "attempt": attempt, "delay": delay, "error": err})
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// recordInitConfig records the init configuration that this cluster member was bootstrapped or joined with to the database.
+func (d *Daemon) recordInitConfig(initConfig map[string]string) error {
+	err := d.db.IdempotentTransaction(d.shutdownCtx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.SetInitConfig(ctx, tx, d.Name(), initConfig)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to record init configuration: %w", err)
+	}
+
+	return nil
+}
+
+// recordFailureDomain records the failure domain of this cluster member in the database.
+func (d *Daemon) recordFailureDomain() error {
+	err := d.db.IdempotentTransaction(d.shutdownCtx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.SetFailureDomain(ctx, tx, d.Name(), d.failureDomain)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to record failure domain: %w", err)
+	}
+
+	return nil
+}
+
+// recordReplicaOnly records whether this cluster member is a read-only replica in the database. A replica also
+// requests the spare role, so that it is reported alongside any role assigned by an operator, and is given the highest
+// dqlite weight, so that dqlite promotes any other cluster member first. As dqlite would still promote the replica if
+// there are too few other cluster members to reach the target number of voters and stand-bys, it refuses to start in
+// that case.
+func (d *Daemon) recordReplicaOnly() error {
+	err := d.db.IdempotentTransaction(d.shutdownCtx, func(ctx context.Context, tx *sql.Tx) error {
+		err := cluster.SetClusterMemberReplicaOnly(ctx, tx, d.Name(), d.replicaOnly)
+		if err != nil {
+			return err
+		}
+
+		if !d.replicaOnly {
+			return nil
+		}
+
+		members, err := cluster.GetInternalClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		replicaOnly, err := cluster.GetReplicaOnlyClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		voters, standBys := d.db.TargetRoles()
+		if voters+standBys > len(members)-len(replicaOnly) {
+			return fmt.Errorf("The cluster targets %d voters and %d stand-bys, but only %d cluster members are not read-only replicas", voters, standBys, len(members)-len(replicaOnly))
+		}
+
+		return cluster.SetRequestedRole(ctx, tx, d.Name(), dqliteClient.Spare.String())
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to record replica-only status: %w", err)
+	}
+
+	var weight uint64
+	if d.replicaOnly {
+		weight = math.MaxUint64
+	}
+
+	err = d.db.SetWeight(d.shutdownCtx, weight)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// recordDqliteAddress records the address at which this cluster member accepts dqlite connections in the database, so
+// that the other cluster members learn it through the heartbeat.
+func (d *Daemon) recordDqliteAddress() error {
+	err := d.db.IdempotentTransaction(d.shutdownCtx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.SetDqliteAddress(ctx, tx, d.Name(), d.db.DqliteAddress())
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to record dqlite address: %w", err)
+	}
+
+	return nil
+}
+
+// restoreDraining marks this cluster member as draining if it is recorded as draining in the database, so that it
+// does not accept new writes again after a restart ahead of its removal.
+func (d *Daemon) restoreDraining() error {
+	var draining map[string]bool
+	err := d.db.IdempotentTransaction(d.shutdownCtx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		draining, err = cluster.GetDrainingClusterMembers(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to get draining status: %w", err)
+	}
+
+	if draining[d.Name()] && !d.draining.Swap(true) {
+		d.log.Info("Resuming draining of cluster member", logger.Ctx{"member": d.Name()})
+	}
+
+	return nil
+}
+
+// recordInitInfo records the time and type of the operation that initialized this cluster member,
+// along with its schema version at the time, to the state directory.
+func (d *Daemon) recordInitInfo(operation types.InitOperation, joinAddresses types.AddrPorts) error {
+	info := types.InitInfo{
+		Operation:     operation,
+		Time:          time.Now().UTC(),
+		JoinAddresses: joinAddresses,
+		APIExtensions: d.Extensions,
+	}
+
+	info.SchemaInternal, info.SchemaExternal, _ = d.db.Schema().Version()
+
+	bytes, err := yaml.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("Failed to parse initialization info to yaml: %w", err)
+	}
+
+	err = renameio.WriteFile(d.os.InitInfoPath(), bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to write initialization info yaml: %w", err)
+	}
+
+	return nil
+}
+
+// startUnixServer starts up the core unix listener with the resources of the given servers.
+func (d *Daemon) startUnixServer(servers []rest.Server) error {
+	ctlServer := d.initServer(servers...)
+	d.ctlSocket = endpoints.NewSocket(d.shutdownCtx, d.log, ctlServer, d.os.ControlSocket(), d.os.SocketGroup)
+	d.ctlSocket.SetErrorHandler(d.errorHandler(types.RuntimeErrorEndpoint))
+	d.endpoints = endpoints.NewEndpoints(d.shutdownCtx, d.log, d.ctlSocket)
+
+	return d.endpoints.Up()
+}
+
+// setSocketGroup changes the group that owns the control socket, without restarting its listener.
+func (d *Daemon) setSocketGroup(group string) error {
+	if d.ctlSocket == nil {
+		return fmt.Errorf("Control socket is not running")
+	}
+
+	err := d.ctlSocket.SetGroup(group)
+	if err != nil {
+		return err
+	}
+
+	d.os.SocketGroup = group
+	d.log.Info("Changed control socket group", logger.Ctx{"group": group})
+
+	return nil
+}
+
+// clusterCertInitialized returns the cluster certificate, or an error if it has not been loaded yet.
+func (d *Daemon) clusterCertInitialized() (*shared.CertInfo, error) {
+	d.clusterMu.RLock()
+	initialized := d.clusterCert != nil
+	d.clusterMu.RUnlock()
+
+	if !initialized {
+		return nil, fmt.Errorf("Cluster certificate is not available")
+	}
+
+	return d.ClusterCert(), nil
+}
+
+// exportTrustStore writes the trust store to the writer as a bundle signed with the cluster certificate.
+func (d *Daemon) exportTrustStore(w io.Writer) error {
+	cert, err := d.clusterCertInitialized()
+	if err != nil {
+		return err
+	}
+
+	return d.trustStore.Export(w, cert)
+}
+
+// importTrustStore replaces the trust store with a bundle signed with the certificate with the given fingerprint, or
+// with the cluster certificate if the fingerprint is empty.
+func (d *Daemon) importTrustStore(r io.Reader, fingerprint string) (int, error) {
+	if fingerprint == "" {
+		cert, err := d.clusterCertInitialized()
+		if err != nil {
+			return 0, fmt.Errorf("The fingerprint of the cluster certificate must be given if it is not available locally: %w", err)
+		}
+
+		fingerprint = cert.Fingerprint()
+	}
+
+	var err error
+	local := trust.Location{Name: d.Name()}
+	if d.Address().URL.Host != "" {
+		local.Address, err = types.ParseAddrPort(d.Address().URL.Host)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to parse address of the local member: %w", err)
+		}
+	}
+
+	count, err := d.trustStore.Import(r, fingerprint, d.os.TrustDir, local)
+	if err != nil {
+		return 0, err
+	}
+
+	d.log.Info("Imported trust store", logger.Ctx{"remotes": count})
+
+	return count, nil
+}
+
+// addCoreServers initializes the default resources with the default address and certificate.
+// If the default address and certificate may be applied to any extension servers, those will be started as well.
+func (d *Daemon) addCoreServers(preInit bool, defaultURL api.URL, defaultCert *shared.CertInfo, defaultResources []rest.Resources) error {
+	servers := []rest.Server{{Resources: defaultResources}}
+	tlsConfig := types.TLSConfig{}
+
+	// Append all extension servers whose address is empty or matches the default URL.
+	for _, s := range d.extensionServers {
+		// If the server is not available prior to initialization, then skip it if we are before initialization.
+		if !s.PreInit && preInit {
+			continue
+		}
+
+		// If the Server resources are not part of the core API, then skip it.
+		if !s.CoreAPI {
+			continue
+		}
+
+		servers = append(servers, s)
+
+		// Core API servers are validated to have the same TLS configuration.
+		if s.TLSConfig != nil {
+			tlsConfig = *s.TLSConfig
+		}
+	}
+
+	server := d.initServer(servers...)
+	advertiseExtensions(server, servers...)
+	network := endpoints.NewNetwork(d.shutdownCtx, d.log, endpoints.EndpointCore, server, defaultURL, defaultCert, tlsConfig)
+	network.SetErrorHandler(d.errorHandler(types.RuntimeErrorEndpoint))
+	network.SetVerifyPeerCertificate(d.verifyPeerCertificate)
+
+	return d.endpoints.Add(network)
+}
+
+// addDqliteServer starts the dedicated listener for dqlite connections at the dqlite address, which only serves the
+// database endpoint.
+func (d *Daemon) addDqliteServer(cert *shared.CertInfo) error {
+	server := d.initServer(rest.Server{Resources: []rest.Resources{resources.DatabaseResources}})
+	connContext := server.ConnContext
+	server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, c)
+		}
+
+		return internalREST.WithDqliteListener(ctx)
+	}
+
+	url := api.NewURL().Scheme(sys.Scheme()).Host(d.db.DqliteAddress())
+	network := endpoints.NewNetwork(d.shutdownCtx, d.log, endpoints.EndpointDqlite, server, *url, cert, types.TLSConfig{})
+	network.SetErrorHandler(d.errorHandler(types.RuntimeErrorEndpoint))
+	network.SetVerifyPeerCertificate(d.verifyPeerCertificate)
+
+	return d.endpoints.Add(network)
+}
+
+// validateDqliteAddress checks that the dqlite address, if any, is not also used by the API or an extension server.
+func (d *Daemon) validateDqliteAddress() error {
+	address := d.db.DqliteAddress()
+	if address == "" {
+		return nil
+	}
+
+	if address == d.address.URL.Host {
+		return fmt.Errorf("Dqlite address %q must differ from the API address", address)
+	}
+
+	for _, server := range d.extensionServers {
+		if server.Address != (types.AddrPort{}) && server.Address.String() == address {
+			return fmt.Errorf("Dqlite address %q is already used by an extension server", address)
+		}
+	}
+
+	return nil
+}
+
+// dqliteDialAddress returns the address at which the cluster member with the given API address accepts dqlite
+// connections, according to the trust store.
+func (d *Daemon) dqliteDialAddress(address string) string {
+	addrPort, err := types.ParseAddrPort(address)
+	if err != nil {
+		return address
+	}
+
+	remote := d.trustStore.Remotes().RemoteByAddress(addrPort)
+	if remote == nil || remote.DqliteAddress == "" {
+		return address
+	}
+
+	return remote.DqliteAddress
+}
+
+// addExtensionServers initialises a new *endpoints.Network for each extension server and adds it to the Daemon endpoints.
+// Only servers with a defined address will be started.
+// If a server lacks a certificate, the fallbackCert will be used instead.
+// If an optional server fails to start, the error is reported and the remaining servers are still started.
+func (d *Daemon) addExtensionServers(preInit bool, fallbackCert *shared.CertInfo, coreAddress string) error {
+	var networks []endpoints.Endpoint
+	var optionalNetworks []*endpoints.Network
+	for _, extensionServer := range d.extensionServers {
+		// Skip any core API servers, and servers that must never be exposed over the network.
+		if extensionServer.CoreAPI || extensionServer.UnixOnly {
+			continue
+		}
+
+		// If we are before initialization, only start the servers who have `PreInit` set.
+		if !extensionServer.PreInit && preInit {
+			continue
+		}
+
+		// If the server has no defined address, then do not start it as it should have already started with the core servers.
+		if extensionServer.Address == (types.AddrPort{}) {
+			continue
+		}
+
+		// If the server address matches the core address, then it should have already started.
+		if extensionServer.Address.String() == coreAddress {
+			continue
+		}
+
+		// If there is no certificate defined, apply the default certificate for the core server.
+		cert := extensionServer.Certificate
+		if cert == nil {
+			cert = fallbackCert
+		}
+
+		tlsConfig := types.TLSConfig{}
+		if extensionServer.TLSConfig != nil {
+			tlsConfig = *extensionServer.TLSConfig
+		}
+
+		// Serve the extensions endpoint on the server's listener if it restricts its advertised extensions.
+		if extensionServer.AdvertisedExtensions != nil {
+			extensionServer.Resources = append([]rest.Resources{resources.ExtensionsResources}, extensionServer.Resources...)
+		}
+
+		server := d.initServer(extensionServer)
+		advertiseExtensions(server, extensionServer)
+		url := api.NewURL().Scheme(extensionServer.Protocol).Host(extensionServer.Address.String())
+		network := endpoints.NewNetwork(d.shutdownCtx, d.log, endpoints.EndpointNetwork, server, *url, cert, tlsConfig)
+		network.SetErrorHandler(d.errorHandler(types.RuntimeErrorEndpoint))
+		network.SetVerifyPeerCertificate(d.verifyPeerCertificate)
+
+		if extensionServer.Optional {
+			optionalNetworks = append(optionalNetworks, network)
+		} else {
+			networks = append(networks, network)
+		}
+	}
+
+	if len(networks) > 0 {
+		err := d.endpoints.Add(networks...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, network := range optionalNetworks {
+		err := d.endpoints.AddOptional(network)
+		if err != nil {
+			d.log.Error("Failed to start optional API listener", logger.Ctx{"error": err})
+			d.errorHandler(types.RuntimeErrorEndpoint)(fmt.Errorf("Failed to start optional API listener: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// advertiseExtensions restricts the API extensions advertised on the listener of the given HTTP server to those allowed
+// by the rest servers it serves. If none of the rest servers restrict their advertised extensions, all are advertised.
+func advertiseExtensions(server *http.Server, servers ...rest.Server) {
+	var advertised []string
+	for _, s := range servers {
+		if s.AdvertisedExtensions == nil {
+			continue
+		}
+
+		if advertised == nil {
+			advertised = []string{}
+		}
+
+		for _, extension := range s.AdvertisedExtensions {
+			if !slices.Contains(advertised, extension) {
+				advertised = append(advertised, extension)
+			}
+		}
+	}
+
+	if advertised == nil {
+		return
+	}
+
+	connContext := server.ConnContext
+	server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, c)
+		}
+
+		return internalREST.WithAdvertisedExtensions(ctx, advertised)
+	}
 }
 
 func (d *Daemon) sendUpgradeNotification(ctx context.Context, c *client.Client) error {
@@ -710,50 +2081,162 @@ func (d *Daemon) sendUpgradeNotification(ctx context.Context, c *client.Client)
 		return err
 	}
 
-	upgradeRequest.Header.Set("X-Dqlite-Version", fmt.Sprintf("%d", 1))
-	upgradeRequest = upgradeRequest.WithContext(ctx)
+	upgradeRequest.Header.Set("X-Dqlite-Version", fmt.Sprintf("%d", 1))
+	upgradeRequest = upgradeRequest.WithContext(ctx)
+
+	resp, err := c.Client.Do(upgradeRequest)
+	if err != nil {
+		d.log.Error("Failed to send database upgrade request", logger.Ctx{"error": err})
+		return nil
+	}
+
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		d.log.Error("Failed to read upgrade notification response body", logger.Ctx{"error": err})
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		d.log.Error("Database upgrade notification failed", logger.Ctx{"status": resp.Status})
+	}
+
+	return nil
+}
+
+// ClusterCert ensures both the daemon and state have the same cluster cert.
+func (d *Daemon) ClusterCert() *shared.CertInfo {
+	d.clusterMu.RLock()
+	defer d.clusterMu.RUnlock()
+
+	return shared.NewCertInfo(d.clusterCert.KeyPair(), d.clusterCert.CA(), d.clusterCert.CRL())
+}
+
+// clusterCertAttempts is the number of times the cluster certificate files are checked for before giving up.
+const clusterCertAttempts = 5
+
+// clusterCertRetryDelay is the initial delay before checking for missing cluster certificate files again.
+// It doubles after each attempt.
+const clusterCertRetryDelay = 100 * time.Millisecond
+
+// ReloadClusterCert reloads the cluster keypair from the state directory.
+// If the certificate files are missing, for example because they are being replaced, they are checked for again with
+// a short backoff before failing. Errors parsing the files are returned immediately.
+func (d *Daemon) ReloadClusterCert() error {
+	d.clusterMu.Lock()
+	defer d.clusterMu.Unlock()
+
+	// The cluster certificate files may be briefly missing while they are being replaced, so wait for them to
+	// reappear. Otherwise they would be regenerated, rather than loaded.
+	delay := clusterCertRetryDelay
+	for attempt := 1; ; attempt++ {
+		missing := ""
+		for _, ext := range []string{".crt", ".key"} {
+			path := filepath.Join(d.os.StateDir, "cluster"+ext)
+			_, err := os.Stat(path)
+			if err == nil {
+				continue
+			}
+
+			if !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("Failed to check cluster certificate file %q: %w", path, err)
+			}
+
+			missing = path
+			break
+		}
+
+		if missing == "" {
+			break
+		}
+
+		if attempt >= clusterCertAttempts {
+			return fmt.Errorf("Cluster certificate file %q is missing after %d attempts", missing, attempt)
+		}
+
+		d.log.Warn("Cluster certificate file is missing, retrying", logger.Ctx{"path": missing, "attempt": attempt, "delay": delay})
+
+		select {
+		case <-d.shutdownCtx.Done():
+			return fmt.Errorf("Stopped waiting for cluster certificate file %q: %w", missing, d.shutdownCtx.Err())
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+
+	clusterCert, err := util.LoadClusterCert(d.os.StateDir)
+	if err != nil {
+		return err
+	}
+
+	d.clusterCert = clusterCert
+	d.endpoints.UpdateTLS(clusterCert)
+
+	// Drop any cached clients, as they trust the old cluster certificate.
+	d.clientPool.Clear()
 
-	resp, err := c.Client.Do(upgradeRequest)
-	if err != nil {
-		logger.Error("Failed to send database upgrade request", logger.Ctx{"error": err})
-		return nil
+	return nil
+}
+
+// validateClusterCert checks that the given cluster certificate has a private key, is usable for server
+// authentication, and is signed by its CA.
+func validateClusterCert(cert *shared.CertInfo) error {
+	if len(cert.PrivateKey()) == 0 {
+		return fmt.Errorf("Certificate has no private key")
 	}
 
-	defer resp.Body.Close()
-	_, err = io.Copy(io.Discard, resp.Body)
+	leaf, err := cert.PublicKeyX509()
 	if err != nil {
-		logger.Error("Failed to read upgrade notification response body", logger.Ctx{"error": err})
+		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		logger.Errorf("Database upgrade notification failed: %s", resp.Status)
+	if cert.CA() == nil {
+		return fmt.Errorf("Certificate has no CA, which cluster members need to verify it")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.CA())
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	if err != nil {
+		return fmt.Errorf("Certificate can't be used for server authentication with its CA: %w", err)
 	}
 
 	return nil
 }
 
-// ClusterCert ensures both the daemon and state have the same cluster cert.
-func (d *Daemon) ClusterCert() *shared.CertInfo {
-	d.clusterMu.RLock()
-	defer d.clusterMu.RUnlock()
+// writeClusterCert writes the given cluster keypair and its CA to the state directory.
+func writeClusterCert(dir string, cert *shared.CertInfo) error {
+	err := renameio.WriteFile(filepath.Join(dir, "cluster.ca"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.CA().Raw}), 0644)
+	if err != nil {
+		return err
+	}
 
-	return shared.NewCertInfo(d.clusterCert.KeyPair(), d.clusterCert.CA(), d.clusterCert.CRL())
+	err = renameio.WriteFile(filepath.Join(dir, "cluster.crt"), cert.PublicKey(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return renameio.WriteFile(filepath.Join(dir, "cluster.key"), cert.PrivateKey(), 0600)
 }
 
-// ReloadClusterCert reloads the cluster keypair from the state directory.
-func (d *Daemon) ReloadClusterCert() error {
-	d.clusterMu.Lock()
-	defer d.clusterMu.Unlock()
+// generateServerCert writes a new server keypair to the state directory, if it doesn't have one yet.
+// The key is written before the certificate, so that a certificate is never found without its key.
+func generateServerCert(dir string) error {
+	if shared.PathExists(filepath.Join(dir, "server.crt")) && shared.PathExists(filepath.Join(dir, "server.key")) {
+		return nil
+	}
 
-	clusterCert, err := util.LoadClusterCert(d.os.StateDir)
+	cert, key, err := shared.GenerateMemCert(false, true)
 	if err != nil {
 		return err
 	}
 
-	d.clusterCert = clusterCert
-	d.endpoints.UpdateTLS(clusterCert)
+	err = renameio.WriteFile(filepath.Join(dir, "server.key"), key, 0600)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return renameio.WriteFile(filepath.Join(dir, "server.crt"), cert, 0644)
 }
 
 // ServerCert ensures both the daemon and state have the same server cert.
@@ -772,13 +2255,108 @@ func (d *Daemon) Name() string {
 	return d.name
 }
 
-// State creates a State instance with the daemon's stateful components.
-func (d *Daemon) State() *state.State {
+// diagnosticsConfig returns the effective runtime configuration and database settings of the daemon.
+func (d *Daemon) diagnosticsConfig() types.Diagnostics {
+	serverAddresses := []string{}
+	for _, server := range d.extensionServers {
+		if server.CoreAPI || server.UnixOnly || server.Address == (types.AddrPort{}) {
+			continue
+		}
+
+		serverAddresses = append(serverAddresses, server.Address.String())
+	}
+
+	schemaInternal, schemaExternal, _ := d.db.Schema().Version()
+	voters, standBys := d.db.TargetRoles()
+	threshold, trailing := d.db.SnapshotParams()
+
+	return types.Diagnostics{
+		Name:              d.Name(),
+		Address:           d.Address().URL.Host,
+		DqliteAddress:     d.db.DqliteAddress(),
+		ServerAddresses:   serverAddresses,
+		ControlSocket:     d.os.ControlSocketPath(),
+		SocketGroup:       d.os.SocketGroup,
+		SchemaInternal:    schemaInternal,
+		SchemaExternal:    schemaExternal,
+		APIExtensions:     d.Extensions,
+		HeartbeatInterval: db.HeartbeatInterval.String(),
+		TargetVoters:      voters,
+		TargetStandBys:    standBys,
+		Hooks:             slices.Clone(d.registeredHooks),
+		SnapshotThreshold: threshold,
+		SnapshotTrailing:  trailing,
+	}
+}
+
+// runNamedHook runs the hook with the given name, one of the hook types, with the given arguments. The error of the
+// hook is returned as is, or a Not Found error if there is no hook with the name.
+func (d *Daemon) runNamedHook(ctx context.Context, s *state.State, hook string, args types.HookArgs) error {
+	switch internalTypes.HookType(hook) {
+	case internalTypes.OnStart:
+		return d.hooks.OnStart(s)
+	case internalTypes.OnDatabaseReady:
+		return d.hooks.OnDatabaseReady(ctx, s)
+	case internalTypes.PreBootstrap:
+		return d.hooks.PreBootstrap(ctx, s, args.InitConfig)
+	case internalTypes.PostBootstrap:
+		return d.hooks.PostBootstrap(ctx, s, args.InitConfig)
+	case internalTypes.PreJoin:
+		return d.hooks.PreJoin(s, args.InitConfig)
+	case internalTypes.PostJoin:
+		return d.hooks.PostJoin(s, args.InitConfig)
+	case internalTypes.PreRemove:
+		return d.hooks.PreRemove(s, args.Force)
+	case internalTypes.PostRemove:
+		return d.hooks.PostRemove(s, args.Force)
+	case internalTypes.PreNewMember:
+		return d.hooks.PreNewMember(ctx, s, args.Candidate)
+	case internalTypes.OnNewMember:
+		return d.hooks.OnNewMember(s)
+	case internalTypes.OnHeartbeat:
+		return d.hooks.OnHeartbeat(s)
+	case internalTypes.OnConfigChange:
+		return d.hooks.OnConfigChange(ctx, s, args.OldConfig, args.NewConfig)
+	case internalTypes.OnLeaderChange:
+		return d.hooks.OnLeaderChange(ctx, s, args.IsLeader)
+	case internalTypes.PreShutdown:
+		return d.hooks.PreShutdown(ctx, s)
+	case internalTypes.OnLowDisk:
+		return d.hooks.OnLowDisk(ctx, s, args.Free)
+	default:
+		return api.StatusErrorf(http.StatusNotFound, "No hook found with name %q", hook)
+	}
+}
+
+// watchPath registers a hook with the daemon's filesystem watcher for the given path under the state directory.
+func (d *Daemon) watchPath(path string, hook func(path string, event fsnotify.Op) error) error {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(d.os.StateDir, path)
+	}
+
+	return d.fsWatcher.WatchPath(path, hook)
+}
+
+// setStateHooks sets the hooks and daemon functions that the state package calls. They are set once during init,
+// before any listener is started, as request handlers read them concurrently.
+func (d *Daemon) setStateHooks() {
 	state.PreRemoveHook = d.hooks.PreRemove
 	state.PostRemoveHook = d.hooks.PostRemove
 	state.OnHeartbeatHook = d.hooks.OnHeartbeat
+	state.HeartbeatPayloadHook = d.hooks.HeartbeatPayload
+	state.OnHeartbeatPayloadsHook = d.hooks.OnHeartbeatPayloads
+	state.PreNewMemberHook = d.hooks.PreNewMember
 	state.OnNewMemberHook = d.hooks.OnNewMember
+	state.OnConfigChangeHook = d.hooks.OnConfigChange
+	state.OnLeaderChangeHook = d.hooks.OnLeaderChange
+	state.PreShutdownHook = d.hooks.PreShutdown
 	state.ReloadClusterCert = d.ReloadClusterCert
+	state.RefreshTrustStore = d.trustStore.Refresh
+	state.SetMemberName = d.setMemberName
+	state.SetControlSocketGroup = d.setSocketGroup
+	state.ExportTrustStore = d.exportTrustStore
+	state.ImportTrustStore = d.importTrustStore
+	state.RunNamedHook = d.runNamedHook
 	state.StopListeners = func() error {
 		err := d.fsWatcher.Close()
 		if err != nil {
@@ -787,20 +2365,30 @@ func (d *Daemon) State() *state.State {
 
 		return d.endpoints.Down()
 	}
+}
 
+// State creates a State instance with the daemon's stateful components.
+func (d *Daemon) State() *state.State {
 	state := &state.State{
-		Context:     d.shutdownCtx,
-		ReadyCh:     d.ReadyChan,
-		OS:          d.os,
-		Address:     d.Address,
-		Name:        d.Name,
-		Endpoints:   d.endpoints,
-		ServerCert:  d.ServerCert,
-		ClusterCert: d.ClusterCert,
-		Database:    d.db,
-		Remotes:     d.trustStore.Remotes,
-		StartAPI:    d.StartAPI,
-		Stop: func() (exit func(), stopErr error) {
+		Context:        d.shutdownCtx,
+		ReadyCh:        d.ReadyChan,
+		ShutdownDoneCh: d.shutdownDoneCh,
+		OS:             d.os,
+		WatchPath:      d.watchPath,
+		Address:        d.Address,
+		Name:           d.Name,
+		Project:        func() string { return d.project },
+		Endpoints:      d.endpoints,
+		ServerCert:     d.ServerCert,
+		ClusterCert:    d.ClusterCert,
+		Database:       d.db,
+		Remotes:        d.trustStore.Remotes,
+		StartAPI:       d.StartAPI,
+		Stop: func(force bool) (exit func(), stopErr error) {
+			if force {
+				d.forceShutdown.Store(true)
+			}
+
 			stopErr = d.stop()
 			exit = func() {
 				d.shutdownDoneCh <- stopErr
@@ -808,40 +2396,513 @@ func (d *Daemon) State() *state.State {
 
 			return exit, stopErr
 		},
-		Extensions: d.Extensions,
+		Operations:        func() *operations.Operations { return d.operations },
+		Draining:          d.draining.Load,
+		Syncing:           d.syncing.Load,
+		LowDisk:           d.lowDisk.Load,
+		RegisterWorker:    d.registerWorker,
+		Drain:             d.drain,
+		Undrain:           d.undrain,
+		DescribeEndpoints: func() []types.EndpointInfo { return resources.DescribeEndpoints(d.extensionServers) },
+		DiagnosticsConfig: d.diagnosticsConfig,
+		Extensions:        d.Extensions,
+		ClientPool:        d.clientPool,
+		ExtensionCache:    d.extensionCache,
+		Log:               d.log,
+		AllowRemoteSQL:    d.allowRemoteSQL,
+		NetworkDisabled:   d.networkDisabled,
+		ReplicaOnly:       d.replicaOnly,
+
+		RejectReadsWhileSyncing: d.rejectReadsWhileSyncing,
+		Events:                  d.events,
+	}
+
+	if d.configRegistry != nil {
+		state.ConfigSchema = d.configRegistry
+	}
+
+	state.RemoveSelf = func(ctx context.Context, force bool) error {
+		return resources.RemoveSelf(ctx, state, force)
 	}
 
 	return state
 }
 
-// setDaemonConfig sets the daemon's address and name from the given location information. If none is supplied, the file
-// at `state-dir/daemon.yaml` will be read for the information.
+// registerWorker runs the worker in a new goroutine with the shutdown context, to be waited on during shutdown.
+func (d *Daemon) registerWorker(worker func(ctx context.Context)) {
+	d.workersMu.Lock()
+	defer d.workersMu.Unlock()
+
+	if d.workersStopped {
+		d.log.Warn("Not running worker registered during shutdown")
+		return
+	}
+
+	d.workers.Add(1)
+	go func() {
+		defer d.workers.Done()
+		worker(d.shutdownCtx)
+	}()
+}
+
+// Timeouts for each phase of shutdown. Once a phase exceeds its timeout, shutdown moves on to the next phase.
+const (
+	preShutdownTimeout    = 15 * time.Second // Handing off leadership and running the PreShutdown hook.
+	drainEndpointsTimeout = 10 * time.Second // Waiting for in-flight requests on the network listeners.
+	drainDatabaseTimeout  = 5 * time.Second  // Waiting for requests on the core and dqlite listeners once dqlite has stopped.
+	stopWorkersTimeout    = 30 * time.Second // Waiting for background workers to return.
+	stopDatabaseTimeout   = 30 * time.Second // Stopping dqlite.
+	stopControlTimeout    = 5 * time.Second  // Closing the control socket.
+)
+
+// shutdown stops the daemon in a fixed order, so that nothing is left using a component after it has been stopped:
+//  1. Leadership is handed off and the PreShutdown hook is run, see preShutdown.
+//  2. The extension server listeners stop accepting connections, and in-flight requests are drained.
+//  3. The shutdown context is cancelled, so the core API rejects new requests other than dqlite connections, and
+//     background workers are waited on.
+//  4. The database is stopped, while other cluster members can still open dqlite connections to this member.
+//  5. The core API and dqlite listeners are drained.
+//  6. The control socket is closed.
+//
+// Each phase is bounded by a timeout, after which an error is logged and shutdown moves on to the next phase. The
+// database is not stopped if background workers that may be using it have not returned.
+func (d *Daemon) shutdown() error {
+	var errs []error
+	err := d.shutdownPhase("pre-shutdown", preShutdownTimeout, func(ctx context.Context) error {
+		d.preShutdown(ctx)
+
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if d.endpoints != nil {
+		err := d.shutdownPhase("drain endpoints", drainEndpointsTimeout, func(ctx context.Context) error {
+			return d.endpoints.Drain(ctx, endpoints.EndpointNetwork)
+		})
+		if err != nil {
+			d.log.Error("Failed draining network listeners", logger.Ctx{"error": err})
+			errs = append(errs, err)
+		}
+	}
+
+	workersErr := d.shutdownPhase("stop workers", stopWorkersTimeout, func(ctx context.Context) error {
+		if d.shutdownCancel != nil {
+			d.shutdownCancel()
+		}
+
+		d.clientPool.Clear()
+
+		// Wait for background workers to return before stopping the database they may be using.
+		d.workersMu.Lock()
+		d.workersStopped = true
+		d.workersMu.Unlock()
+		d.workers.Wait()
+
+		return nil
+	})
+	if workersErr != nil {
+		errs = append(errs, workersErr)
+	}
+
+	if d.db != nil && workersErr != nil {
+		d.log.Error("Not stopping the database while background workers are still running")
+	} else if d.db != nil {
+		err := d.shutdownPhase("stop database", stopDatabaseTimeout, func(ctx context.Context) error {
+			return d.db.Stop()
+		})
+		if err != nil {
+			d.log.Error("Failed shutting down database", logger.Ctx{"error": err})
+			errs = append(errs, err)
+		}
+	}
+
+	if d.endpoints != nil {
+		err := d.shutdownPhase("drain database endpoints", drainDatabaseTimeout, func(ctx context.Context) error {
+			return d.endpoints.Drain(ctx, endpoints.EndpointCore, endpoints.EndpointDqlite)
+		})
+		if err != nil {
+			d.log.Error("Failed draining core and dqlite listeners", logger.Ctx{"error": err})
+			errs = append(errs, err)
+		}
+
+		err = d.shutdownPhase("close control socket", stopControlTimeout, func(ctx context.Context) error {
+			return d.endpoints.Down()
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdownPhase runs the named phase of shutdown, and waits for it to return until the timeout elapses. A phase that
+// exceeds its timeout is logged and left to finish in the background, and an error is returned.
+func (d *Daemon) shutdownPhase(name string, timeout time.Duration, phase func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- phase(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		d.log.Error("Shutdown phase exceeded its timeout", logger.Ctx{"phase": name, "timeout": timeout})
+		return fmt.Errorf("Shutdown phase %q exceeded its timeout of %s", name, timeout)
+	}
+}
+
+// preShutdown hands off dqlite leadership to another voter if this cluster member is the leader, and then runs the
+// PreShutdown hook, unless the shutdown is forced. Failures are logged, but do not prevent the shutdown.
+func (d *Daemon) preShutdown(ctx context.Context) {
+	// Skip the hand-off if the daemon never finished starting up.
+	select {
+	case <-d.ReadyChan:
+	default:
+		return
+	}
+
+	if d.forceShutdown.Load() {
+		d.log.Info("Skipping pre-shutdown hand-off for forced shutdown")
+		return
+	}
+
+	s := d.State()
+	if s.Database.IsOpen(ctx) == nil {
+		isLeader, err := s.IsLeader(ctx)
+		if err != nil {
+			d.log.Warn("Failed to check dqlite leadership before shutdown", logger.Ctx{"error": err})
+		} else if isLeader {
+			err = resources.TransferLeadership(ctx, s, "")
+			if err != nil {
+				d.log.Warn("Failed to transfer dqlite leadership before shutdown", logger.Ctx{"error": err})
+			}
+		}
+	}
+
+	err := d.hooks.PreShutdown(ctx, s)
+	if err != nil {
+		d.log.Error("Failed to run pre-shutdown hook", logger.Ctx{"error": err})
+	}
+}
+
+// leaderCheckInterval is how often the daemon checks whether it has gained or lost dqlite leadership.
+const leaderCheckInterval = 5 * time.Second
+
+// watchLeader periodically checks whether the local cluster member is the dqlite leader, and runs the OnLeaderChange
+// hook whenever that changes. Checks that fail, for example while the database is unavailable, are skipped.
+func (d *Daemon) watchLeader() {
+	d.registerWorker(func(ctx context.Context) {
+		s := d.State()
+		isLeader := false
+		ticker := time.NewTicker(leaderCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			leader, err := s.IsLeader(ctx)
+			if err != nil {
+				d.log.Debug("Failed to check dqlite leadership", logger.Ctx{"error": err})
+			} else if leader != isLeader {
+				isLeader = leader
+				d.log.Info("Dqlite leadership changed", logger.Ctx{"leader": isLeader})
+
+				err = d.hooks.OnLeaderChange(ctx, s, isLeader)
+				if err != nil {
+					d.log.Error("Failed to run leader change hook", logger.Ctx{"error": err})
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// keepSpareRole periodically checks the dqlite role of this read-only replica, and demotes it back to spare whenever
+// dqlite has promoted it to keep the target number of voters and stand-bys. Leadership is handed off first if the
+// replica was elected leader. Checks that fail, for example while the database is unavailable, are skipped.
+func (d *Daemon) keepSpareRole() {
+	d.registerWorker(func(ctx context.Context) {
+		s := d.State()
+		ticker := time.NewTicker(leaderCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			err := d.demoteReplica(ctx, s)
+			if err != nil {
+				d.log.Debug("Failed to keep replica-only cluster member as spare", logger.Ctx{"error": err})
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// demoteReplica assigns the spare role to this cluster member if dqlite has given it any other role.
+func (d *Daemon) demoteReplica(ctx context.Context, s *state.State) error {
+	members, err := s.DqliteMembers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.Address.String() != s.Address().URL.Host || member.Role == dqliteClient.Spare.String() {
+			continue
+		}
+
+		isLeader, err := s.IsLeader(ctx)
+		if err != nil {
+			return err
+		}
+
+		if isLeader {
+			err = resources.TransferLeadership(ctx, s, "")
+			if err != nil {
+				return err
+			}
+		}
+
+		d.log.Info("Demoting replica-only cluster member to spare", logger.Ctx{"role": member.Role})
+
+		return s.SetMemberRole(ctx, d.Name(), dqliteClient.Spare.String())
+	}
+
+	return nil
+}
+
+// watchDiskSpace periodically checks the free space in the database directory. When it drops below the threshold, the
+// cluster member is marked as low on disk space and the OnLowDisk hook is run, until the free space recovers.
+func (d *Daemon) watchDiskSpace() {
+	threshold := d.lowDiskThreshold
+	if threshold == 0 {
+		threshold = DefaultLowDiskThreshold
+	}
+
+	if threshold < 0 {
+		return
+	}
+
+	interval := d.diskCheckInterval
+	if interval <= 0 {
+		interval = DefaultDiskCheckInterval
+	}
+
+	d.registerWorker(func(ctx context.Context) {
+		s := d.State()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			free, err := sys.FreeSpace(d.os.DatabaseDir)
+			if err != nil {
+				d.log.Warn("Failed to check free disk space", logger.Ctx{"error": err})
+			} else if free < uint64(threshold) && !d.lowDisk.Swap(true) {
+				d.log.Warn("Database directory is low on disk space", logger.Ctx{"path": d.os.DatabaseDir, "free": free, "threshold": threshold})
+
+				err = d.hooks.OnLowDisk(ctx, s, free)
+				if err != nil {
+					d.log.Error("Failed to run low disk hook", logger.Ctx{"error": err})
+				}
+			} else if free >= uint64(threshold) && d.lowDisk.Swap(false) {
+				d.log.Info("Database directory has recovered disk space", logger.Ctx{"path": d.os.DatabaseDir, "free": free})
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// drain stops the daemon from accepting new writes and reports it as unavailable to health checks, and then waits for
+// its running operations to complete.
+func (d *Daemon) drain(ctx context.Context) error {
+	if !d.draining.Swap(true) {
+		d.log.Info("Draining cluster member", logger.Ctx{"member": d.Name()})
+	}
+
+	return d.operations.Wait(ctx)
+}
+
+// undrain has the daemon accept new writes and pass health checks again after it was drained.
+func (d *Daemon) undrain() {
+	if d.draining.Swap(false) {
+		d.log.Info("Cluster member is no longer draining", logger.Ctx{"member": d.Name()})
+	}
+}
+
+// configCodec encodes and decodes the daemon configuration file in a particular format.
+type configCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// yamlCodec encodes the daemon configuration as YAML.
+type yamlCodec struct{}
+
+// Marshal implements configCodec.
+func (yamlCodec) Marshal(v any) ([]byte, error) { return yaml.Marshal(v) }
+
+// Unmarshal implements configCodec.
+func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.Unmarshal(data, v) }
+
+// jsonCodec encodes the daemon configuration as JSON.
+type jsonCodec struct{}
+
+// Marshal implements configCodec.
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.MarshalIndent(v, "", "  ") }
+
+// Unmarshal implements configCodec.
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// daemonConfigFiles are the supported daemon configuration files in the state directory, and their formats.
+var daemonConfigFiles = map[string]configCodec{
+	"daemon.yaml": yamlCodec{},
+	"daemon.json": jsonCodec{},
+}
+
+// daemonConfigFile returns the name of the daemon configuration file that the daemon writes.
+func (d *Daemon) daemonConfigFile() string {
+	if d.daemonConfigJSON {
+		return "daemon.json"
+	}
+
+	return "daemon.yaml"
+}
+
+// findDaemonConfig returns the path and format of the existing daemon configuration file, preferring the format the
+// daemon writes. An error satisfying os.IsNotExist is returned if there is none.
+func (d *Daemon) findDaemonConfig() (string, configCodec, error) {
+	names := []string{d.daemonConfigFile()}
+	for name := range daemonConfigFiles {
+		if name != names[0] {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		path := filepath.Join(d.os.StateDir, name)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, daemonConfigFiles[name], nil
+		}
+
+		if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+
+	return "", nil, fs.ErrNotExist
+}
+
+// setDaemonConfig sets the daemon's address and name from the given location information. If none is supplied, the
+// existing daemon configuration file in the state directory will be read for the information, in either format.
+// Otherwise the file is written in the format selected for the daemon, replacing any file in the other format.
 func (d *Daemon) setDaemonConfig(config *trust.Location) error {
 	if config != nil {
-		bytes, err := yaml.Marshal(config)
+		name := d.daemonConfigFile()
+		bytes, err := daemonConfigFiles[name].Marshal(config)
 		if err != nil {
-			return fmt.Errorf("Failed to parse daemon config to yaml: %w", err)
+			return fmt.Errorf("Failed to encode daemon configuration: %w", err)
 		}
 
-		err = os.WriteFile(filepath.Join(d.os.StateDir, "daemon.yaml"), bytes, 0644)
+		err = renameio.WriteFile(filepath.Join(d.os.StateDir, name), bytes, 0644)
 		if err != nil {
-			return fmt.Errorf("Failed to write daemon configuration yaml: %w", err)
+			return fmt.Errorf("Failed to write daemon configuration %q: %w", name, err)
+		}
+
+		for other := range daemonConfigFiles {
+			if other == name {
+				continue
+			}
+
+			err = os.Remove(filepath.Join(d.os.StateDir, other))
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("Failed to remove daemon configuration %q: %w", other, err)
+			}
 		}
 	} else {
-		data, err := os.ReadFile(filepath.Join(d.os.StateDir, "daemon.yaml"))
+		path, codec, err := d.findDaemonConfig()
 		if err != nil {
 			return fmt.Errorf("Failed to find daemon configuration: %w", err)
 		}
 
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Failed to read daemon configuration: %w", err)
+		}
+
 		config = &trust.Location{}
-		err = yaml.Unmarshal(data, config)
+		err = codec.Unmarshal(data, config)
 		if err != nil {
-			return fmt.Errorf("Failed to parse daemon config from yaml: %w", err)
+			return fmt.Errorf("Failed to parse daemon configuration %q: %w", path, err)
 		}
 	}
 
-	d.address = *api.NewURL().Scheme("https").Host(config.Address.String())
+	d.address = *api.NewURL().Scheme(sys.Scheme()).Host(config.Address.String())
 	d.name = config.Name
+	d.iface = config.Interface
 
 	return nil
 }
+
+// setMemberName records a new name for this cluster member in the daemon configuration.
+func (d *Daemon) setMemberName(name string) error {
+	addrPort, err := types.ParseAddrPort(d.address.URL.Host)
+	if err != nil {
+		return fmt.Errorf("Failed to parse listen address: %w", err)
+	}
+
+	return d.setDaemonConfig(&trust.Location{Name: name, Address: addrPort, Interface: d.iface})
+}
+
+// resolveInterfaceAddress updates the listen address with the current address of the configured network interface,
+// waiting for the interface to be assigned an address if necessary. If the address has changed since it was last
+// resolved, the daemon configuration is updated, unless this member is already initialized, in which case the
+// other cluster members still know it by its recorded address and an error is returned instead.
+func (d *Daemon) resolveInterfaceAddress(initialized bool) error {
+	if d.iface == "" {
+		return nil
+	}
+
+	oldAddrPort, err := types.ParseAddrPort(d.address.URL.Host)
+	if err != nil {
+		return fmt.Errorf("Failed to parse listen address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(d.shutdownCtx, 30*time.Second)
+	defer cancel()
+
+	addr, err := sys.WaitInterfaceAddress(ctx, d.iface)
+	if err != nil {
+		return err
+	}
+
+	newAddrPort := types.AddrPort{AddrPort: netip.AddrPortFrom(addr, oldAddrPort.Port())}
+	if newAddrPort == oldAddrPort {
+		return nil
+	}
+
+	if initialized {
+		return fmt.Errorf("Address %q of network interface %q differs from the recorded cluster member address %q", newAddrPort.String(), d.iface, oldAddrPort.String())
+	}
+
+	d.log.Warn("Address of network interface has changed", logger.Ctx{"interface": d.iface, "old": oldAddrPort.String(), "new": newAddrPort.String()})
+
+	return d.setDaemonConfig(&trust.Location{Name: d.name, Address: newAddrPort, Interface: d.iface})
+}
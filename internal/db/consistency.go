@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// consistencyPollInterval is how often the consistency index is checked while waiting for it to catch up.
+const consistencyPollInterval = 100 * time.Millisecond
+
+// NextConsistencyIndex increments the cluster-wide consistency index and returns its new value.
+// The increment is committed through the dqlite leader after every write committed before it, so any cluster member
+// that observes the returned index has also observed those writes.
+func (db *DB) NextConsistencyIndex(ctx context.Context) (uint64, error) {
+	var index uint64
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE internal_consistency_index SET value = value + 1 WHERE id = 1")
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, "SELECT value FROM internal_consistency_index WHERE id = 1").Scan(&index)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to increment consistency index: %w", err)
+	}
+
+	return index, nil
+}
+
+// ConsistencyIndex returns the consistency index. Like every query through Transaction, it is read through the dqlite
+// leader rather than from the local replica, so it is the latest committed value.
+func (db *DB) ConsistencyIndex(ctx context.Context) (uint64, error) {
+	var index uint64
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT value FROM internal_consistency_index WHERE id = 1").Scan(&index)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get consistency index: %w", err)
+	}
+
+	return index, nil
+}
+
+// WaitConsistencyIndex waits until the consistency index is at least the given value, or the context is cancelled.
+// As the index is read through the dqlite leader, it only waits if the token was issued by a different leader whose
+// writes are not yet committed, so in practice it returns once the leader is reachable.
+func (db *DB) WaitConsistencyIndex(ctx context.Context, index uint64) error {
+	for {
+		current, err := db.ConsistencyIndex(ctx)
+		if err != nil {
+			return err
+		}
+
+		if current >= index {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Consistency index is %d, waiting for %d: %w", current, index, ctx.Err())
+		case <-time.After(consistencyPollInterval):
+		}
+	}
+}
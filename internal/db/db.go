@@ -18,8 +18,10 @@ import (
 	"github.com/canonical/lxd/shared/revert"
 
 	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/internal/db/update"
 	"github.com/canonical/microcluster/internal/extensions"
 	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 // Open opens the dqlite database and loads the schema.
@@ -84,10 +86,10 @@ func (db *DB) Open(ext extensions.Extensions, bootstrap bool, project string) er
 
 // waitUpgrade compares the version information of all cluster members in the database to the local version.
 // If this node's version is ahead of others, then it will block on the `db.upgradeCh` or up to a minute.
-// If this node's version is behind others, then it returns an error.
+// If this node's version is behind others, then it returns a types.SchemaVersionMismatch error.
 func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
-	checkSchemaVersion := func(schemaVersion uint64, clusterMemberVersions []uint64) (otherNodesBehind bool, err error) {
-		nodeIsBehind := false
+	checkSchemaVersion := func(schemaVersion uint64, clusterMemberVersions []uint64) (otherNodesBehind bool, clusterVersion uint64, nodeIsBehind bool) {
+		clusterVersion = schemaVersion
 		for _, version := range clusterMemberVersions {
 			if schemaVersion == version {
 				// Versions are equal, there's hope for the
@@ -99,18 +101,21 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 				// Our version is bigger, we should stop here
 				// and wait for other nodes to be upgraded and
 				// restarted.
-				nodeIsBehind = true
+				otherNodesBehind = true
 				continue
 			}
 
 			// Another node has a version greater than ours
 			// and presumeably is waiting for other nodes
-			// to upgrade. Let's error out and shutdown
-			// since we need a greater version.
-			return false, fmt.Errorf("This node's version is behind, please upgrade")
+			// to upgrade. Record the highest version so we
+			// can report what we need to upgrade to.
+			nodeIsBehind = true
+			if version > clusterVersion {
+				clusterVersion = version
+			}
 		}
 
-		return nodeIsBehind, nil
+		return otherNodesBehind, clusterVersion, nodeIsBehind
 	}
 
 	checkAPIExtensions := func(currentAPIExtensions extensions.Extensions, clusterMemberAPIExtensions []extensions.Extensions) (otherNodesBehind bool, err error) {
@@ -133,7 +138,17 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 				// and presumeably is waiting for other nodes
 				// to upgrade. Let's error out and shutdown
 				// since we need a greater version.
-				return false, fmt.Errorf("This node's API extensions are behind, please upgrade")
+				schemaVersionInternal, schemaVersionExternal, _ := db.Schema().Version()
+				missing, extra := currentAPIExtensions.Diff(extensions)
+
+				return false, types.SchemaVersionMismatch{
+					LocalSchemaInternal:   schemaVersionInternal,
+					LocalSchemaExternal:   schemaVersionExternal,
+					ClusterSchemaInternal: schemaVersionInternal,
+					ClusterSchemaExternal: schemaVersionExternal,
+					MissingExtensions:     missing,
+					ExtraExtensions:       extra,
+				}
 			}
 		}
 
@@ -155,19 +170,27 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 				return fmt.Errorf("Failed to get other members' schema versions: %w", err)
 			}
 
-			otherNodesBehindInternal, err := checkSchemaVersion(schemaVersionInternal, versionsInternal)
-			if err != nil {
-				return err
+			otherNodesBehindInternal, clusterVersionInternal, behindInternal := checkSchemaVersion(schemaVersionInternal, versionsInternal)
+			otherNodesBehindExternal, clusterVersionExternal, behindExternal := checkSchemaVersion(schemaVersionExternal, versionsExternal)
+
+			// If another node has a version greater than ours, error out and shutdown since we need a greater version.
+			if behindInternal || behindExternal {
+				return types.SchemaVersionMismatch{
+					LocalSchemaInternal:   schemaVersionInternal,
+					LocalSchemaExternal:   schemaVersionExternal,
+					ClusterSchemaInternal: clusterVersionInternal,
+					ClusterSchemaExternal: clusterVersionExternal,
+				}
 			}
 
-			otherNodesBehindExternal, err := checkSchemaVersion(schemaVersionExternal, versionsExternal)
+			otherNodesBehindExtensions, err := db.checkSchemaExtensions(ctx, tx, newSchema)
 			if err != nil {
 				return err
 			}
 
-			// Wait until after considering both internal and external schema versions to determine if we should wait for other nodes.
+			// Wait until after considering internal, external, and extension schema versions to determine if we should wait for other nodes.
 			// This is to prevent nodes accidentally waiting for each other in case of an awkward upgrade.
-			if otherNodesBehindInternal || otherNodesBehindExternal {
+			if otherNodesBehindInternal || otherNodesBehindExternal || otherNodesBehindExtensions {
 				otherNodesBehind = true
 
 				return schema.ErrGracefulAbort
@@ -194,6 +217,12 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 					return fmt.Errorf("Failed to update API extensions when joining cluster: %w", err)
 				}
 
+				// The schema extension versions can't be recorded before the internal schema update tracking them.
+				err = db.recordSchemaExtensions(ctx, tx)
+				if err != nil {
+					return err
+				}
+
 				clusterMembersAPIExtensions, err := cluster.GetClusterMemberAPIExtensions(ctx, tx)
 				if err != nil {
 					return fmt.Errorf("Failed to get other members' API extensions: %w", err)
@@ -225,7 +254,13 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 		db.status = StatusWaiting
 		db.statusLock.Unlock()
 
-		logger.Warn("Waiting for other cluster members to upgrade their versions", logger.Ctx{"address": db.listenAddr.String()})
+		logCtx := logger.Ctx{"address": db.listenAddr.String()}
+		awaiting, awaitingErr := db.AwaitingMembers(db.ctx)
+		if awaitingErr == nil {
+			logCtx["awaiting"] = awaiting
+		}
+
+		logger.Warn("Waiting for other cluster members to upgrade their versions before applying the schema update", logCtx)
 		select {
 		case <-db.upgradeCh:
 		case <-time.After(30 * time.Second):
@@ -235,6 +270,91 @@ func (db *DB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error {
 	return err
 }
 
+// recordSchemaExtensions records the versions of named schema extensions supported by this cluster member,
+// if the database already tracks them.
+func (db *DB) recordSchemaExtensions(ctx context.Context, tx *sql.Tx) error {
+	exists, err := cluster.HasSchemaExtensionTables(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("Failed to check for schema extension tables: %w", err)
+	}
+
+	if !exists {
+		return nil
+	}
+
+	err = cluster.UpdateClusterMemberSchemaExtensions(ctx, tx, db.Schema().ExtensionVersions(), db.listenAddr.URL.Host)
+	if err != nil {
+		return fmt.Errorf("Failed to update schema extension versions: %w", err)
+	}
+
+	return nil
+}
+
+// checkSchemaExtensions records the versions of named schema extensions supported by this cluster member, and compares
+// them to those of all other cluster members. If another cluster member supports a newer version of any extension,
+// a types.SchemaVersionMismatch error is returned. Otherwise, it returns whether any other cluster members are behind.
+func (db *DB) checkSchemaExtensions(ctx context.Context, tx *sql.Tx, newSchema *update.SchemaUpdate) (otherNodesBehind bool, err error) {
+	exists, err := cluster.HasSchemaExtensionTables(ctx, tx)
+	if err != nil {
+		return false, fmt.Errorf("Failed to check for schema extension tables: %w", err)
+	}
+
+	// If the tables don't exist yet, the cluster has not yet been upgraded to track schema extensions.
+	if !exists {
+		return false, nil
+	}
+
+	localVersions := newSchema.ExtensionVersions()
+	err = cluster.UpdateClusterMemberSchemaExtensions(ctx, tx, localVersions, db.listenAddr.URL.Host)
+	if err != nil {
+		return false, fmt.Errorf("Failed to update schema extension versions: %w", err)
+	}
+
+	memberVersions, err := cluster.GetClusterMemberSchemaExtensions(ctx, tx)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get other members' schema extension versions: %w", err)
+	}
+
+	behindLocal := map[string]uint64{}
+	behindCluster := map[string]uint64{}
+	for _, versions := range memberVersions {
+		names := make(map[string]bool, len(localVersions)+len(versions))
+		for name := range localVersions {
+			names[name] = true
+		}
+
+		for name := range versions {
+			names[name] = true
+		}
+
+		for name := range names {
+			if localVersions[name] > versions[name] {
+				otherNodesBehind = true
+			} else if localVersions[name] < versions[name] {
+				behindLocal[name] = localVersions[name]
+				if versions[name] > behindCluster[name] {
+					behindCluster[name] = versions[name]
+				}
+			}
+		}
+	}
+
+	if len(behindCluster) > 0 {
+		schemaVersionInternal, schemaVersionExternal, _ := newSchema.Version()
+
+		return false, types.SchemaVersionMismatch{
+			LocalSchemaInternal:     schemaVersionInternal,
+			LocalSchemaExternal:     schemaVersionExternal,
+			ClusterSchemaInternal:   schemaVersionInternal,
+			ClusterSchemaExternal:   schemaVersionExternal,
+			LocalSchemaExtensions:   behindLocal,
+			ClusterSchemaExtensions: behindCluster,
+		}
+	}
+
+	return otherNodesBehind, nil
+}
+
 // Transaction handles performing a transaction on the dqlite database.
 func (db *DB) Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
 	status := db.Status()
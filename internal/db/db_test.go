@@ -3,19 +3,27 @@ package db
 import (
 	"context"
 	"database/sql"
+	sqlDriver "database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/go-dqlite/driver"
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/db/schema"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/internal/db/update"
 	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 type dbSuite struct {
@@ -60,49 +68,49 @@ func (s *dbSuite) Test_waitUpgradeSchema() {
 			name:              "All other nodes ahead (internal only)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 1, schemaExt: 0}, {schemaInt: 1, schemaExt: 0}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 2, ClusterSchemaExternal: 1},
 		},
 		{
 			name:              "All other nodes ahead (external only)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 0, schemaExt: 1}, {schemaInt: 0, schemaExt: 1}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 1, ClusterSchemaExternal: 2},
 		},
 		{
 			name:              "All other nodes ahead (internal/external mismatch)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 1, schemaExt: 0}, {schemaInt: 0, schemaExt: 1}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 2, ClusterSchemaExternal: 2},
 		},
 		{
 			name:              "All other nodes ahead (both internal/external)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 1, schemaExt: 1}, {schemaInt: 1, schemaExt: 1}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 2, ClusterSchemaExternal: 2},
 		},
 		{
 			name:              "All other nodes ahead (other node is waiting)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 2, schemaExt: 2}, {schemaInt: 1, schemaExt: 1}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 3, ClusterSchemaExternal: 3},
 		},
 		{
 			name:              "Some other nodes ahead (internal only)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 0, schemaExt: 0}, {schemaInt: 1, schemaExt: 0}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 2, ClusterSchemaExternal: 1},
 		},
 		{
 			name:              "Some other nodes ahead (external only)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 0, schemaExt: 0}, {schemaInt: 0, schemaExt: 1}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 1, ClusterSchemaExternal: 2},
 		},
 		{
 			name:              "Some other nodes ahead (both internal/external)",
 			upgradedLocalInfo: versions{schemaInt: 0, schemaExt: 0},
 			clusterMembers:    []versions{{schemaInt: 0, schemaExt: 0}, {schemaInt: 1, schemaExt: 1}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 2, ClusterSchemaExternal: 2},
 		},
 		{
 			name:              "All other nodes behind (internal only)",
@@ -150,7 +158,7 @@ func (s *dbSuite) Test_waitUpgradeSchema() {
 			name:              "Some nodes behind, others ahead (both internal/external)",
 			upgradedLocalInfo: versions{schemaInt: 1, schemaExt: 1},
 			clusterMembers:    []versions{{schemaInt: 0, schemaExt: 0}, {schemaInt: 2, schemaExt: 2}},
-			expectErr:         fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr:         types.SchemaVersionMismatch{LocalSchemaInternal: 2, LocalSchemaExternal: 2, ClusterSchemaInternal: 3, ClusterSchemaExternal: 3},
 		},
 	}
 
@@ -305,13 +313,13 @@ func (s *dbSuite) Test_waitUpgradeAPI() {
 			name:                       "All other nodes ahead",
 			upgradedLocalAPIExtensions: extensions.Extensions{"internal:a", "ext"},
 			clusterMembersExtensions:   []extensions.Extensions{{"internal:a", "ext", "ext2"}, {"internal:a", "ext", "ext2"}},
-			expectErr:                  fmt.Errorf("This node's API extensions are behind, please upgrade"),
+			expectErr:                  types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 1, ClusterSchemaExternal: 1, MissingExtensions: []string{"ext2"}},
 		},
 		{
 			name:                       "Some other nodes ahead",
 			upgradedLocalAPIExtensions: extensions.Extensions{"internal:a", "ext"},
 			clusterMembersExtensions:   []extensions.Extensions{{"internal:a", "ext"}, {"internal:a", "ext", "ext2"}},
-			expectErr:                  fmt.Errorf("This node's API extensions are behind, please upgrade"),
+			expectErr:                  types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 1, ClusterSchemaExternal: 1, MissingExtensions: []string{"ext2"}},
 		},
 		{
 			name:                       "All other nodes behind",
@@ -329,7 +337,7 @@ func (s *dbSuite) Test_waitUpgradeAPI() {
 			name:                       "Some nodes behind, others ahead",
 			upgradedLocalAPIExtensions: extensions.Extensions{"internal:a", "ext", "ext2"},
 			clusterMembersExtensions:   []extensions.Extensions{{"internal:a", "ext"}, {"internal:a", "ext", "ext2", "ext3"}},
-			expectErr:                  fmt.Errorf("This node's API extensions are behind, please upgrade"),
+			expectErr:                  types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 1, ClusterSchemaExternal: 1, MissingExtensions: []string{"ext3"}},
 		},
 	}
 
@@ -470,7 +478,7 @@ func (s *dbSuite) Test_waitUpgradeSchemaAndAPI() {
 				{schemaInt: 1, schemaExt: 1, ext: extensions.Extensions{"internal:a"}},
 				{schemaInt: 1, schemaExt: 1, ext: extensions.Extensions{"internal:a"}},
 			},
-			expectErr: fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr: types.SchemaVersionMismatch{LocalSchemaInternal: 1, LocalSchemaExternal: 1, ClusterSchemaInternal: 2, ClusterSchemaExternal: 2},
 		},
 		{
 			name: "Local node ahead, all other nodes behind",
@@ -496,7 +504,7 @@ func (s *dbSuite) Test_waitUpgradeSchemaAndAPI() {
 				{schemaInt: 2, schemaExt: 2, ext: extensions.Extensions{"internal:a", "b"}},
 				{schemaInt: 0, schemaExt: 0, ext: extensions.Extensions{"internal:a", "b"}},
 			},
-			expectErr: fmt.Errorf("This node's version is behind, please upgrade"),
+			expectErr: types.SchemaVersionMismatch{LocalSchemaInternal: 2, LocalSchemaExternal: 2, ClusterSchemaInternal: 3, ClusterSchemaExternal: 3},
 		},
 	}
 
@@ -647,6 +655,306 @@ func (s *dbSuite) Test_waitUpgradeSchemaAndAPI() {
 	}
 }
 
+// Ensures dqlite connections to link-local IPv6 addresses keep the zone, and encode it in the request URL.
+func (s *dbSuite) Test_databaseURL() {
+	u := databaseURL("[fe80::1%eth0]:9000")
+	s.Equal("[fe80::1%eth0]:9000", u.Host)
+	s.Equal("fe80::1%eth0", u.Hostname())
+	s.Contains(u.String(), "[fe80::1%25eth0]:9000")
+
+	addrPort, err := types.ParseAddrPort(u.Host)
+	s.NoError(err)
+	s.Equal("eth0", addrPort.Addr().Zone())
+}
+
+// Ensures leadership changes are distinguished from other database errors.
+func (s *dbSuite) Test_IsLeadershipError() {
+	s.True(IsLeadershipError(driver.ErrNoAvailableLeader))
+	s.True(IsLeadershipError(fmt.Errorf("Failed to connect to leader: %w", driver.ErrNoAvailableLeader)))
+	s.True(IsLeadershipError(sqlDriver.ErrBadConn))
+	s.True(IsLeadershipError(fmt.Errorf("Failed to commit transaction: %w", sqlDriver.ErrBadConn)))
+	s.False(IsLeadershipError(nil))
+	s.False(IsLeadershipError(sql.ErrNoRows))
+	s.False(IsLeadershipError(driver.Error{Code: driver.ErrBusy, Message: "database is locked"}))
+
+	// Errors of the application that mention leadership are not mistaken for leadership changes.
+	s.False(IsLeadershipError(fmt.Errorf("Member is not leader of its group")))
+	s.False(IsLeadershipError(driver.Error{Code: 10 | 40<<8, Message: "not leader"}))
+}
+
+// Ensures an idempotent transaction is retried once the leader changes, and is not retried for other errors.
+func (s *dbSuite) Test_IdempotentTransaction() {
+	db, err := NewTestDB(nil)
+	s.Require().NoError(err)
+
+	db.status = StatusReady
+
+	// Pretend that a new leader has been elected and found to be reachable.
+	db.leaderReachableChecked = time.Now()
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		success  bool
+	}{
+		{
+			name:     "Leader lost during the transaction",
+			errs:     []error{fmt.Errorf("Failed to begin transaction: %w", driver.ErrNoAvailableLeader)},
+			attempts: 2,
+			success:  true,
+		},
+		{
+			name:     "Leader lost on every attempt",
+			errs:     []error{driver.ErrNoAvailableLeader, driver.ErrNoAvailableLeader},
+			attempts: MaxLeadershipRetries + 1,
+		},
+		{
+			name:     "Application error mentioning leadership",
+			errs:     []error{fmt.Errorf("Member is not leader of its group")},
+			attempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		attempts := 0
+		err := db.IdempotentTransaction(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			if attempts <= len(test.errs) {
+				return test.errs[attempts-1]
+			}
+
+			return nil
+		})
+
+		s.Equal(test.attempts, attempts)
+		if test.success {
+			s.NoError(err)
+		} else {
+			s.Error(err)
+		}
+	}
+}
+
+// Ensures the heartbeat history keeps only the most recent outcomes of each member, oldest first.
+func (s *dbSuite) Test_heartbeatHistory() {
+	db := &DB{}
+	db.SetHeartbeatHistoryDepth(3)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		db.RecordHeartbeatOutcome("c1", types.HeartbeatOutcome{Time: start.Add(time.Duration(i) * time.Second), Success: i%2 == 0})
+	}
+
+	db.RecordHeartbeatOutcome("c2", types.HeartbeatOutcome{Time: start, Error: "unreachable"})
+
+	history := db.HeartbeatHistory("c1")
+	s.Len(history, 3)
+	for i, outcome := range history {
+		s.Equal(start.Add(time.Duration(i+2)*time.Second), outcome.Time)
+		s.Equal(i%2 == 0, outcome.Success)
+	}
+
+	s.Len(db.HeartbeatHistory("c2"), 1)
+	s.Empty(db.HeartbeatHistory("c3"))
+
+	db.ForgetHeartbeatHistory(map[string]bool{"c1": true})
+	s.Len(db.HeartbeatHistory("c1"), 3)
+	s.Empty(db.HeartbeatHistory("c2"))
+
+	db.SetHeartbeatHistoryDepth(-1)
+	db.RecordHeartbeatOutcome("c2", types.HeartbeatOutcome{Time: start})
+	s.Empty(db.HeartbeatHistory("c2"))
+}
+
+// Ensures the end of the most recent pause of heartbeats is kept after they are resumed, so that heartbeats sent
+// before then can be told apart from those sent since.
+func (s *dbSuite) Test_heartbeatsLastPaused() {
+	db := &DB{}
+	s.True(db.HeartbeatsLastPaused().IsZero())
+
+	// Resuming heartbeats that were never paused does not record a pause.
+	db.PauseHeartbeats(0)
+	s.True(db.HeartbeatsLastPaused().IsZero())
+
+	sent := time.Now()
+	db.PauseHeartbeats(time.Hour)
+	until, paused := db.HeartbeatsPausedUntil()
+	s.True(paused)
+	s.Equal(until, db.HeartbeatsLastPaused())
+	s.False(sent.After(db.HeartbeatsLastPaused()))
+
+	db.PauseHeartbeats(0)
+	_, paused = db.HeartbeatsPausedUntil()
+	s.False(paused)
+	s.False(db.HeartbeatsLastPaused().After(time.Now()))
+	s.False(sent.After(db.HeartbeatsLastPaused()))
+}
+
+// Ensures the time of the last heartbeat received is recorded.
+func (s *dbSuite) Test_heartbeatReceived() {
+	db := &DB{}
+	_, ok := db.HeartbeatReceived()
+	s.False(ok)
+
+	before := time.Now()
+	db.RecordHeartbeatReceived()
+	received, ok := db.HeartbeatReceived()
+	s.True(ok)
+	s.False(before.After(received))
+}
+
+// Ensures the recovery tarball replaces the database of another surviving node, keeping its node info, and is only
+// loaded by a node that is part of the recovered cluster configuration.
+func (s *dbSuite) Test_recoveryTarball() {
+	writeNodeFiles := func(dir string, local dqliteClient.NodeInfo, nodes []dqliteClient.NodeInfo, files map[string]string) {
+		data, err := yaml.Marshal(local)
+		s.Require().NoError(err)
+		s.Require().NoError(os.WriteFile(filepath.Join(dir, "info.yaml"), data, 0600))
+
+		data, err = yaml.Marshal(nodes)
+		s.Require().NoError(err)
+		s.Require().NoError(os.WriteFile(filepath.Join(dir, "cluster.yaml"), data, 0600))
+
+		for name, content := range files {
+			s.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+		}
+	}
+
+	node1 := dqliteClient.NodeInfo{ID: 1, Address: "10.0.0.1:9000", Role: dqliteClient.Voter}
+	node2 := dqliteClient.NodeInfo{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Voter}
+	node3 := dqliteClient.NodeInfo{ID: 3, Address: "10.0.0.3:9000", Role: dqliteClient.Voter}
+
+	recovered := s.T().TempDir()
+	writeNodeFiles(recovered, node1, []dqliteClient.NodeInfo{node1, node2}, map[string]string{"0000000000000001-0000000000000002": "recovered", "metadata1": "recovered"})
+
+	tarball := filepath.Join(s.T().TempDir(), RecoveryTarballName)
+	s.Require().NoError(writeRecoveryTarball(recovered, tarball))
+
+	survivor := s.T().TempDir()
+	writeNodeFiles(survivor, node2, []dqliteClient.NodeInfo{node1, node2, node3}, map[string]string{"0000000000000001-0000000000000001": "stale", "metadata1": "stale"})
+	s.Require().NoError(loadRecoveryTarball(tarball, survivor))
+
+	entries, err := os.ReadDir(survivor)
+	s.Require().NoError(err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	s.ElementsMatch([]string{"0000000000000001-0000000000000002", "cluster.yaml", "info.yaml", "metadata1"}, names)
+
+	data, err := os.ReadFile(filepath.Join(survivor, "metadata1"))
+	s.Require().NoError(err)
+	s.Equal("recovered", string(data))
+
+	var local dqliteClient.NodeInfo
+	data, err = os.ReadFile(filepath.Join(survivor, "info.yaml"))
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(data, &local))
+	s.Equal(node2, local)
+
+	var nodes []dqliteClient.NodeInfo
+	data, err = os.ReadFile(filepath.Join(survivor, "cluster.yaml"))
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(data, &nodes))
+	s.Equal([]dqliteClient.NodeInfo{node1, node2}, nodes)
+
+	// A node that was removed from the cluster configuration keeps its database.
+	lost := s.T().TempDir()
+	writeNodeFiles(lost, node3, []dqliteClient.NodeInfo{node1, node2, node3}, map[string]string{"metadata1": "lost"})
+	s.Error(loadRecoveryTarball(tarball, lost))
+
+	data, err = os.ReadFile(filepath.Join(lost, "metadata1"))
+	s.Require().NoError(err)
+	s.Equal("lost", string(data))
+}
+
+// Ensures additional certificates of a cluster member are recorded, and can be promoted and retired.
+func (s *dbSuite) Test_certificateRotation() {
+	db, err := NewTestDB([]schema.Update{})
+	s.Require().NoError(err)
+
+	certs := make([]types.X509Certificate, 3)
+	for i := range certs {
+		cert, key, err := shared.GenerateMemCert(true, false)
+		s.Require().NoError(err)
+
+		certInfo, err := shared.KeyPairFromRaw(cert, key)
+		s.Require().NoError(err)
+
+		certs[i].Certificate, err = certInfo.PublicKeyX509()
+		s.Require().NoError(err)
+	}
+
+	fingerprint := func(cert types.X509Certificate) string { return shared.CertFingerprint(cert.Certificate) }
+
+	ctx := context.Background()
+	tx, err := db.db.BeginTx(ctx, nil)
+	s.Require().NoError(err)
+
+	defer func() { _ = tx.Rollback() }()
+
+	apiExtensions, err := extensions.NewExtensionRegistry(true)
+	s.Require().NoError(err)
+
+	_, err = cluster.CreateInternalClusterMember(ctx, tx, cluster.InternalClusterMember{
+		Name:          "cluster-member-0",
+		Address:       "10.0.0.0:8443",
+		Certificate:   certs[0].String(),
+		APIExtensions: apiExtensions,
+		Role:          "voter",
+	})
+	s.Require().NoError(err)
+
+	// Adding the certificate of the member or an existing additional certificate does nothing.
+	s.NoError(cluster.AddAdditionalCertificate(ctx, tx, "cluster-member-0", certs[0]))
+	s.NoError(cluster.AddAdditionalCertificate(ctx, tx, "cluster-member-0", certs[1]))
+	s.NoError(cluster.AddAdditionalCertificate(ctx, tx, "cluster-member-0", certs[1]))
+	s.NoError(cluster.AddAdditionalCertificate(ctx, tx, "cluster-member-0", certs[2]))
+	s.Error(cluster.AddAdditionalCertificate(ctx, tx, "cluster-member-1", certs[1]))
+
+	additional, err := cluster.GetAdditionalCertificates(ctx, tx)
+	s.Require().NoError(err)
+	s.Require().Len(additional["cluster-member-0"], 2)
+	s.Equal(fingerprint(certs[1]), fingerprint(additional["cluster-member-0"][0]))
+	s.Equal(fingerprint(certs[2]), fingerprint(additional["cluster-member-0"][1]))
+
+	// Promoting a certificate keeps the previous one as an additional certificate.
+	certificate, others, err := cluster.PromoteCertificate(ctx, tx, "cluster-member-0", fingerprint(certs[1]))
+	s.Require().NoError(err)
+	s.Equal(fingerprint(certs[1]), fingerprint(certificate))
+	s.Len(others, 2)
+
+	member, err := cluster.GetInternalClusterMember(ctx, tx, "cluster-member-0")
+	s.Require().NoError(err)
+	s.Equal(certs[1].String(), member.Certificate)
+
+	// The certificate of the member can't be retired, and unknown certificates can't be promoted or retired.
+	_, _, err = cluster.RetireCertificate(ctx, tx, "cluster-member-0", fingerprint(certs[1]))
+	s.Error(err)
+
+	_, _, err = cluster.PromoteCertificate(ctx, tx, "cluster-member-0", "unknown")
+	s.Error(err)
+
+	_, _, err = cluster.RetireCertificate(ctx, tx, "cluster-member-0", "unknown")
+	s.Error(err)
+
+	_, others, err = cluster.RetireCertificate(ctx, tx, "cluster-member-0", fingerprint(certs[0]))
+	s.Require().NoError(err)
+	s.Require().Len(others, 1)
+	s.Equal(fingerprint(certs[2]), fingerprint(others[0]))
+
+	_, _, err = cluster.RetireCertificate(ctx, tx, "cluster-member-0", fingerprint(certs[2]))
+	s.Require().NoError(err)
+
+	additional, err = cluster.GetAdditionalCertificates(ctx, tx)
+	s.Require().NoError(err)
+	s.Empty(additional)
+}
+
 // NewTedb returns a sqlite DB set up with the default microcluster schema.
 func NewTestDB(extensionsExternal []schema.Update) (*DB, error) {
 	var err error
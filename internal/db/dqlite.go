@@ -13,9 +13,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	dqliteNode "github.com/canonical/go-dqlite"
 	dqlite "github.com/canonical/go-dqlite/app"
 	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/db/schema"
@@ -43,15 +45,43 @@ type DB struct {
 	dbName string // This is db.bin.
 	os     *sys.OS
 
-	db        *sql.DB
-	dqlite    *dqlite.App
-	acceptCh  chan net.Conn
-	upgradeCh chan struct{}
+	db     *sql.DB
+	dqlite *dqlite.App
+	dial   dqliteClient.DialFunc // Optional dialer for connections to other dqlite nodes.
+
+	dqliteAddress string                      // Optional address at which dqlite connections are accepted, instead of listenAddr.
+	dialAddress   func(address string) string // Optional mapping from the address of a dqlite node to the address to dial.
+
+	failureDomain uint64 // Failure domain of this dqlite node.
+	voters        int    // Target number of dqlite voters.
+	standBys      int    // Target number of dqlite stand-bys, or negative for the default.
+
+	snapshotThreshold uint64 // Raft log entries after which a snapshot is taken, or zero for the default.
+	snapshotTrailing  uint64 // Raft log entries retained after a snapshot, or zero for the default.
+	acceptCh          chan net.Conn
+	upgradeCh         chan struct{}
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	logFunc dqliteClient.LogFunc // Optional log function for dqlite's own log messages.
+
+	leaderReachableLock    sync.Mutex
+	leaderReachableChecked time.Time // Local time of the last check of whether the leader is reachable.
+	leaderReachableErr     error     // Result of the last check of whether the leader is reachable.
+
 	heartbeatLock sync.Mutex
+	onError       func(err error) // Optional handler for heartbeat rounds that fail to start.
+
+	heartbeatSentLock     sync.Mutex
+	heartbeatSent         map[string]time.Time // Local time of the last heartbeat sent to each cluster member by address.
+	heartbeatsPausedUntil time.Time            // Local time until which no heartbeat rounds are begun.
+	heartbeatReceived     time.Time            // Local time of the last heartbeat received from the leader.
+	heartbeatPayloads     map[string][]byte    // Latest heartbeat payloads of the cluster members by name.
+	clockSkewThreshold    time.Duration        // Clock difference with other members above which a warning is logged.
+
+	heartbeatHistory      map[string]*heartbeatHistory // Recent heartbeat outcomes of the cluster members by name.
+	heartbeatHistoryDepth int                          // Number of heartbeat outcomes kept per member, or zero for the default.
 
 	schema *update.SchemaUpdate
 
@@ -98,13 +128,195 @@ func NewDB(ctx context.Context, serverCert *shared.CertInfo, clusterCert func()
 		ctx:         shutdownCtx,
 		cancel:      shutdownCancel,
 		status:      StatusNotReady,
+		standBys:    -1,
+	}
+}
+
+// SetDialFunc sets the function used to establish the underlying connections to other dqlite nodes.
+// TLS and the dqlite protocol upgrade are still handled on top of the returned connection.
+func (db *DB) SetDialFunc(dial dqliteClient.DialFunc) {
+	db.dial = dial
+}
+
+// SetDqliteAddress sets the address at which this cluster member accepts dqlite connections, if it differs from its
+// API address. Dqlite still identifies the node by its API address, so only the connections between nodes use it.
+// It must be called before the database is started.
+func (db *DB) SetDqliteAddress(address string) {
+	db.dqliteAddress = address
+}
+
+// DqliteAddress returns the address at which this cluster member accepts dqlite connections, or an empty string if
+// it accepts them at its API address.
+func (db *DB) DqliteAddress() string {
+	return db.dqliteAddress
+}
+
+// SetDialAddressFunc sets the function that maps the address of another dqlite node, which is the API address of its
+// cluster member, to the address at which that member accepts dqlite connections.
+func (db *DB) SetDialAddressFunc(dialAddress func(address string) string) {
+	db.dialAddress = dialAddress
+}
+
+// resolveDialAddress returns the address at which the dqlite node with the given address accepts connections.
+func (db *DB) resolveDialAddress(address string) string {
+	if db.dqliteAddress != "" && address == db.listenAddr.URL.Host {
+		return db.dqliteAddress
+	}
+
+	if db.dialAddress != nil {
+		return db.dialAddress(address)
 	}
+
+	return address
+}
+
+// SetErrorHandler sets a function to be called when a heartbeat round fails to start.
+func (db *DB) SetErrorHandler(f func(err error)) {
+	db.onError = f
+}
+
+// SetLogFunc sets the function that receives dqlite's own log messages, such as those about raft elections and
+// snapshots. If unset, dqlite's default of logging errors only is used. It must be called before the database is started.
+func (db *DB) SetLogFunc(log dqliteClient.LogFunc) {
+	db.logFunc = log
+}
+
+// withOptions appends the options for the configured log function and snapshot parameters, if any, to the dqlite
+// options.
+func (db *DB) withOptions(options ...dqlite.Option) []dqlite.Option {
+	if db.logFunc != nil {
+		options = append(options, dqlite.WithLogFunc(db.logFunc))
+	}
+
+	if db.snapshotThreshold != 0 || db.snapshotTrailing != 0 {
+		threshold, trailing := db.SnapshotParams()
+		options = append(options, dqlite.WithSnapshotParams(dqliteNode.SnapshotParams{Threshold: threshold, Trailing: trailing}))
+	}
+
+	return options
+}
+
+// SetFailureDomain sets the failure domain of this cluster member, which dqlite takes into account to spread voters
+// across distinct failure domains. It must be called before the database is started.
+func (db *DB) SetFailureDomain(code uint64) {
+	db.failureDomain = code
+}
+
+// SetWeight sets the weight of the local dqlite node. When dqlite's automatic role assignment promotes a node, it
+// prefers the candidates with the lowest weight.
+func (db *DB) SetWeight(ctx context.Context, weight uint64) error {
+	client, err := db.dqlite.Client(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to local dqlite node: %w", err)
+	}
+
+	defer func() { _ = client.Close() }()
+
+	err = client.Weight(ctx, weight)
+	if err != nil {
+		return fmt.Errorf("Failed to set weight of local dqlite node: %w", err)
+	}
+
+	return nil
+}
+
+// DefaultVoters and DefaultStandBys are the number of voters and stand-bys dqlite targets if none are configured.
+const (
+	DefaultVoters   = 3
+	DefaultStandBys = 3
+)
+
+// SetTargetRoles sets the number of voters and stand-bys that dqlite's automatic role assignment targets. A number of
+// voters of zero, or a negative number of stand-bys, uses the default. All cluster members must use the same values.
+// It must be called before the database is started.
+func (db *DB) SetTargetRoles(voters int, standBys int) error {
+	if voters != 0 && (voters < 3 || voters%2 == 0) {
+		return fmt.Errorf("Target number of voters must be an odd number of at least 3, got %d", voters)
+	}
+
+	db.voters = voters
+	db.standBys = standBys
+
+	return nil
+}
+
+// TargetRoles returns the number of voters and stand-bys that dqlite's automatic role assignment targets.
+func (db *DB) TargetRoles() (voters int, standBys int) {
+	voters = db.voters
+	if voters == 0 {
+		voters = DefaultVoters
+	}
+
+	standBys = db.standBys
+	if standBys < 0 {
+		standBys = DefaultStandBys
+	}
+
+	return voters, standBys
+}
+
+// DefaultSnapshotThreshold and DefaultSnapshotTrailing are dqlite's own snapshot parameters, used if none are configured.
+const (
+	DefaultSnapshotThreshold = 1024
+	DefaultSnapshotTrailing  = 8192
+)
+
+// minSnapshotTrailing is the smallest number of trailing raft log entries dqlite accepts.
+const minSnapshotTrailing = 4
+
+// SetSnapshotParams sets the number of raft log entries after which dqlite takes a snapshot of the database, and the
+// number of entries it retains in memory after the snapshot, with zero using dqlite's default for either value.
+//
+// Lower values reduce the memory used by the raft log on write-heavy cluster members, at the cost of taking
+// snapshots more often. Fewer trailing entries also make it more likely that a member which falls behind, for
+// example after a restart, must be sent a full snapshot rather than the missing entries, which slows its recovery.
+// The trailing entries must be at least as many as the threshold. It must be called before the database is started.
+func (db *DB) SetSnapshotParams(threshold uint64, trailing uint64) error {
+	effectiveThreshold, effectiveTrailing := threshold, trailing
+	if effectiveThreshold == 0 {
+		effectiveThreshold = DefaultSnapshotThreshold
+	}
+
+	if effectiveTrailing == 0 {
+		effectiveTrailing = DefaultSnapshotTrailing
+	}
+
+	if effectiveTrailing < minSnapshotTrailing {
+		return fmt.Errorf("Snapshot trailing entries must be at least %d, got %d", minSnapshotTrailing, effectiveTrailing)
+	}
+
+	if effectiveThreshold > effectiveTrailing {
+		return fmt.Errorf("Snapshot threshold (%d) must not exceed the trailing entries (%d)", effectiveThreshold, effectiveTrailing)
+	}
+
+	db.snapshotThreshold = threshold
+	db.snapshotTrailing = trailing
+
+	return nil
+}
+
+// SnapshotParams returns the effective number of raft log entries after which dqlite takes a snapshot, and the
+// number of entries it retains after the snapshot.
+func (db *DB) SnapshotParams() (threshold uint64, trailing uint64) {
+	threshold = db.snapshotThreshold
+	if threshold == 0 {
+		threshold = DefaultSnapshotThreshold
+	}
+
+	trailing = db.snapshotTrailing
+	if trailing == 0 {
+		trailing = DefaultSnapshotTrailing
+	}
+
+	return threshold, trailing
 }
 
 // SetSchema sets schema and API extensions on the DB.
-func (db *DB) SetSchema(schemaExtensions []schema.Update, apiExtensions extensions.Extensions) {
+// Any named schema extensions are applied after the given schema updates, tracking their versions separately.
+func (db *DB) SetSchema(schemaExtensions []schema.Update, apiExtensions extensions.Extensions, namedSchemas ...update.SchemaExtension) {
 	s := update.NewSchema()
 	s.AppendSchema(schemaExtensions, apiExtensions)
+	s.SetSchemaExtensions(namedSchemas)
 	db.schema = s.Schema()
 }
 
@@ -117,10 +329,15 @@ func (db *DB) Schema() *update.SchemaUpdate {
 func (db *DB) Bootstrap(extensions extensions.Extensions, project string, addr api.URL, clusterRecord cluster.InternalClusterMember) error {
 	var err error
 	db.listenAddr = addr
-	db.dqlite, err = dqlite.New(db.os.DatabaseDir,
+	voters, standBys := db.TargetRoles()
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, db.withOptions(
 		dqlite.WithAddress(db.listenAddr.URL.Host),
+		dqlite.WithFailureDomain(db.failureDomain),
+		dqlite.WithVoters(voters),
+		dqlite.WithStandBys(standBys),
 		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
-		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)),
+	)...)
 	if err != nil {
 		return fmt.Errorf("Failed to bootstrap dqlite: %w", err)
 	}
@@ -134,8 +351,11 @@ func (db *DB) Bootstrap(extensions extensions.Extensions, project string, addr a
 	clusterRecord.APIExtensions = extensions
 	err = db.Transaction(db.ctx, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := cluster.CreateInternalClusterMember(ctx, tx, clusterRecord)
+		if err != nil {
+			return err
+		}
 
-		return err
+		return db.recordSchemaExtensions(ctx, tx)
 	})
 	if err != nil {
 		return err
@@ -150,11 +370,16 @@ func (db *DB) Bootstrap(extensions extensions.Extensions, project string, addr a
 func (db *DB) Join(extensions extensions.Extensions, project string, addr api.URL, joinAddresses ...string) error {
 	var err error
 	db.listenAddr = addr
-	db.dqlite, err = dqlite.New(db.os.DatabaseDir,
+	voters, standBys := db.TargetRoles()
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, db.withOptions(
 		dqlite.WithCluster(joinAddresses),
 		dqlite.WithAddress(db.listenAddr.URL.Host),
+		dqlite.WithFailureDomain(db.failureDomain),
+		dqlite.WithVoters(voters),
+		dqlite.WithStandBys(standBys),
 		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
-		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)),
+	)...)
 	if err != nil {
 		return fmt.Errorf("Failed to join dqlite cluster %w", err)
 	}
@@ -195,6 +420,46 @@ func (db *DB) Leader(ctx context.Context) (*dqliteClient.Client, error) {
 	return db.dqlite.Leader(ctx)
 }
 
+// leaderReachableTTL is how long the result of a check for whether the leader is reachable is reused.
+const leaderReachableTTL = time.Second
+
+// LeaderReachable returns an error with the http status 503 if the leader of the dqlite cluster can't be reached.
+// The result is reused for a short time, so that a burst of requests does not dial the leader for each request.
+func (db *DB) LeaderReachable(ctx context.Context) error {
+	db.leaderReachableLock.Lock()
+	defer db.leaderReachableLock.Unlock()
+
+	if !db.leaderReachableChecked.IsZero() && time.Since(db.leaderReachableChecked) < leaderReachableTTL {
+		return db.leaderReachableErr
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	client, err := db.Leader(dialCtx)
+	if err != nil {
+		err = api.StatusErrorf(http.StatusServiceUnavailable, "Cluster leader is unreachable, only read requests are allowed: %v", err)
+	} else {
+		_ = client.Close()
+	}
+
+	// Don't remember failures caused by the request itself going away.
+	if ctx.Err() != nil {
+		return err
+	}
+
+	db.leaderReachableChecked = time.Now()
+	db.leaderReachableErr = err
+
+	return err
+}
+
+// AwaitReady blocks until the local dqlite node has finished joining the cluster and taking on its initial role, or
+// the context is done.
+func (db *DB) AwaitReady(ctx context.Context) error {
+	return db.dqlite.Ready(ctx)
+}
+
 // Cluster returns information about dqlite cluster members.
 func (db *DB) Cluster(ctx context.Context, client *dqliteClient.Client) ([]dqliteClient.NodeInfo, error) {
 	members, err := client.Cluster(ctx)
@@ -205,6 +470,32 @@ func (db *DB) Cluster(ctx context.Context, client *dqliteClient.Client) ([]dqlit
 	return members, nil
 }
 
+// nodeStore opens the store of dqlite nodes kept alongside the database.
+func (db *DB) nodeStore() (*dqliteClient.YamlNodeStore, error) {
+	store, err := dqliteClient.NewYamlNodeStore(filepath.Join(db.os.DatabaseDir, "cluster.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open dqlite node store: %w", err)
+	}
+
+	return store, nil
+}
+
+// Nodes returns the nodes of the dqlite cluster and their roles, as last recorded in the local node store.
+// Unlike Cluster, it does not need the leader, so it is available even if the cluster has lost quorum.
+func (db *DB) Nodes(ctx context.Context) ([]dqliteClient.NodeInfo, error) {
+	store, err := db.nodeStore()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := store.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dqlite cluster configuration: %w", err)
+	}
+
+	return nodes, nil
+}
+
 // Status returns the current status of the database.
 func (db *DB) Status() Status {
 	if db == nil {
@@ -241,35 +532,47 @@ func (db *DB) IsOpen(ctx context.Context) error {
 		return api.StatusErrorf(http.StatusServiceUnavailable, string(status))
 
 	case StatusWaiting:
-		intVersion, extversion, apiExtensions := db.Schema().Version()
+		awaiting, err := db.AwaitingMembers(ctx)
+		if err != nil {
+			return api.StatusErrorf(http.StatusInternalServerError, "Failed to fetch awaiting cluster members: %w", err)
+		}
 
-		awaitingSystems := 0
-		err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
-			allMembers, awaitingMembers, err := cluster.GetUpgradingClusterMembers(ctx, tx, intVersion, extversion, apiExtensions)
-			if err != nil {
-				return err
-			}
+		return api.StatusErrorf(http.StatusServiceUnavailable, "%s: %d cluster members have not yet received the update: %s", status, len(awaiting), strings.Join(awaiting, ", "))
+	default:
+		return api.StatusErrorf(http.StatusInternalServerError, "Database status is invalid")
+	}
+}
 
-			for _, member := range allMembers {
-				if member.Address == db.listenAddr.URL.Host {
-					continue
-				}
+// AwaitingMembers returns the names of the other cluster members that have not yet announced support for the schema
+// version and API extensions of this cluster member. While any remain, the schema update is not applied, and the
+// database status is StatusWaiting.
+func (db *DB) AwaitingMembers(ctx context.Context) ([]string, error) {
+	intVersion, extVersion, apiExtensions := db.Schema().Version()
 
-				if awaitingMembers[member.Name] {
-					awaitingSystems++
-				}
+	awaiting := []string{}
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		allMembers, awaitingMembers, err := cluster.GetUpgradingClusterMembers(ctx, tx, intVersion, extVersion, apiExtensions)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range allMembers {
+			if member.Address == db.listenAddr.URL.Host {
+				continue
 			}
 
-			return nil
-		})
-		if err != nil {
-			return api.StatusErrorf(http.StatusInternalServerError, "Failed to fetch awaiting cluster members: %w", err)
+			if awaitingMembers[member.Name] {
+				awaiting = append(awaiting, member.Name)
+			}
 		}
 
-		return api.StatusErrorf(http.StatusServiceUnavailable, "%s: %d cluster members have not yet received the update", status, awaitingSystems)
-	default:
-		return api.StatusErrorf(http.StatusInternalServerError, "Database status is invalid")
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+
+	return awaiting, nil
 }
 
 // NotifyUpgraded sends a notification that we can stop waiting for a cluster member to be upgraded.
@@ -292,11 +595,14 @@ func (db *DB) dialFunc() dqliteClient.DialFunc {
 	}
 }
 
-// loopHeartbeat runs the heartbeat command continuously every second.
+// HeartbeatInterval is how often the dqlite leader begins a heartbeat round.
+const HeartbeatInterval = 10 * time.Second
+
+// loopHeartbeat runs the heartbeat command continuously every HeartbeatInterval.
 func (db *DB) loopHeartbeat() {
 	for {
 		db.heartbeat(db.ctx)
-		time.Sleep(10 * time.Second)
+		time.Sleep(HeartbeatInterval)
 	}
 }
 
@@ -306,6 +612,12 @@ func (db *DB) heartbeat(ctx context.Context) {
 		return
 	}
 
+	until, paused := db.HeartbeatsPausedUntil()
+	if paused {
+		logger.Debug("Heartbeats are paused, skipping heartbeat", logger.Ctx{"address": db.listenAddr.String(), "until": until})
+		return
+	}
+
 	// Use the heartbeat lock to prevent another heartbeat attempt if we are currently initiating one.
 	db.heartbeatLock.Lock()
 	defer db.heartbeatLock.Unlock()
@@ -317,15 +629,26 @@ func (db *DB) heartbeat(ctx context.Context) {
 	}
 
 	// Initiate a heartbeat from this node.
-	err = client.Heartbeat(ctx, internalTypes.HeartbeatInfo{BeginRound: true})
+	_, err = client.Heartbeat(ctx, internalTypes.HeartbeatInfo{BeginRound: true})
 	if err != nil && err.Error() != "Attempt to initiate heartbeat from non-leader" {
 		logger.Error("Failed to initiate heartbeat round", logger.Ctx{"address": db.dqlite.Address(), "error": err})
+		if db.onError != nil {
+			db.onError(fmt.Errorf("Failed to initiate heartbeat round: %w", err))
+		}
+
 		return
 	}
 }
 
-// dqliteNetworkDial creates a connection to the internal database endpoint.
+// databaseURL returns the URL of the internal database endpoint of the cluster member at the given address.
+// Building the URL from its parts, rather than parsing it, keeps the zone of a link-local IPv6 address intact.
+func databaseURL(addr string) *url.URL {
+	return &api.NewURL().Scheme(sys.Scheme()).Host(addr).Path(string(internalTypes.InternalEndpoint), "database").URL
+}
+
+// dqliteNetworkDial creates a connection to the internal database endpoint of the dqlite node with the given address.
 func dqliteNetworkDial(ctx context.Context, addr string, db *DB) (net.Conn, error) {
+	addr = db.resolveDialAddress(addr)
 	peerCert, err := db.clusterCert().PublicKeyX509()
 	if err != nil {
 		return nil, err
@@ -346,11 +669,7 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DB) (net.Conn, erro
 		Host:       addr,
 	}
 
-	path := fmt.Sprintf("https://%s/%s/%s", addr, internalTypes.InternalEndpoint, "database")
-	request.URL, err = url.Parse(path)
-	if err != nil {
-		return nil, err
-	}
+	request.URL = databaseURL(addr)
 
 	request.Header.Set("Upgrade", "dqlite")
 	request.Header.Set("X-Dqlite-Version", fmt.Sprintf("%d", 1))
@@ -361,7 +680,28 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DB) (net.Conn, erro
 
 	deadline, _ := ctx.Deadline()
 	dialer := &net.Dialer{Timeout: time.Until(deadline)}
-	conn, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+	var conn net.Conn
+	if db.dial != nil {
+		conn, err = db.dial(ctx, addr)
+		if err == nil && !sys.Plaintext() {
+			if config.ServerName == "" {
+				config.ServerName, _, _ = net.SplitHostPort(addr)
+			}
+
+			tlsConn := tls.Client(conn, config)
+			err = tlsConn.HandshakeContext(ctx)
+			if err != nil {
+				_ = conn.Close()
+			}
+
+			conn = tlsConn
+		}
+	} else if sys.Plaintext() {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, config)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("Failed connecting to HTTP endpoint %q: %w", addr, err)
 	}
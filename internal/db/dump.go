@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+)
+
+// dumpExcludedTables are tables whose contents describe the topology of the cluster the dump was taken from,
+// rather than application state, and so are not carried over to the cluster the dump is restored on.
+var dumpExcludedTables = []string{"schemas", "internal_schema_extensions", "internal_cluster_members", "internal_cluster_member_schema_extensions", "internal_cluster_member_failure_domains", "internal_token_records", "internal_init_config", "internal_consistency_index"}
+
+// Dump writes a consistent snapshot of the contents of the database to the given writer, as a SQL script.
+//
+// All tables are read in a single transaction, so the snapshot reflects a single point in time.
+// As dqlite uses a write-ahead log, the snapshot does not block concurrent writes to the database.
+//
+// The script first deletes the existing rows of every dumped table, and then re-inserts the dumped rows. It contains
+// no transaction statements, so that it can be executed within a single transaction against a freshly bootstrapped
+// cluster at the same schema version to restore its contents. The schema version tables, cluster members, their init
+// configuration, and join tokens are not included, as they are specific to the cluster the dump was taken from.
+func (db *DB) Dump(ctx context.Context, w io.Writer) error {
+	dw := &dumpWriter{w: w}
+	err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// The transaction may be retried, but the rows are streamed to the writer, so only if nothing was written yet.
+		if dw.written {
+			return fmt.Errorf("Cannot retry a partially written dump")
+		}
+
+		internalVersion, externalVersion, _ := db.Schema().Version()
+		_, err := fmt.Fprintf(dw, "-- Schema version: internal %d, external %d\n", internalVersion, externalVersion)
+		if err != nil {
+			return err
+		}
+
+		// Foreign keys can't be disabled within a transaction, so only check them once the restore is committed.
+		_, err = io.WriteString(dw, "PRAGMA defer_foreign_keys=ON;\n")
+		if err != nil {
+			return err
+		}
+
+		tables, err := dumpTables(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		// Delete rows in reverse order of table creation, so that referencing rows are removed first.
+		for i := len(tables) - 1; i >= 0; i-- {
+			_, err = fmt.Fprintf(dw, "DELETE FROM %s;\n", quoteIdentifier(tables[i]))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, table := range tables {
+			err = dumpTable(ctx, tx, dw, table)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to dump database: %w", err)
+	}
+
+	return nil
+}
+
+// dumpWriter records whether anything has been written to the underlying writer.
+type dumpWriter struct {
+	w       io.Writer
+	written bool
+}
+
+// Write implements io.Writer.
+func (d *dumpWriter) Write(p []byte) (int, error) {
+	d.written = true
+
+	return d.w.Write(p)
+}
+
+// quoteIdentifier returns the given table or column name as a quoted SQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// dumpTables returns the names of all tables to include in a dump, in the order they were created.
+func dumpTables(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY rowid")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch table names: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan table name: %w", err)
+		}
+
+		if shared.ValueInSlice(name, dumpExcludedTables) {
+			continue
+		}
+
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// dumpTable writes an INSERT statement for every row of the given table to the writer.
+func dumpTable(ctx context.Context, tx *sql.Tx, w io.Writer, table string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", quoteIdentifier(table)))
+	if err != nil {
+		return fmt.Errorf("Failed to fetch rows for table %q: %w", table, err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("Failed to get columns for table %q: %w", table, err)
+	}
+
+	for i := 0; rows.Next(); i++ {
+		raw := make([]any, len(columns))
+		row := make([]any, len(columns))
+		for j := range raw {
+			row[j] = &raw[j]
+		}
+
+		err := rows.Scan(row...)
+		if err != nil {
+			return fmt.Errorf("Failed to scan row %d in table %q: %w", i, table, err)
+		}
+
+		values := make([]string, len(columns))
+		for j, v := range raw {
+			values[j], err = dumpValue(v)
+			if err != nil {
+				return fmt.Errorf("Failed to dump column %q of row %d in table %q: %w", columns[j], i, table, err)
+			}
+		}
+
+		_, err = fmt.Fprintf(w, "INSERT INTO %s VALUES(%s);\n", quoteIdentifier(table), strings.Join(values, ","))
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// dumpValue returns the SQL literal for the given column value.
+func dumpValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+
+		return "0", nil
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999999-07:00") + "'", nil
+	default:
+		return "", fmt.Errorf("Unsupported type %T", v)
+	}
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// MaxHeartbeatPause is the longest that heartbeats can be paused for at once.
+const MaxHeartbeatPause = time.Hour
+
+// DefaultClockSkewThreshold is the difference between the clocks of this cluster member and another, above which a
+// warning is logged, if no threshold is configured.
+const DefaultClockSkewThreshold = 5 * time.Second
+
+// SetClockSkewThreshold sets the difference between the clocks of this cluster member and another, above which a
+// warning is logged when a heartbeat is sent. Zero uses the default, and a negative value disables the warning.
+func (db *DB) SetClockSkewThreshold(threshold time.Duration) {
+	db.clockSkewThreshold = threshold
+}
+
+// ClockSkewThreshold returns the difference between the clocks of this cluster member and another, above which a
+// warning is logged, or a negative value if the warning is disabled.
+func (db *DB) ClockSkewThreshold() time.Duration {
+	if db.clockSkewThreshold == 0 {
+		return DefaultClockSkewThreshold
+	}
+
+	return db.clockSkewThreshold
+}
+
+// DefaultHeartbeatHistoryDepth is the number of recent heartbeat outcomes kept for each cluster member, if no depth is
+// configured.
+const DefaultHeartbeatHistoryDepth = 64
+
+// heartbeatHistory is a ring buffer of the most recent heartbeat outcomes of a cluster member.
+type heartbeatHistory struct {
+	outcomes []types.HeartbeatOutcome
+	next     int // Index of the oldest outcome, which is overwritten next once the buffer is full.
+}
+
+// add records the outcome, overwriting the oldest one if the history already holds depth outcomes.
+func (h *heartbeatHistory) add(outcome types.HeartbeatOutcome, depth int) {
+	if len(h.outcomes) < depth {
+		h.outcomes = append(h.outcomes, outcome)
+		return
+	}
+
+	h.outcomes[h.next] = outcome
+	h.next = (h.next + 1) % len(h.outcomes)
+}
+
+// list returns a copy of the outcomes, oldest first.
+func (h *heartbeatHistory) list() []types.HeartbeatOutcome {
+	return append(slices.Clone(h.outcomes[h.next:]), h.outcomes[:h.next]...)
+}
+
+// SetHeartbeatHistoryDepth sets the number of recent heartbeat outcomes kept for each cluster member. Zero uses the
+// default, and a negative value disables the history.
+func (db *DB) SetHeartbeatHistoryDepth(depth int) {
+	db.heartbeatHistoryDepth = depth
+}
+
+// HeartbeatHistoryDepth returns the number of recent heartbeat outcomes kept for each cluster member, or a negative
+// value if the history is disabled.
+func (db *DB) HeartbeatHistoryDepth() int {
+	if db.heartbeatHistoryDepth == 0 {
+		return DefaultHeartbeatHistoryDepth
+	}
+
+	return db.heartbeatHistoryDepth
+}
+
+// RecordHeartbeatOutcome records the outcome of a heartbeat sent to the cluster member with the given name, dropping
+// its oldest outcome once the history is full.
+func (db *DB) RecordHeartbeatOutcome(name string, outcome types.HeartbeatOutcome) {
+	depth := db.HeartbeatHistoryDepth()
+	if depth < 0 {
+		return
+	}
+
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	if db.heartbeatHistory == nil {
+		db.heartbeatHistory = map[string]*heartbeatHistory{}
+	}
+
+	history, ok := db.heartbeatHistory[name]
+	if !ok {
+		history = &heartbeatHistory{}
+		db.heartbeatHistory[name] = history
+	}
+
+	history.add(outcome, depth)
+}
+
+// HeartbeatHistory returns the recent heartbeat outcomes of the cluster member with the given name, oldest first.
+// Outcomes are only recorded while this cluster member is the leader, and are lost when it restarts.
+func (db *DB) HeartbeatHistory(name string) []types.HeartbeatOutcome {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	history, ok := db.heartbeatHistory[name]
+	if !ok {
+		return []types.HeartbeatOutcome{}
+	}
+
+	return history.list()
+}
+
+// ForgetHeartbeatHistory discards the heartbeat outcomes of any cluster members not in the given set of names.
+func (db *DB) ForgetHeartbeatHistory(names map[string]bool) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	maps.DeleteFunc(db.heartbeatHistory, func(name string, _ *heartbeatHistory) bool { return !names[name] })
+}
+
+// HeartbeatSent returns the local time at which this cluster member last sent a heartbeat to the cluster member at the
+// given address while it was the leader, and false if it has not done so since it started. The time includes a
+// monotonic clock reading, so the time since then is unaffected by changes to the wall clock of any cluster member.
+func (db *DB) HeartbeatSent(address string) (time.Time, bool) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	sent, ok := db.heartbeatSent[address]
+
+	return sent, ok
+}
+
+// RecordHeartbeatSent records the local time at which this cluster member sent a heartbeat to the cluster member at
+// the given address.
+func (db *DB) RecordHeartbeatSent(address string, sent time.Time) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	if db.heartbeatSent == nil {
+		db.heartbeatSent = map[string]time.Time{}
+	}
+
+	db.heartbeatSent[address] = sent
+}
+
+// HeartbeatReceived returns the local time at which this cluster member last received a heartbeat from the leader, and
+// false if it has not received one since it started.
+func (db *DB) HeartbeatReceived() (time.Time, bool) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	return db.heartbeatReceived, !db.heartbeatReceived.IsZero()
+}
+
+// RecordHeartbeatReceived records that this cluster member has received a heartbeat from the leader.
+func (db *DB) RecordHeartbeatReceived() {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	db.heartbeatReceived = time.Now()
+}
+
+// HeartbeatPayloads returns the latest heartbeat payloads of the cluster members known to this cluster member, keyed by
+// member name.
+func (db *DB) HeartbeatPayloads() map[string][]byte {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	return maps.Clone(db.heartbeatPayloads)
+}
+
+// SetHeartbeatPayloads replaces the latest heartbeat payloads of the cluster members, keyed by member name.
+func (db *DB) SetHeartbeatPayloads(payloads map[string][]byte) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	db.heartbeatPayloads = maps.Clone(payloads)
+}
+
+// PauseHeartbeats stops this cluster member from beginning heartbeat rounds while it is the leader, until the given
+// duration has elapsed on its monotonic clock. A duration of zero resumes heartbeats immediately.
+func (db *DB) PauseHeartbeats(d time.Duration) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	if d <= 0 {
+		// Record when heartbeats were resumed, so that heartbeats sent before then are known to be stale.
+		if time.Now().Before(db.heartbeatsPausedUntil) {
+			db.heartbeatsPausedUntil = time.Now()
+		}
+
+		return
+	}
+
+	db.heartbeatsPausedUntil = time.Now().Add(d)
+}
+
+// HeartbeatsLastPaused returns the local time at which the most recent pause of heartbeats ends or ended, which is in
+// the future while heartbeats are paused, or the zero time if they have not been paused since this member started.
+func (db *DB) HeartbeatsLastPaused() time.Time {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	return db.heartbeatsPausedUntil
+}
+
+// HeartbeatsPausedUntil returns the local time until which heartbeats are paused, and false if they are not paused.
+func (db *DB) HeartbeatsPausedUntil() (time.Time, bool) {
+	db.heartbeatSentLock.Lock()
+	defer db.heartbeatSentLock.Unlock()
+
+	if !time.Now().Before(db.heartbeatsPausedUntil) {
+		return time.Time{}, false
+	}
+
+	return db.heartbeatsPausedUntil, true
+}
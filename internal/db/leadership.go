@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	dqliteDriver "github.com/canonical/go-dqlite/driver"
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// MaxLeadershipRetries is the number of times an idempotent transaction is retried after failing because of a change
+// of dqlite leader.
+const MaxLeadershipRetries = 1
+
+// IsLeadershipError returns whether the error was caused by the dqlite leader changing or being unavailable, rather
+// than by the operation itself. Such operations may succeed if retried against the new leader.
+//
+// The dqlite driver reports requests made to a member that is no longer the leader, or whose leadership was lost, as
+// driver.ErrBadConn, and a failure to find any leader as ErrNoAvailableLeader.
+func IsLeadershipError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, dqliteDriver.ErrNoAvailableLeader)
+}
+
+// IdempotentTransaction handles performing a transaction on the dqlite database, like Transaction. If the transaction
+// fails because the dqlite leader changed, it is retried up to MaxLeadershipRetries times once a new leader is
+// available. Other failures are returned as is.
+//
+// The transaction may have been applied by the previous leader before failing, so this must only be used for
+// transactions that can safely be run more than once, such as reads.
+func (db *DB) IdempotentTransaction(ctx context.Context, f func(context.Context, *sql.Tx) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = db.Transaction(ctx, f)
+		if attempt >= MaxLeadershipRetries || !IsLeadershipError(err) {
+			return err
+		}
+
+		logger.Warn("Transaction failed due to a leadership change, retrying", logger.Ctx{"attempt": attempt + 1, "err": err})
+
+		// Give the cluster time to elect a new leader before retrying.
+		leaderErr := db.LeaderReachable(ctx)
+		if leaderErr != nil {
+			logger.Warn("No leader available to retry the transaction", logger.Ctx{"err": leaderErr})
+
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	dqliteNode "github.com/canonical/go-dqlite"
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/shared/api"
+	"gopkg.in/yaml.v2"
+)
+
+// RecoveryTarballName is the name of the tarball of the recovered database, written to the state directory by Recover.
+// Copied into the state directory of another surviving member, it replaces that member's database when it next starts.
+const RecoveryTarballName = "recovery_db.tar.gz"
+
+// Recover forcibly rewrites the dqlite cluster configuration of this node to contain only the nodes with the given
+// addresses, all as voters, so that a cluster which permanently lost the majority of its voters can elect a leader
+// again. Every address must belong to a node in the current configuration.
+//
+// Recover must only be run on one of the surviving nodes. The recovered database directory is then written as a
+// tarball named RecoveryTarballName to the state directory, to be loaded by the other survivors with
+// LoadRecoveryTarball, so that all of them start from the same data and configuration.
+//
+// Any changes that were committed by the lost voters but not yet replicated to this node are lost. It must only be
+// run while dqlite is stopped, and it returns a Conflict error if dqlite has been started.
+func (db *DB) Recover(ctx context.Context, addresses []string) error {
+	if db.dqlite != nil {
+		return api.StatusErrorf(http.StatusConflict, "Cannot recover the database while dqlite is running")
+	}
+
+	store, err := db.nodeStore()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := store.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get dqlite cluster configuration: %w", err)
+	}
+
+	survivors := make([]dqliteClient.NodeInfo, 0, len(addresses))
+	for _, address := range addresses {
+		i := slices.IndexFunc(nodes, func(node dqliteClient.NodeInfo) bool { return node.Address == address })
+		if i < 0 {
+			return api.StatusErrorf(http.StatusNotFound, "No dqlite node found with address %q", address)
+		}
+
+		node := nodes[i]
+		node.Role = dqliteClient.Voter
+		survivors = append(survivors, node)
+	}
+
+	err = dqliteNode.ReconfigureMembershipExt(db.os.DatabaseDir, survivors)
+	if err != nil {
+		return fmt.Errorf("Failed to reconfigure dqlite cluster membership: %w", err)
+	}
+
+	err = store.Set(ctx, survivors)
+	if err != nil {
+		return fmt.Errorf("Failed to update dqlite node store: %w", err)
+	}
+
+	err = writeRecoveryTarball(db.os.DatabaseDir, filepath.Join(db.os.StateDir, RecoveryTarballName))
+	if err != nil {
+		return fmt.Errorf("Failed to write recovery tarball: %w", err)
+	}
+
+	return nil
+}
+
+// LoadRecoveryTarball replaces the database of this node with the one in the recovery tarball written by Recover on
+// another surviving node, if one has been copied into the state directory, and then removes the tarball. The local
+// dqlite node info is kept, and this node must be part of the recovered cluster configuration.
+// It returns whether a tarball was loaded. It must only be run while dqlite is stopped.
+func (db *DB) LoadRecoveryTarball() (bool, error) {
+	if db.dqlite != nil {
+		return false, api.StatusErrorf(http.StatusConflict, "Cannot load a recovery tarball while dqlite is running")
+	}
+
+	path := filepath.Join(db.os.StateDir, RecoveryTarballName)
+	_, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	err = loadRecoveryTarball(path, db.os.DatabaseDir)
+	if err != nil {
+		return false, err
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return false, fmt.Errorf("Failed to remove loaded recovery tarball: %w", err)
+	}
+
+	return true, nil
+}
+
+// writeRecoveryTarball writes the files of the database directory to a gzipped tarball at the given path.
+func writeRecoveryTarball(databaseDir string, path string) error {
+	entries, err := os.ReadDir(databaseDir)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	defer func() { _ = tmpFile.Close() }()
+
+	gw := gzip.NewWriter(tmpFile)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		err = addTarballFile(tw, filepath.Join(databaseDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = gw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// addTarballFile writes the regular file at the given path to the top level of the tarball.
+func addTarballFile(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// readRecoveryTarball calls f with each file of the recovery tarball at the given path, rejecting any entry that is
+// not a regular file at the top level of the tarball.
+func readRecoveryTarball(path string, f func(header *tar.Header, r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = file.Close() }()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != header.Name || header.Name == ".." {
+			return fmt.Errorf("Invalid recovery tarball entry %q", header.Name)
+		}
+
+		err = f(header, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// loadRecoveryTarball replaces the contents of the database directory with those of the recovery tarball at the
+// given path, keeping the local dqlite node info. The tarball is checked before any local file is removed, and the
+// local node must be part of its dqlite cluster configuration.
+func loadRecoveryTarball(path string, databaseDir string) error {
+	data, err := os.ReadFile(filepath.Join(databaseDir, "info.yaml"))
+	if err != nil {
+		return fmt.Errorf("Failed to read local dqlite node info: %w", err)
+	}
+
+	var local dqliteClient.NodeInfo
+	err = yaml.Unmarshal(data, &local)
+	if err != nil {
+		return fmt.Errorf("Failed to parse local dqlite node info: %w", err)
+	}
+
+	var nodes []dqliteClient.NodeInfo
+	err = readRecoveryTarball(path, func(header *tar.Header, r io.Reader) error {
+		if header.Name != "cluster.yaml" {
+			return nil
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		return yaml.Unmarshal(data, &nodes)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to read recovery tarball: %w", err)
+	}
+
+	if !slices.ContainsFunc(nodes, func(node dqliteClient.NodeInfo) bool { return node.ID == local.ID && node.Address == local.Address }) {
+		return fmt.Errorf("Local dqlite node %d with address %q is not part of the recovered cluster configuration", local.ID, local.Address)
+	}
+
+	entries, err := os.ReadDir(databaseDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == "info.yaml" {
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(databaseDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("Failed to remove %q from the database directory: %w", entry.Name(), err)
+		}
+	}
+
+	err = readRecoveryTarball(path, func(header *tar.Header, r io.Reader) error {
+		if header.Name == "info.yaml" {
+			return nil
+		}
+
+		f, err := os.OpenFile(filepath.Join(databaseDir, header.Name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		_, err = io.Copy(f, r)
+		if err != nil {
+			return err
+		}
+
+		return f.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to extract recovery tarball: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package update
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/db/schema"
+)
+
+// schemaExtensionNameRegex matches valid names of schema extensions, following the rules for API extension names.
+var schemaExtensionNameRegex = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// SchemaExtension is an ordered series of schema updates contributed by a named extension.
+// The version of each extension's schema is tracked separately, so that extensions developed independently of each
+// other don't need to coordinate their update indices.
+type SchemaExtension struct {
+	Name    string
+	Updates []schema.Update
+}
+
+// SchemaExtensions is the set of named schema extensions, in the order they are applied.
+type SchemaExtensions []SchemaExtension
+
+// Register adds the schema updates of the extension with the given name. Extensions are applied in the order they
+// are registered, after all internal and external schema updates.
+func (s *SchemaExtensions) Register(name string, updates []schema.Update) error {
+	if !schemaExtensionNameRegex.MatchString(name) {
+		return fmt.Errorf("Schema extension name %q is invalid: Name must contain only lowercase letters, numbers, and underscores, and must not begin or end with an underscore", name)
+	}
+
+	for _, ext := range *s {
+		if ext.Name == name {
+			return fmt.Errorf("Schema extension %q is already registered", name)
+		}
+	}
+
+	*s = append(*s, SchemaExtension{Name: name, Updates: updates})
+
+	return nil
+}
+
+// Versions returns the schema version of each extension, corresponding to the number of its updates.
+func (s SchemaExtensions) Versions() map[string]uint64 {
+	versions := make(map[string]uint64, len(s))
+	for _, ext := range s {
+		versions[ext.Name] = uint64(len(ext.Updates))
+	}
+
+	return versions
+}
+
+// ensureExtensionUpdatesAreApplied applies any pending updates of each schema extension, in order,
+// recording the resulting version of each extension in the internal_schema_extensions table.
+func ensureExtensionUpdatesAreApplied(ctx context.Context, tx *sql.Tx, exts SchemaExtensions, hook schema.Hook) error {
+	for _, ext := range exts {
+		versions, err := query.SelectIntegers(ctx, tx, "SELECT COALESCE(MAX(version), 0) FROM internal_schema_extensions WHERE name = ?", ext.Name)
+		if err != nil {
+			return fmt.Errorf("Failed to get schema version of extension %q: %w", ext.Name, err)
+		}
+
+		version := versions[0]
+		if version > len(ext.Updates) {
+			return fmt.Errorf("Schema version %d of extension %q is more recent than expected %d", version, ext.Name, len(ext.Updates))
+		}
+
+		for _, update := range ext.Updates[version:] {
+			if hook != nil {
+				err := hook(ctx, version, tx)
+				if err != nil {
+					return fmt.Errorf("Failed to execute hook (extension %q, version %d): %w", ext.Name, version, err)
+				}
+			}
+
+			err := update(ctx, tx)
+			if err != nil {
+				return fmt.Errorf("Failed to apply update %d of extension %q: %w", version, ext.Name, err)
+			}
+
+			version++
+
+			statement := `INSERT INTO internal_schema_extensions (name, version, updated_at) VALUES (?, ?, strftime("%s"))`
+			_, err = tx.ExecContext(ctx, statement, ext.Name, version)
+			if err != nil {
+				return fmt.Errorf("Failed to insert version %d of extension %q: %w", version, ext.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
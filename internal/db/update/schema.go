@@ -26,12 +26,13 @@ const (
 
 // SchemaUpdate holds the configuration for executing schema updates.
 type SchemaUpdate struct {
-	updates       map[updateType][]schema.Update // Ordered series of internal and external updates making up the schema
-	apiExtensions extensions.Extensions
-	hook          schema.Hook  // Optional hook to execute whenever a update gets applied
-	fresh         string       // Optional SQL statement used to create schema from scratch
-	check         schema.Check // Optional callback invoked before doing any update
-	path          string       // Optional path to a file containing extra queries to run
+	updates          map[updateType][]schema.Update // Ordered series of internal and external updates making up the schema
+	schemaExtensions SchemaExtensions               // Named extensions with their own ordered series of updates
+	apiExtensions    extensions.Extensions
+	hook             schema.Hook  // Optional hook to execute whenever a update gets applied
+	fresh            string       // Optional SQL statement used to create schema from scratch
+	check            schema.Check // Optional callback invoked before doing any update
+	path             string       // Optional path to a file containing extra queries to run
 }
 
 // Fresh sets a statement that will be used to create the schema from scratch
@@ -54,6 +55,11 @@ func (s *SchemaUpdate) Version() (internalVersion uint64, externalVersion uint64
 	return uint64(len(s.updates[updateInternal])), uint64(len(s.updates[updateExternal])), s.apiExtensions
 }
 
+// ExtensionVersions returns the schema version of each named schema extension.
+func (s *SchemaUpdate) ExtensionVersions() map[string]uint64 {
+	return s.schemaExtensions.Versions()
+}
+
 // Ensure makes sure that the actual schema in the given database matches the
 // one defined by our updates.
 //
@@ -242,6 +248,15 @@ func (s *SchemaUpdate) Ensure(db *sql.DB) (int, error) {
 		return -1, err
 	}
 
+	if len(s.schemaExtensions) > 0 {
+		err = query.Transaction(context.TODO(), db, func(ctx context.Context, tx *sql.Tx) error {
+			return ensureExtensionUpdatesAreApplied(ctx, tx, s.schemaExtensions, s.hook)
+		})
+		if err != nil {
+			return -1, err
+		}
+	}
+
 	return current, nil
 }
 
@@ -24,6 +24,8 @@ CREATE TABLE schemas (
 type SchemaUpdateManager struct {
 	updates map[updateType][]schema.Update
 
+	schemaExtensions SchemaExtensions
+
 	apiExtensions extensions.Extensions
 }
 
@@ -36,6 +38,16 @@ func NewSchema() *SchemaUpdateManager {
 			updateFromV1,
 			updateFromV2,
 			mgr.updateFromV3,
+			updateFromV4,
+			updateFromV5,
+			updateFromV6,
+			updateFromV7,
+			updateFromV8,
+			updateFromV9,
+			updateFromV10,
+			updateFromV11,
+			updateFromV12,
+			updateFromV13,
 		},
 	}
 
@@ -62,7 +74,7 @@ func (s *SchemaUpdateManager) SetExternalUpdates(updates []schema.Update) {
 
 // Schema returns a SchemaUpdate from the SchemaUpdateManager config.
 func (s *SchemaUpdateManager) Schema() *SchemaUpdate {
-	schema := &SchemaUpdate{updates: s.updates, apiExtensions: s.apiExtensions}
+	schema := &SchemaUpdate{updates: s.updates, schemaExtensions: s.schemaExtensions, apiExtensions: s.apiExtensions}
 	schema.Fresh("")
 	return schema
 }
@@ -73,6 +85,139 @@ func (s *SchemaUpdateManager) AppendSchema(schemaExtensions []schema.Update, api
 	s.apiExtensions = apiExtensions
 }
 
+// SetSchemaExtensions sets the named schema extensions, applied after all internal and external schema updates.
+func (s *SchemaUpdateManager) SetSchemaExtensions(schemaExtensions SchemaExtensions) {
+	s.schemaExtensions = schemaExtensions
+}
+
+// updateFromV13 adds the additional certificates that are accepted for each cluster member, such as its new
+// certificate while it is being rotated.
+func updateFromV13(ctx context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE internal_cluster_members ADD COLUMN additional_certificates TEXT NOT NULL DEFAULT '';`
+
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV12 adds the consistency index, which is incremented to issue consistency tokens for writes.
+func updateFromV12(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE internal_consistency_index (
+  id     INTEGER  PRIMARY  KEY  NOT  NULL,
+  value  INTEGER  NOT      NULL
+);
+
+INSERT INTO internal_consistency_index (id, value) VALUES (1, 0);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV11 adds the address at which each cluster member accepts dqlite connections, if it differs from its API
+// address.
+func updateFromV11(ctx context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE internal_cluster_members ADD COLUMN dqlite_address TEXT NOT NULL DEFAULT '';`
+
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV10 adds a flag for cluster members that only ever hold the dqlite spare role and serve reads.
+func updateFromV10(ctx context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE internal_cluster_members ADD COLUMN replica_only INTEGER NOT NULL DEFAULT 0;`
+
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV9 adds the dqlite role requested for each cluster member by an operator, if any.
+func updateFromV9(ctx context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE internal_cluster_members ADD COLUMN requested_role TEXT NOT NULL DEFAULT '';`
+
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV8 adds a draining flag to cluster members, set while a member is quiesced ahead of its removal.
+func updateFromV8(ctx context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE internal_cluster_members ADD COLUMN draining INTEGER NOT NULL DEFAULT 0;`
+
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV7 introduces the internal_cluster_member_failure_domains table for the placement of each cluster member.
+func updateFromV7(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE internal_cluster_member_failure_domains (
+  id              INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  member_id       INTEGER  NOT      NULL,
+  failure_domain  INTEGER  NOT      NULL,
+  tags            TEXT     NOT      NULL   DEFAULT '{}',
+  FOREIGN KEY (member_id) REFERENCES internal_cluster_members (id) ON DELETE CASCADE,
+  UNIQUE(member_id)
+);
+`
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV6 introduces tables tracking the schema versions of named schema extensions,
+// both for the database as a whole and as supported by each cluster member.
+func updateFromV6(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE internal_schema_extensions (
+  id          INTEGER   PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  name        TEXT      NOT      NULL,
+  version     INTEGER   NOT      NULL,
+  updated_at  DATETIME  NOT      NULL,
+  UNIQUE(name, version)
+);
+
+CREATE TABLE internal_cluster_member_schema_extensions (
+  id         INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  member_id  INTEGER  NOT      NULL,
+  name       TEXT     NOT      NULL,
+  version    INTEGER  NOT      NULL,
+  FOREIGN KEY (member_id) REFERENCES internal_cluster_members (id) ON DELETE CASCADE,
+  UNIQUE(member_id, name)
+);
+`
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV5 introduces the internal_init_config table for the init configuration of each cluster member.
+func updateFromV5(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE internal_init_config (
+  id         INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  member_id  INTEGER  NOT      NULL,
+  key        TEXT     NOT      NULL,
+  value      TEXT     NOT      NULL,
+  FOREIGN KEY (member_id) REFERENCES internal_cluster_members (id) ON DELETE CASCADE,
+  UNIQUE(member_id, key)
+);
+`
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
+// updateFromV4 introduces the internal_config table for cluster-wide configuration.
+func updateFromV4(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE internal_config (
+  id     INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  key    TEXT     NOT      NULL,
+  value  TEXT     NOT      NULL,
+  UNIQUE(key)
+);
+`
+	_, err := tx.ExecContext(ctx, stmt)
+	return err
+}
+
 // updateFromV3 auto-applies the initial set of API extensions to the internal_cluster_members table.
 // This is done so that the cluster won't have to be notified twice,
 // once for the schema update that introduces API extensions to be applied,
@@ -226,6 +226,40 @@ func (s *updateSuite) Test_updateFromV1() {
 	}
 }
 
+// Ensures the updates of each named schema extension are applied in order, with their versions tracked separately.
+func (s *updateSuite) Test_schemaExtensions() {
+	dummyUpdate := func(ctx context.Context, tx *sql.Tx) error { return nil }
+
+	exts := SchemaExtensions{}
+	s.NoError(exts.Register("first", []schema.Update{dummyUpdate}))
+	s.NoError(exts.Register("second", []schema.Update{dummyUpdate, dummyUpdate}))
+	s.Error(exts.Register("first", []schema.Update{dummyUpdate}))
+	s.Error(exts.Register("_invalid", []schema.Update{dummyUpdate}))
+
+	mgr := NewSchema()
+	mgr.SetSchemaExtensions(exts)
+	db, err := NewTestDBWithSchema(mgr)
+	s.NoError(err)
+
+	// Add an update to the first extension, and apply it on top of the existing schema.
+	exts[0].Updates = append(exts[0].Updates, dummyUpdate)
+	mgr.SetSchemaExtensions(exts)
+	_, err = mgr.Schema().Ensure(db)
+	s.NoError(err)
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	s.NoError(err)
+
+	versions, err := query.SelectIntegers(ctx, tx, "SELECT MAX(version) FROM internal_schema_extensions WHERE name = 'first' UNION ALL SELECT MAX(version) FROM internal_schema_extensions WHERE name = 'second'")
+	s.NoError(err)
+	s.Equal([]int{2, 2}, versions)
+	s.Equal(map[string]uint64{"first": 2, "second": 2}, mgr.Schema().ExtensionVersions())
+
+	s.NoError(tx.Commit())
+	s.NoError(db.Close())
+}
+
 // NewTestDBWithSchema returns a sqlite DB set up with the given schema updates.
 func NewTestDBWithSchema(schemaManager *SchemaUpdateManager) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", ":memory:")
@@ -17,6 +17,12 @@ const (
 
 	// EndpointNetwork represents the user endpoint accessible over https (on a different port to the user endpoint).
 	EndpointNetwork
+
+	// EndpointCore represents the core API endpoint accessible over https, which also accepts dqlite connections.
+	EndpointCore
+
+	// EndpointDqlite represents the dedicated endpoint for dqlite connections accessible over https.
+	EndpointDqlite
 )
 
 // String labels EndpointTypes for logging purposes.
@@ -26,6 +32,10 @@ func (et EndpointType) String() string {
 		return "control socket"
 	case EndpointNetwork:
 		return "https socket"
+	case EndpointCore:
+		return "core https socket"
+	case EndpointDqlite:
+		return "dqlite https socket"
 	default:
 		return ""
 	}
@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"context"
+	"slices"
 	"sync"
 
 	"github.com/canonical/lxd/shared"
@@ -13,17 +14,14 @@ type Endpoints struct {
 	mu          sync.RWMutex
 	shutdownCtx context.Context // Parent context for shutting down cleanly.
 
-	listeners map[EndpointType]Endpoint // Map of supported listeners.
+	listeners []Endpoint // Supported listeners, of which there may be several of each type.
+
+	log logger.Logger
 }
 
 // NewEndpoints aggregates the given endpoints so we can manage them from one source.
-func NewEndpoints(shutdownCtx context.Context, endpoints ...Endpoint) *Endpoints {
-	listeners := map[EndpointType]Endpoint{}
-	for _, endpoint := range endpoints {
-		listeners[endpoint.Type()] = endpoint
-	}
-
-	return &Endpoints{listeners: listeners, shutdownCtx: shutdownCtx}
+func NewEndpoints(shutdownCtx context.Context, log logger.Logger, endpoints ...Endpoint) *Endpoints {
+	return &Endpoints{listeners: endpoints, shutdownCtx: shutdownCtx, log: log}
 }
 
 // Up calls Serve on each of the configured listeners.
@@ -54,16 +52,11 @@ func (e *Endpoints) UpdateTLS(cert *shared.CertInfo) {
 
 // Add calls Serve on the additional set of listeners, and adds them to Endpoints.
 func (e *Endpoints) Add(endpoints ...Endpoint) error {
-	newListeners := map[EndpointType]Endpoint{}
-	for _, endpoint := range endpoints {
-		newListeners[endpoint.Type()] = endpoint
-	}
-
-	for k, v := range newListeners {
-		e.listeners[k] = v
-	}
+	e.mu.Lock()
+	e.listeners = append(e.listeners, endpoints...)
+	e.mu.Unlock()
 
-	err := e.up(newListeners)
+	err := e.up(endpoints)
 	if err != nil {
 		// Attempt to call Down() in case something actually got brought up.
 		_ = e.Down()
@@ -74,23 +67,36 @@ func (e *Endpoints) Add(endpoints ...Endpoint) error {
 	return nil
 }
 
-func (e *Endpoints) up(listeners map[EndpointType]Endpoint) error {
+// AddOptional calls Serve on the given listener, and adds it to Endpoints. Unlike Add, if the listener fails to start
+// it is not added, and the already running listeners are left untouched.
+func (e *Endpoints) AddOptional(endpoint Endpoint) error {
+	err := e.up([]Endpoint{endpoint})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.listeners = append(e.listeners, endpoint)
+	e.mu.Unlock()
+
+	return nil
+}
+
+func (e *Endpoints) up(listeners []Endpoint) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	// Startup listeners.
-	for key := range listeners {
-		err := listeners[key].Listen()
+	for _, listener := range listeners {
+		err := listener.Listen()
 		if err != nil {
 			return err
 		}
 
-		listener := listeners[key]
-
 		go func() {
 			select {
 			case <-e.shutdownCtx.Done():
-				logger.Infof("Received shutdown signal - aborting endpoint startup for %s", listener.Type().String())
+				e.log.Info("Received shutdown signal - aborting endpoint startup", logger.Ctx{"type": listener.Type().String()})
 				return
 
 			default:
@@ -103,25 +109,47 @@ func (e *Endpoints) up(listeners map[EndpointType]Endpoint) error {
 }
 
 // Down closes all of the configured listeners, or any for the type specifically supplied.
+// Closed listeners are removed from Endpoints.
 func (e *Endpoints) Down(types ...EndpointType) error {
+	return e.down(types, func(listener Endpoint) error {
+		return listener.Close()
+	})
+}
+
+// Drain gracefully shuts down all of the configured listeners, or any for the type specifically supplied. Network
+// listeners stop accepting new connections and wait for in-flight requests to complete until the context is done,
+// while other listeners are closed immediately. Drained listeners are removed from Endpoints.
+func (e *Endpoints) Drain(ctx context.Context, types ...EndpointType) error {
+	return e.down(types, func(listener Endpoint) error {
+		n, ok := listener.(*Network)
+		if ok {
+			return n.Shutdown(ctx)
+		}
+
+		return listener.Close()
+	})
+}
+
+// down stops each listener of the given types, or all listeners if no types are given, with the stop function.
+func (e *Endpoints) down(types []EndpointType, stop func(listener Endpoint) error) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	for _, listener := range e.listeners {
-		remove := false
-		for _, endpoint := range types {
-			if listener.Type() == endpoint {
-				remove = true
-			}
+	remaining := make([]Endpoint, 0, len(e.listeners))
+	for i, listener := range e.listeners {
+		if types != nil && !slices.Contains(types, listener.Type()) {
+			remaining = append(remaining, listener)
+			continue
 		}
 
-		if types == nil || remove {
-			err := listener.Close()
-			if err != nil {
-				return err
-			}
+		err := stop(listener)
+		if err != nil {
+			e.listeners = append(remaining, e.listeners[i:]...)
+			return err
 		}
 	}
 
+	e.listeners = remaining
+
 	return nil
 }
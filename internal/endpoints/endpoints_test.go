@@ -0,0 +1,96 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type endpointsSuite struct {
+	suite.Suite
+}
+
+func TestEndpointsSuite(t *testing.T) {
+	suite.Run(t, new(endpointsSuite))
+}
+
+// testEndpoint is an Endpoint that records whether it is listening.
+type testEndpoint struct {
+	endpointType EndpointType
+	listenErr    error
+	closeErr     error
+	listening    bool
+}
+
+func (e *testEndpoint) Listen() error {
+	if e.listenErr != nil {
+		return e.listenErr
+	}
+
+	e.listening = true
+
+	return nil
+}
+
+func (e *testEndpoint) Serve() {}
+
+func (e *testEndpoint) Close() error {
+	if e.closeErr != nil {
+		return e.closeErr
+	}
+
+	e.listening = false
+
+	return nil
+}
+
+func (e *testEndpoint) Type() EndpointType {
+	return e.endpointType
+}
+
+// Ensures every listener of a type is closed, including optional listeners of the same type as the core API.
+func (s *endpointsSuite) Test_down() {
+	control := &testEndpoint{endpointType: EndpointControl}
+	core := &testEndpoint{endpointType: EndpointNetwork}
+	endpoints := NewEndpoints(context.Background(), logger.AddContext(logger.Ctx{}), control, core)
+	s.Require().NoError(endpoints.Up())
+
+	optional := &testEndpoint{endpointType: EndpointNetwork}
+	s.Require().NoError(endpoints.AddOptional(optional))
+
+	// A listener that fails to start is not added, and leaves the others running.
+	failed := &testEndpoint{endpointType: EndpointNetwork, listenErr: errors.New("bind failed")}
+	s.Error(endpoints.AddOptional(failed))
+	s.True(core.listening)
+	s.True(optional.listening)
+
+	s.NoError(endpoints.Down(EndpointNetwork))
+	s.False(core.listening)
+	s.False(optional.listening)
+	s.True(control.listening)
+
+	s.NoError(endpoints.Down())
+	s.False(control.listening)
+}
+
+// Ensures listeners that were not closed remain in Endpoints if closing one fails.
+func (s *endpointsSuite) Test_downError() {
+	first := &testEndpoint{endpointType: EndpointNetwork}
+	broken := &testEndpoint{endpointType: EndpointNetwork}
+	last := &testEndpoint{endpointType: EndpointNetwork}
+	endpoints := NewEndpoints(context.Background(), logger.AddContext(logger.Ctx{}), first, broken, last)
+	s.Require().NoError(endpoints.Up())
+
+	broken.closeErr = errors.New("close failed")
+	s.Error(endpoints.Down())
+	s.False(first.listening)
+	s.True(last.listening)
+
+	broken.closeErr = nil
+	s.NoError(endpoints.Down())
+	s.False(broken.listening)
+	s.False(last.listening)
+}
@@ -2,16 +2,19 @@ package endpoints
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
 	"strings"
 
-	"github.com/canonical/lxd/lxd/endpoints/listeners"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 // Network represents an HTTPS listener and its server.
@@ -19,26 +22,35 @@ type Network struct {
 	address     api.URL
 	cert        *shared.CertInfo
 	networkType EndpointType
+	tlsConfig   types.TLSConfig
 
 	listener net.Listener
 	server   *http.Server
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	log     logger.Logger
+	onError func(err error) // Optional handler for the server stopping unexpectedly.
+
+	verifyPeer func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error // Optional extra check of client certificates.
 }
 
 // NewNetwork assigns an address, certificate, and server to the Network.
-func NewNetwork(ctx context.Context, endpointType EndpointType, server *http.Server, address api.URL, cert *shared.CertInfo) *Network {
+// Any fields set on the tlsConfig override the default TLS configuration of the listener.
+func NewNetwork(ctx context.Context, log logger.Logger, endpointType EndpointType, server *http.Server, address api.URL, cert *shared.CertInfo, tlsConfig types.TLSConfig) *Network {
 	ctx, cancel := context.WithCancel(ctx)
 
 	return &Network{
 		address:     address,
 		cert:        cert,
 		networkType: endpointType,
+		tlsConfig:   tlsConfig,
 
 		server: server,
 		ctx:    ctx,
 		cancel: cancel,
+		log:    log,
 	}
 }
 
@@ -66,20 +78,39 @@ func (n *Network) Listen() error {
 		return fmt.Errorf("Failed to listen on https socket: %w", err)
 	}
 
-	n.listener = listeners.NewFancyTLSListener(listener, n.cert)
+	// In plaintext mode, serve plain HTTP.
+	if sys.Plaintext() {
+		n.listener = listener
+
+		return nil
+	}
+
+	n.listener = newTLSListener(listener, n.cert, n.tlsConfig, n.verifyPeer)
 
 	return nil
 }
 
 // UpdateTLS updates the TLS configuration of the network listener.
 func (n *Network) UpdateTLS(cert *shared.CertInfo) {
-	l, ok := n.listener.(*listeners.FancyTLSListener)
+	l, ok := n.listener.(*tlsListener)
 	if ok {
 		n.cert = cert
 		l.Config(cert)
 	}
 }
 
+// SetErrorHandler sets a function to be called if the server stops unexpectedly after it started serving.
+func (n *Network) SetErrorHandler(f func(err error)) {
+	n.onError = f
+}
+
+// SetVerifyPeerCertificate sets a function to be run during the TLS handshake on any certificate presented by a client,
+// which rejects the connection if it returns an error. As client certificates are not verified by the handshake, it is
+// always given nil verifiedChains. It must be called before Listen.
+func (n *Network) SetVerifyPeerCertificate(verify func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error) {
+	n.verifyPeer = verify
+}
+
 // Serve binds to the Network's server.
 func (n *Network) Serve() {
 	if n.listener == nil {
@@ -87,20 +118,23 @@ func (n *Network) Serve() {
 	}
 
 	ctx := logger.Ctx{"network": n.listener.Addr()}
-	logger.Info(" - binding https socket", ctx)
+	n.log.Info(" - binding https socket", ctx)
 
 	go func() {
 		select {
 		case <-n.ctx.Done():
-			logger.Infof("Received shutdown signal - aborting https socket server startup")
+			n.log.Info("Received shutdown signal - aborting https socket server startup")
 		default:
 			err := n.server.Serve(n.listener)
 			if err != nil {
 				select {
 				case <-n.ctx.Done():
-					logger.Infof("Received shutdown signal - aborting https socket server startup")
+					n.log.Info("Received shutdown signal - aborting https socket server startup")
 				default:
-					logger.Error("Failed to start server", logger.Ctx{"err": err})
+					n.log.Error("Failed to start server", logger.Ctx{"err": err})
+					if n.onError != nil {
+						n.onError(fmt.Errorf("Server on %q stopped: %w", n.address.URL.Host, err))
+					}
 				}
 			}
 		}
@@ -113,8 +147,26 @@ func (n *Network) Close() error {
 		return nil
 	}
 
-	logger.Info("Stopping REST API handler - closing https socket", logger.Ctx{"address": n.listener.Addr()})
+	n.log.Info("Stopping REST API handler - closing https socket", logger.Ctx{"address": n.listener.Addr()})
 	n.cancel()
 
 	return n.listener.Close()
 }
+
+// Shutdown gracefully shuts down the server, waiting for in-flight requests to complete until the context is done.
+// Hijacked connections, such as those used by the database, are not waited on.
+func (n *Network) Shutdown(ctx context.Context) error {
+	if n.listener == nil {
+		return nil
+	}
+
+	n.log.Info("Stopping REST API handler - draining https socket", logger.Ctx{"address": n.listener.Addr()})
+	n.cancel()
+
+	err := n.server.Shutdown(ctx)
+
+	// The server may not have started serving on the listener yet, in which case it must be closed separately.
+	_ = n.listener.Close()
+
+	return err
+}
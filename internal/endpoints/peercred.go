@@ -0,0 +1,126 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/canonical/lxd/shared/logger"
+	"golang.org/x/sys/unix"
+)
+
+// peerCredListener is a unix socket listener that only accepts connections from processes running as root or as the
+// user of the daemon, or as a member of the socket group, as checked from the peer credentials of each connection.
+// It restricts access to abstract sockets the same way as the file permissions of a socket file.
+type peerCredListener struct {
+	*net.UnixListener
+
+	gid atomic.Int64
+	log logger.Logger
+}
+
+// newPeerCredListener wraps the given listener, allowing connections from members of the given group, or of the
+// group of the daemon if empty.
+func newPeerCredListener(inner *net.UnixListener, log logger.Logger, group string) (*peerCredListener, error) {
+	listener := &peerCredListener{UnixListener: inner, log: log}
+
+	err := listener.SetGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// SetGroup sets the group whose members may connect, or the group of the daemon if empty.
+func (l *peerCredListener) SetGroup(group string) error {
+	gid := os.Getgid()
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return fmt.Errorf("Cannot get group ID of '%s': %w", group, err)
+		}
+
+		gid, err = strconv.Atoi(g.Gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	l.gid.Store(int64(gid))
+
+	return nil
+}
+
+// Accept waits for and returns the next connection from an allowed process, closing any others.
+func (l *peerCredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.UnixListener.AcceptUnix()
+		if err != nil {
+			return nil, err
+		}
+
+		cred, err := peerCredentials(conn)
+		if err == nil && !l.allowed(cred) {
+			err = fmt.Errorf("Process %d of user %d is not allowed to access the control socket", cred.Pid, cred.Uid)
+		}
+
+		if err == nil {
+			return conn, nil
+		}
+
+		l.log.Warn("Rejected connection to control socket", logger.Ctx{"socket": l.Addr().String(), "error": err})
+		_ = conn.Close()
+	}
+}
+
+// allowed returns whether the process with the given credentials is root, runs as the user of the daemon, or is a
+// member of the allowed group.
+func (l *peerCredListener) allowed(cred *unix.Ucred) bool {
+	if cred.Uid == 0 || int(cred.Uid) == os.Getuid() {
+		return true
+	}
+
+	gid := l.gid.Load()
+	if int64(cred.Gid) == gid {
+		return true
+	}
+
+	u, err := user.LookupId(strconv.FormatUint(uint64(cred.Uid), 10))
+	if err != nil {
+		return false
+	}
+
+	groups, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(groups, strconv.FormatInt(gid, 10))
+}
+
+// peerCredentials returns the credentials of the process at the other end of the unix socket connection.
+func peerCredentials(conn *net.UnixConn) (*unix.Ucred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+
+	err = errors.Join(err, credErr)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get peer credentials: %w", err)
+	}
+
+	return cred, nil
+}
@@ -0,0 +1,87 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/sys/unix"
+)
+
+type peerCredSuite struct {
+	suite.Suite
+}
+
+func TestPeerCredSuite(t *testing.T) {
+	suite.Run(t, new(peerCredSuite))
+}
+
+// Ensures only root, the user of the daemon, and members of the allowed group may connect to an abstract socket.
+func (s *peerCredSuite) Test_allowed() {
+	listener, err := newPeerCredListener(nil, logger.AddContext(logger.Ctx{}), "")
+	s.Require().NoError(err)
+
+	// Use IDs that are unlikely to belong to any user or group of the system running the tests.
+	otherID := uint32(4000000000)
+
+	tests := []struct {
+		name    string
+		cred    unix.Ucred
+		allowed bool
+	}{
+		{
+			name:    "Root",
+			cred:    unix.Ucred{Uid: 0, Gid: otherID},
+			allowed: true,
+		},
+		{
+			name:    "User of the daemon",
+			cred:    unix.Ucred{Uid: uint32(os.Getuid()), Gid: otherID},
+			allowed: true,
+		},
+		{
+			name:    "Member of the group of the daemon",
+			cred:    unix.Ucred{Uid: otherID, Gid: uint32(os.Getgid())},
+			allowed: true,
+		},
+		{
+			name: "Other user and group",
+			cred: unix.Ucred{Uid: otherID, Gid: otherID},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		s.Equal(test.allowed, listener.allowed(&test.cred))
+	}
+}
+
+// Ensures connections from the user of the daemon are accepted on an abstract socket.
+func (s *peerCredSuite) Test_abstractSocket() {
+	path := fmt.Sprintf("@microcluster-test-%d-%d", os.Getpid(), time.Now().UnixNano())
+
+	addr, err := net.ResolveUnixAddr("unix", path)
+	s.Require().NoError(err)
+
+	inner, err := net.ListenUnix("unix", addr)
+	s.Require().NoError(err)
+
+	listener, err := newPeerCredListener(inner, logger.AddContext(logger.Ctx{}), "")
+	s.Require().NoError(err)
+
+	defer func() { _ = listener.Close() }()
+
+	client, err := net.Dial("unix", path)
+	s.Require().NoError(err)
+
+	defer func() { _ = client.Close() }()
+
+	conn, err := listener.Accept()
+	s.Require().NoError(err)
+	s.NoError(conn.Close())
+}
@@ -9,9 +9,12 @@ import (
 	"os/user"
 	"strconv"
 
+	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/sys"
 )
 
 // Socket represents a unix socket with a given path.
@@ -19,15 +22,22 @@ type Socket struct {
 	Path  string
 	Group string
 
-	listener *net.UnixListener
+	listener net.Listener
 	server   *http.Server
 
+	// peerCred restricts access to an abstract socket, which has no file permissions, by the peer credentials of each
+	// connection.
+	peerCred *peerCredListener
+
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	log     logger.Logger
+	onError func(err error) // Optional handler for the server stopping unexpectedly.
 }
 
 // NewSocket returns a Socket struct with no listener attached yet.
-func NewSocket(ctx context.Context, server *http.Server, path api.URL, group string) *Socket {
+func NewSocket(ctx context.Context, log logger.Logger, server *http.Server, path api.URL, group string) *Socket {
 	ctx, cancel := context.WithCancel(ctx)
 	return &Socket{
 		Path:  path.Hostname(),
@@ -36,6 +46,7 @@ func NewSocket(ctx context.Context, server *http.Server, path api.URL, group str
 		server: server,
 		ctx:    ctx,
 		cancel: cancel,
+		log:    log,
 	}
 }
 
@@ -45,7 +56,20 @@ func (s *Socket) Type() EndpointType {
 }
 
 // Listen on the unix socket path.
+// If the daemon was started through systemd socket activation with a unix socket at the same path,
+// the inherited socket is used instead, and its file is left for systemd to manage.
+// Abstract sockets have no file permissions, so connections to them are only accepted from processes running as root
+// or as the user of the daemon, or as a member of the socket group, or of the group of the daemon if none is set.
 func (s *Socket) Listen() error {
+	abstract := sys.IsAbstractSocket(s.Path)
+	listener := s.inheritedListener()
+	if listener != nil {
+		s.log.Info("Using socket activated control socket", logger.Ctx{"socket": s.Path})
+		listener.SetUnlinkOnClose(false)
+
+		return s.setListener(listener, abstract)
+	}
+
 	_, err := net.Dial("unix", s.Path)
 	if err == nil {
 		return fmt.Errorf("Unix socket at %q is already running", s.Path)
@@ -61,16 +85,21 @@ func (s *Socket) Listen() error {
 		return fmt.Errorf("Cannot resolve socket address: %w", err)
 	}
 
-	s.listener, err = net.ListenUnix("unix", addr)
+	listener, err = net.ListenUnix("unix", addr)
 	if err != nil {
 		return fmt.Errorf("Cannot bind socket: %w", err)
 	}
 
+	err = s.setListener(listener, abstract)
+	if err != nil || abstract {
+		return err
+	}
+
 	err = localSetAccess(s.Path, s.Group)
 	if err != nil {
 		closeErr := s.listener.Close()
 		if closeErr != nil {
-			logger.Error("Failed to close socket listener", logger.Ctx{"error": closeErr})
+			s.log.Error("Failed to close socket listener", logger.Ctx{"error": closeErr})
 		}
 
 		return err
@@ -79,6 +108,35 @@ func (s *Socket) Listen() error {
 	return nil
 }
 
+// setListener sets the listener of the socket, restricting access to abstract sockets by peer credentials.
+func (s *Socket) setListener(listener *net.UnixListener, abstract bool) error {
+	if !abstract {
+		s.listener = listener
+
+		return nil
+	}
+
+	peerCred, err := newPeerCredListener(listener, s.log, s.Group)
+	if err != nil {
+		closeErr := listener.Close()
+		if closeErr != nil {
+			s.log.Error("Failed to close socket listener", logger.Ctx{"error": closeErr})
+		}
+
+		return err
+	}
+
+	s.peerCred = peerCred
+	s.listener = peerCred
+
+	return nil
+}
+
+// SetErrorHandler sets a function to be called if the server stops unexpectedly after it started serving.
+func (s *Socket) SetErrorHandler(f func(err error)) {
+	s.onError = f
+}
+
 // Serve binds to the Socket's server.
 func (s *Socket) Serve() {
 	if s.listener == nil {
@@ -86,20 +144,23 @@ func (s *Socket) Serve() {
 	}
 
 	ctx := logger.Ctx{"socket": s.listener.Addr()}
-	logger.Info(" - binding control socket", ctx)
+	s.log.Info(" - binding control socket", ctx)
 
 	go func() {
 		select {
 		case <-s.ctx.Done():
-			logger.Infof("Received shutdown signal - aborting unix socket server startup")
+			s.log.Info("Received shutdown signal - aborting unix socket server startup")
 		default:
 			err := s.server.Serve(s.listener)
 			if err != nil {
 				select {
 				case <-s.ctx.Done():
-					logger.Infof("Received shutdown signal - aborting unix socket server startup")
+					s.log.Info("Received shutdown signal - aborting unix socket server startup")
 				default:
-					logger.Error("Failed to start server", logger.Ctx{"err": err})
+					s.log.Error("Failed to start server", logger.Ctx{"err": err})
+					if s.onError != nil {
+						s.onError(fmt.Errorf("Server on control socket %q stopped: %w", s.Path, err))
+					}
 				}
 			}
 		}
@@ -112,20 +173,41 @@ func (s *Socket) Close() error {
 		return nil
 	}
 
-	logger.Info("Stopping REST API handler - closing socket", logger.Ctx{"socket": s.listener.Addr()})
+	s.log.Info("Stopping REST API handler - closing socket", logger.Ctx{"socket": s.listener.Addr()})
 	s.cancel()
 
 	return s.listener.Close()
 }
 
+// inheritedListener returns the unix socket listener for the socket path passed by systemd socket activation, if any.
+// Any other inherited listeners are closed.
+func (s *Socket) inheritedListener() *net.UnixListener {
+	var inherited *net.UnixListener
+	for _, listener := range util.GetListeners(util.SystemdListenFDsStart) {
+		unixListener, ok := listener.(*net.UnixListener)
+		if ok && inherited == nil && unixListener.Addr().String() == s.Path {
+			inherited = unixListener
+			continue
+		}
+
+		s.log.Warn("Closing unused socket activated listener", logger.Ctx{"address": listener.Addr().String()})
+		err := listener.Close()
+		if err != nil {
+			s.log.Error("Failed to close socket activated listener", logger.Ctx{"error": err})
+		}
+	}
+
+	return inherited
+}
+
 // Remove any stale socket file at the given path.
 func (s *Socket) removeStale() error {
-	// If there's no socket file at all, there's nothing to do.
-	if !shared.PathExists(s.Path) {
+	// If there's no socket file at all, there's nothing to do. Abstract sockets never have a file.
+	if sys.IsAbstractSocket(s.Path) || !shared.PathExists(s.Path) {
 		return nil
 	}
 
-	logger.Debugf("Detected stale control socket, deleting")
+	s.log.Debug("Detected stale control socket, deleting")
 	err := os.Remove(s.Path)
 	if err != nil {
 		return fmt.Errorf("Could not delete stale local socket: %w", err)
@@ -134,6 +216,32 @@ func (s *Socket) removeStale() error {
 	return nil
 }
 
+// SetGroup changes the group that owns the socket file, without recreating the listener.
+// Abstract sockets have no file, so the group is instead used to check the peer credentials of new connections.
+func (s *Socket) SetGroup(group string) error {
+	if group != "" {
+		_, err := user.LookupGroup(group)
+		if err != nil {
+			return api.StatusErrorf(http.StatusBadRequest, "Group %q does not exist: %v", group, err)
+		}
+	}
+
+	var err error
+	if s.peerCred != nil {
+		err = s.peerCred.SetGroup(group)
+	} else if !sys.IsAbstractSocket(s.Path) {
+		err = socketControlSetOwnership(s.Path, group)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.Group = group
+
+	return nil
+}
+
 // Change the file mode and ownership of the local endpoint control socket file,
 // so access is granted only to the process user and to the given group (or the
 // process group if group is empty).
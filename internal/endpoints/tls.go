@@ -0,0 +1,80 @@
+package endpoints
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
+
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// tlsListener is a TLS listener whose certificate can be updated at runtime, and which applies any TLS overrides
+// on top of the default server TLS configuration.
+type tlsListener struct {
+	net.Listener
+
+	mu        sync.RWMutex
+	config    *tls.Config
+	overrides types.TLSConfig
+	verify    func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+}
+
+// newTLSListener wraps the given listener with TLS, using the given certificate and TLS overrides.
+// If verify is not nil, it is run during the handshake on any certificate presented by the client.
+func newTLSListener(inner net.Listener, cert *shared.CertInfo, overrides types.TLSConfig, verify func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error) *tlsListener {
+	listener := &tlsListener{
+		Listener:  inner,
+		overrides: overrides,
+		verify:    verify,
+	}
+
+	listener.Config(cert)
+
+	return listener
+}
+
+// Accept waits for and returns the next incoming TLS connection, using the current TLS configuration.
+func (l *tlsListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return tls.Server(c, l.config), nil
+}
+
+// Config updates the TLS configuration of the listener with the given certificate.
+func (l *tlsListener) Config(cert *shared.CertInfo) {
+	config := util.ServerTLSConfig(cert)
+	l.overrides.Apply(config)
+
+	if l.verify != nil {
+		config.VerifyPeerCertificate = verifyPresentedCertificate(l.verify)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.config = config
+}
+
+// verifyPresentedCertificate returns a tls.Config.VerifyPeerCertificate function that runs verify only if the client
+// presented a certificate, as clients without one may still reach untrusted endpoints. An error returned by verify
+// aborts the handshake with a bad certificate alert. The listener only requests client certificates, so verifiedChains
+// is always nil.
+func verifyPresentedCertificate(verify func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return nil
+		}
+
+		return verify(rawCerts, verifiedChains)
+	}
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// subscriberBuffer is the number of events buffered for each subscriber. Events published while the buffer of a
+// subscriber is full are dropped for that subscriber, so that a slow consumer never blocks the daemon.
+const subscriberBuffer = 256
+
+// subscriber receives the events of the selected types.
+type subscriber struct {
+	types  []types.EventType
+	events chan types.Event
+}
+
+// Bus distributes the events published by the daemon to any number of subscribers.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// NewBus returns an event bus without any subscribers.
+func NewBus() *Bus {
+	return &Bus{subscribers: map[*subscriber]struct{}{}}
+}
+
+// Publish sends the event to every subscriber of its type, without waiting on any of them.
+func (b *Bus) Publish(event types.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, event.Type) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of the published events of the given types, or of all types if none are given.
+// The channel is closed once the context is cancelled.
+func (b *Bus) Subscribe(ctx context.Context, eventTypes ...types.EventType) <-chan types.Event {
+	sub := &subscriber{types: eventTypes, events: make(chan types.Event, subscriberBuffer)}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		close(sub.events)
+		b.mu.Unlock()
+	}()
+
+	return sub.events
+}
@@ -70,6 +70,21 @@ func (e Extensions) HasExtension(ext string) bool {
 	return false
 }
 
+// Split returns the internal extensions provided by microcluster, and the external extensions registered by the application.
+func (e Extensions) Split() (internal []string, external []string) {
+	internal = []string{}
+	external = []string{}
+	for _, extension := range e {
+		if validateInternalExtension(extension) == nil {
+			internal = append(internal, extension)
+		} else {
+			external = append(external, extension)
+		}
+	}
+
+	return internal, external
+}
+
 // Version returns the number of extensions in the set, representing its version number.
 func (e Extensions) Version() int {
 	return len(e)
@@ -193,3 +208,21 @@ func (e Extensions) IsSameVersion(t Extensions) error {
 
 	return nil
 }
+
+// Diff returns the extensions in the target registry that are not in the source registry (missing), and the
+// extensions in the source registry that are not in the target registry (extra).
+func (e Extensions) Diff(t Extensions) (missing []string, extra []string) {
+	for _, extension := range t {
+		if !e.HasExtension(extension) {
+			missing = append(missing, extension)
+		}
+	}
+
+	for _, extension := range e {
+		if !t.HasExtension(extension) {
+			extra = append(extra, extension)
+		}
+	}
+
+	return missing, extra
+}
@@ -115,6 +115,27 @@ func TestIsSameVersion(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDiff(t *testing.T) {
+	registry1 := Extensions{"internal:runtime_extension_v1", "valid_extension"}
+	registry2 := Extensions{"internal:runtime_extension_v1", "other_extension"}
+
+	missing, extra := registry1.Diff(registry1)
+	assert.Nil(t, missing)
+	assert.Nil(t, extra)
+
+	missing, extra = registry1.Diff(registry2)
+	assert.Equal(t, []string{"other_extension"}, missing)
+	assert.Equal(t, []string{"valid_extension"}, extra)
+}
+
+func TestSplit(t *testing.T) {
+	registry := Extensions{"internal:runtime_extension_v1", "valid_extension"}
+
+	internal, external := registry.Split()
+	assert.Equal(t, []string{"internal:runtime_extension_v1"}, internal)
+	assert.Equal(t, []string{"valid_extension"}, external)
+}
+
 func TestRegisterALotOfExtensions(t *testing.T) {
 	registry, _ := NewExtensionRegistry(false)
 	for i := 0; i < 10000; i++ {
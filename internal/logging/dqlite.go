@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+)
+
+// dqliteLevels maps dqlite log levels to slog levels.
+var dqliteLevels = map[dqliteClient.LogLevel]slog.Level{
+	dqliteClient.LogDebug: slog.LevelDebug,
+	dqliteClient.LogInfo:  slog.LevelInfo,
+	dqliteClient.LogWarn:  slog.LevelWarn,
+	dqliteClient.LogError: slog.LevelError,
+}
+
+// NewDqliteLogFunc returns a dqlite log function that sends dqlite's own log messages at or above the given level to
+// the slog.Handler, with a "component" attribute of "dqlite" so that they can be told apart from other messages.
+func NewDqliteLogFunc(handler slog.Handler, minLevel slog.Level) dqliteClient.LogFunc {
+	return func(l dqliteClient.LogLevel, format string, a ...any) {
+		level, ok := dqliteLevels[l]
+		if !ok || level < minLevel || !handler.Enabled(context.Background(), level) {
+			return
+		}
+
+		record := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, a...), 0)
+		record.AddAttrs(slog.String("component", "dqlite"))
+
+		_ = handler.Handle(context.Background(), record)
+	}
+}
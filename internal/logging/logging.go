@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// LevelTrace is the slog level used for trace messages, which are more verbose than debug messages.
+const LevelTrace = slog.LevelDebug - 4
+
+// slogLogger implements logger.Logger by passing records to a slog.Handler.
+type slogLogger struct {
+	handler slog.Handler
+}
+
+// NewSlogLogger returns a logger.Logger that sends all log records to the given slog.Handler.
+func NewSlogLogger(handler slog.Handler) logger.Logger {
+	return &slogLogger{handler: handler}
+}
+
+// log sends a record with the given level, message, and context to the handler, if the level is enabled.
+func (l *slogLogger) log(level slog.Level, msg string, ctx ...logger.Ctx) {
+	if !l.handler.Enabled(context.Background(), level) {
+		return
+	}
+
+	record := slog.NewRecord(time.Now(), level, msg, 0)
+	for _, c := range ctx {
+		for k, v := range c {
+			record.AddAttrs(slog.Any(k, v))
+		}
+	}
+
+	_ = l.handler.Handle(context.Background(), record)
+}
+
+// Panic logs a message at the error level and panics.
+func (l *slogLogger) Panic(msg string, ctx ...logger.Ctx) {
+	l.log(slog.LevelError, msg, ctx...)
+	panic(msg)
+}
+
+// Fatal logs a message at the error level and exits.
+func (l *slogLogger) Fatal(msg string, ctx ...logger.Ctx) {
+	l.log(slog.LevelError, msg, ctx...)
+	os.Exit(1)
+}
+
+// Error logs a message at the error level.
+func (l *slogLogger) Error(msg string, ctx ...logger.Ctx) {
+	l.log(slog.LevelError, msg, ctx...)
+}
+
+// Warn logs a message at the warn level.
+func (l *slogLogger) Warn(msg string, ctx ...logger.Ctx) {
+	l.log(slog.LevelWarn, msg, ctx...)
+}
+
+// Info logs a message at the info level.
+func (l *slogLogger) Info(msg string, ctx ...logger.Ctx) {
+	l.log(slog.LevelInfo, msg, ctx...)
+}
+
+// Debug logs a message at the debug level.
+func (l *slogLogger) Debug(msg string, ctx ...logger.Ctx) {
+	l.log(slog.LevelDebug, msg, ctx...)
+}
+
+// Trace logs a message at the trace level.
+func (l *slogLogger) Trace(msg string, ctx ...logger.Ctx) {
+	l.log(LevelTrace, msg, ctx...)
+}
+
+// AddContext returns a logger that includes the given context with every message.
+func (l *slogLogger) AddContext(ctx logger.Ctx) logger.Logger {
+	attrs := make([]slog.Attr, 0, len(ctx))
+	for k, v := range ctx {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+
+	return &slogLogger{handler: l.handler.WithAttrs(attrs)}
+}
+
+// globalLogger implements logger.Logger by forwarding to the package-global logger.Log at the time of each call,
+// so that it respects any later call to logger.InitLogger.
+type globalLogger struct {
+	ctx logger.Ctx
+}
+
+// Global returns a logger.Logger that forwards to the package-global logger.
+func Global() logger.Logger {
+	return &globalLogger{}
+}
+
+// target returns the package-global logger, with the context of this logger applied.
+func (l *globalLogger) target() logger.Logger {
+	if l.ctx == nil {
+		return logger.Log
+	}
+
+	return logger.Log.AddContext(l.ctx)
+}
+
+// Panic logs a message at the panic level.
+func (l *globalLogger) Panic(msg string, ctx ...logger.Ctx) {
+	l.target().Panic(msg, ctx...)
+}
+
+// Fatal logs a message at the fatal level.
+func (l *globalLogger) Fatal(msg string, ctx ...logger.Ctx) {
+	l.target().Fatal(msg, ctx...)
+}
+
+// Error logs a message at the error level.
+func (l *globalLogger) Error(msg string, ctx ...logger.Ctx) {
+	l.target().Error(msg, ctx...)
+}
+
+// Warn logs a message at the warn level.
+func (l *globalLogger) Warn(msg string, ctx ...logger.Ctx) {
+	l.target().Warn(msg, ctx...)
+}
+
+// Info logs a message at the info level.
+func (l *globalLogger) Info(msg string, ctx ...logger.Ctx) {
+	l.target().Info(msg, ctx...)
+}
+
+// Debug logs a message at the debug level.
+func (l *globalLogger) Debug(msg string, ctx ...logger.Ctx) {
+	l.target().Debug(msg, ctx...)
+}
+
+// Trace logs a message at the trace level.
+func (l *globalLogger) Trace(msg string, ctx ...logger.Ctx) {
+	l.target().Trace(msg, ctx...)
+}
+
+// AddContext returns a logger that includes the given context with every message.
+func (l *globalLogger) AddContext(ctx logger.Ctx) logger.Logger {
+	merged := make(logger.Ctx, len(l.ctx)+len(ctx))
+	for k, v := range l.ctx {
+		merged[k] = v
+	}
+
+	for k, v := range ctx {
+		merged[k] = v
+	}
+
+	return &globalLogger{ctx: merged}
+}
@@ -0,0 +1,242 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// retention is how long a completed operation is kept in the registry.
+const retention = 5 * time.Minute
+
+// Operation is a long-running cluster action that can be inspected and cancelled.
+type Operation struct {
+	id          string
+	description string
+	status      types.OperationStatus
+	createdAt   time.Time
+	updatedAt   time.Time
+	err         error
+
+	cancel context.CancelFunc
+	mu     sync.RWMutex
+}
+
+// ID returns the unique identifier of the operation.
+func (op *Operation) ID() string {
+	return op.id
+}
+
+// Render returns the API representation of the operation.
+func (op *Operation) Render() types.Operation {
+	op.mu.RLock()
+	defer op.mu.RUnlock()
+
+	apiOp := types.Operation{
+		ID:          op.id,
+		Description: op.description,
+		Status:      op.status,
+		CreatedAt:   op.createdAt,
+		UpdatedAt:   op.updatedAt,
+	}
+
+	if op.err != nil {
+		apiOp.Err = op.err.Error()
+	}
+
+	return apiOp
+}
+
+// finish records the result of the operation.
+func (op *Operation) finish(ctx context.Context, err error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.err = err
+	op.updatedAt = time.Now()
+	if err == nil {
+		op.status = types.OperationSuccess
+	} else if errors.Is(ctx.Err(), context.Canceled) {
+		op.status = types.OperationCancelled
+	} else {
+		op.status = types.OperationFailure
+	}
+}
+
+// Operations is a registry of the long-running operations on the local cluster member.
+type Operations struct {
+	operations map[string]*Operation
+	mu         sync.RWMutex
+}
+
+// NewOperations returns an empty operations registry.
+func NewOperations() *Operations {
+	return &Operations{operations: map[string]*Operation{}}
+}
+
+// Run registers a new operation with the given description and runs f, blocking until it completes.
+// The context passed to f is cancelled if the operation is cancelled through the registry.
+func (o *Operations) Run(ctx context.Context, description string, f func(ctx context.Context) error) error {
+	op, ctx, err := o.register(ctx, description)
+	if err != nil {
+		return err
+	}
+
+	defer op.cancel()
+
+	return op.run(ctx, f)
+}
+
+// Start registers a new operation with the given description and runs f in the background, returning the operation
+// so that its progress can be observed through the registry. The context passed to f is cancelled if the operation is
+// cancelled through the registry, or if the given context is cancelled.
+func (o *Operations) Start(ctx context.Context, description string, f func(ctx context.Context) error) (*Operation, error) {
+	op, ctx, err := o.register(ctx, description)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer op.cancel()
+
+		err := op.run(ctx, f)
+		if err != nil {
+			logger.Error("Background operation failed", logger.Ctx{"id": op.id, "description": op.description, "err": err})
+		}
+	}()
+
+	return op, nil
+}
+
+// register adds a new running operation with the given description to the registry, and returns it along with the
+// context that is cancelled when the operation is cancelled.
+func (o *Operations) register(ctx context.Context, description string) (*Operation, context.Context, error) {
+	id, err := shared.RandomCryptoString()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to generate operation ID: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	now := time.Now()
+	op := &Operation{
+		id:          id,
+		description: description,
+		status:      types.OperationRunning,
+		createdAt:   now,
+		updatedAt:   now,
+		cancel:      cancel,
+	}
+
+	o.mu.Lock()
+	o.prune()
+	o.operations[op.id] = op
+	o.mu.Unlock()
+
+	return op, ctx, nil
+}
+
+// run runs f for the operation and records its result.
+func (op *Operation) run(ctx context.Context, f func(ctx context.Context) error) error {
+	logger.Debug("Started operation", logger.Ctx{"id": op.id, "description": op.description})
+	err := f(ctx)
+	op.finish(ctx, err)
+	logger.Debug("Completed operation", logger.Ctx{"id": op.id, "description": op.description, "status": op.Render().Status})
+
+	return err
+}
+
+// Get returns the operation with the given ID.
+func (o *Operations) Get(id string) (*Operation, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	op, ok := o.operations[id]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Operation %q not found", id)
+	}
+
+	return op, nil
+}
+
+// List returns the API representation of all operations in the registry, sorted by creation time.
+func (o *Operations) List() []types.Operation {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	ops := make([]types.Operation, 0, len(o.operations))
+	for _, op := range o.operations {
+		ops = append(ops, op.Render())
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].CreatedAt.Before(ops[j].CreatedAt)
+	})
+
+	return ops
+}
+
+// Cancel cancels the running operation with the given ID.
+func (o *Operations) Cancel(id string) error {
+	op, err := o.Get(id)
+	if err != nil {
+		return err
+	}
+
+	// Hold the lock of the operation so that it can't finish between checking its status and cancelling it.
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if op.status != types.OperationRunning {
+		return api.StatusErrorf(http.StatusBadRequest, "Operation %q is not running", id)
+	}
+
+	logger.Info("Cancelling operation", logger.Ctx{"id": id, "description": op.description})
+	op.cancel()
+
+	return nil
+}
+
+// Wait blocks until no operations are running, or the given context is done.
+func (o *Operations) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		running := 0
+		for _, op := range o.List() {
+			if op.Status == types.OperationRunning {
+				running++
+			}
+		}
+
+		if running == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Timed out waiting for %d running operations: %w", running, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune removes completed operations that are older than the retention period. The registry lock must be held.
+func (o *Operations) prune() {
+	for id, op := range o.operations {
+		apiOp := op.Render()
+		if apiOp.Status != types.OperationRunning && time.Since(apiOp.UpdatedAt) > retention {
+			delete(o.operations, id)
+		}
+	}
+}
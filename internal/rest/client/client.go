@@ -13,7 +13,9 @@ import (
 	"net/url"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	clusterRequest "github.com/canonical/lxd/lxd/cluster/request"
@@ -22,7 +24,11 @@ import (
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/tcp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/rest/types"
 )
 
@@ -30,6 +36,18 @@ import (
 type Client struct {
 	*http.Client
 	url api.URL
+
+	tokens *consistencyTokens // Consistency token of the last write, if read-your-writes is enabled.
+
+	ifMatch string // Version that writes expect the resource to have, if set.
+
+	notificationClient func() (*http.Client, error) // Returns the pooled client for notifications, if from a Pool.
+}
+
+// consistencyTokens records the latest consistency token issued for the writes made by a client.
+type consistencyTokens struct {
+	mu    sync.Mutex
+	token uint64
 }
 
 // New returns a new client configured with the given url and certificates.
@@ -37,10 +55,16 @@ func New(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate,
 	var err error
 	var httpClient *http.Client
 
-	// If the url is an absolute path to the control.socket, return a client to the local unix socket.
-	if strings.HasSuffix(url.String(), "control.socket") && path.IsAbs(url.Hostname()) {
+	// If the url is an absolute path to a unix socket such as the control.socket, or an abstract socket address,
+	// return a client to the local unix socket.
+	if path.IsAbs(url.Hostname()) {
 		httpClient, err = unixHTTPClient(shared.HostPath(url.Hostname()))
 		url.Host(filepath.Base(url.Hostname()))
+	} else if sys.IsAbstractSocket(url.Hostname()) {
+		httpClient, err = unixHTTPClient(url.Hostname())
+		url.Host(strings.TrimPrefix(url.Hostname(), "@"))
+	} else if url.URL.Scheme == "http" && sys.Plaintext() {
+		httpClient = plainHTTPClient()
 	} else {
 		proxy := shared.ProxyFromEnvironment
 		if forwarding {
@@ -92,6 +116,16 @@ func unixHTTPClient(path string) (*http.Client, error) {
 	return client, nil
 }
 
+// plainHTTPClient returns a client that connects to cluster members over plain HTTP, for use in plaintext mode.
+func plainHTTPClient() *http.Client {
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+		Proxy:             shared.ProxyFromEnvironment,
+	}
+
+	return &http.Client{Transport: transport}
+}
+
 func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, proxy func(req *http.Request) (*url.URL, error)) (*http.Client, error) {
 	var tlsConfig *tls.Config
 	if remoteCert != nil {
@@ -162,8 +196,21 @@ func tlsHTTPClient(clientCert *shared.CertInfo, remoteCert *x509.Certificate, pr
 }
 
 // SetClusterNotification sets the client's proxy to apply the forwarding headers to a request.
+// Clients from a Pool switch to the pooled notification client for the same cluster member. Otherwise, the transport
+// is copied first, so that any clients sharing it are unaffected.
 func (c *Client) SetClusterNotification() {
-	c.Transport.(*http.Transport).Proxy = forwardingProxy
+	if c.notificationClient != nil {
+		httpClient, err := c.notificationClient()
+		if err == nil {
+			c.Client = httpClient
+			c.notificationClient = nil
+			return
+		}
+	}
+
+	transport := c.Transport.(*http.Transport).Clone()
+	transport.Proxy = forwardingProxy
+	c.Client = &http.Client{Transport: transport, CheckRedirect: c.CheckRedirect, Timeout: c.Timeout}
 }
 
 func forwardingProxy(r *http.Request) (*url.URL, error) {
@@ -248,12 +295,46 @@ func (c *Client) rawQuery(ctx context.Context, method string, url *api.URL, data
 
 // MakeRequest performs a request and parses the response into an api.Response.
 func (c *Client) MakeRequest(r *http.Request) (*api.Response, error) {
+	// Reads carry the latest consistency token, if any, while writes always carry one to ask for a new token.
+	if c.tokens != nil {
+		c.tokens.mu.Lock()
+		token := c.tokens.token
+		c.tokens.mu.Unlock()
+
+		if token > 0 || r.Method != http.MethodGet {
+			r.Header.Set(types.ConsistencyTokenHeader, strconv.FormatUint(token, 10))
+		}
+	}
+
+	if c.ifMatch != "" && r.Method != http.MethodGet {
+		r.Header.Set(types.IfMatchHeader, c.ifMatch)
+	}
+
+	// Continue any trace of the request context with a client span, and propagate it to the remote.
+	// Without a trace, the span is a no-op.
+	tracer := trace.SpanFromContext(r.Context()).TracerProvider().Tracer(internalTypes.TracerName)
+	ctx, span := tracer.Start(r.Context(), r.Method+" "+r.URL.Path, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	r = r.WithContext(ctx)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
 	// Send the request
 	resp, err := c.Do(r)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.tokens != nil && r.Method != http.MethodGet {
+		token, err := strconv.ParseUint(resp.Header.Get(types.ConsistencyTokenHeader), 10, 64)
+		if err == nil {
+			// Concurrent writes may complete out of order, so only keep the latest token.
+			c.tokens.mu.Lock()
+			c.tokens.token = max(c.tokens.token, token)
+			c.tokens.mu.Unlock()
+		}
+	}
+
 	parsedResponse, err := parseResponse(resp)
 	if err != nil {
 		return nil, err
@@ -327,7 +408,38 @@ func (c *Client) UseTarget(name string) *Client {
 	localURL = localURL.WithQuery("target", name)
 
 	return &Client{
-		Client: c.Client,
-		url:    *localURL,
+		Client:  c.Client,
+		url:     *localURL,
+		tokens:  c.tokens,
+		ifMatch: c.ifMatch,
+	}
+}
+
+// ReadYourWrites returns a new client whose reads wait until its previous writes are committed.
+// The client asks for a consistency token on every write, and passes the latest one along on reads so that the
+// receiving cluster member waits until the token's index is committed. Clients derived from the returned client share
+// its tokens.
+func (c *Client) ReadYourWrites() *Client {
+	return &Client{
+		Client:  c.Client,
+		url:     c.url,
+		tokens:  &consistencyTokens{},
+		ifMatch: c.ifMatch,
+	}
+}
+
+// IfMatch returns a new client whose writes only apply if the resource they modify has the given version, on
+// endpoints that support conditional writes. Otherwise they fail with Precondition Failed (412). A version of "*"
+// matches any existing version. Versions cannot contain double quotes.
+func (c *Client) IfMatch(version string) *Client {
+	if version != "*" {
+		version = `"` + version + `"`
+	}
+
+	return &Client{
+		Client:  c.Client,
+		url:     c.url,
+		tokens:  c.tokens,
+		ifMatch: version,
 	}
 }
@@ -35,6 +35,103 @@ func (c *Client) GetClusterMembers(ctx context.Context) ([]types.ClusterMember,
 	return clusterMembers, err
 }
 
+// GetClusterHealth returns a summary of the health of the cluster, and whether the dqlite voters have quorum.
+func (c *Client) GetClusterHealth(ctx context.Context) (*apiTypes.ClusterHealth, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	health := apiTypes.ClusterHealth{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("health"), nil, &health)
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
+// GetReplicationStatus returns how the cluster member is keeping up with the dqlite leader.
+func (c *Client) GetReplicationStatus(ctx context.Context) (*apiTypes.ReplicationStatus, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	status := apiTypes.ReplicationStatus{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("replication"), nil, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// GetClusterRoles returns the number of dqlite voters and stand-bys targeted by the cluster, and the number of nodes
+// that currently have each role.
+func (c *Client) GetClusterRoles(ctx context.Context) (*apiTypes.ClusterRoles, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	roles := apiTypes.ClusterRoles{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("roles"), nil, &roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &roles, nil
+}
+
+// GetEndpoints returns a description of the REST endpoints served by the cluster member.
+func (c *Client) GetEndpoints(ctx context.Context) ([]apiTypes.EndpointInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoints := []apiTypes.EndpointInfo{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("endpoints"), nil, &endpoints)
+
+	return endpoints, err
+}
+
+// DrainClusterMember marks the cluster member with the given name as draining, and waits for its running operations to
+// complete. The deadline of the context, if any, bounds how long to wait.
+func (c *Client) DrainClusterMember(ctx context.Context, name string) error {
+	return c.QueryStruct(ctx, "POST", types.PublicEndpoint, api.NewURL().Path("cluster", name, "drain"), nil, nil)
+}
+
+// UndrainClusterMember clears the draining status of the cluster member with the given name, so that it accepts new
+// writes again.
+func (c *Client) UndrainClusterMember(ctx context.Context, name string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", types.PublicEndpoint, api.NewURL().Path("cluster", name, "drain"), nil, nil)
+}
+
+// GetHeartbeatHistory returns the recent heartbeat outcomes of the cluster member with the given name, as recorded by
+// the dqlite leader.
+func (c *Client) GetHeartbeatHistory(ctx context.Context, name string) ([]apiTypes.HeartbeatOutcome, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	history := []apiTypes.HeartbeatOutcome{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("cluster", name, "heartbeats"), nil, &history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// DrainLocalMember has the cluster member stop accepting new writes and wait for its running operations to complete.
+func (c *Client) DrainLocalMember(ctx context.Context) error {
+	return c.QueryStruct(ctx, "POST", types.InternalEndpoint, api.NewURL().Path("drain"), nil, nil)
+}
+
+// UndrainLocalMember has the cluster member accept new writes again after it was drained.
+func (c *Client) UndrainLocalMember(ctx context.Context) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", types.InternalEndpoint, api.NewURL().Path("drain"), nil, nil)
+}
+
 // DeleteClusterMember deletes the cluster member with the given name.
 func (c *Client) DeleteClusterMember(ctx context.Context, name string, force bool) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -69,3 +166,34 @@ func (c *Client) UpdateClusterCertificate(ctx context.Context, args apiTypes.Clu
 	endpoint := api.NewURL().Path("cluster", "certificates")
 	return c.QueryStruct(queryCtx, "PUT", types.InternalEndpoint, endpoint, args, nil)
 }
+
+// GetClusterMemberVersion returns the schema versions and API extensions of the cluster member.
+// The secret of a pending join token must be supplied.
+func (c *Client) GetClusterMemberVersion(ctx context.Context, secret string) (*types.ClusterMemberVersion, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	version := types.ClusterMemberVersion{}
+	err := c.QueryStruct(queryCtx, "POST", types.PublicEndpoint, api.NewURL().Path("compatibility"), types.ClusterMemberVersionPost{Secret: secret}, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
+
+// AssignRole assigns a dqlite role to a cluster member. The request must be sent to the dqlite leader.
+func (c *Client) AssignRole(ctx context.Context, args types.RoleAssignment) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("role"), args, nil)
+}
+
+// TransferLeadership transfers dqlite leadership from the cluster member to another voter.
+func (c *Client) TransferLeadership(ctx context.Context, args types.LeaderTransfer) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("leader"), args, nil)
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// UpdateClusterConfig sets the given keys of the cluster-wide configuration. Keys with an empty value are removed.
+func (c *Client) UpdateClusterConfig(ctx context.Context, config map[string]string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", types.InternalEndpoint, api.NewURL().Path("config"), config, nil)
+}
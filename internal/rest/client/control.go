@@ -2,11 +2,51 @@ package client
 
 import (
 	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
 )
 
 // ControlDaemon posts control data to the daemon.
 func (c *Client) ControlDaemon(ctx context.Context, args types.Control) error {
 	return c.QueryStruct(ctx, "POST", types.ControlEndpoint, nil, args, nil)
 }
+
+// SetSocketGroup changes the group that owns the control socket of the daemon.
+func (c *Client) SetSocketGroup(ctx context.Context, group string) error {
+	return c.QueryStruct(ctx, "PUT", types.ControlEndpoint, api.NewURL().Path("socket-group"), types.SocketGroup{Group: group}, nil)
+}
+
+// CheckJoin checks whether the daemon is compatible with the cluster members at the given join addresses.
+func (c *Client) CheckJoin(ctx context.Context, args types.JoinCheck) (*apiTypes.JoinCheck, error) {
+	report := apiTypes.JoinCheck{}
+	err := c.QueryStruct(ctx, "POST", types.ControlEndpoint, api.NewURL().Path("check-join"), args, &report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// RecoverFromQuorumLoss rewrites the dqlite cluster configuration of the local cluster member, which must have been
+// started in recovery mode, so that only the given surviving members remain.
+func (c *Client) RecoverFromQuorumLoss(ctx context.Context, members []string) error {
+	return c.QueryStruct(ctx, "POST", types.ControlEndpoint, api.NewURL().Path("recover"), types.QuorumRecovery{Members: members}, nil)
+}
+
+// GetDiagnostics returns the effective runtime configuration and database settings of the cluster member.
+func (c *Client) GetDiagnostics(ctx context.Context) (*apiTypes.Diagnostics, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	diagnostics := apiTypes.Diagnostics{}
+	err := c.QueryStruct(queryCtx, "GET", types.ControlEndpoint, api.NewURL().Path("diagnostics"), nil, &diagnostics)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diagnostics, nil
+}
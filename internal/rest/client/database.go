@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+)
+
+// GetDatabaseDump streams a consistent SQL dump of the database contents to the given writer.
+// The dump is not bounded by a timeout, other than that of the given context.
+func (c *Client) GetDatabaseDump(ctx context.Context, w io.Writer) error {
+	dumpURL := c.url
+	dumpURL.URL.Path = filepath.Join("/", string(types.PublicEndpoint), "database", "dump")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", dumpURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Errors are still returned as regular API responses.
+	if resp.StatusCode != http.StatusOK {
+		_, err := parseResponse(resp)
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("Failed to fetch database dump: %q", resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("Failed to read database dump: %w", err)
+	}
+
+	// The trailer is only available once the body has been read.
+	dumpErr := resp.Trailer.Get(types.DatabaseDumpErrorTrailer)
+	if dumpErr != "" {
+		return fmt.Errorf("Database dump is incomplete: %s", dumpErr)
+	}
+
+	return nil
+}
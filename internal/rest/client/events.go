@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
+)
+
+// StreamEvents streams the events of the given types emitted by the cluster member, or of all types if none are given,
+// calling the handler for each as it occurs. It returns once the context is cancelled, the cluster member closes the
+// stream, or the handler returns an error.
+func (c *Client) StreamEvents(ctx context.Context, handler func(event apiTypes.Event) error, eventTypes ...apiTypes.EventType) error {
+	eventsURL := c.url
+	eventsURL.URL.Path = filepath.Join("/", string(types.PublicEndpoint), "events")
+	if len(eventTypes) > 0 {
+		filter := make([]string, 0, len(eventTypes))
+		for _, eventType := range eventTypes {
+			filter = append(filter, string(eventType))
+		}
+
+		eventsURL = *eventsURL.WithQuery("type", strings.Join(filter, ","))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", eventsURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Errors are still returned as regular API responses.
+	if resp.StatusCode != http.StatusOK {
+		_, err := parseResponse(resp)
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("Failed to stream events: %q", resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event apiTypes.Event
+		err := decoder.Decode(&event)
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("Failed to read event: %w", err)
+		}
+
+		err = handler(event)
+		if err != nil {
+			return err
+		}
+	}
+}
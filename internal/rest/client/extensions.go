@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
+)
+
+// GetAPIExtensions returns the internal and external API extensions supported by the cluster member.
+func (c *Client) GetAPIExtensions(ctx context.Context) (*apiTypes.APIExtensions, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	extensions := apiTypes.APIExtensions{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("extensions"), nil, &extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &extensions, nil
+}
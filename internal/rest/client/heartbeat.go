@@ -12,10 +12,26 @@ import (
 // HeartbeatTimeout is the maximum request timeout for a heartbeat request.
 const HeartbeatTimeout = 30
 
-// Heartbeat initiates a new heartbeat sequence if this is a leader node.
-func (c *Client) Heartbeat(ctx context.Context, hbInfo types.HeartbeatInfo) error {
+// Heartbeat initiates a new heartbeat sequence if this is a leader node, or otherwise sends the heartbeat to the
+// cluster member. Cluster members that handle the heartbeat respond with the time on their clock.
+func (c *Client) Heartbeat(ctx context.Context, hbInfo types.HeartbeatInfo) (*types.HeartbeatResponse, error) {
 	queryCtx, cancel := context.WithTimeout(ctx, HeartbeatTimeout*time.Second)
 	defer cancel()
 
-	return c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("heartbeat"), hbInfo, nil)
+	resp := types.HeartbeatResponse{}
+	err := c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("heartbeat"), hbInfo, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// PauseHeartbeats pauses heartbeat rounds on the cluster member for the given duration, or resumes them if it is 0.
+// Unless the client is a cluster notification, the cluster member pauses heartbeats on every other member too.
+func PauseHeartbeats(ctx context.Context, c *Client, d time.Duration) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PUT", types.InternalEndpoint, api.NewURL().Path("heartbeat", "pause"), types.HeartbeatPause{Duration: d}, nil)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
 )
 
 // RunPreRemoveHook executes the PreRemove hook with the given configuration on the cluster member targeted by this client.
@@ -32,3 +33,17 @@ func RunNewMemberHook(ctx context.Context, c *Client, config types.HookNewMember
 
 	return c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("hooks", string(types.OnNewMember)), config, nil)
 }
+
+// RunConfigChangeHook executes the OnConfigChange hook with the given configuration on the cluster member targeted by this client.
+func RunConfigChangeHook(ctx context.Context, c *Client, config types.HookConfigChangeOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("hooks", string(types.OnConfigChange)), config, nil)
+}
+
+// RunHook runs the named hook on demand with the given arguments on the local cluster member, over the control socket.
+// As hooks may take a while, the deadline of the context is used, with the default request timeout if it has none.
+func (c *Client) RunHook(ctx context.Context, hook string, args apiTypes.HookArgs) error {
+	return c.QueryStruct(ctx, "POST", types.ControlEndpoint, api.NewURL().Path("hooks", hook), args, nil)
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
+)
+
+// GetInitInfo returns how and when the cluster member was bootstrapped or joined the cluster.
+func (c *Client) GetInitInfo(ctx context.Context) (*apiTypes.InitInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	info := apiTypes.InitInfo{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("init-info"), nil, &info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// GetSchemaStatus returns the schema versions and API extensions supported by the cluster member.
+func (c *Client) GetSchemaStatus(ctx context.Context) (*apiTypes.SchemaStatus, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	status := apiTypes.SchemaStatus{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("schema"), nil, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
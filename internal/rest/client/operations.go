@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
+)
+
+// GetOperations returns the long-running operations on the cluster member.
+func (c *Client) GetOperations(ctx context.Context) ([]apiTypes.Operation, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	operations := []apiTypes.Operation{}
+	err := c.QueryStruct(queryCtx, "GET", types.PublicEndpoint, api.NewURL().Path("operations"), nil, &operations)
+
+	return operations, err
+}
+
+// CancelOperation cancels the running operation with the given ID.
+func (c *Client) CancelOperation(ctx context.Context, id string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "DELETE", types.PublicEndpoint, api.NewURL().Path("operations", id), nil, nil)
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"crypto/x509"
+	"net/http"
+	"sync"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// poolKey identifies a cached HTTP client by the remote address and the certificates used to connect to it.
+type poolKey struct {
+	host              string
+	clientFingerprint string
+	remoteFingerprint string
+	forwarding        bool
+}
+
+// Pool caches HTTP clients to cluster members, so that their TLS connections are kept alive and reused across queries.
+type Pool struct {
+	mu      sync.Mutex
+	clients map[poolKey]*http.Client
+}
+
+// NewPool returns an empty client pool.
+func NewPool() *Pool {
+	return &Pool{clients: map[poolKey]*http.Client{}}
+}
+
+// Get returns a client to the given url, reusing the underlying HTTP client and its idle connections if one exists
+// for the same address and certificates. Clients to the unix socket, or without a remote certificate, are not cached.
+func (p *Pool) Get(url api.URL, clientCert *shared.CertInfo, remoteCert *x509.Certificate, forwarding bool) (*Client, error) {
+	if p == nil || remoteCert == nil || clientCert == nil || url.URL.Scheme != "https" {
+		return New(url, clientCert, remoteCert, forwarding)
+	}
+
+	key := poolKey{
+		host:              url.URL.Host,
+		clientFingerprint: clientCert.Fingerprint(),
+		remoteFingerprint: shared.CertFingerprint(remoteCert),
+		forwarding:        forwarding,
+	}
+
+	httpClient, err := p.httpClient(key, clientCert, remoteCert)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{Client: httpClient, url: url}
+
+	// Notifications to the same cluster member share a single cached client with the forwarding proxy.
+	if !forwarding {
+		notificationKey := key
+		notificationKey.forwarding = true
+		c.notificationClient = func() (*http.Client, error) {
+			return p.httpClient(notificationKey, clientCert, remoteCert)
+		}
+	}
+
+	return c, nil
+}
+
+// httpClient returns the cached HTTP client for the given key, creating it if it does not exist yet.
+func (p *Pool) httpClient(key poolKey, clientCert *shared.CertInfo, remoteCert *x509.Certificate) (*http.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	httpClient, ok := p.clients[key]
+	if ok {
+		return httpClient, nil
+	}
+
+	proxy := shared.ProxyFromEnvironment
+	if key.forwarding {
+		proxy = forwardingProxy
+	}
+
+	httpClient, err := tlsHTTPClient(clientCert, remoteCert, proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient.Transport.(*http.Transport).DisableKeepAlives = false
+	p.clients[key] = httpClient
+
+	return httpClient, nil
+}
+
+// Prune closes the idle connections of the cached clients to any address not in the given list, and removes them
+// from the pool, so that connections to removed cluster members are not kept alive.
+func (p *Pool) Prune(hosts []string) {
+	if p == nil {
+		return
+	}
+
+	keep := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		keep[host] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, httpClient := range p.clients {
+		if !keep[key.host] {
+			httpClient.CloseIdleConnections()
+			delete(p.clients, key)
+		}
+	}
+}
+
+// Clear closes the idle connections of all cached clients and removes them from the pool.
+func (p *Pool) Clear() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, httpClient := range p.clients {
+		httpClient.CloseIdleConnections()
+		delete(p.clients, key)
+	}
+}
@@ -2,10 +2,15 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/rest/types"
 )
 
 func parseResponse(resp *http.Response) (*api.Response, error) {
@@ -25,8 +30,47 @@ func parseResponse(resp *http.Response) (*api.Response, error) {
 
 	// Handle errors
 	if response.Type == api.ErrorResponse {
-		return nil, api.StatusErrorf(resp.StatusCode, response.Error)
+		err := api.StatusErrorf(resp.StatusCode, response.Error)
+
+		// Include the reason for any failed join reported by the remote, and any schema version mismatch that caused
+		// it, so that callers can detect them.
+		failure := types.JoinFailure{}
+		if len(response.Metadata) > 0 && response.MetadataAsStruct(&failure) == nil && failure.Err() != nil {
+			return nil, failure.Wrap(err)
+		}
+
+		// Include the delay requested by the remote before retrying an unavailable request, if any.
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After"))
+			if parseErr == nil && seconds >= 0 {
+				return nil, retryAfterError{error: err, after: time.Duration(seconds) * time.Second}
+			}
+		}
+
+		return nil, err
 	}
 
 	return &response, nil
 }
+
+// retryAfterError is an API error for an unavailable remote that also carries the delay it requested before a retry.
+type retryAfterError struct {
+	error
+	after time.Duration
+}
+
+// Unwrap returns the API status error.
+func (e retryAfterError) Unwrap() error {
+	return e.error
+}
+
+// RetryAfter returns the delay requested by the remote with a Retry-After header before retrying a request that
+// failed with the given error, and false if none was requested.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retry retryAfterError
+	if !errors.As(err, &retry) {
+		return 0, false
+	}
+
+	return retry.after, true
+}
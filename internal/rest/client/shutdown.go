@@ -10,9 +10,15 @@ import (
 )
 
 // ShutdownDaemon begins the daemon shutdown sequence.
-func (c *Client) ShutdownDaemon(ctx context.Context) error {
+// If force is set, the daemon stops without first handing off leadership or running the PreShutdown hook.
+func (c *Client) ShutdownDaemon(ctx context.Context, force bool) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	return c.QueryStruct(queryCtx, "POST", types.ControlEndpoint, api.NewURL().Path("shutdown"), nil, nil)
+	endpoint := api.NewURL().Path("shutdown")
+	if force {
+		endpoint = endpoint.WithQuery("force", "1")
+	}
+
+	return c.QueryStruct(queryCtx, "POST", types.ControlEndpoint, endpoint, nil, nil)
 }
@@ -7,9 +7,11 @@ import (
 	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/internal/rest/types"
+	apiTypes "github.com/canonical/microcluster/rest/types"
 )
 
-// AddTrustStoreEntry adds a new record to the truststore on all cluster members.
+// AddTrustStoreEntry adds a new record to the truststore on all cluster members. If a record with the same name already
+// exists, the certificate is accepted for it alongside its existing certificates.
 func AddTrustStoreEntry(ctx context.Context, c *Client, args types.ClusterMemberLocal) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -24,3 +26,18 @@ func DeleteTrustStoreEntry(ctx context.Context, c *Client, name string) error {
 
 	return c.QueryStruct(queryCtx, "DELETE", types.InternalEndpoint, api.NewURL().Path("truststore", name), nil, nil)
 }
+
+// RefreshTrustStore reloads the trust store of the cluster member from its state directory, and returns the number
+// of remotes it contains afterwards.
+func (c *Client) RefreshTrustStore(ctx context.Context) (int, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var refresh apiTypes.TrustStoreRefresh
+	err := c.QueryStruct(queryCtx, "POST", types.InternalEndpoint, api.NewURL().Path("truststore-refresh"), nil, &refresh)
+	if err != nil {
+		return 0, err
+	}
+
+	return refresh.Remotes, nil
+}
@@ -0,0 +1,237 @@
+package rest
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionThreshold is the default size in bytes above which responses are compressed, if no threshold is
+// set for the daemon.
+const DefaultCompressionThreshold = 1024
+
+// compressedContentTypes are content types whose data is already compressed, so compressing it again is wasted work.
+var compressedContentTypes = []string{
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-xz",
+	"application/x-bzip2",
+	"image/",
+	"video/",
+	"audio/",
+}
+
+// compressWriterKey is the context key for the compressWriter of a request.
+type compressWriterKey struct{}
+
+// Compress returns middleware that compresses responses with gzip if the client accepts it, once the handler has
+// written more than threshold bytes. Smaller responses, and any response flushed before reaching the threshold, are
+// sent uncompressed, so that streaming responses are never held back. Responses that already have a Content-Encoding,
+// or whose content type is already compressed, are not compressed again.
+func Compress(threshold int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, threshold: threshold}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), compressWriterKey{}, cw)))
+		})
+	}
+}
+
+// disableCompression stops the response to the request from being compressed, if the Compress middleware is in use
+// and nothing has been written yet.
+func disableCompression(r *http.Request) {
+	cw, ok := r.Context().Value(compressWriterKey{}).(*compressWriter)
+	if ok {
+		cw.disabled = true
+	}
+}
+
+// acceptsGzip returns whether the Accept-Encoding header of the request allows a gzip encoded response.
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+			if name != "gzip" && name != "*" {
+				continue
+			}
+
+			// An encoding with a quality of zero is not acceptable.
+			q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+			if ok {
+				quality, err := strconv.ParseFloat(q, 64)
+				if err == nil && quality == 0 {
+					return false
+				}
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressWriter buffers the start of a response until it is known whether it is large enough to compress.
+type compressWriter struct {
+	http.ResponseWriter
+	threshold int
+	disabled  bool
+
+	status   int
+	buf      []byte
+	gz       *gzip.Writer
+	started  bool
+	hijacked bool
+}
+
+// canCompress returns whether the response may be compressed, based on its status and headers.
+func (w *compressWriter) canCompress() bool {
+	if w.disabled || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	for _, compressed := range compressedContentTypes {
+		if strings.HasPrefix(contentType, compressed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// start writes the response headers, compressing the rest of the response if requested and allowed, followed by any
+// buffered data.
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if compress && w.canCompress() {
+		header := w.ResponseWriter.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// WriteHeader implements http.ResponseWriter. The status is written along with the first data.
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.started {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) <= w.threshold {
+			return len(b), nil
+		}
+
+		err := w.start(true)
+		if err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher. A response that is flushed before reaching the threshold is not compressed.
+func (w *compressWriter) Flush() {
+	if !w.started {
+		_ = w.start(false)
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
+
+// close writes any buffered data and completes the compressed stream once the handler has returned.
+func (w *compressWriter) close() {
+	if w.hijacked {
+		return
+	}
+
+	if !w.started {
+		_ = w.start(false)
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+// Hijack implements http.Hijacker, so that connections can still be hijacked for database requests.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("ResponseWriter is not type http.Hijacker")
+	}
+
+	w.hijacked = true
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,249 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type compressSuite struct {
+	suite.Suite
+}
+
+func TestCompressSuite(t *testing.T) {
+	suite.Run(t, new(compressSuite))
+}
+
+// hijackRecorder is a ResponseRecorder that can be hijacked, and records whether any headers were written.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked    bool
+	wroteHeader bool
+}
+
+func (r *hijackRecorder) WriteHeader(statusCode int) {
+	r.wroteHeader = true
+	r.ResponseRecorder.WriteHeader(statusCode)
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+// gunzip returns the decompressed data.
+func gunzip(data []byte) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(gr)
+}
+
+// Ensures responses are only compressed once they exceed the threshold, for clients that accept gzip, and are not
+// compressed again if they are already encoded or of a compressed content type.
+func (s *compressSuite) Test_compress() {
+	threshold := 16
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		encoding       string
+		body           string
+		compressed     bool
+	}{
+		{
+			name:           "Response at the threshold",
+			acceptEncoding: "gzip",
+			body:           strings.Repeat("a", threshold),
+		},
+		{
+			name:           "Response above the threshold",
+			acceptEncoding: "gzip",
+			body:           strings.Repeat("a", threshold+1),
+			compressed:     true,
+		},
+		{
+			name:           "Client accepting any encoding",
+			acceptEncoding: "br, *",
+			body:           strings.Repeat("a", threshold+1),
+			compressed:     true,
+		},
+		{
+			name:           "Client accepting gzip with a quality",
+			acceptEncoding: "gzip;q=0.5",
+			body:           strings.Repeat("a", threshold+1),
+			compressed:     true,
+		},
+		{
+			name:           "Client rejecting gzip with a quality of zero",
+			acceptEncoding: "gzip; q=0",
+			body:           strings.Repeat("a", threshold+1),
+		},
+		{
+			name:           "Client rejecting any encoding with a quality of zero",
+			acceptEncoding: "*;q=0.0",
+			body:           strings.Repeat("a", threshold+1),
+		},
+		{
+			name: "Client not accepting gzip",
+			body: strings.Repeat("a", threshold+1),
+		},
+		{
+			name:           "Response that is already encoded",
+			acceptEncoding: "gzip",
+			encoding:       "br",
+			body:           strings.Repeat("a", threshold+1),
+		},
+		{
+			name:           "Response of a compressed content type",
+			acceptEncoding: "gzip",
+			contentType:    "application/gzip",
+			body:           strings.Repeat("a", threshold+1),
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		handler := Compress(threshold)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.contentType != "" {
+				w.Header().Set("Content-Type", test.contentType)
+			}
+
+			if test.encoding != "" {
+				w.Header().Set("Content-Encoding", test.encoding)
+			}
+
+			w.WriteHeader(http.StatusAccepted)
+
+			// Write the body in small pieces, so that the threshold is crossed part way through.
+			for i := 0; i < len(test.body); i += 5 {
+				_, err := w.Write([]byte(test.body[i:min(i+5, len(test.body))]))
+				s.NoError(err)
+			}
+		}))
+
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		s.Equal(http.StatusAccepted, w.Code)
+		s.Equal("Accept-Encoding", w.Header().Get("Vary"))
+
+		body := w.Body.Bytes()
+		if test.compressed {
+			s.Equal("gzip", w.Header().Get("Content-Encoding"))
+
+			var err error
+			body, err = gunzip(body)
+			s.Require().NoError(err)
+		} else {
+			s.Equal(test.encoding, w.Header().Get("Content-Encoding"))
+		}
+
+		s.Equal(test.body, string(body))
+	}
+}
+
+// Ensures a response flushed before reaching the threshold is sent uncompressed, and one flushed after is compressed.
+func (s *compressSuite) Test_compressFlush() {
+	threshold := 16
+	tests := []struct {
+		name       string
+		before     string
+		after      string
+		compressed bool
+	}{
+		{
+			name:   "Flush before the threshold",
+			before: "start",
+			after:  strings.Repeat("a", threshold+1),
+		},
+		{
+			name:       "Flush after the threshold",
+			before:     strings.Repeat("a", threshold+1),
+			after:      "end",
+			compressed: true,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		var flushed []byte
+		w := httptest.NewRecorder()
+		handler := Compress(threshold)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte(test.before))
+			s.NoError(err)
+
+			rw.(http.Flusher).Flush()
+			s.True(w.Flushed)
+			flushed = bytes.Clone(w.Body.Bytes())
+
+			_, err = rw.Write([]byte(test.after))
+			s.NoError(err)
+		}))
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		handler.ServeHTTP(w, r)
+
+		body := w.Body.Bytes()
+		if test.compressed {
+			s.Equal("gzip", w.Header().Get("Content-Encoding"))
+			s.NotEmpty(flushed)
+
+			var err error
+			body, err = gunzip(body)
+			s.Require().NoError(err)
+		} else {
+			s.Empty(w.Header().Get("Content-Encoding"))
+			s.Equal(test.before, string(flushed))
+		}
+
+		s.Equal(test.before+test.after, string(body))
+	}
+}
+
+// Ensures a hijacked connection is handed to the handler, and nothing is written to the response once it returns.
+func (s *compressSuite) Test_compressHijack() {
+	handler := Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		s.Require().True(ok)
+
+		_, _, err := hijacker.Hijack()
+		s.NoError(err)
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, r)
+
+	s.True(w.hijacked)
+	s.False(w.wroteHeader)
+	s.Empty(w.Header().Get("Content-Encoding"))
+	s.Empty(w.Body.Bytes())
+
+	// A ResponseWriter that can't be hijacked returns an error.
+	handler = Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := w.(http.Hijacker).Hijack()
+		s.Error(err)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+}
@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// setExpectedVersion records the version from the If-Match header of a write request in its context, so that handlers
+// supporting conditional writes can retrieve it with rest.ExpectedVersion.
+func setExpectedVersion(r *http.Request) (*http.Request, error) {
+	header := r.Header.Get(types.IfMatchHeader)
+	if r.Method == http.MethodGet || header == "" {
+		return r, nil
+	}
+
+	version, err := parseEntityTag(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), types.CtxExpectedVersion, version)), nil
+}
+
+// parseEntityTag returns the version from a single strong entity tag, or "*".
+func parseEntityTag(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "*" {
+		return value, nil
+	}
+
+	if strings.HasPrefix(value, "W/") {
+		return "", fmt.Errorf("Weak entity tag %q cannot be used for conditional writes", value)
+	}
+
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) || strings.Contains(value[1:len(value)-1], `"`) {
+		return "", fmt.Errorf("Invalid entity tag %q", value)
+	}
+
+	return value[1 : len(value)-1], nil
+}
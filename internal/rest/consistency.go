@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// consistencyWriter issues a consistency token in the response just before it is written, if the response status is
+// successful.
+type consistencyWriter struct {
+	http.ResponseWriter
+	state   *state.State
+	r       *http.Request
+	written bool
+}
+
+// issueToken sets a new consistency token in the response for the given status, if it has not been written yet.
+func (w *consistencyWriter) issueToken(statusCode int) {
+	if w.written {
+		return
+	}
+
+	w.written = true
+	if statusCode < 200 || statusCode > 299 || w.state.Database.IsOpen(w.r.Context()) != nil {
+		return
+	}
+
+	index, err := w.state.Database.NextConsistencyIndex(w.r.Context())
+	if err != nil {
+		w.state.Log.Warn("Failed to issue consistency token", logger.Ctx{"url": w.r.URL, "err": err})
+		return
+	}
+
+	w.ResponseWriter.Header().Set(types.ConsistencyTokenHeader, strconv.FormatUint(index, 10))
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *consistencyWriter) WriteHeader(statusCode int) {
+	w.issueToken(statusCode)
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.
+func (w *consistencyWriter) Write(b []byte) (int, error) {
+	w.issueToken(http.StatusOK)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *consistencyWriter) Flush() {
+	w.issueToken(http.StatusOK)
+
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *consistencyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("ResponseWriter is not type http.Hijacker")
+	}
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by http.ResponseController.
+func (w *consistencyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,21 @@
+package rest
+
+import (
+	"context"
+)
+
+// dqliteListenerKey is the context key marking connections received on the dedicated dqlite listener.
+type dqliteListenerKey struct{}
+
+// WithDqliteListener returns a copy of the context that marks the connection as received on the dedicated listener
+// for dqlite connections.
+func WithDqliteListener(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dqliteListenerKey{}, true)
+}
+
+// isDqliteListener returns whether the request was received on the dedicated listener for dqlite connections.
+func isDqliteListener(ctx context.Context) bool {
+	dqlite, _ := ctx.Value(dqliteListenerKey{}).(bool)
+
+	return dqlite
+}
@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"context"
+)
+
+// advertisedExtensionsKey is the context key for the API extensions advertised on a listener.
+type advertisedExtensionsKey struct{}
+
+// WithAdvertisedExtensions returns a copy of the context that restricts the API extensions advertised to the client to
+// the given subset.
+func WithAdvertisedExtensions(ctx context.Context, extensions []string) context.Context {
+	return context.WithValue(ctx, advertisedExtensionsKey{}, extensions)
+}
+
+// AdvertisedExtensions returns the API extensions that may be advertised to the client, and whether they are restricted
+// at all for the listener that the request was received on.
+func AdvertisedExtensions(ctx context.Context) ([]string, bool) {
+	extensions, ok := ctx.Value(advertisedExtensionsKey{}).([]string)
+
+	return extensions, ok
+}
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ResponseHeaders returns middleware that adds the given static headers to every response. Headers set by the
+// handler itself, such as Content-Type, take precedence. Headers with an empty value are removed from the response,
+// even if the handler sets them.
+func ResponseHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			removed := []string{}
+			for name, value := range headers {
+				if value == "" {
+					removed = append(removed, name)
+					continue
+				}
+
+				w.Header().Set(name, value)
+			}
+
+			if len(removed) > 0 {
+				w = &headerWriter{ResponseWriter: w, removed: removed}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerWriter removes headers from the response just before it is written.
+type headerWriter struct {
+	http.ResponseWriter
+	removed []string
+	written bool
+}
+
+// removeHeaders removes the headers from the response, if it has not been written yet.
+func (w *headerWriter) removeHeaders() {
+	if w.written {
+		return
+	}
+
+	w.written = true
+	for _, name := range w.removed {
+		w.ResponseWriter.Header().Del(name)
+	}
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *headerWriter) WriteHeader(statusCode int) {
+	w.removeHeaders()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.removeHeaders()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *headerWriter) Flush() {
+	w.removeHeaders()
+
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so that connections can still be hijacked for database requests.
+func (w *headerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("ResponseWriter is not type http.Hijacker")
+	}
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by http.ResponseController.
+func (w *headerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
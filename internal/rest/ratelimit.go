@@ -0,0 +1,164 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// maxIdleBuckets is the number of per-client buckets after which full, and thus idle, buckets are discarded.
+const maxIdleBuckets = 1024
+
+// tokenBucket is a token-bucket rate limiter.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit types.RateLimit, now time.Time) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(limit.Rate))
+	}
+
+	return &tokenBucket{rate: limit.Rate, burst: burst, tokens: burst, last: now}
+}
+
+// refill adds the tokens accumulated since the last call.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take takes a token from the bucket. If none is available, it returns how long until one is.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RateLimiter limits requests with a token bucket for each client, and one shared by all clients.
+type RateLimiter struct {
+	limits types.RateLimits
+
+	mu      sync.Mutex
+	global  *tokenBucket
+	clients map[string]*tokenBucket
+	members map[string]*tokenBucket
+}
+
+// NewRateLimiter returns a RateLimiter enforcing the given limits.
+func NewRateLimiter(limits types.RateLimits) *RateLimiter {
+	l := &RateLimiter{
+		limits:  limits,
+		clients: map[string]*tokenBucket{},
+		members: map[string]*tokenBucket{},
+	}
+
+	if limits.Global.Rate > 0 {
+		l.global = newTokenBucket(limits.Global, time.Now())
+	}
+
+	return l
+}
+
+// take takes a token from the bucket of the given key, creating it if needed.
+func (l *RateLimiter) take(buckets map[string]*tokenBucket, limit types.RateLimit, key string, now time.Time) time.Duration {
+	bucket, ok := buckets[key]
+	if !ok {
+		// Discard buckets that have refilled completely, as their clients are idle.
+		if len(buckets) >= maxIdleBuckets {
+			for k, b := range buckets {
+				b.refill(now)
+				if b.tokens >= b.burst {
+					delete(buckets, k)
+				}
+			}
+		}
+
+		bucket = newTokenBucket(limit, now)
+		buckets[key] = bucket
+	}
+
+	return bucket.take(now)
+}
+
+// allow returns how long the client must wait before making another request at the given time, or 0 if the request
+// is allowed.
+func (l *RateLimiter) allow(key string, isMember bool, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if isMember {
+		if l.limits.Cluster.Rate <= 0 {
+			return 0
+		}
+
+		return l.take(l.members, l.limits.Cluster, key, now)
+	}
+
+	if l.limits.PerClient.Rate > 0 {
+		wait := l.take(l.clients, l.limits.PerClient, key, now)
+		if wait > 0 {
+			return wait
+		}
+	}
+
+	if l.global != nil {
+		return l.global.take(now)
+	}
+
+	return 0
+}
+
+// Middleware returns middleware that rejects requests over the rate limits with 429 Too Many Requests,
+// and a Retry-After header indicating when the client may retry.
+func (l *RateLimiter) Middleware(s *state.State) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Requests over the unix socket are never limited.
+			if r.RemoteAddr == "@" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var key string
+			var isMember bool
+			if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+				key = shared.CertFingerprint(r.TLS.PeerCertificates[0])
+				isMember = s.Remotes().RemoteByCertificateFingerprint(key) != nil
+			} else {
+				key, _, _ = net.SplitHostPort(r.RemoteAddr)
+			}
+
+			wait := l.allow(key, isMember, time.Now())
+			if wait == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			err := response.ErrorResponse(http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded, retry in %s", wait.Round(time.Millisecond))).Render(w)
+			if err != nil {
+				s.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+type rateLimitSuite struct {
+	suite.Suite
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(rateLimitSuite))
+}
+
+// Ensures a full bucket allows a burst of requests at once, defaulting the burst to the rate rounded up.
+func (s *rateLimitSuite) Test_tokenBucketBurst() {
+	tests := []struct {
+		name  string
+		limit types.RateLimit
+		burst int
+	}{
+		{
+			name:  "Explicit burst",
+			limit: types.RateLimit{Rate: 1, Burst: 3},
+			burst: 3,
+		},
+		{
+			name:  "Default burst",
+			limit: types.RateLimit{Rate: 2.5},
+			burst: 3,
+		},
+		{
+			name:  "Default burst below one request per second",
+			limit: types.RateLimit{Rate: 0.1},
+			burst: 1,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		now := time.Now()
+		bucket := newTokenBucket(test.limit, now)
+		for i := 0; i < test.burst; i++ {
+			s.Zero(bucket.take(now), "Request %d of the burst was limited", i)
+		}
+
+		s.Positive(bucket.take(now))
+	}
+}
+
+// Ensures an empty bucket refills at its rate, up to its burst, and reports how long until the next token.
+func (s *rateLimitSuite) Test_tokenBucketRefill() {
+	now := time.Now()
+	bucket := newTokenBucket(types.RateLimit{Rate: 2, Burst: 1}, now)
+
+	s.Zero(bucket.take(now))
+	s.Equal(500*time.Millisecond, bucket.take(now))
+	s.Equal(250*time.Millisecond, bucket.take(now.Add(250*time.Millisecond)))
+	s.Zero(bucket.take(now.Add(500 * time.Millisecond)))
+
+	// A long idle period only refills the bucket up to its burst.
+	now = now.Add(time.Minute)
+	s.Zero(bucket.take(now))
+	s.Positive(bucket.take(now))
+}
+
+// Ensures each client and cluster member is limited by its own bucket, and only clients share the global bucket.
+func (s *rateLimitSuite) Test_rateLimiterKeys() {
+	limiter := NewRateLimiter(types.RateLimits{
+		PerClient: types.RateLimit{Rate: 1, Burst: 1},
+		Global:    types.RateLimit{Rate: 1, Burst: 2},
+		Cluster:   types.RateLimit{Rate: 1, Burst: 1},
+	})
+
+	now := time.Now()
+
+	// Each client has its own bucket.
+	s.Zero(limiter.allow("client1", false, now))
+	s.Positive(limiter.allow("client1", false, now))
+	s.Zero(limiter.allow("client2", false, now))
+
+	// The global bucket is exhausted by the first two clients.
+	s.Positive(limiter.allow("client3", false, now))
+
+	// Cluster members are limited separately from clients with the same key, and not by the global bucket.
+	s.Zero(limiter.allow("client1", true, now))
+	s.Positive(limiter.allow("client1", true, now))
+	s.Zero(limiter.allow("member", true, now))
+
+	// Cluster members are not limited if no cluster limit is set.
+	limiter = NewRateLimiter(types.RateLimits{PerClient: types.RateLimit{Rate: 1, Burst: 1}})
+	for i := 0; i < 10; i++ {
+		s.Zero(limiter.allow("member", true, now))
+	}
+}
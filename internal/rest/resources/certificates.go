@@ -6,11 +6,11 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net/http"
-	"os"
 	"path/filepath"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/google/renameio"
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/internal/state"
@@ -66,25 +66,18 @@ func clusterCertificatesPut(s *state.State, r *http.Request) response.Response {
 	}
 
 	// If a CA was specified, validate that as well.
+	var ca []byte
 	if req.CA != "" {
 		caBlock, _ := pem.Decode([]byte(req.CA))
 		if caBlock == nil {
 			return response.BadRequest(fmt.Errorf("CA must be base64 encoded PEM key"))
 		}
 
-		err = os.WriteFile(filepath.Join(s.OS.StateDir, "cluster.ca"), []byte(req.CA), 0650)
-		if err != nil {
-			return response.SmartError(err)
-		}
+		ca = []byte(req.CA)
 	}
 
 	// Write the keypair to the state directory.
-	err = os.WriteFile(filepath.Join(s.OS.StateDir, "cluster.crt"), []byte(req.PublicKey), 0650)
-	if err != nil {
-		return response.SmartError(err)
-	}
-
-	err = os.WriteFile(filepath.Join(s.OS.StateDir, "cluster.key"), []byte(req.PrivateKey), 0650)
+	err = writeCert(s.OS.StateDir, "cluster", []byte(req.PublicKey), []byte(req.PrivateKey), ca)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -97,3 +90,25 @@ func clusterCertificatesPut(s *state.State, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// writeCert atomically writes the given keypair and optional CA to the state directory with the given file prefix.
+func writeCert(dir string, prefix string, cert []byte, key []byte, ca []byte) error {
+	err := renameio.WriteFile(filepath.Join(dir, prefix+".crt"), cert, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = renameio.WriteFile(filepath.Join(dir, prefix+".key"), key, 0600)
+	if err != nil {
+		return err
+	}
+
+	if ca != nil {
+		err = renameio.WriteFile(filepath.Join(dir, prefix+".ca"), ca, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dqliteClient "github.com/canonical/go-dqlite/client"
@@ -26,6 +28,7 @@ import (
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/internal/trust"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
@@ -40,8 +43,27 @@ var clusterCmd = rest.Endpoint{
 	Get:  rest.EndpointAction{Handler: clusterGet, AccessHandler: access.AllowAuthenticated},
 }
 
+var clusterMemberDrainCmd = rest.Endpoint{
+	Path:                 "cluster/{name}/drain",
+	AllowedWhileDraining: true,
+
+	Post:   rest.EndpointAction{Handler: clusterMemberDrainPost, AccessHandler: access.AllowAuthenticated},
+	Delete: rest.EndpointAction{Handler: clusterMemberDrainDelete, AccessHandler: access.AllowAuthenticated},
+}
+
+var drainCmd = rest.Endpoint{
+	Path:                 "drain",
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+
+	Post:   rest.EndpointAction{Handler: drainPost, AccessHandler: access.AllowAuthenticated},
+	Delete: rest.EndpointAction{Handler: drainDelete, AccessHandler: access.AllowAuthenticated},
+}
+
 var clusterMemberCmd = rest.Endpoint{
-	Path: "cluster/{name}",
+	Path:                 "cluster/{name}",
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
 
 	Put:    rest.EndpointAction{Handler: clusterMemberPut, AccessHandler: access.AllowAuthenticated},
 	Delete: rest.EndpointAction{Handler: clusterMemberDelete, AccessHandler: access.AllowAuthenticated},
@@ -74,9 +96,21 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	err = validateFQDN(req.Name)
+	err = trust.ValidateName(req.Name)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Invalid cluster member name %q: %w", req.Name, err))
+		return response.BadRequest(err)
+	}
+
+	if req.DqliteAddress != "" {
+		_, err = types.ParseAddrPort(req.DqliteAddress)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid dqlite address %q: %w", req.DqliteAddress, err))
+		}
+	}
+
+	_, ok := s.Remotes().RemotesByName()[req.Name]
+	if ok {
+		return response.SmartError(trust.ErrNameInUse(req.Name))
 	}
 
 	// Check if any of the remote's addresses are currently in use.
@@ -94,7 +128,7 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 
 		tokenResponse, err := client.AddClusterMember(s.Context, req)
 		if err != nil {
-			return response.SmartError(err)
+			return joinError(err)
 		}
 
 		return response.SyncResponse(true, tokenResponse)
@@ -102,10 +136,36 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 
 	// Check if the joining node's extensions are compatible with the leader's.
 	err = s.Extensions.IsSameVersion(req.Extensions)
+	if err != nil {
+		schemaInternal, schemaExternal, _ := s.Database.Schema().Version()
+		missing, extra := req.Extensions.Diff(s.Extensions)
+
+		return joinError(types.SchemaVersionMismatch{
+			LocalSchemaInternal:   req.SchemaInternalVersion,
+			LocalSchemaExternal:   req.SchemaExternalVersion,
+			ClusterSchemaInternal: schemaInternal,
+			ClusterSchemaExternal: schemaExternal,
+			MissingExtensions:     missing,
+			ExtraExtensions:       extra,
+		})
+	}
+
+	// Only consult the PreNewMember hook for members that hold a valid join token.
+	err = s.Database.IdempotentTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.GetInternalTokenRecord(ctx, tx, req.Secret)
+		return err
+	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Give the application a chance to reject the member before any of its state is recorded, so that nothing needs
+	// to be cleaned up if it does.
+	err = state.PreNewMemberHook(ctx, s, req.ClusterMemberLocal)
+	if err != nil {
+		return joinError(types.JoinFailed(types.ErrJoinRejected, fmt.Errorf("Cluster member %q was rejected: %w", req.Name, err)))
+	}
+
 	err = s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
 		dbClusterMember := cluster.InternalClusterMember{
 			Name:           req.Name,
@@ -128,6 +188,13 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 			return err
 		}
 
+		if req.DqliteAddress != "" {
+			err = cluster.SetDqliteAddress(ctx, tx, req.Name, req.DqliteAddress)
+			if err != nil {
+				return err
+			}
+		}
+
 		return cluster.DeleteInternalTokenRecord(ctx, tx, record.Name)
 	})
 	if err != nil {
@@ -138,9 +205,11 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 	clusterMembers := make([]internalTypes.ClusterMemberLocal, 0, remotes.Count())
 	for _, clusterMember := range remotes.RemotesByName() {
 		clusterMember := internalTypes.ClusterMemberLocal{
-			Name:        clusterMember.Name,
-			Address:     clusterMember.Address,
-			Certificate: clusterMember.Certificate,
+			Name:                   clusterMember.Name,
+			Address:                clusterMember.Address,
+			Certificate:            clusterMember.Certificate,
+			DqliteAddress:          clusterMember.DqliteAddress,
+			AdditionalCertificates: clusterMember.AdditionalCertificates,
 		}
 
 		clusterMembers = append(clusterMembers, clusterMember)
@@ -156,17 +225,28 @@ func clusterPost(s *state.State, r *http.Request) response.Response {
 		ClusterCert: types.X509Certificate{Certificate: clusterCert},
 		ClusterKey:  string(s.ClusterCert().PrivateKey()),
 
-		TrustedMember:  internalTypes.ClusterMemberLocal{Name: s.Name(), Address: localRemote.Address, Certificate: localRemote.Certificate},
+		TrustedMember: internalTypes.ClusterMemberLocal{
+			Name:                   s.Name(),
+			Address:                localRemote.Address,
+			Certificate:            localRemote.Certificate,
+			DqliteAddress:          localRemote.DqliteAddress,
+			AdditionalCertificates: localRemote.AdditionalCertificates,
+		},
 		ClusterMembers: clusterMembers,
 	}
 
+	if s.ClusterCert().CA() != nil {
+		tokenResponse.ClusterCA = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.ClusterCert().CA().Raw}))
+	}
+
 	newRemote := trust.Remote{
-		Location:    trust.Location{Name: req.Name, Address: req.Address},
-		Certificate: req.Certificate,
+		Location:      trust.Location{Name: req.Name, Address: req.Address},
+		Certificate:   req.Certificate,
+		DqliteAddress: req.DqliteAddress,
 	}
 
 	// Add the cluster member to our local store for authentication.
-	err = s.Remotes().Add(s.OS.TrustDir, newRemote)
+	err = s.Remotes().Add(s.OS.TrustDir, r.RemoteAddr, newRemote)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -183,7 +263,11 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 	}
 
 	var apiClusterMembers []internalTypes.ClusterMember
-	err := s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
+	var draining map[string]bool
+	var replicaOnly map[string]bool
+	var requestedRoles map[string]string
+	var dqliteAddresses map[string]string
+	err := s.Database.IdempotentTransaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
 		var err error
 		var clusterMembers []cluster.InternalClusterMember
 		var awaitingUpgrade map[string]bool
@@ -198,6 +282,35 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 			return err
 		}
 
+		// Failure domains and draining are only tracked once the cluster has been upgraded to do so.
+		var failureDomains map[string]cluster.FailureDomain
+		if status == db.StatusReady {
+			failureDomains, err = cluster.GetFailureDomains(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			draining, err = cluster.GetDrainingClusterMembers(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			requestedRoles, err = cluster.GetRequestedRoles(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			replicaOnly, err = cluster.GetReplicaOnlyClusterMembers(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			dqliteAddresses, err = cluster.GetDqliteAddresses(ctx, tx)
+			if err != nil {
+				return err
+			}
+		}
+
 		apiClusterMembers = make([]internalTypes.ClusterMember, 0, len(clusterMembers))
 		for _, clusterMember := range clusterMembers {
 			apiClusterMember, err := clusterMember.ToAPI()
@@ -205,6 +318,16 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 				return err
 			}
 
+			apiClusterMember.RequestedRole = requestedRoles[apiClusterMember.Name]
+			apiClusterMember.ReplicaOnly = replicaOnly[apiClusterMember.Name]
+			apiClusterMember.DqliteAddress = dqliteAddresses[apiClusterMember.Name]
+
+			domain, ok := failureDomains[apiClusterMember.Name]
+			if ok {
+				apiClusterMember.FailureDomain = domain.Code
+				apiClusterMember.Tags = domain.Tags
+			}
+
 			// Assign an upgrade status if the cluster member is awaiting an upgrade.
 			if awaitingUpgrade != nil {
 				if awaitingUpgrade[apiClusterMember.Name] {
@@ -231,12 +354,18 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 		}
 
 		for i, clusterMember := range apiClusterMembers {
-			addr := api.NewURL().Scheme("https").Host(clusterMember.Address.String())
+			addr := api.NewURL().Scheme(sys.Scheme()).Host(clusterMember.Address.String())
 			d, err := internalClient.New(*addr, s.ServerCert(), clusterCert, false)
 			if err != nil {
 				return response.SmartError(fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", addr.String(), err))
 			}
 
+			// Draining cluster members report themselves as unavailable, so don't check them.
+			if draining[clusterMember.Name] {
+				apiClusterMembers[i].Status = internalTypes.MemberDraining
+				continue
+			}
+
 			err = d.CheckReady(s.Context)
 			if err == nil {
 				apiClusterMembers[i].Status = internalTypes.MemberOnline
@@ -249,6 +378,49 @@ func clusterGet(s *state.State, r *http.Request) response.Response {
 	return response.SyncResponse(true, apiClusterMembers)
 }
 
+func clusterMemberDrainPost(s *state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.DrainMember(r.Context(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func clusterMemberDrainDelete(s *state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.UndrainMember(r.Context(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func drainPost(s *state.State, r *http.Request) response.Response {
+	err := s.Drain(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func drainDelete(s *state.State, r *http.Request) response.Response {
+	s.Undrain()
+
+	return response.EmptySyncResponse
+}
+
 // clusterDisableMu is used to prevent the daemon process from being replaced/stopped during removal from the
 // cluster until such time as the request that initiated the removal has finished. This allows for self removal
 // from the cluster when not the leader.
@@ -293,9 +465,9 @@ func resetClusterMember(ctx context.Context, s *state.State, force bool) (reExec
 		return nil, fmt.Errorf("Failed shutting down listeners: %w", err)
 	}
 
-	err = os.RemoveAll(s.OS.StateDir)
+	err = s.OS.RemoveState()
 	if err != nil && !force {
-		return nil, fmt.Errorf("Failed to remove the s directory: %w", err)
+		return nil, err
 	}
 
 	reExec = func() {
@@ -305,6 +477,13 @@ func resetClusterMember(ctx context.Context, s *state.State, force bool) (reExec
 		// replace/stop the LXD daemon until that request has finished.
 		clusterDisableMu.Lock()
 		defer clusterDisableMu.Unlock()
+
+		// If the cluster member removed itself, the daemon is stopped instead.
+		if removeSelfStop.Load() {
+			logger.Info("Not restarting daemon following self removal from cluster")
+			return
+		}
+
 		execPath, err := os.Readlink("/proc/self/exe")
 		if err != nil {
 			execPath = "bad-exec-path"
@@ -323,6 +502,28 @@ func resetClusterMember(ctx context.Context, s *state.State, force bool) (reExec
 	return reExec, nil
 }
 
+// forceRemoveTimeout is how long a cluster member that is forcibly removed is given to respond to each request, as it
+// is usually offline.
+const forceRemoveTimeout = 5 * time.Second
+
+// forceRemoveCall runs the given request to a cluster member that is being removed. If the removal is forced, the
+// request is bounded by forceRemoveTimeout, and any failure is logged.
+func forceRemoveCall(ctx context.Context, force bool, f func(ctx context.Context) error) error {
+	if !force {
+		return f(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, forceRemoveTimeout)
+	defer cancel()
+
+	err := f(ctx)
+	if err != nil {
+		logger.Warn("Request to forcibly removed cluster member failed", logger.Ctx{"err": err})
+	}
+
+	return err
+}
+
 // clusterMemberDelete Removes a cluster member from dqlite and re-execs its daemon.
 func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 	force := r.URL.Query().Get("force") == "1"
@@ -513,89 +714,287 @@ func clusterMemberDelete(s *state.State, r *http.Request) response.Response {
 		})
 	}
 
-	publicKey, err := s.ClusterCert().PublicKeyX509()
+	// Carry out the removal as an operation, so that it can be listed and cancelled while in progress.
+	err = s.Operations().Run(s.Context, fmt.Sprintf("Removing cluster member %q", name), func(ctx context.Context) error {
+		// Drain the cluster member first, so that it is not removed while it is still running operations.
+		if !force {
+			err := s.DrainMember(ctx, name)
+			if err != nil {
+				return fmt.Errorf("Failed to drain cluster member %q: %w", name, err)
+			}
+		}
+
+		publicKey, err := s.ClusterCert().PublicKeyX509()
+		if err != nil {
+			return err
+		}
+
+		// Set the forwarded flag so that the the system to be removed knows the removal is in progress.
+		c, err := internalClient.New(remote.URL(), s.ServerCert(), publicKey, true)
+		if err != nil {
+			return err
+		}
+
+		// Tell the cluster member to run its PreRemove hook and return.
+		err = forceRemoveCall(ctx, force, func(ctx context.Context) error {
+			return internalClient.RunPreRemoveHook(ctx, c.UseTarget(name), internalTypes.HookRemoveMemberOptions{Force: force})
+		})
+		if err != nil && !force {
+			return err
+		}
+
+		// Remove the cluster member from the database.
+		err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			return cluster.DeleteInternalClusterMember(ctx, tx, remote.Address.String())
+		})
+		if err != nil {
+			return err
+		}
+
+		// Remove the node from dqlite, if it has a record there.
+		if index >= 0 {
+			err = leader.Remove(ctx, info[index].ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		localClient, err := internalClient.New(s.OS.ControlSocket(), nil, nil, false)
+		if err != nil {
+			return err
+		}
+
+		err = internalClient.DeleteTrustStoreEntry(ctx, localClient, name)
+		if err != nil && !force {
+			return err
+		}
+
+		c, err = internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
+		if err != nil {
+			return err
+		}
+
+		err = forceRemoveCall(ctx, force, func(ctx context.Context) error {
+			return c.ResetClusterMember(ctx, name, force)
+		})
+		if err != nil && !force {
+			return err
+		}
+
+		cluster, err := s.Cluster(false)
+		if err != nil {
+			return err
+		}
+
+		// Run the PostRemove hook locally.
+		err = state.PostRemoveHook(s, force)
+		if err != nil {
+			return err
+		}
+
+		// Run the PostRemove hook on all other members.
+		remotes := s.Remotes()
+		err = cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+			c.SetClusterNotification()
+			addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+			if err != nil {
+				return err
+			}
+
+			remote := remotes.RemoteByAddress(addrPort)
+			if remote == nil {
+				return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
+			}
+
+			return internalClient.RunPostRemoveHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force})
+		})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Set the forwarded flag so that the the system to be removed knows the removal is in progress.
-	c, err := internalClient.New(remote.URL(), s.ServerCert(), publicKey, true)
+	return response.EmptySyncResponse
+}
+
+// removeSelfStop is set while the local cluster member removes itself, so that the daemon is stopped once it has been
+// reset by the leader, rather than re-executed.
+var removeSelfStop atomic.Bool
+
+// RemoveSelf removes the local cluster member from the cluster and stops the daemon.
+// If this member is the dqlite leader, leadership is first transferred to another voter. The removal is then carried
+// out by the leader, which runs the PreRemove hook on this member and the PostRemove hook on all remaining members.
+// If force is true and no other cluster member is able to carry out the removal, the PreRemove and PostRemove hooks
+// are run locally, the member is removed from dqlite if the leader can still be reached, and the local cluster state
+// is removed.
+func RemoveSelf(ctx context.Context, s *state.State, force bool) error {
+	leaderClient, err := removeSelfLeader(ctx, s)
+	if err != nil && !force {
+		return err
+	}
+
+	if leaderClient != nil {
+		err = removeSelfThroughLeader(ctx, s, leaderClient, force)
+		if err == nil {
+			return nil
+		}
+
+		if !force {
+			return fmt.Errorf("Failed to remove cluster member %q: %w", s.Name(), err)
+		}
+	}
+
+	logger.Warn("Failed to remove cluster member through the cluster leader, removing local state instead", logger.Ctx{"member": s.Name(), "error": err})
+
+	err = state.PreRemoveHook(s, force)
 	if err != nil {
-		return response.SmartError(err)
+		logger.Error("Failed to run pre-remove hook", logger.Ctx{"error": err})
 	}
 
-	// Tell the cluster member to run its PreRemove hook and return.
-	err = internalClient.RunPreRemoveHook(ctx, c.UseTarget(name), internalTypes.HookRemoveMemberOptions{Force: force})
-	if err != nil && !force {
-		return response.SmartError(err)
+	err = removeSelfFromDqlite(ctx, s)
+	if err != nil {
+		logger.Error("Failed to remove cluster member from dqlite", logger.Ctx{"member": s.Name(), "error": err})
 	}
 
-	// Remove the cluster member from the database.
-	err = s.Database.Transaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
-		return cluster.DeleteInternalClusterMember(ctx, tx, remote.Address.String())
-	})
+	err = state.PostRemoveHook(s, force)
 	if err != nil {
-		return response.SmartError(err)
+		logger.Error("Failed to run post-remove hook", logger.Ctx{"error": err})
 	}
 
-	// Remove the node from dqlite, if it has a record there.
-	if index >= 0 {
-		err = leader.Remove(s.Context, info[index].ID)
-		if err != nil {
-			return response.SmartError(err)
-		}
+	// The member is no longer part of the cluster, so it has nothing left to hand off.
+	exit, err := s.Stop(true)
+	if err != nil {
+		logger.Error("Failed to cleanly stop the daemon", logger.Ctx{"error": err})
 	}
 
-	localClient, err := internalClient.New(s.OS.ControlSocket(), nil, nil, false)
+	err = s.OS.RemoveState()
 	if err != nil {
-		return response.SmartError(err)
+		logger.Error("Failed to remove the state directory", logger.Ctx{"error": err})
 	}
 
-	err = internalClient.DeleteTrustStoreEntry(ctx, localClient, name)
-	if err != nil && !force {
-		return response.SmartError(err)
+	go exit()
+
+	return nil
+}
+
+// removeSelfThroughLeader has the leader remove the local cluster member, and then stops the daemon.
+func removeSelfThroughLeader(ctx context.Context, s *state.State, leaderClient *client.Client, force bool) error {
+	// Hold the removal lock so that the leader resetting this cluster member does not re-exec the daemon until the
+	// removal has been recorded, and have the reset stop the daemon instead.
+	clusterDisableMu.Lock()
+	logger.Info("Acquired cluster self removal lock", logger.Ctx{"member": s.Name()})
+	defer func() {
+		logger.Info("Releasing cluster self removal lock", logger.Ctx{"member": s.Name()})
+		clusterDisableMu.Unlock()
+	}()
+
+	removeSelfStop.Store(true)
+	err := leaderClient.DeleteClusterMember(ctx, s.Name(), force)
+	if err != nil {
+		removeSelfStop.Store(false)
+		return err
 	}
 
-	c, err = internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
+	// The database and listeners have already been stopped by the reset, so stopping only waits for the workers.
+	exit, err := s.Stop(true)
 	if err != nil {
-		return response.SmartError(err)
+		logger.Warn("Failed to cleanly stop the daemon after removal", logger.Ctx{"error": err})
 	}
 
-	err = c.ResetClusterMember(s.Context, name, force)
-	if err != nil && !force {
-		return response.SmartError(err)
+	go exit()
+
+	return nil
+}
+
+// removeSelfFromDqlite deletes the local cluster member from the database and from the dqlite configuration through
+// the current leader, for when the leader could not carry out the removal itself.
+func removeSelfFromDqlite(ctx context.Context, s *state.State) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return err
 	}
 
-	cluster, err := s.Cluster(false)
+	defer func() { _ = leader.Close() }()
+
+	leaderInfo, err := leader.Leader(ctx)
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	// Run the PostRemove hook locally.
-	err = state.PostRemoveHook(s, force)
+	address := s.Address().URL.Host
+	if leaderInfo.Address == address {
+		return fmt.Errorf("Cannot remove the dqlite leader from dqlite")
+	}
+
+	err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.DeleteInternalClusterMember(ctx, tx, address)
+	})
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	// Run the PostRemove hook on all other members.
-	remotes := s.Remotes()
-	err = cluster.Query(s.Context, true, func(ctx context.Context, c *client.Client) error {
-		c.SetClusterNotification()
-		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+	nodes, err := leader.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if node.Address == address {
+			return leader.Remove(ctx, node.ID)
+		}
+	}
+
+	return nil
+}
+
+// removeSelfLeader returns a client connected to the leader that should carry out the removal of the local cluster
+// member. If the local cluster member is the leader, leadership is transferred to another voter first.
+func removeSelfLeader(ctx context.Context, s *state.State) (*client.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = leader.Close() }()
+
+	leaderInfo, err := leader.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if leaderInfo.Address == s.Address().URL.Host {
+		info, err := leader.Cluster(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		remote := remotes.RemoteByAddress(addrPort)
-		if remote == nil {
-			return fmt.Errorf("No remote found at address %q run the post-remove hook", c.URL().URL.Host)
+		otherNodes := []uint64{}
+		for _, node := range info {
+			if node.Address != leaderInfo.Address && node.Role == dqliteClient.Voter {
+				otherNodes = append(otherNodes, node.ID)
+			}
 		}
 
-		return internalClient.RunPostRemoveHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRemoveMemberOptions{Force: force})
-	})
-	if err != nil {
-		return response.SmartError(err)
+		if len(otherNodes) == 0 {
+			return nil, fmt.Errorf("Found no voters to transfer leadership to")
+		}
+
+		randomID := otherNodes[rand.Intn(len(otherNodes))]
+		err = leader.Transfer(ctx, randomID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to transfer leadership: %w", err)
+		}
 	}
 
-	return response.EmptySyncResponse
+	return s.Leader()
 }
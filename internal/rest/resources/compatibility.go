@@ -0,0 +1,83 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/cluster"
+
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var compatibilityCmd = rest.Endpoint{
+	Path: "compatibility",
+
+	Post: rest.EndpointAction{Handler: compatibilityPost, AllowUntrusted: true},
+}
+
+var checkJoinCmd = rest.Endpoint{
+	Path:                 "check-join",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+
+	Post: rest.EndpointAction{Handler: checkJoinPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// compatibilityPost returns the schema versions and API extensions of this cluster member, so that a system can check
+// whether it is compatible with the cluster before joining it. The system must present the secret of its join token.
+func compatibilityPost(s *state.State, r *http.Request) response.Response {
+	req := internalTypes.ClusterMemberVersionPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = s.Database.Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.GetInternalTokenRecord(ctx, tx, req.Secret)
+		return err
+	})
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.Forbidden(fmt.Errorf("Join token is not valid"))
+		}
+
+		return response.SmartError(err)
+	}
+
+	internalVersion, externalVersion, _ := s.Database.Schema().Version()
+
+	return response.SyncResponse(true, internalTypes.ClusterMemberVersion{
+		SchemaInternalVersion: internalVersion,
+		SchemaExternalVersion: externalVersion,
+		Extensions:            s.Extensions,
+	})
+}
+
+func checkJoinPost(s *state.State, r *http.Request) response.Response {
+	req := internalTypes.JoinCheck{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	token, err := internalTypes.DecodeToken(req.JoinToken)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid join token: %w", err))
+	}
+
+	report, err := s.CheckJoin(r.Context(), *token)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, report)
+}
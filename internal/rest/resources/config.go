@@ -0,0 +1,109 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/cluster"
+	internalClient "github.com/canonical/microcluster/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+var configCmd = rest.Endpoint{
+	Path: "config",
+
+	Put: rest.EndpointAction{Handler: configPut, AccessHandler: access.AllowAuthenticated},
+}
+
+// configMu serializes changes to the cluster-wide configuration, so that each OnConfigChange hook sees
+// the configuration before and after a single change.
+var configMu sync.Mutex
+
+func configPut(s *state.State, r *http.Request) response.Response {
+	req := map[string]string{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Reject invalid values of declared keys before anything is persisted.
+	if s.ConfigSchema != nil {
+		for key, value := range req {
+			if !s.ConfigSchema.Registered(key) {
+				continue
+			}
+
+			err := s.ConfigSchema.Validate(key, value)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	var hookOpts internalTypes.HookConfigChangeOptions
+	err = s.Database.Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		hookOpts.OldConfig, err = cluster.GetConfig(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = cluster.UpdateConfig(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		hookOpts.NewConfig, err = cluster.GetConfig(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	cluster, err := s.Cluster(false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Run the OnConfigChange hook locally.
+	err = state.OnConfigChangeHook(r.Context(), s, hookOpts.OldConfig, hookOpts.NewConfig)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to execute config change hook on cluster member %q: %w", s.Name(), err))
+	}
+
+	// Run the OnConfigChange hook on all other members.
+	remotes := s.Remotes()
+	err = cluster.Query(r.Context(), true, func(ctx context.Context, c *client.Client) error {
+		c.SetClusterNotification()
+		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+		if err != nil {
+			return err
+		}
+
+		remote := remotes.RemoteByAddress(addrPort)
+		if remote == nil {
+			return fmt.Errorf("No remote found at address %q to run the config change hook", c.URL().URL.Host)
+		}
+
+		return internalClient.RunConfigChangeHook(ctx, c.Client.UseTarget(remote.Name), hookOpts)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
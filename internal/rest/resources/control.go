@@ -3,21 +3,23 @@ package resources
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"net/netip"
+	"time"
 
 	"github.com/canonical/lxd/lxd/response"
-	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
-	"github.com/canonical/lxd/shared/validate"
 
+	"github.com/canonical/microcluster/internal/db"
 	"github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/internal/trust"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
@@ -25,29 +27,12 @@ import (
 )
 
 var controlCmd = rest.Endpoint{
-	AllowedBeforeInit: true,
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
 
 	Post: rest.EndpointAction{Handler: controlPost, AccessHandler: access.AllowAuthenticated},
 }
 
-// validateFQDN validates that the given name is a a valid fully qualified domain name.
-func validateFQDN(name string) error {
-	// Validate length
-	if len(name) < 1 || len(name) > 255 {
-		return fmt.Errorf("Name must be 1-255 characters long")
-	}
-
-	hostnames := strings.Split(name, ".")
-	for _, h := range hostnames {
-		err := validate.IsHostname(h)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func controlPost(state *state.State, r *http.Request) response.Response {
 	req := &internalTypes.Control{}
 	// Parse the request.
@@ -60,16 +45,52 @@ func controlPost(state *state.State, r *http.Request) response.Response {
 		return response.SmartError(fmt.Errorf("Invalid options - received join token and bootstrap flag"))
 	}
 
-	err = validateFQDN(req.Name)
+	// A member that already has a database can neither bootstrap nor join another cluster.
+	if state.Database.Status() != db.StatusNotReady {
+		return joinError(types.ErrAlreadyClustered)
+	}
+
+	// Default to the name the daemon was started with.
+	if req.Name == "" {
+		req.Name = state.Name()
+	}
+
+	err = trust.ValidateName(req.Name)
 	if err != nil {
-		return response.SmartError(fmt.Errorf("Invalid cluster member name %q: %w", req.Name, err))
+		return response.BadRequest(err)
+	}
+
+	// Resolve the listen address from the network interface, if one was given.
+	if req.Interface != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		addr, err := sys.WaitInterfaceAddress(ctx, req.Interface)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		req.Address = types.AddrPort{AddrPort: netip.AddrPortFrom(addr, req.Address.Port())}
 	}
 
 	if req.JoinToken != "" {
+		if state.NetworkDisabled {
+			return response.BadRequest(fmt.Errorf("Cannot join a cluster while the network API is disabled, as other cluster members could not reach this member"))
+		}
+
 		return joinWithToken(state, r, req)
 	}
 
-	daemonConfig := &trust.Location{Address: req.Address, Name: req.Name}
+	if req.Bootstrap && state.ReplicaOnly {
+		return response.BadRequest(fmt.Errorf("Cannot bootstrap a cluster from a read-only replica, as it can't become a voter"))
+	}
+
+	// Without the network API the address is never listened on, so default to a loopback address to identify the member.
+	if state.NetworkDisabled && !req.Address.IsValid() {
+		req.Address = types.AddrPort{AddrPort: netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), 0)}
+	}
+
+	daemonConfig := &trust.Location{Address: req.Address, Name: req.Name, Interface: req.Interface}
 	err = state.StartAPI(req.Bootstrap, req.InitConfig, daemonConfig)
 	if err != nil {
 		return response.SmartError(err)
@@ -90,19 +111,21 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 	}
 
 	// Add the local node to the list of clusterMembers.
-	daemonConfig := &trust.Location{Address: req.Address, Name: req.Name}
+	daemonConfig := &trust.Location{Address: req.Address, Name: req.Name, Interface: req.Interface}
 	localClusterMember := trust.Remote{
-		Location:    *daemonConfig,
-		Certificate: types.X509Certificate{Certificate: serverCert},
+		Location:      *daemonConfig,
+		Certificate:   types.X509Certificate{Certificate: serverCert},
+		DqliteAddress: state.Database.DqliteAddress(),
 	}
 
 	// Prepare the cluster for the incoming dqlite request by creating a database entry.
 	internalVersion, externalVersion, _ := state.Database.Schema().Version()
 	newClusterMember := internalTypes.ClusterMember{
 		ClusterMemberLocal: internalTypes.ClusterMemberLocal{
-			Name:        localClusterMember.Name,
-			Address:     localClusterMember.Address,
-			Certificate: localClusterMember.Certificate,
+			Name:          localClusterMember.Name,
+			Address:       localClusterMember.Address,
+			Certificate:   localClusterMember.Certificate,
+			DqliteAddress: localClusterMember.DqliteAddress,
 		},
 		SchemaInternalVersion: internalVersion,
 		SchemaExternalVersion: externalVersion,
@@ -114,16 +137,16 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 	var lastErr error
 	var joinInfo *internalTypes.TokenResponse
 	for _, addr := range token.JoinAddresses {
-		url := api.NewURL().Scheme("https").Host(addr.String())
+		url := api.NewURL().Scheme(sys.Scheme()).Host(addr.String())
 
-		cert, err := shared.GetRemoteCertificate(url.String(), "")
+		cert, err := sys.RemoteCertificate(url.String())
 		if err != nil {
-			return response.SmartError(fmt.Errorf("Failed to get certificate of cluster member %q: %w", url.URL.Host, err))
+			return joinError(types.JoinFailed(types.ErrJoinUnreachable, fmt.Errorf("Failed to get certificate of cluster member %q: %w", url.URL.Host, err)))
 		}
 
-		fingerprint := shared.CertFingerprint(cert)
-		if fingerprint != token.Fingerprint {
-			return response.SmartError(fmt.Errorf("Cluster certificate token does not match that of cluster member %q", url.URL.Host))
+		// In plaintext mode there is no certificate to verify the token against.
+		if cert != nil && shared.CertFingerprint(cert) != token.Fingerprint {
+			return joinError(types.JoinFailed(types.ErrJoinNotTrusted, fmt.Errorf("Cluster certificate token does not match that of cluster member %q", url.URL.Host)))
 		}
 
 		d, err := client.New(*url, state.ServerCert(), cert, false)
@@ -138,10 +161,15 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 
 		logger.Error("Unable to complete cluster join request", logger.Ctx{"address": addr.String(), "error": err})
 		lastErr = err
+
+		// The leader handles the request regardless of the address it was sent to, so a rejection is final.
+		if errors.Is(err, types.ErrJoinRejected) {
+			return joinError(err)
+		}
 	}
 
 	if joinInfo == nil {
-		return response.SmartError(fmt.Errorf("%d join attempts were unsuccessful. Last error: %w", len(token.JoinAddresses), lastErr))
+		return joinError(classifyJoinError(fmt.Errorf("%d join attempts were unsuccessful. Last error: %w", len(token.JoinAddresses), lastErr)))
 	}
 
 	reverter := revert.New()
@@ -150,8 +178,8 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 	// If at some point we fail to join the cluster, instruct whoever authorized us to remove us from the cluster.
 	// This should also reset our database and listeners.
 	reverter.Add(func() {
-		url := api.NewURL().Scheme("https").Host(joinInfo.TrustedMember.Address.String())
-		cert, err := shared.GetRemoteCertificate(url.String(), "")
+		url := api.NewURL().Scheme(sys.Scheme()).Host(joinInfo.TrustedMember.Address.String())
+		cert, err := sys.RemoteCertificate(url.String())
 		if err != nil {
 			return
 		}
@@ -176,7 +204,13 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 		}
 	})
 
-	err = util.WriteCert(state.OS.StateDir, "cluster", []byte(joinInfo.ClusterCert.String()), []byte(joinInfo.ClusterKey), nil)
+	// Also trust the CA of the cluster certificate, if it was signed by one.
+	var clusterCA []byte
+	if joinInfo.ClusterCA != "" {
+		clusterCA = []byte(joinInfo.ClusterCA)
+	}
+
+	err = writeCert(state.OS.StateDir, "cluster", []byte(joinInfo.ClusterCert.String()), []byte(joinInfo.ClusterKey), clusterCA)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -185,8 +219,10 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 	clusterMembers := make([]trust.Remote, 0, len(joinInfo.ClusterMembers))
 	for _, clusterMember := range joinInfo.ClusterMembers {
 		remote := trust.Remote{
-			Location:    trust.Location{Name: clusterMember.Name, Address: clusterMember.Address},
-			Certificate: clusterMember.Certificate,
+			Location:               trust.Location{Name: clusterMember.Name, Address: clusterMember.Address},
+			Certificate:            clusterMember.Certificate,
+			AdditionalCertificates: clusterMember.AdditionalCertificates,
+			DqliteAddress:          clusterMember.DqliteAddress,
 		}
 
 		joinAddrs = append(joinAddrs, clusterMember.Address)
@@ -194,7 +230,7 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 	}
 
 	clusterMembers = append(clusterMembers, localClusterMember)
-	err = state.Remotes().Add(state.OS.TrustDir, clusterMembers...)
+	err = state.Remotes().Add(state.OS.TrustDir, joinInfo.TrustedMember.Address.String(), clusterMembers...)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -202,10 +238,32 @@ func joinWithToken(state *state.State, r *http.Request, req *internalTypes.Contr
 	// Start the HTTPS listeners and join Dqlite.
 	err = state.StartAPI(false, req.InitConfig, daemonConfig, joinAddrs.Strings()...)
 	if err != nil {
-		return response.SmartError(err)
+		return joinError(err)
 	}
 
 	reverter.Success()
 
 	return response.EmptySyncResponse
 }
+
+// classifyJoinError determines the reason a cluster member rejected a join request, if the error doesn't already
+// carry one. Requests that received no response at all indicate the cluster is unreachable, while a rejected
+// certificate or unknown join token indicates the member is not trusted.
+func classifyJoinError(err error) error {
+	_, ok := types.NewJoinFailure(err)
+	if ok {
+		return err
+	}
+
+	code, ok := api.StatusErrorMatch(err)
+	if !ok {
+		return types.JoinFailed(types.ErrJoinUnreachable, err)
+	}
+
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return types.JoinFailed(types.ErrJoinNotTrusted, err)
+	}
+
+	return err
+}
@@ -7,18 +7,69 @@ import (
 
 	"github.com/canonical/lxd/lxd/response"
 
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
 )
 
 var databaseCmd = rest.Endpoint{
-	AllowedBeforeInit: true,
-	Path:              "database",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+	Path:                 "database",
+
+	// Other cluster members must still reach dqlite while this member hands over and stops it during shutdown.
+	AllowedDuringShutdown: true,
+
+	// The connection is hijacked by dqlite, so the request body must not be limited.
+	MaxRequestBytes: -1,
 
 	Post:  rest.EndpointAction{Handler: databasePost},
 	Patch: rest.EndpointAction{Handler: databasePatch},
 }
 
+var databaseDumpCmd = rest.Endpoint{
+	Path: "database/dump",
+
+	Get: rest.EndpointAction{Handler: databaseDumpGet, AccessHandler: access.AllowAuthenticated},
+}
+
+func databaseDumpGet(s *state.State, r *http.Request) response.Response {
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		// The dump is streamed, so an error after it has started is reported in a trailer instead.
+		w.Header().Set("Content-Type", "application/sql")
+		w.Header().Set("Trailer", internalTypes.DatabaseDumpErrorTrailer)
+		dw := &dumpResponseWriter{ResponseWriter: w}
+		err := s.DatabaseDump(r.Context(), dw)
+		if err == nil {
+			return nil
+		}
+
+		if !dw.started {
+			w.Header().Del("Trailer")
+			w.Header().Del("Content-Type")
+			return response.SmartError(err).Render(w)
+		}
+
+		w.Header().Set(internalTypes.DatabaseDumpErrorTrailer, err.Error())
+
+		return nil
+	})
+}
+
+// dumpResponseWriter records whether the dump has started to be written to the response.
+type dumpResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// Write implements io.Writer.
+func (w *dumpResponseWriter) Write(p []byte) (int, error) {
+	w.started = true
+
+	return w.ResponseWriter.Write(p)
+}
+
 func databasePost(state *state.State, r *http.Request) response.Response {
 	// Compare the dqlite version of the connecting client with our own.
 	versionHeader := r.Header.Get("X-Dqlite-Version")
@@ -0,0 +1,24 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var diagnosticsCmd = rest.Endpoint{
+	Path:                 "diagnostics",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+
+	Get: rest.EndpointAction{Handler: diagnosticsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+func diagnosticsGet(s *state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.DiagnosticsConfig())
+}
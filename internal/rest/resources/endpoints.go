@@ -0,0 +1,92 @@
+package resources
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+var endpointsCmd = rest.Endpoint{
+	Path: "endpoints",
+
+	Get: rest.EndpointAction{Handler: endpointsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+func endpointsGet(s *state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.DescribeEndpoints())
+}
+
+// DescribeEndpoints returns a description of every core endpoint, followed by the endpoints of the given extension servers.
+func DescribeEndpoints(extensionServers []rest.Server) []types.EndpointInfo {
+	servers := append([]rest.Server{{Resources: []rest.Resources{UnixEndpoints, PublicEndpoints, InternalEndpoints}}}, extensionServers...)
+
+	var infos []types.EndpointInfo
+	for _, server := range servers {
+		address := ""
+		if server.Address != (types.AddrPort{}) {
+			address = server.Address.String()
+		}
+
+		for _, resource := range server.Resources {
+			for _, e := range resource.Endpoints {
+				unixOnly := server.UnixOnly || resource.PathPrefix == UnixEndpoints.PathPrefix
+				authenticated := e.Authenticator != nil || server.Authenticator != nil
+				info := types.EndpointInfo{
+					Path:     path.Join("/", string(resource.PathPrefix), e.Path),
+					Name:     e.Name,
+					Aliases:  make([]string, 0, len(e.Aliases)),
+					Methods:  describeMethods(e, unixOnly || authenticated),
+					Address:  address,
+					UnixOnly: unixOnly,
+				}
+
+				for _, alias := range e.Aliases {
+					info.Aliases = append(info.Aliases, path.Join("/", string(resource.PathPrefix), alias.Path))
+				}
+
+				infos = append(infos, info)
+			}
+		}
+	}
+
+	return infos
+}
+
+// describeMethods returns the HTTP methods that have a handler on the given endpoint. If the endpoint is not served over
+// the network, or accepts credentials other than the client certificate, none of its methods require trust.
+func describeMethods(e rest.Endpoint, untrustedAccess bool) []types.EndpointMethod {
+	actions := []struct {
+		method string
+		action rest.EndpointAction
+	}{
+		{method: http.MethodGet, action: e.Get},
+		{method: http.MethodPut, action: e.Put},
+		{method: http.MethodPost, action: e.Post},
+		{method: http.MethodDelete, action: e.Delete},
+		{method: http.MethodPatch, action: e.Patch},
+	}
+
+	methods := []types.EndpointMethod{}
+	for _, a := range actions {
+		if a.action.Handler == nil {
+			continue
+		}
+
+		requiresTrust := !untrustedAccess && (!a.action.AllowUntrusted || isAllowAuthenticated(a.action.AccessHandler))
+		methods = append(methods, types.EndpointMethod{Method: a.method, RequiresTrust: requiresTrust})
+	}
+
+	return methods
+}
+
+// isAllowAuthenticated returns whether the access handler is access.AllowAuthenticated.
+func isAllowAuthenticated(handler func(s *state.State, r *http.Request) response.Response) bool {
+	return handler != nil && reflect.ValueOf(handler).Pointer() == reflect.ValueOf(access.AllowAuthenticated).Pointer()
+}
@@ -0,0 +1,89 @@
+package resources
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// joinErrorResponse is an error response for a failed join that includes the reason for the failure as metadata,
+// so that clients can determine why the join failed.
+type joinErrorResponse struct {
+	msg      string
+	code     int
+	metadata any
+}
+
+// Render implements response.Response.
+func (r *joinErrorResponse) Render(w http.ResponseWriter) error {
+	metadata, err := json.Marshal(r.metadata)
+	if err != nil {
+		return err
+	}
+
+	resp := api.ResponseRaw{
+		Type:     api.ErrorResponse,
+		Error:    r.msg,
+		Code:     r.code,
+		Metadata: json.RawMessage(metadata),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(r.code)
+
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// String implements response.Response.
+func (r *joinErrorResponse) String() string {
+	return r.msg
+}
+
+// joinError returns an error response for a failed join. The reason for any known join failure, including a schema
+// version mismatch, is included in the response metadata.
+func joinError(err error) response.Response {
+	failure, ok := types.NewJoinFailure(err)
+	if !ok {
+		return response.SmartError(err)
+	}
+
+	code := http.StatusInternalServerError
+	switch failure.Err() {
+	case types.ErrAlreadyClustered:
+		code = http.StatusConflict
+	case types.ErrJoinUnreachable:
+		code = http.StatusServiceUnavailable
+	case types.ErrJoinNotTrusted, types.ErrJoinRejected:
+		code = http.StatusForbidden
+	case types.ErrJoinIncompatible:
+		code = http.StatusPreconditionFailed
+	}
+
+	return &joinErrorResponse{msg: err.Error(), code: code, metadata: failure}
+}
+
+// retryAfterResponse is an Unavailable error response with a Retry-After header, telling the client when it may retry.
+type retryAfterResponse struct {
+	response.Response
+	after time.Duration
+}
+
+// Render implements response.Response.
+func (r retryAfterResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(r.after.Seconds()))))
+
+	return r.Response.Render(w)
+}
+
+// retryAfter returns an Unavailable error response for the given error, asking the client to retry after the given delay.
+func retryAfter(err error, after time.Duration) response.Response {
+	return retryAfterResponse{Response: response.Unavailable(err), after: after}
+}
@@ -0,0 +1,64 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+var eventsCmd = rest.Endpoint{
+	Path:                 "events",
+	AllowedBeforeInit:    true,
+	AllowedWhileDraining: true,
+	DisableCompression:   true,
+
+	Get: rest.EndpointAction{Handler: eventsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+// eventsGet streams the events of the local cluster member as newline-delimited JSON until the client disconnects.
+// The comma-separated type query parameter selects the types of events to stream.
+func eventsGet(s *state.State, r *http.Request) response.Response {
+	var eventTypes []types.EventType
+	filter := r.URL.Query().Get("type")
+	if filter != "" {
+		for _, eventType := range strings.Split(filter, ",") {
+			switch types.EventType(eventType) {
+			case types.EventMember, types.EventHook, types.EventError:
+				eventTypes = append(eventTypes, types.EventType(eventType))
+			default:
+				return response.BadRequest(fmt.Errorf("Unknown event type %q", eventType))
+			}
+		}
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			return fmt.Errorf("ResponseWriter is not type http.Flusher")
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		f.Flush()
+
+		encoder := json.NewEncoder(w)
+		for event := range s.SubscribeEvents(r.Context(), eventTypes...) {
+			err := encoder.Encode(event)
+			if err != nil {
+				return err
+			}
+
+			f.Flush()
+		}
+
+		return nil
+	})
+}
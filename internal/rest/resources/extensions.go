@@ -0,0 +1,38 @@
+package resources
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	internalREST "github.com/canonical/microcluster/internal/rest"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+var extensionsCmd = rest.Endpoint{
+	Path:              "extensions",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: extensionsGet, AllowUntrusted: true},
+}
+
+func extensionsGet(s *state.State, r *http.Request) response.Response {
+	internal, external := s.Extensions.Split()
+
+	// Only advertise the application extensions allowed on the listener that the request was received on.
+	advertised, ok := internalREST.AdvertisedExtensions(r.Context())
+	if ok {
+		external = slices.DeleteFunc(external, func(extension string) bool {
+			return !slices.Contains(advertised, extension)
+		})
+	}
+
+	return response.SyncResponse(true, types.APIExtensions{
+		Internal: internal,
+		External: external,
+		Version:  s.Extensions.Version(),
+	})
+}
@@ -5,27 +5,117 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/mux"
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/config"
+	"github.com/canonical/microcluster/internal/db"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	"github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	apiTypes "github.com/canonical/microcluster/rest/types"
 )
 
 var heartbeatCmd = rest.Endpoint{
-	Path: "heartbeat",
+	Path:                 "heartbeat",
+	AllowedWithoutLeader: true,
 
 	Post: rest.EndpointAction{Handler: heartbeatPost, AllowUntrusted: true},
 }
 
+var heartbeatPauseCmd = rest.Endpoint{
+	Path:                 "heartbeat/pause",
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+
+	Put: rest.EndpointAction{Handler: heartbeatPausePut, AccessHandler: access.AllowAuthenticated},
+}
+
+var heartbeatHistoryCmd = rest.Endpoint{
+	Path:                 "cluster/{name}/heartbeats",
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+
+	Get: rest.EndpointAction{Handler: heartbeatHistoryGet, AccessHandler: access.AllowAuthenticated},
+}
+
+// heartbeatHistoryGet returns the recent heartbeat outcomes of a cluster member. As they are recorded by the dqlite
+// leader, the request is forwarded to the leader if this cluster member is not the leader.
+func heartbeatHistoryGet(s *state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	isLeader, err := s.IsLeader(ctx)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to find the dqlite leader, which records the heartbeat history: %w", err))
+	}
+
+	// Forward request to leader.
+	if !isLeader {
+		leader, err := s.Leader()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		history, err := leader.GetHeartbeatHistory(ctx, name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.SyncResponse(true, history)
+	}
+
+	history, err := s.MemberHistory(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, history)
+}
+
+// heartbeatPausePut pauses or resumes heartbeats. Unless the request is a cluster notification, heartbeats are paused
+// on every other cluster member too.
+func heartbeatPausePut(s *state.State, r *http.Request) response.Response {
+	var req types.HeartbeatPause
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !client.IsNotification(r) {
+		err = s.PauseHeartbeats(r.Context(), req.Duration)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	if req.Duration < 0 || req.Duration > db.MaxHeartbeatPause {
+		return response.BadRequest(fmt.Errorf("Heartbeat pause must be between 0 and %s", db.MaxHeartbeatPause))
+	}
+
+	s.Database.PauseHeartbeats(req.Duration)
+
+	return response.EmptySyncResponse
+}
+
 func heartbeatPost(s *state.State, r *http.Request) response.Response {
 	var hbInfo types.HeartbeatInfo
 	err := json.NewDecoder(r.Body).Decode(&hbInfo)
@@ -50,7 +140,7 @@ func heartbeatPost(s *state.State, r *http.Request) response.Response {
 		clusterMemberList = append(clusterMemberList, clusterMember)
 	}
 
-	err = s.Remotes().Replace(s.OS.TrustDir, clusterMemberList...)
+	err = s.Remotes().Replace(s.OS.TrustDir, r.RemoteAddr, clusterMemberList...)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -80,7 +170,112 @@ func heartbeatPost(s *state.State, r *http.Request) response.Response {
 
 	// TODO: If our schema version is behind, we should try to update here.
 
-	return response.EmptySyncResponse
+	s.Database.RecordHeartbeatReceived()
+
+	payloads := capHeartbeatPayloads(hbInfo.Payloads)
+	s.Database.SetHeartbeatPayloads(payloads)
+	onHeartbeatPayloads(s, payloads)
+
+	return response.SyncResponse(true, types.HeartbeatResponse{Time: time.Now().UTC(), Payload: localHeartbeatPayload(s, heartbeatPayloadTimeout)})
+}
+
+// heartbeatPayloadTimeout is how long the HeartbeatPayload hook may run before its payload is dropped, so that a slow
+// hook does not delay the heartbeat and get this member counted as failing.
+const heartbeatPayloadTimeout = 2 * time.Second
+
+// capHeartbeatPayloads returns the given payloads without any that are empty or larger than the maximum size.
+func capHeartbeatPayloads(payloads map[string][]byte) map[string][]byte {
+	capped := make(map[string][]byte, len(payloads))
+	for name, payload := range payloads {
+		if len(payload) > 0 && len(payload) <= config.MaxHeartbeatPayload {
+			capped[name] = payload
+		}
+	}
+
+	return capped
+}
+
+// mergeHeartbeatPayloads returns the payloads sent with a heartbeat updated with those received in response. Members
+// that didn't respond keep their previous payload, and those that responded without a payload, or with one that is too
+// large, have it removed.
+func mergeHeartbeatPayloads(sent map[string][]byte, received map[string][]byte) map[string][]byte {
+	payloads := maps.Clone(sent)
+	if payloads == nil {
+		payloads = map[string][]byte{}
+	}
+
+	for name, payload := range received {
+		if len(payload) == 0 || len(payload) > config.MaxHeartbeatPayload {
+			delete(payloads, name)
+			continue
+		}
+
+		payloads[name] = payload
+	}
+
+	return payloads
+}
+
+// localHeartbeatPayload returns the heartbeat payload of the local cluster member, or nil if it has none. Payloads
+// that fail, are too large, or are not returned before the timeout are logged and dropped, as payloads are only
+// best-effort.
+func localHeartbeatPayload(s *state.State, timeout time.Duration) []byte {
+	type result struct {
+		payload []byte
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		payload, err := state.HeartbeatPayloadHook(s)
+		done <- result{payload: payload, err: err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(timeout):
+		s.Log.Warn("Dropping heartbeat payload as the hook did not return in time", logger.Ctx{"timeout": timeout})
+		return nil
+	}
+
+	if res.err != nil {
+		s.Log.Warn("Failed to get heartbeat payload", logger.Ctx{"error": res.err})
+		return nil
+	}
+
+	if len(res.payload) > config.MaxHeartbeatPayload {
+		s.Log.Warn("Dropping heartbeat payload as it is too large", logger.Ctx{"size": len(res.payload), "max": config.MaxHeartbeatPayload})
+		return nil
+	}
+
+	return res.payload
+}
+
+// onHeartbeatPayloadsMu ensures only one run of the OnHeartbeatPayloads hook at a time.
+var onHeartbeatPayloadsMu sync.Mutex
+
+// onHeartbeatPayloads runs the OnHeartbeatPayloads hook with the given payloads in the background, if there are any,
+// so that it doesn't delay the heartbeat. If the previous run has not returned yet, the payloads are skipped, as the
+// next heartbeat brings newer ones. Failures are only logged.
+func onHeartbeatPayloads(s *state.State, payloads map[string][]byte) {
+	if len(payloads) == 0 {
+		return
+	}
+
+	s.RegisterWorker(func(ctx context.Context) {
+		if !onHeartbeatPayloadsMu.TryLock() {
+			s.Log.Warn("Skipping heartbeat payloads hook as its previous run has not returned")
+			return
+		}
+
+		defer onHeartbeatPayloadsMu.Unlock()
+
+		err := state.OnHeartbeatPayloadsHook(s, payloads)
+		if err != nil {
+			s.Log.Warn("Failed to run heartbeat payloads hook", logger.Ctx{"error": err})
+		}
+	})
 }
 
 // beginHeartbeat initiates a heartbeat from the leader node to all other cluster members, if we haven't sent one out
@@ -113,6 +308,21 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 			return err
 		}
 
+		dqliteAddresses, err := cluster.GetDqliteAddresses(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		requestedRoles, err := cluster.GetRequestedRoles(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		additionalCertificates, err := cluster.GetAdditionalCertificates(ctx, tx)
+		if err != nil {
+			return err
+		}
+
 		clusterMembers = make([]types.ClusterMember, 0, len(dbClusterMembers))
 		for _, clusterMember := range dbClusterMembers {
 			apiClusterMember, err := clusterMember.ToAPI()
@@ -120,6 +330,9 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 				return err
 			}
 
+			apiClusterMember.DqliteAddress = dqliteAddresses[apiClusterMember.Name]
+			apiClusterMember.RequestedRole = requestedRoles[apiClusterMember.Name]
+			apiClusterMember.AdditionalCertificates = additionalCertificates[apiClusterMember.Name]
 			clusterMembers = append(clusterMembers, *apiClusterMember)
 		}
 
@@ -136,7 +349,7 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 	}
 
 	if len(clusterMembers) == 0 || len(dqliteCluster) == 0 {
-		logger.Info("Skipping heartbeat as the cluster is still initializing")
+		s.Log.Info("Skipping heartbeat as the cluster is still initializing")
 		return response.EmptySyncResponse
 	}
 
@@ -152,7 +365,7 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 
 		// If a cluster member is pending and dqlite does not have a record for it yet, then skip it this round.
 		if !ok && clusterMember.Role == string(cluster.Pending) {
-			logger.Debug("Skipping heartbeat for pending cluster member", logger.Ctx{"address": clusterMember.Address})
+			s.Log.Debug("Skipping heartbeat for pending cluster member", logger.Ctx{"address": clusterMember.Address})
 			continue
 		}
 
@@ -162,12 +375,15 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 
 	// If we sent out a heartbeat within double the request timeout,
 	// then wait the up to half the request timeout before exiting to prevent sending more unsuccessful attempts.
+	// Only heartbeats sent by this member are considered, measured on its own monotonic clock, as the recorded
+	// heartbeat times may have been set by a previous leader with a different clock.
 	leaderEntry := clusterMap[s.Address().URL.Host]
 	heartbeatInterval := time.Duration(time.Second * internalClient.HeartbeatTimeout * 2)
-	timeSinceLast := time.Since(leaderEntry.LastHeartbeat)
-	if timeSinceLast < heartbeatInterval {
+	lastSent, sent := s.Database.HeartbeatSent(s.Address().URL.Host)
+	timeSinceLast := time.Since(lastSent)
+	if sent && timeSinceLast < heartbeatInterval {
 		sleepInterval := time.Duration(time.Second * internalClient.HeartbeatTimeout / 2)
-		timeUntilNext := time.Until(leaderEntry.LastHeartbeat.Add(heartbeatInterval))
+		timeUntilNext := time.Until(lastSent.Add(heartbeatInterval))
 
 		// If we can send out a heartbeat sooner than the sleep timeout, sleep just long enough.
 		if timeUntilNext < sleepInterval {
@@ -179,22 +395,34 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 			sleepInterval = 2 * time.Second
 		}
 
-		logger.Debugf("Heartbeat was sent %v ago, sleep %v seconds before retrying", timeSinceLast, sleepInterval)
+		s.Log.Debug("Heartbeat was sent recently, sleeping before retrying", logger.Ctx{"since": timeSinceLast, "sleep": sleepInterval})
 		<-time.After(sleepInterval)
 
 		return response.EmptySyncResponse
 	}
 
-	logger.Debug("Beginning new heartbeat round", logger.Ctx{"address": s.Address().URL.Host})
+	s.Log.Debug("Beginning new heartbeat round", logger.Ctx{"address": s.Address().URL.Host})
+
+	// Re-apply any roles requested by an operator that dqlite has since reassigned.
+	reconcileRequestedRoles(ctx, s, clusterMembers, dqliteMap)
 
 	// Update local record of cluster members from the database, including any pending nodes for authentication.
-	err = s.Remotes().Replace(s.OS.TrustDir, clusterMembers...)
+	err = s.Remotes().Replace(s.OS.TrustDir, "", clusterMembers...)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// Discard the heartbeat history of any members that have since been removed.
+	memberNames := make(map[string]bool, len(clusterMembers))
+	for _, clusterMember := range clusterMembers {
+		memberNames[clusterMember.Name] = true
+	}
+
+	s.Database.ForgetHeartbeatHistory(memberNames)
+
 	// Set the time of the last heartbeat to now.
 	leaderEntry.LastHeartbeat = time.Now()
+	s.Database.RecordHeartbeatSent(s.Address().URL.Host, leaderEntry.LastHeartbeat)
 	clusterMap[s.Address().URL.Host] = leaderEntry
 
 	// Record the maximum schema version discovered.
@@ -209,13 +437,30 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 		}
 	}
 
+	// Send the latest heartbeat payloads of the current cluster members, with a new one for this member.
+	previousPayloads := s.Database.HeartbeatPayloads()
+	hbInfo.Payloads = map[string][]byte{}
+	for _, node := range clusterMembers {
+		payload, ok := previousPayloads[node.Name]
+		if ok {
+			hbInfo.Payloads[node.Name] = payload
+		}
+	}
+
+	delete(hbInfo.Payloads, s.Name())
+	localPayload := localHeartbeatPayload(s, heartbeatPayloadTimeout)
+	if localPayload != nil {
+		hbInfo.Payloads[s.Name()] = localPayload
+	}
+
 	clusterClients, err := s.Cluster(false)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Use a lock to handle concurrent access to hbInfo.
+	// Use a lock to handle concurrent access to hbInfo, and the payloads received in response.
 	mapLock := sync.RWMutex{}
+	receivedPayloads := map[string][]byte{}
 	// Send heartbeat to non-leader members, updating their local member cache and updating the node.
 	// If we sent a heartbeat to this node within double the request timeout, then we can skip the node this round.
 	err = clusterClients.Query(s.Context, true, func(ctx context.Context, c *client.Client) error {
@@ -225,26 +470,34 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 		currentMember, ok := hbInfo.ClusterMembers[addr]
 		mapLock.RUnlock()
 		if !ok {
-			logger.Warnf("Skipping heartbeat cluster member record with address %v due to pending status", addr)
+			s.Log.Warn("Skipping heartbeat for cluster member record due to pending status", logger.Ctx{"address": addr})
 			return nil
 		}
 
-		timeSinceLast := time.Since(currentMember.LastHeartbeat)
-		if timeSinceLast < time.Duration(time.Second*internalClient.HeartbeatTimeout*2) {
-			logger.Warnf("Skipping heartbeat, one was sent %q ago", timeSinceLast.String())
+		lastSent, sent := s.Database.HeartbeatSent(addr)
+		timeSinceLast := time.Since(lastSent)
+		if sent && timeSinceLast < time.Duration(time.Second*internalClient.HeartbeatTimeout*2) {
+			s.Log.Warn("Skipping heartbeat, one was sent recently", logger.Ctx{"since": timeSinceLast.String()})
 			return nil
 		}
 
-		err := c.Heartbeat(ctx, hbInfo)
+		start := time.Now()
+		resp, err := c.Heartbeat(ctx, hbInfo)
 		if err != nil {
-			logger.Error("Received error sending heartbeat to cluster member", logger.Ctx{"target": addr, "error": err})
+			s.Log.Error("Received error sending heartbeat to cluster member", logger.Ctx{"target": addr, "error": err})
+			s.Database.RecordHeartbeatOutcome(currentMember.Name, apiTypes.HeartbeatOutcome{Time: start, Error: err.Error()})
 			return nil
 		}
 
+		s.Database.RecordHeartbeatOutcome(currentMember.Name, apiTypes.HeartbeatOutcome{Time: start, Success: true})
+
 		currentMember.LastHeartbeat = time.Now()
+		s.Database.RecordHeartbeatSent(addr, currentMember.LastHeartbeat)
+		checkClockSkew(s, addr, resp.Time, start, currentMember.LastHeartbeat)
 
 		mapLock.Lock()
 		hbInfo.ClusterMembers[addr] = currentMember
+		receivedPayloads[currentMember.Name] = resp.Payload
 		mapLock.Unlock()
 
 		return nil
@@ -280,6 +533,10 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	payloads := mergeHeartbeatPayloads(hbInfo.Payloads, receivedPayloads)
+	s.Database.SetHeartbeatPayloads(payloads)
+	onHeartbeatPayloads(s, payloads)
+
 	err = state.OnHeartbeatHook(s)
 	if err != nil {
 		return response.SmartError(err)
@@ -287,3 +544,20 @@ func beginHeartbeat(s *state.State, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// checkClockSkew logs a warning if the time reported by the cluster member at the given address, while handling a
+// request sent and answered at the given local times, differs from the local clock by more than the threshold.
+// Cluster members that don't report their time are skipped.
+func checkClockSkew(s *state.State, address string, remoteTime time.Time, sent time.Time, received time.Time) {
+	threshold := s.Database.ClockSkewThreshold()
+	if threshold < 0 || remoteTime.IsZero() {
+		return
+	}
+
+	// Compare the remote time with the local time halfway through the request, allowing for the network delay.
+	localTime := sent.Add(received.Sub(sent) / 2)
+	skew := remoteTime.Sub(localTime)
+	if skew.Abs() > threshold+received.Sub(sent)/2 {
+		s.Log.Warn("Clock of cluster member differs from the local clock", logger.Ctx{"address": address, "skew": skew.String(), "threshold": threshold.String()})
+	}
+}
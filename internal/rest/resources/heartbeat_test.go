@@ -0,0 +1,160 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/config"
+	"github.com/canonical/microcluster/internal/state"
+)
+
+type heartbeatSuite struct {
+	suite.Suite
+}
+
+func TestHeartbeatSuite(t *testing.T) {
+	suite.Run(t, new(heartbeatSuite))
+}
+
+// Ensures heartbeat payloads that are empty or larger than the maximum size are dropped.
+func (s *heartbeatSuite) Test_capHeartbeatPayloads() {
+	payloads := capHeartbeatPayloads(map[string][]byte{
+		"empty":   {},
+		"max":     bytes.Repeat([]byte("a"), config.MaxHeartbeatPayload),
+		"too-big": bytes.Repeat([]byte("a"), config.MaxHeartbeatPayload+1),
+		"small":   []byte("load=1"),
+	})
+
+	s.Len(payloads, 2)
+	s.Contains(payloads, "max")
+	s.Equal([]byte("load=1"), payloads["small"])
+
+	s.Empty(capHeartbeatPayloads(nil))
+}
+
+// Ensures the payloads received in response to a heartbeat replace those that were sent, and members that did not
+// respond keep their previous payload.
+func (s *heartbeatSuite) Test_mergeHeartbeatPayloads() {
+	sent := map[string][]byte{
+		"leader":      []byte("leader"),
+		"no-response": []byte("old"),
+		"updated":     []byte("old"),
+		"cleared":     []byte("old"),
+		"too-big":     []byte("old"),
+	}
+
+	received := map[string][]byte{
+		"updated": []byte("new"),
+		"cleared": nil,
+		"too-big": bytes.Repeat([]byte("a"), config.MaxHeartbeatPayload+1),
+		"new":     []byte("new"),
+	}
+
+	payloads := mergeHeartbeatPayloads(sent, received)
+	s.Equal(map[string][]byte{
+		"leader":      []byte("leader"),
+		"no-response": []byte("old"),
+		"updated":     []byte("new"),
+		"new":         []byte("new"),
+	}, payloads)
+
+	// The sent payloads are left untouched.
+	s.Equal([]byte("old"), sent["updated"])
+	s.Contains(sent, "cleared")
+
+	s.Empty(mergeHeartbeatPayloads(nil, nil))
+}
+
+// Ensures the local heartbeat payload is dropped if the hook fails, returns too large a payload, or is too slow.
+func (s *heartbeatSuite) Test_localHeartbeatPayload() {
+	st := &state.State{Log: logger.AddContext(logger.Ctx{})}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := []struct {
+		name    string
+		hook    func(s *state.State) ([]byte, error)
+		payload []byte
+	}{
+		{
+			name:    "Payload within the maximum size",
+			hook:    func(s *state.State) ([]byte, error) { return []byte("load=1"), nil },
+			payload: []byte("load=1"),
+		},
+		{
+			name: "Hook that fails",
+			hook: func(s *state.State) ([]byte, error) { return []byte("load=1"), context.Canceled },
+		},
+		{
+			name: "Payload larger than the maximum size",
+			hook: func(s *state.State) ([]byte, error) {
+				return bytes.Repeat([]byte("a"), config.MaxHeartbeatPayload+1), nil
+			},
+		},
+		{
+			name: "Hook that does not return in time",
+			hook: func(s *state.State) ([]byte, error) {
+				<-release
+				return []byte("load=1"), nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		state.HeartbeatPayloadHook = test.hook
+		s.Equal(test.payload, localHeartbeatPayload(st, 50*time.Millisecond))
+	}
+}
+
+// Ensures the heartbeat payloads hook runs in the background, and is skipped while its previous run is in progress.
+func (s *heartbeatSuite) Test_onHeartbeatPayloads() {
+	// Record when the last registered worker returns.
+	var done chan struct{}
+	st := &state.State{
+		Log: logger.AddContext(logger.Ctx{}),
+		RegisterWorker: func(worker func(ctx context.Context)) {
+			workerDone := make(chan struct{})
+			done = workerDone
+			go func() {
+				defer close(workerDone)
+				worker(context.Background())
+			}()
+		},
+	}
+
+	release := make(chan struct{})
+	ran := make(chan map[string][]byte, 3)
+	state.OnHeartbeatPayloadsHook = func(s *state.State, payloads map[string][]byte) error {
+		ran <- payloads
+		<-release
+		return nil
+	}
+
+	// No payloads don't run the hook.
+	onHeartbeatPayloads(st, nil)
+	s.Nil(done)
+
+	onHeartbeatPayloads(st, map[string][]byte{"a": []byte("first")})
+	firstDone := done
+	s.Equal([]byte("first"), (<-ran)["a"])
+
+	// The hook has not returned yet, so the next payloads are skipped.
+	onHeartbeatPayloads(st, map[string][]byte{"a": []byte("second")})
+	<-done
+	s.Empty(ran)
+
+	close(release)
+	<-firstDone
+
+	onHeartbeatPayloads(st, map[string][]byte{"a": []byte("third")})
+	<-done
+	s.Equal([]byte("third"), (<-ran)["a"])
+}
@@ -2,6 +2,7 @@ package resources
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -9,18 +10,49 @@ import (
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/gorilla/mux"
 
+	"github.com/canonical/microcluster/config"
 	"github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
+	apiTypes "github.com/canonical/microcluster/rest/types"
 )
 
 var hooksCmd = rest.Endpoint{
-	Path: "hooks/{hookType}",
+	Path:                 "hooks/{hookType}",
+	AllowedWithoutLeader: true,
 
 	Post: rest.EndpointAction{Handler: hooksPost, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
 }
 
+var runHookCmd = rest.Endpoint{
+	Path:                 "hooks/{hookType}",
+	AllowedWithoutLeader: true,
+
+	Post: rest.EndpointAction{Handler: runHookPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// runHookPost runs the named hook on demand with the given arguments, returning the error of the hook as is.
+func runHookPost(s *state.State, r *http.Request) response.Response {
+	hookType, err := url.PathUnescape(mux.Vars(r)["hookType"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var args apiTypes.HookArgs
+	err = json.NewDecoder(r.Body).Decode(&args)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = s.RunHook(r.Context(), hookType, args)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
 func hooksPost(s *state.State, r *http.Request) response.Response {
 	hookTypeStr, err := url.PathUnescape(mux.Vars(r)["hookType"])
 	if err != nil {
@@ -63,9 +95,26 @@ func hooksPost(s *state.State, r *http.Request) response.Response {
 		}
 
 		err = state.OnNewMemberHook(s)
+		var retry config.ErrRetryHook
+		if errors.As(err, &retry) {
+			return retryAfter(fmt.Errorf("Failed to run hook after system %q has joined the cluster: %w", req.Name, err), retry.After)
+		}
+
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed to run hook after system %q has joined the cluster: %w", req.Name, err))
 		}
+
+	case types.OnConfigChange:
+		var req types.HookConfigChangeOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = state.OnConfigChangeHook(r.Context(), s, req.OldConfig, req.NewConfig)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to execute config change hook on cluster member %q: %w", s.Name(), err))
+		}
 	default:
 		return response.SmartError(fmt.Errorf("No valid hook found for the given type"))
 	}
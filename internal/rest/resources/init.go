@@ -0,0 +1,26 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var initInfoCmd = rest.Endpoint{
+	Path: "init-info",
+
+	Get: rest.EndpointAction{Handler: initInfoGet, AccessHandler: access.AllowAuthenticated},
+}
+
+func initInfoGet(s *state.State, r *http.Request) response.Response {
+	info, err := s.InitInfo()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, info)
+}
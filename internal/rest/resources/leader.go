@@ -0,0 +1,157 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/cluster"
+	internalClient "github.com/canonical/microcluster/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/sys"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var leaderCmd = rest.Endpoint{
+	Path: "leader",
+
+	Post: rest.EndpointAction{Handler: leaderPost, AccessHandler: access.AllowAuthenticated},
+}
+
+func leaderPost(s *state.State, r *http.Request) response.Response {
+	req := internalTypes.LeaderTransfer{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = TransferLeadership(r.Context(), s, req.Target)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// TransferLeadership transfers dqlite leadership from this cluster member to the voter with the given name.
+// If no name is given, leadership is transferred to the reachable voter with the most recent heartbeat.
+func TransferLeadership(ctx context.Context, s *state.State, targetName string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = leader.Close() }()
+
+	leaderInfo, err := leader.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if leaderInfo.Address != s.Address().URL.Host {
+		return api.StatusErrorf(http.StatusConflict, "Cluster member %q is not the dqlite leader", s.Name())
+	}
+
+	nodes, err := leader.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	voterIDs := make(map[string]uint64, len(nodes))
+	for _, node := range nodes {
+		if node.Role == dqliteClient.Voter && node.Address != leaderInfo.Address {
+			voterIDs[node.Address] = node.ID
+		}
+	}
+
+	var clusterMembers []cluster.InternalClusterMember
+	err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		clusterMembers, err = cluster.GetInternalClusterMembers(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]cluster.InternalClusterMember, 0, len(voterIDs))
+	for _, member := range clusterMembers {
+		if targetName != "" && member.Name != targetName {
+			continue
+		}
+
+		_, ok := voterIDs[member.Address]
+		if !ok {
+			if targetName != "" {
+				return api.StatusErrorf(http.StatusBadRequest, "Cluster member %q is not a voter", targetName)
+			}
+
+			continue
+		}
+
+		candidates = append(candidates, member)
+	}
+
+	if len(candidates) == 0 {
+		if targetName != "" {
+			return api.StatusErrorf(http.StatusNotFound, "Cluster member %q not found", targetName)
+		}
+
+		return fmt.Errorf("Found no voters to transfer leadership to")
+	}
+
+	// Prefer the voters with the most recent heartbeats.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Heartbeat.After(candidates[j].Heartbeat)
+	})
+
+	var lastErr error
+	for _, candidate := range candidates {
+		err := checkMemberReachable(ctx, s, candidate.Address)
+		if err != nil {
+			lastErr = fmt.Errorf("Cluster member %q is unreachable: %w", candidate.Name, err)
+			continue
+		}
+
+		err = leader.Transfer(ctx, voterIDs[candidate.Address])
+		if err != nil {
+			return fmt.Errorf("Failed to transfer leadership to cluster member %q: %w", candidate.Name, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Failed to find a reachable voter to transfer leadership to: %w", lastErr)
+}
+
+// checkMemberReachable returns an error if the cluster member at the given address does not respond to requests.
+func checkMemberReachable(ctx context.Context, s *state.State, address string) error {
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	url := api.NewURL().Scheme(sys.Scheme()).Host(address)
+	c, err := internalClient.New(*url, s.ServerCert(), publicKey, false)
+	if err != nil {
+		return err
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, nil, nil, nil)
+}
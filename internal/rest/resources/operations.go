@@ -0,0 +1,61 @@
+package resources
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var operationsCmd = rest.Endpoint{
+	Path:              "operations",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: operationsGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var operationCmd = rest.Endpoint{
+	Path:                 "operations/{id}",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+
+	Get:    rest.EndpointAction{Handler: operationGet, AccessHandler: access.AllowAuthenticated},
+	Delete: rest.EndpointAction{Handler: operationDelete, AccessHandler: access.AllowAuthenticated},
+}
+
+func operationsGet(s *state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.Operations().List())
+}
+
+func operationGet(s *state.State, r *http.Request) response.Response {
+	id, err := url.PathUnescape(mux.Vars(r)["id"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	op, err := s.Operations().Get(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, op.Render())
+}
+
+func operationDelete(s *state.State, r *http.Request) response.Response {
+	id, err := url.PathUnescape(mux.Vars(r)["id"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = s.Operations().Cancel(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -23,6 +23,18 @@ func getWaitReady(state *state.State, r *http.Request) response.Response {
 		return response.Unavailable(fmt.Errorf("Daemon is shutting down"))
 	}
 
+	if state.Draining() {
+		return response.Unavailable(fmt.Errorf("Cluster member is draining"))
+	}
+
+	if state.Syncing() {
+		return response.Unavailable(fmt.Errorf("Cluster member is syncing with the cluster"))
+	}
+
+	if state.LowDisk() {
+		return response.Unavailable(fmt.Errorf("Database directory is low on disk space"))
+	}
+
 	select {
 	case <-state.ReadyCh:
 	default:
@@ -0,0 +1,38 @@
+package resources
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var recoverCmd = rest.Endpoint{
+	Path:                 "recover",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+
+	Post: rest.EndpointAction{Handler: recoverPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// recoverPost rewrites the dqlite cluster configuration of the local cluster member to the surviving members.
+func recoverPost(s *state.State, r *http.Request) response.Response {
+	var req internalTypes.QuorumRecovery
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = s.RecoverFromQuorumLoss(r.Context(), req.Members)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -2,9 +2,13 @@ package resources
 
 import (
 	"fmt"
+	"net/http"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/types"
 )
@@ -14,7 +18,12 @@ var UnixEndpoints = rest.Resources{
 	PathPrefix: internalTypes.ControlEndpoint,
 	Endpoints: []rest.Endpoint{
 		controlCmd,
+		checkJoinCmd,
 		shutdownCmd,
+		socketGroupCmd,
+		runHookCmd,
+		recoverCmd,
+		diagnosticsCmd,
 	},
 }
 
@@ -23,10 +32,24 @@ var PublicEndpoints = rest.Resources{
 	PathPrefix: internalTypes.PublicEndpoint,
 	Endpoints: []rest.Endpoint{
 		api10Cmd,
+		extensionsCmd,
 		clusterCmd,
 		clusterMemberCmd,
+		clusterMemberDrainCmd,
+		heartbeatHistoryCmd,
 		tokensCmd,
 		readyCmd,
+		operationsCmd,
+		operationCmd,
+		compatibilityCmd,
+		initInfoCmd,
+		schemaCmd,
+		rolesCmd,
+		healthCmd,
+		replicationCmd,
+		databaseDumpCmd,
+		endpointsCmd,
+		eventsCmd,
 	},
 }
 
@@ -39,37 +62,70 @@ var InternalEndpoints = rest.Resources{
 		sqlCmd,
 		tokenCmd,
 		heartbeatCmd,
+		heartbeatPauseCmd,
 		trustCmd,
 		trustEntryCmd,
+		trustRefreshCmd,
+		drainCmd,
 		hooksCmd,
+		configCmd,
+		leaderCmd,
+		roleCmd,
 	},
 }
 
+// DatabaseResources serve the database endpoint on the dedicated listener for dqlite connections, if there is one.
+var DatabaseResources = rest.Resources{
+	PathPrefix: internalTypes.InternalEndpoint,
+	Endpoints:  []rest.Endpoint{databaseCmd},
+}
+
+// ExtensionsResources serve the extensions endpoint on the listener of an extension server that restricts its
+// advertised extensions.
+var ExtensionsResources = rest.Resources{
+	PathPrefix: internalTypes.PublicEndpoint,
+	Endpoints:  []rest.Endpoint{extensionsCmd},
+}
+
 // ValidateEndpoints checks if any endpoints defined in extensionServers conflict with other endpoints.
 // An invalid server is defined as one of the following:
-// - The PathPrefix+Path of an endpoint conflicts with another endpoint in the same server.
+// - The path of an endpoint, or of one of its aliases, conflicts with another endpoint served on the same listener.
 // - The address of the server clashes with another server.
 // - The server does not have defined resources.
 // If the Server is a core API server, its resources must not conflict with any other server, and it must not have a defined address or certificate.
+// If the Server is served only over the unix socket, its resources must not conflict with any core API server, and it must not have any network
+// configuration.
+//
+// Paths conflict if they are the same, or differ only in the names of their path variables such as "{name}". A literal
+// segment next to a path variable, as with "cluster/certificates" and "cluster/{name}", is allowed. As each path is
+// handled by a single endpoint, paths conflict even if their endpoints define different methods.
 func ValidateEndpoints(extensionServers []rest.Server, coreAddress string) error {
-	allExistingEndpoints := []rest.Resources{UnixEndpoints, PublicEndpoints, InternalEndpoints}
-	existingEndpointPaths := make(map[string]bool)
 	serverAddresses := map[string]bool{coreAddress: true}
+	var coreTLSConfig *types.TLSConfig
 
-	// Record the paths for all internal endpoints.
-	for _, endpoints := range allExistingEndpoints {
-		for _, e := range endpoints.Endpoints {
-			url := filepath.Join(string(endpoints.PathPrefix), e.Path)
-			existingEndpointPaths[url] = true
-		}
-	}
+	// Record the routes of all internal endpoints, which may be served alongside any core API server.
+	coreRoutes := endpointRoutes("core API", UnixEndpoints, PublicEndpoints, InternalEndpoints)
+
+	for i, server := range extensionServers {
+		name := serverName(i, server)
 
-	for _, server := range extensionServers {
 		// Ensure all servers have resources.
 		if len(server.Resources) == 0 {
 			return fmt.Errorf("Server must have defined resources")
 		}
 
+		if server.UnixOnly && (server.CoreAPI || server.ServeUnix) {
+			return fmt.Errorf("Unix socket only server cannot also be a core API server")
+		}
+
+		if server.UnixOnly && (server.Address != (types.AddrPort{}) || server.Protocol != "" || server.Certificate != nil || server.TLSConfig != nil || server.AdvertisedExtensions != nil) {
+			return fmt.Errorf("Unix socket only server cannot have a pre-defined address, protocol, certificate, TLS configuration or advertised extensions")
+		}
+
+		if server.Optional && (server.CoreAPI || server.UnixOnly) {
+			return fmt.Errorf("Core API and unix socket only servers cannot be optional")
+		}
+
 		if server.ServeUnix && !server.CoreAPI {
 			return fmt.Errorf("Cannot serve non-core API resources over the core unix socket")
 		}
@@ -82,6 +138,34 @@ func ValidateEndpoints(extensionServers []rest.Server, coreAddress string) error
 			return fmt.Errorf("Core API server cannot have a pre-defined address")
 		}
 
+		if server.TLSConfig != nil {
+			err := server.TLSConfig.Validate()
+			if err != nil {
+				return fmt.Errorf("Invalid server TLS configuration: %w", err)
+			}
+
+			// All core API servers share the same listener, so they must agree on its TLS configuration.
+			if server.CoreAPI {
+				if coreTLSConfig != nil && !reflect.DeepEqual(coreTLSConfig, server.TLSConfig) {
+					return fmt.Errorf("Core API server TLS configuration conflicts with another core API server")
+				}
+
+				coreTLSConfig = server.TLSConfig
+			}
+		}
+
+		// Without TLS there is no client certificate to check, so restricted endpoints could never be reached over
+		// the network.
+		if sys.Plaintext() && !server.UnixOnly {
+			for _, resources := range server.Resources {
+				for _, e := range resources.Endpoints {
+					if e.AllowedCerts != nil {
+						return fmt.Errorf("Endpoint %q of %s restricts client certificates, which is not supported in plaintext mode", e.Path, name)
+					}
+				}
+			}
+		}
+
 		// Ensure all servers with a defined address are unique.
 		if server.Address != (types.AddrPort{}) {
 			if serverAddresses[server.Address.String()] {
@@ -93,18 +177,27 @@ func ValidateEndpoints(extensionServers []rest.Server, coreAddress string) error
 			return fmt.Errorf("Server protocol defined without address")
 		}
 
-		// Ensure no endpoint path conflicts with another endpoint on the same server.
-		// If a server lacks an address, we need to compare it to every other server
-		// that also lacks an address, as well as the internal endpoints.
-		serverPaths, err := resourcesConflict(server, existingEndpointPaths)
-		if err != nil {
-			return err
-		}
+		// Ensure no endpoint path conflicts with another endpoint on the same listener.
+		// Core API servers share the listener of the internal endpoints and every other core API server,
+		// while any other server only shares its listener with the extensions endpoint, if it serves it.
+		// Unix socket only servers share the unix socket with the core API, so they are held to the same rules.
+		routes := endpointRoutes(name, server.Resources...)
+		if server.CoreAPI || server.UnixOnly {
+			err := routesConflict(coreRoutes, routes)
+			if err != nil {
+				return err
+			}
+
+			coreRoutes = append(coreRoutes, routes...)
+		} else {
+			var existing []route
+			if server.Address != (types.AddrPort{}) && server.AdvertisedExtensions != nil {
+				existing = endpointRoutes("core API", ExtensionsResources)
+			}
 
-		// Update the existing paths now that we have checked a new Server.
-		for k, v := range serverPaths {
-			if !existingEndpointPaths[k] {
-				existingEndpointPaths[k] = v
+			err := routesConflict(existing, routes)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -112,30 +205,105 @@ func ValidateEndpoints(extensionServers []rest.Server, coreAddress string) error
 	return nil
 }
 
-// resourcesConflict returns an error if the endpoint paths of the given server conflict with any paths in the given map of existing paths.
-func resourcesConflict(server rest.Server, existingPaths map[string]bool) (map[string]bool, error) {
-	perServerPaths := map[string]bool{}
-	for _, resource := range server.Resources {
-		for _, endpoint := range resource.Endpoints {
-			url := filepath.Join(string(resource.PathPrefix), endpoint.Path)
+// serverName describes the extension server at the given index for error messages.
+func serverName(index int, server rest.Server) string {
+	if server.Name != "" {
+		return fmt.Sprintf("server %q", server.Name)
+	}
+
+	if server.Address != (types.AddrPort{}) {
+		return fmt.Sprintf("server %d at %q", index, server.Address.String())
+	}
+
+	return fmt.Sprintf("server %d", index)
+}
+
+// route is the full path of an endpoint, or of one of its aliases, as registered by a server.
+type route struct {
+	server  string
+	url     string
+	methods []string
+}
 
-			// If the server uses the core API, its resources must not conflict with any existing listener.
-			if server.CoreAPI {
-				if existingPaths[url] || perServerPaths[url] {
-					return nil, fmt.Errorf("Core endpoint %q conflicts with another endpoint", url)
-				}
+// endpointRoutes returns the routes of all endpoints and aliases in the given resources, registered by the named server.
+func endpointRoutes(server string, resources ...rest.Resources) []route {
+	routes := []route{}
+	for _, resource := range resources {
+		for _, e := range resource.Endpoints {
+			methods := endpointMethods(e)
+			routes = append(routes, route{server: server, url: filepath.Join(string(resource.PathPrefix), e.Path), methods: methods})
+			for _, alias := range e.Aliases {
+				routes = append(routes, route{server: server, url: filepath.Join(string(resource.PathPrefix), alias.Path), methods: methods})
+			}
+		}
+	}
 
-				perServerPaths[url] = true
-			} else {
-				// The server has a unique address, so only compare it to other resources in the server.
-				if perServerPaths[url] {
-					return nil, fmt.Errorf("Endpoint %q conflicts with another endpoint on the same server", url)
-				}
+	return routes
+}
+
+// endpointMethods returns the methods that the endpoint has a handler for.
+func endpointMethods(e rest.Endpoint) []string {
+	methods := []string{}
+	actions := []struct {
+		method string
+		action rest.EndpointAction
+	}{
+		{method: http.MethodGet, action: e.Get},
+		{method: http.MethodPut, action: e.Put},
+		{method: http.MethodPost, action: e.Post},
+		{method: http.MethodDelete, action: e.Delete},
+		{method: http.MethodPatch, action: e.Patch},
+	}
+
+	for _, a := range actions {
+		if a.action.Handler != nil {
+			methods = append(methods, a.method)
+		}
+	}
+
+	return methods
+}
 
-				perServerPaths[url] = true
+// routesConflict returns an error naming the conflicting servers and paths if any of the routes conflict with each
+// other, or with any of the existing routes.
+func routesConflict(existing []route, routes []route) error {
+	for i, r := range routes {
+		others := append(existing[:len(existing):len(existing)], routes[:i]...)
+		for _, other := range others {
+			if !pathsConflict(r.url, other.url) {
+				continue
 			}
+
+			return fmt.Errorf("Endpoint %q (%s) of %s conflicts with endpoint %q (%s) of %s", r.url, strings.Join(r.methods, ", "), r.server, other.url, strings.Join(other.methods, ", "), other.server)
+		}
+	}
+
+	return nil
+}
+
+// pathsConflict returns whether the two paths are the same, or differ only in the names of their path variables, such
+// as "{name}", so that only the first one registered can ever be matched. A literal segment next to a path variable is
+// not a conflict, as with "cluster/certificates" and "cluster/{name}", since the router matches routes in the order
+// they are registered.
+func pathsConflict(a string, b string) bool {
+	aParts := strings.Split(a, "/")
+	bParts := strings.Split(b, "/")
+	if len(aParts) != len(bParts) {
+		return false
+	}
+
+	for i := range aParts {
+		if aParts[i] == bParts[i] || (isPathVariable(aParts[i]) && isPathVariable(bParts[i])) {
+			continue
 		}
+
+		return false
 	}
 
-	return perServerPaths, nil
+	return true
+}
+
+// isPathVariable returns whether the path segment is a mux variable, such as "{name}".
+func isPathVariable(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
 }
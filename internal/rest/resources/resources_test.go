@@ -0,0 +1,259 @@
+package resources
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+
+	internalREST "github.com/canonical/microcluster/internal/rest"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+type resourcesSuite struct {
+	suite.Suite
+}
+
+func TestResourcesSuite(t *testing.T) {
+	suite.Run(t, new(resourcesSuite))
+}
+
+// newTestCert returns a new self-signed certificate.
+func newTestCert() (*x509.Certificate, error) {
+	cert, key, err := shared.GenerateMemCert(true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	certInfo, err := shared.KeyPairFromRaw(cert, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return certInfo.PublicKeyX509()
+}
+
+// Ensures the join, heartbeat and cluster member endpoints of a cluster member on a link-local IPv6 address handle
+// requests whose Host header has the zone stripped, and trust those presenting the certificate of a cluster member.
+func (s *resourcesSuite) Test_zonedAddressEndpoints() {
+	address := "[fe80::1%eth0]:9000"
+	addrPort, err := types.ParseAddrPort(address)
+	s.Require().NoError(err)
+
+	trustedCert, err := newTestCert()
+	s.Require().NoError(err)
+
+	untrustedCert, err := newTestCert()
+	s.Require().NoError(err)
+
+	remotes := &trust.Remotes{}
+	err = remotes.Replace(s.T().TempDir(), "", internalTypes.ClusterMember{
+		ClusterMemberLocal: internalTypes.ClusterMemberLocal{
+			Name:        "c1",
+			Address:     addrPort,
+			Certificate: types.X509Certificate{Certificate: trustedCert},
+		},
+	})
+	s.Require().NoError(err)
+
+	st := &state.State{
+		Context:  context.Background(),
+		Log:      logger.AddContext(logger.Ctx{}),
+		Address:  func() *api.URL { return api.NewURL().Scheme("https").Host(address) },
+		Name:     func() string { return "c1" },
+		Remotes:  func() *trust.Remotes { return remotes },
+		Draining: func() bool { return false },
+		Syncing:  func() bool { return false },
+	}
+
+	router := mux.NewRouter()
+	internalREST.HandleEndpoint(st, router, string(internalTypes.PublicEndpoint), clusterCmd, nil)
+	internalREST.HandleEndpoint(st, router, string(internalTypes.InternalEndpoint), heartbeatCmd, nil)
+
+	// Without a database, requests that pass authentication fail as unavailable, rather than as forbidden.
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		cert   *x509.Certificate
+		status int
+	}{
+		{
+			name:   "Join request with the zone stripped from the Host header",
+			method: "POST",
+			url:    "https://[fe80::1]:9000/cluster/1.0/cluster",
+			cert:   untrustedCert,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "Heartbeat with the zone stripped from the Host header",
+			method: "POST",
+			url:    "https://[fe80::1]:9000/cluster/internal/heartbeat",
+			cert:   trustedCert,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "Trusted request with the zone stripped from the Host header",
+			method: "GET",
+			url:    "https://[fe80::1]:9000/cluster/1.0/cluster",
+			cert:   trustedCert,
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "Untrusted request with the zone stripped from the Host header",
+			method: "GET",
+			url:    "https://[fe80::1]:9000/cluster/1.0/cluster",
+			cert:   untrustedCert,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Trusted request to another address",
+			method: "GET",
+			url:    "https://[fe80::2]:9000/cluster/1.0/cluster",
+			cert:   trustedCert,
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		r := httptest.NewRequest(test.method, test.url, strings.NewReader("{}"))
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{test.cert}}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		s.Equal(test.status, w.Code, w.Body.String())
+	}
+}
+
+// Ensures only paths that are the same, or differ only in the names of their path variables, conflict.
+func (s *resourcesSuite) Test_pathsConflict() {
+	tests := []struct {
+		a        string
+		b        string
+		conflict bool
+	}{
+		{a: "1.0/foo", b: "1.0/foo", conflict: true},
+		{a: "1.0/foo/{name}", b: "1.0/foo/{id}", conflict: true},
+		{a: "1.0/{a}/{b}", b: "1.0/{c}/{d}", conflict: true},
+		{a: "1.0/foo", b: "1.0/bar"},
+		{a: "1.0/foo/bar", b: "1.0/foo/{name}"},
+		{a: "1.0/{name}/bar", b: "1.0/foo/{name}"},
+		{a: "1.0/foo/{name}", b: "1.0/foo/{name}/bar"},
+		{a: "1.0/foo", b: "2.0/foo"},
+	}
+
+	for _, test := range tests {
+		s.T().Logf("%q and %q", test.a, test.b)
+
+		s.Equal(test.conflict, pathsConflict(test.a, test.b))
+		s.Equal(test.conflict, pathsConflict(test.b, test.a))
+	}
+}
+
+// Ensures endpoint paths only conflict with the paths of endpoints served on the same listener.
+func (s *resourcesSuite) Test_validateEndpointPaths() {
+	handler := func(s *state.State, r *http.Request) response.Response { return response.EmptySyncResponse }
+	resources := func(prefix types.EndpointPrefix, paths ...string) []rest.Resources {
+		endpoints := make([]rest.Endpoint, 0, len(paths))
+		for _, path := range paths {
+			endpoints = append(endpoints, rest.Endpoint{Path: path, Get: rest.EndpointAction{Handler: handler}})
+		}
+
+		return []rest.Resources{{PathPrefix: prefix, Endpoints: endpoints}}
+	}
+
+	address := func(port int) types.AddrPort {
+		addrPort, err := types.ParseAddrPort(fmt.Sprintf("10.0.0.1:%d", port))
+		s.Require().NoError(err)
+
+		return addrPort
+	}
+
+	tests := []struct {
+		name      string
+		servers   []rest.Server
+		expectErr bool
+	}{
+		{
+			name:    "Literal path next to a path variable",
+			servers: []rest.Server{{CoreAPI: true, Resources: resources("1.0", "foo/bar", "foo/{name}")}},
+		},
+		{
+			name:    "Core API path next to a core endpoint with a path variable",
+			servers: []rest.Server{{CoreAPI: true, Resources: resources(internalTypes.PublicEndpoint, "cluster/example")}},
+		},
+		{
+			name:      "Same path in one server",
+			servers:   []rest.Server{{CoreAPI: true, Resources: resources("1.0", "foo", "foo")}},
+			expectErr: true,
+		},
+		{
+			name:      "Paths differing only in their path variables in one server",
+			servers:   []rest.Server{{CoreAPI: true, Resources: resources("1.0", "foo/{name}", "foo/{id}")}},
+			expectErr: true,
+		},
+		{
+			name:      "Same path as a core endpoint",
+			servers:   []rest.Server{{CoreAPI: true, Resources: resources(internalTypes.PublicEndpoint, "cluster/{member}")}},
+			expectErr: true,
+		},
+		{
+			name: "Same path in two core API servers",
+			servers: []rest.Server{
+				{CoreAPI: true, Resources: resources("1.0", "foo")},
+				{CoreAPI: true, Resources: resources("1.0", "foo")},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Same path in a unix socket only server and a core API server",
+			servers: []rest.Server{
+				{CoreAPI: true, Resources: resources("1.0", "foo")},
+				{UnixOnly: true, Resources: resources("1.0", "foo")},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Same path in servers with their own listeners",
+			servers: []rest.Server{
+				{Address: address(9001), Resources: resources("1.0", "foo")},
+				{Address: address(9002), Resources: resources("1.0", "foo")},
+			},
+		},
+		{
+			name: "Same path in a core API server and a server with its own listener",
+			servers: []rest.Server{
+				{CoreAPI: true, Resources: resources("1.0", "foo")},
+				{Address: address(9001), Resources: resources("1.0", "foo")},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		err := ValidateEndpoints(test.servers, "10.0.0.1:9000")
+		if test.expectErr {
+			s.Error(err)
+		} else {
+			s.NoError(err)
+		}
+	}
+}
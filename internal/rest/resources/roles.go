@@ -0,0 +1,237 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/cluster"
+	internalClient "github.com/canonical/microcluster/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+var rolesCmd = rest.Endpoint{
+	Path: "roles",
+
+	Get: rest.EndpointAction{Handler: rolesGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var healthCmd = rest.Endpoint{
+	Path:                 "health",
+	AllowedWithoutLeader: true,
+
+	Get: rest.EndpointAction{Handler: healthGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var replicationCmd = rest.Endpoint{
+	Path: "replication",
+
+	Get: rest.EndpointAction{Handler: replicationGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+var roleCmd = rest.Endpoint{
+	Path: "role",
+
+	Post: rest.EndpointAction{Handler: rolePost, AccessHandler: access.AllowAuthenticated},
+}
+
+func rolesGet(s *state.State, r *http.Request) response.Response {
+	roles, err := s.ClusterRoles(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, roles)
+}
+
+func healthGet(s *state.State, r *http.Request) response.Response {
+	var health *types.ClusterHealth
+	var err error
+
+	// Requests forwarded by another cluster member are answered from this member, which they believe is the leader.
+	if internalClient.IsForwardedRequest(r) {
+		health, err = s.LocalClusterHealth(r.Context())
+	} else {
+		health, err = s.ClusterHealth(r.Context())
+	}
+
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, health)
+}
+
+func replicationGet(s *state.State, r *http.Request) response.Response {
+	status, err := s.ReplicationStatus(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, status)
+}
+
+func rolePost(s *state.State, r *http.Request) response.Response {
+	req := internalTypes.RoleAssignment{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	leaderAddress, err := s.LeaderAddress(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Forward the request to the leader, so that the role is assigned from there.
+	if leaderAddress.String() != s.Address().URL.Host {
+		client, err := s.Leader()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = client.AssignRole(r.Context(), req)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	err = s.Operations().Run(r.Context(), fmt.Sprintf("Assigning role %q to cluster member %q", req.Role, req.Name), func(ctx context.Context) error {
+		return assignRole(ctx, s, req.Name, req.Role)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// assignRole assigns the given dqlite role to the cluster member with the given name, and records it as the role
+// requested for the member. It must be run on the dqlite leader. The last voter and the leader itself can't be demoted,
+// and read-only replicas can only be spares.
+func assignRole(ctx context.Context, s *state.State, name string, roleName string) error {
+	var role dqliteClient.NodeRole
+	switch roleName {
+	case dqliteClient.Voter.String():
+		role = dqliteClient.Voter
+	case dqliteClient.StandBy.String():
+		role = dqliteClient.StandBy
+	case dqliteClient.Spare.String():
+		role = dqliteClient.Spare
+	default:
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid role %q, must be one of %q, %q, or %q", roleName, dqliteClient.Voter.String(), dqliteClient.StandBy.String(), dqliteClient.Spare.String())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	leader, err := s.Database.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = leader.Close() }()
+
+	leaderInfo, err := leader.Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if leaderInfo.Address != s.Address().URL.Host {
+		return api.StatusErrorf(http.StatusConflict, "Cluster member %q is not the dqlite leader", s.Name())
+	}
+
+	var member *cluster.InternalClusterMember
+	var replicaOnly map[string]bool
+	err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		member, err = cluster.GetInternalClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		replicaOnly, err = cluster.GetReplicaOnlyClusterMembers(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	nodes, err := leader.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	var target *dqliteClient.NodeInfo
+	voters := 0
+	for i, node := range nodes {
+		if node.Role == dqliteClient.Voter {
+			voters++
+		}
+
+		if node.Address == member.Address {
+			target = &nodes[i]
+		}
+	}
+
+	if target == nil {
+		return api.StatusErrorf(http.StatusNotFound, "Cluster member %q is not part of the dqlite cluster", name)
+	}
+
+	if replicaOnly[name] && role != dqliteClient.Spare {
+		return api.StatusErrorf(http.StatusBadRequest, "Cannot assign role %q to %q, a read-only replica", roleName, name)
+	}
+
+	if target.Role == dqliteClient.Voter && role != dqliteClient.Voter {
+		if target.Address == leaderInfo.Address {
+			return api.StatusErrorf(http.StatusBadRequest, "Cannot demote the dqlite leader %q, transfer leadership first", name)
+		}
+
+		if voters <= 1 {
+			return api.StatusErrorf(http.StatusBadRequest, "Cannot demote %q, the last voter of the cluster", name)
+		}
+	}
+
+	if target.Role != role {
+		err = leader.Assign(ctx, target.ID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.SetRequestedRole(ctx, tx, name, roleName)
+	})
+}
+
+// reconcileRequestedRoles assigns the role requested by an operator to each cluster member whose dqlite role has since
+// been changed, for example by dqlite's own role adjustment. The given roles are the current dqlite roles, keyed by
+// address. It must be run on the dqlite leader.
+func reconcileRequestedRoles(ctx context.Context, s *state.State, clusterMembers []internalTypes.ClusterMember, roles map[string]string) {
+	for _, member := range clusterMembers {
+		role, ok := roles[member.Address.String()]
+		if !ok || member.RequestedRole == "" || member.RequestedRole == role {
+			continue
+		}
+
+		s.Log.Info("Restoring requested role of cluster member", logger.Ctx{"name": member.Name, "role": role, "requested": member.RequestedRole})
+		err := assignRole(ctx, s, member.Name, member.RequestedRole)
+		if err != nil {
+			s.Log.Warn("Failed to restore requested role of cluster member", logger.Ctx{"name": member.Name, "requested": member.RequestedRole, "error": err})
+		}
+	}
+}
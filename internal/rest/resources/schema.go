@@ -0,0 +1,22 @@
+package resources
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var schemaCmd = rest.Endpoint{
+	Path:              "schema",
+	AllowedBeforeInit: true,
+
+	Get: rest.EndpointAction{Handler: schemaGet, AccessHandler: access.AllowAuthenticated},
+}
+
+func schemaGet(s *state.State, r *http.Request) response.Response {
+	return response.SyncResponse(true, s.SchemaStatus())
+}
@@ -13,8 +13,10 @@ import (
 )
 
 var shutdownCmd = rest.Endpoint{
-	AllowedBeforeInit: true,
-	Path:              "shutdown",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+	Path:                 "shutdown",
 
 	Post: rest.EndpointAction{Handler: shutdownPost, AccessHandler: access.AllowAuthenticated},
 }
@@ -31,7 +33,8 @@ func shutdownPost(state *state.State, r *http.Request) response.Response {
 		}
 
 		// Run shutdown sequence synchronously.
-		exit, stopErr := state.Stop()
+		force := r.URL.Query().Get("force") == "1"
+		exit, stopErr := state.Stop(force)
 		err := response.SmartError(stopErr).Render(w)
 		if err != nil {
 			return err
@@ -0,0 +1,37 @@
+package resources
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/rest"
+	"github.com/canonical/microcluster/rest/access"
+)
+
+var socketGroupCmd = rest.Endpoint{
+	Path:                 "socket-group",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+	AllowedWhileDraining: true,
+
+	Put: rest.EndpointAction{Handler: socketGroupPut, AccessHandler: access.AllowAuthenticated},
+}
+
+func socketGroupPut(s *state.State, r *http.Request) response.Response {
+	req := internalTypes.SocketGroup{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = s.SetSocketGroup(r.Context(), req.Group)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
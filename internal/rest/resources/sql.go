@@ -23,8 +23,20 @@ import (
 var sqlCmd = rest.Endpoint{
 	Path: "sql",
 
-	Get:  rest.EndpointAction{Handler: sqlGet, AccessHandler: access.AllowAuthenticated},
-	Post: rest.EndpointAction{Handler: sqlPost, AccessHandler: access.AllowAuthenticated},
+	Get:  rest.EndpointAction{Handler: sqlGet, AccessHandler: allowSQL},
+	Post: rest.EndpointAction{Handler: sqlPost, AccessHandler: allowSQL},
+}
+
+// sqlMaxResultBytes is the approximate maximum size of the rows returned for a batch of SQL queries.
+const sqlMaxResultBytes = 32 * 1024 * 1024
+
+// allowSQL only allows requests over the unix socket, unless remote SQL access is enabled on the daemon.
+func allowSQL(state *state.State, r *http.Request) response.Response {
+	if r.RemoteAddr != "@" && !state.AllowRemoteSQL {
+		return response.Forbidden(fmt.Errorf("SQL queries are only allowed over the unix socket"))
+	}
+
+	return access.AllowAuthenticated(state, r)
 }
 
 // Perform a database dump.
@@ -71,16 +83,22 @@ func sqlPost(state *state.State, r *http.Request) response.Response {
 	// TODO: Handle .sync query.
 
 	batch := types.SQLBatch{}
+	remainingBytes := sqlMaxResultBytes
 	for _, query := range strings.Split(req.Query, ";") {
-		query = strings.TrimLeft(query, " ")
+		query = strings.TrimSpace(query)
 		if query == "" {
 			continue
 		}
 
+		isSelect := strings.HasPrefix(strings.ToUpper(query), "SELECT")
+		if req.ReadOnly && !isSelect {
+			return response.BadRequest(fmt.Errorf("Only SELECT queries are allowed in read-only mode"))
+		}
+
 		result := types.SQLResult{}
 		err = state.Database.Transaction(parentCtx, func(ctx context.Context, tx *sql.Tx) error {
-			if strings.HasPrefix(strings.ToUpper(query), "SELECT") {
-				err = sqlSelect(ctx, tx, query, &result)
+			if isSelect {
+				err = sqlSelect(ctx, tx, query, &result, &remainingBytes)
 			} else {
 				err = sqlExec(ctx, tx, query, &result)
 			}
@@ -97,7 +115,9 @@ func sqlPost(state *state.State, r *http.Request) response.Response {
 	return response.SyncResponse(true, batch)
 }
 
-func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
+// sqlSelect runs the query and records the returned rows in the result. Once the approximate size of the rows
+// exceeds the remaining bytes, no more rows are returned and the result is marked as truncated.
+func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult, remainingBytes *int) error {
 	result.Type = "select"
 	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
@@ -128,15 +148,27 @@ func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLR
 			return fmt.Errorf("Failed to scan row: %w", err)
 		}
 
+		size := 0
 		for i, column := range row {
 			// Convert bytes to string. This is safe as
 			// long as we don't have any BLOB column type.
 			data, ok := column.([]byte)
 			if ok {
 				row[i] = string(data)
+				size += len(data)
+			} else if str, ok := column.(string); ok {
+				size += len(str)
+			} else {
+				size += 8
 			}
 		}
 
+		if size > *remainingBytes {
+			result.Truncated = true
+			break
+		}
+
+		*remainingBytes -= size
 		result.Rows = append(result.Rows, row)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/canonical/microcluster/cluster"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/trust"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
 	"github.com/canonical/microcluster/rest/types"
@@ -34,6 +36,10 @@ var tokenCmd = rest.Endpoint{
 }
 
 func tokensPost(state *state.State, r *http.Request) response.Response {
+	if state.NetworkDisabled {
+		return response.BadRequest(fmt.Errorf("Cannot issue join tokens while the network API is disabled"))
+	}
+
 	req := internalTypes.TokenRecord{}
 
 	// Parse the request.
@@ -42,6 +48,16 @@ func tokensPost(state *state.State, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = trust.ValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, ok := state.Remotes().RemotesByName()[req.Name]
+	if ok {
+		return response.SmartError(trust.ErrNameInUse(req.Name))
+	}
+
 	// Generate join token for new member. This will be stored alongside the join
 	// address and cluster certificate to simplify setup.
 	tokenKey, err := shared.RandomCryptoString()
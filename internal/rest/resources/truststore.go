@@ -2,6 +2,7 @@ package resources
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,28 +13,49 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/cluster"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
 	"github.com/canonical/microcluster/internal/trust"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 var trustCmd = rest.Endpoint{
-	Path:              "truststore",
-	AllowedBeforeInit: true,
+	Path:                 "truststore",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
 
 	Post: rest.EndpointAction{Handler: trustPost, AccessHandler: access.AllowAuthenticated},
 }
 
 var trustEntryCmd = rest.Endpoint{
-	Path:              "truststore/{name}",
-	AllowedBeforeInit: true,
+	Path:                 "truststore/{name}",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
 
 	Delete: rest.EndpointAction{Handler: trustDelete, AccessHandler: access.AllowAuthenticated},
 }
 
+var trustRefreshCmd = rest.Endpoint{
+	Path:                 "truststore-refresh",
+	AllowedBeforeInit:    true,
+	AllowedWithoutLeader: true,
+
+	Post: rest.EndpointAction{Handler: trustRefreshPost, AccessHandler: access.AllowAuthenticated},
+}
+
+func trustRefreshPost(s *state.State, r *http.Request) response.Response {
+	count, err := s.RefreshTrust(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, types.TrustStoreRefresh{Remotes: count})
+}
+
 func trustPost(s *state.State, r *http.Request) response.Response {
 	req := internalTypes.ClusterMemberLocal{}
 
@@ -44,14 +66,28 @@ func trustPost(s *state.State, r *http.Request) response.Response {
 	}
 
 	newRemote := trust.Remote{
-		Location:    trust.Location{Name: req.Name, Address: req.Address},
-		Certificate: req.Certificate,
+		Location:      trust.Location{Name: req.Name, Address: req.Address},
+		Certificate:   req.Certificate,
+		DqliteAddress: req.DqliteAddress,
 	}
 
 	ctx, cancel := context.WithTimeout(s.Context, 30*time.Second)
 	defer cancel()
 
+	remotes := s.Remotes()
+	_, ok := remotes.RemotesByName()[newRemote.Name]
 	if !client.IsNotification(r) {
+		// If the member already exists, record the given certificate in the database before it is accepted by any
+		// cluster member, so that it is kept when the trust store is next replaced from the database.
+		if ok {
+			err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+				return cluster.AddAdditionalCertificate(ctx, tx, req.Name, req.Certificate)
+			})
+			if err != nil {
+				return response.SmartError(fmt.Errorf("Failed to record certificate of cluster member %q: %w", req.Name, err))
+			}
+		}
+
 		cluster, err := s.Cluster(true)
 		if err != nil {
 			return response.SmartError(err)
@@ -71,13 +107,19 @@ func trustPost(s *state.State, r *http.Request) response.Response {
 	}
 
 	// At this point, the node has joined dqlite so we can add a local record for it if we haven't already from a heartbeat (or if we are the leader).
-	remotes := s.Remotes()
-	_, ok := remotes.RemotesByName()[newRemote.Name]
 	if !ok {
-		err = remotes.Add(s.OS.TrustDir, newRemote)
+		err = remotes.Add(s.OS.TrustDir, r.RemoteAddr, newRemote)
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed adding local record of newly joined node %q: %w", req.Name, err))
 		}
+
+		return response.EmptySyncResponse
+	}
+
+	// If the member already exists, accept the given certificate alongside its existing ones, such as during rotation.
+	err = remotes.AddCertificate(s.OS.TrustDir, r.RemoteAddr, req.Name, req.Certificate)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed adding certificate of cluster member %q: %w", req.Name, err))
 	}
 
 	return response.EmptySyncResponse
@@ -125,16 +167,18 @@ func trustDelete(s *state.State, r *http.Request) response.Response {
 	for _, remote := range remotesMap {
 		newRemote := internalTypes.ClusterMember{
 			ClusterMemberLocal: internalTypes.ClusterMemberLocal{
-				Name:        remote.Name,
-				Address:     remote.Address,
-				Certificate: remote.Certificate,
+				Name:                   remote.Name,
+				Address:                remote.Address,
+				Certificate:            remote.Certificate,
+				DqliteAddress:          remote.DqliteAddress,
+				AdditionalCertificates: remote.AdditionalCertificates,
 			},
 		}
 
 		newRemotes = append(newRemotes, newRemote)
 	}
 
-	err = remotes.Replace(s.OS.TrustDir, newRemotes...)
+	err = remotes.Replace(s.OS.TrustDir, r.RemoteAddr, newRemotes...)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Failed to remove truststore entry for node with name %q: %w", name, err))
 	}
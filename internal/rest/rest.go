@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
@@ -18,12 +21,15 @@ import (
 	"github.com/canonical/microcluster/cluster"
 	internalAccess "github.com/canonical/microcluster/internal/rest/access"
 	"github.com/canonical/microcluster/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/rest"
 	"github.com/canonical/microcluster/rest/access"
+	"github.com/canonical/microcluster/rest/types"
 )
 
-func handleAPIRequest(action rest.EndpointAction, state *state.State, w http.ResponseWriter, r *http.Request) response.Response {
+func handleAPIRequest(e rest.Endpoint, action rest.EndpointAction, state *state.State, w http.ResponseWriter, r *http.Request) response.Response {
 	if action.Handler == nil {
 		return response.NotImplemented(nil)
 	}
@@ -44,6 +50,19 @@ func handleAPIRequest(action rest.EndpointAction, state *state.State, w http.Res
 		}
 	}
 
+	// Return Unavailable Error (503) for mutating requests if the cluster leader can't be reached. This is only checked
+	// once the request is trusted, so that untrusted clients can't have the member dial the leader.
+	err := checkLeaderReachable(state, e, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Wait until the write that the consistency token of a read was issued for is committed.
+	err = waitConsistency(state, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	if action.ProxyTarget {
 		return proxyTarget(action, state, r)
 	}
@@ -51,6 +70,40 @@ func handleAPIRequest(action rest.EndpointAction, state *state.State, w http.Res
 	return action.Handler(state, r)
 }
 
+// consistencyWaitTimeout is how long a read may wait for the write its consistency token was issued for to be
+// committed.
+const consistencyWaitTimeout = 10 * time.Second
+
+// waitConsistency waits until the consistency index in the token of a read request, if any, is committed. The index is
+// read through the dqlite leader, as are the queries of the handler, so this does not wait for the local replica.
+// Requests forwarded by another cluster member are not delayed, as the forwarding member has already waited.
+func waitConsistency(s *state.State, r *http.Request) error {
+	token := r.Header.Get(types.ConsistencyTokenHeader)
+	if r.Method != http.MethodGet || token == "" || client.IsForwardedRequest(r) {
+		return nil
+	}
+
+	index, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid consistency token %q", token)
+	}
+
+	// Without a database there is no replicated state to catch up with.
+	if s.Database.IsOpen(r.Context()) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), consistencyWaitTimeout)
+	defer cancel()
+
+	err = s.Database.WaitConsistencyIndex(ctx, index)
+	if err != nil {
+		return api.StatusErrorf(http.StatusServiceUnavailable, "Failed to observe consistency token %q: %v", token, err)
+	}
+
+	return nil
+}
+
 func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) response.Response {
 	if r.URL == nil {
 		return action.Handler(s, r)
@@ -58,7 +111,7 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 
 	values, err := url.ParseQuery(r.URL.RawQuery)
 	if err != nil {
-		logger.Warnf("Failed to parse query string %q: %v", r.URL.RawQuery, err)
+		s.Log.Warn("Failed to parse query string", logger.Ctx{"query": r.URL.RawQuery, "error": err})
 	}
 
 	var target string
@@ -77,7 +130,7 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 			return fmt.Errorf("Failed to get cluster member for request target name %q: %w", target, err)
 		}
 
-		targetURL = api.NewURL().Scheme("https").Host(clusterMember.Address).Path(r.URL.Path)
+		targetURL = api.NewURL().Scheme(sys.Scheme()).Host(clusterMember.Address).Path(r.URL.Path)
 
 		return nil
 	})
@@ -101,7 +154,7 @@ func proxyTarget(action rest.EndpointAction, s *state.State, r *http.Request) re
 	r.URL.Host = targetURL.URL.Host
 	r.Host = targetURL.URL.Host
 
-	logger.Info("Forwarding request to specified target", logger.Ctx{"source": s.Name(), "target": target})
+	s.Log.Info("Forwarding request to specified target", logger.Ctx{"source": s.Name(), "target": target})
 	resp, err := client.MakeRequest(r)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Failed to send request to target %q: %w", target, err))
@@ -131,6 +184,11 @@ func handleDatabaseRequest(action rest.EndpointAction, state *state.State, w htt
 
 	// If the request is a POST, then it is likely from the dqlite dial function, so hijack the connection.
 	if r.Method == "POST" {
+		// Only accept dqlite connections on the dedicated listener, if there is one.
+		if state.Database.DqliteAddress() != "" && !isDqliteListener(r.Context()) {
+			return response.Forbidden(fmt.Errorf("Dqlite connections are only accepted at %q", state.Database.DqliteAddress()))
+		}
+
 		hijacker, ok := w.(http.Hijacker)
 		if !ok {
 			return response.InternalError(fmt.Errorf("Webserver does not support hijacking"))
@@ -141,21 +199,134 @@ func handleDatabaseRequest(action rest.EndpointAction, state *state.State, w htt
 			return response.InternalError(fmt.Errorf("Failed to hijack connection: %w", err))
 		}
 
+		// The connection is used by dqlite for as long as it stays open, so clear any deadlines set by the server timeouts.
+		err = conn.SetDeadline(time.Time{})
+		if err != nil {
+			_ = conn.Close()
+			return response.InternalError(fmt.Errorf("Failed to clear deadlines of hijacked connection: %w", err))
+		}
+
 		state.Database.Accept(conn)
 	}
 
 	return action.Handler(state, r)
 }
 
+// checkLeaderReachable returns an error if the request would modify state but the cluster leader can't be reached.
+// Read requests, requests to endpoints with AllowedWithoutLeader, and requests made before the database is open are always allowed.
+func checkLeaderReachable(state *state.State, e rest.Endpoint, r *http.Request) error {
+	if e.AllowedWithoutLeader || r.Method == "GET" {
+		return nil
+	}
+
+	if state.Database.IsOpen(r.Context()) != nil {
+		return nil
+	}
+
+	return state.Database.LeaderReachable(r.Context())
+}
+
+// authenticate runs the endpoint's Authenticator, if any, for requests over the network, returning whether it trusts
+// the request, or an error if it rejected it.
+func authenticate(state *state.State, e rest.Endpoint, r *http.Request) (bool, error) {
+	if e.Authenticator == nil || r.RemoteAddr == "@" {
+		return false, nil
+	}
+
+	return e.Authenticator.Authenticate(state, r)
+}
+
+// unauthorized returns a Forbidden (403) response if the error has that status, and an Unauthorized (401) response
+// otherwise.
+func unauthorized(err error) response.Response {
+	if api.StatusErrorCheck(err, http.StatusForbidden) {
+		return response.Forbidden(err)
+	}
+
+	return response.ErrorResponse(http.StatusUnauthorized, fmt.Sprintf("Failed to authenticate request: %v", err))
+}
+
+// isRead returns whether the request only reads state, by its method.
+func isRead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+}
+
+// certAllowed returns whether the endpoint's AllowedCerts, if any, accepts the verified client certificate of the
+// request. Requests over the unix socket are always allowed, while requests without a client certificate are not.
+func certAllowed(state *state.State, e rest.Endpoint, r *http.Request) bool {
+	if e.AllowedCerts == nil || r.RemoteAddr == "@" {
+		return true
+	}
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+
+	return e.AllowedCerts(state, r.TLS.PeerCertificates[0])
+}
+
+// DefaultMaxRequestBytes is the default maximum size of request bodies if no limit is set for the daemon, server, or endpoint.
+const DefaultMaxRequestBytes int64 = 32 * 1024 * 1024
+
+// limitedBody wraps a request body in http.MaxBytesReader and records whether the limit was exceeded,
+// so that the request can be rejected regardless of how the handler reports the read error.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+// requestTooLarge returns a 413 response for a request whose body exceeds the given limit.
+func requestTooLarge(limit int64) response.Response {
+	return response.ErrorResponse(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds the limit of %d bytes", limit))
+}
+
+// tryAcquire takes a slot of the semaphore without blocking, returning false if none are free.
+// A nil semaphore is unlimited.
+func tryAcquire(sem chan struct{}) bool {
+	if sem == nil {
+		return true
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release returns a slot taken from the semaphore with tryAcquire.
+func release(sem chan struct{}) {
+	if sem != nil {
+		<-sem
+	}
+}
+
 // HandleEndpoint adds the endpoint to the mux router. A function variable is used to implement common logic
 // before calling the endpoint action handler associated with the request method, if it exists.
-func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.Endpoint) {
+// Request bodies are limited to the endpoint's MaxRequestBytes, if positive, and the number of requests handled at once
+// by the inFlight semaphore, if not nil. The same semaphore should be given for every path of an endpoint, so that
+// its aliases share its MaxConcurrent.
+// Any middleware is applied around the endpoint handler in the given order, with the first middleware outermost.
+func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.Endpoint, inFlight chan struct{}, middleware ...func(http.Handler) http.Handler) {
 	url := "/" + version
 	if e.Path != "" {
 		url = filepath.Join(url, e.Path)
 	}
 
-	route := mux.HandleFunc(url, func(w http.ResponseWriter, r *http.Request) {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
 		// Actually process the request.
@@ -165,7 +336,40 @@ func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.
 		if state.Context.Err() == context.Canceled && !e.AllowedDuringShutdown {
 			err := response.Unavailable(fmt.Errorf("Daemon is shutting down")).Render(w)
 			if err != nil {
-				logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+				state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		// Return Forbidden Error (403) for mutating requests over the network if the member is a read-only replica.
+		// Internal endpoints, and requests forwarded from other cluster members, are still allowed.
+		if !isRead(r) && state.ReplicaOnly && r.RemoteAddr != "@" && version != string(internalTypes.InternalEndpoint) && !client.IsForwardedRequest(r) {
+			err := response.Forbidden(fmt.Errorf("Cluster member is a read-only replica")).Render(w)
+			if err != nil {
+				state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		// Return Unavailable Error (503) for mutating requests if the member is draining, except for endpoints with AllowedWhileDraining.
+		// Requests forwarded from other cluster members are still allowed, so that the member can be removed.
+		if !isRead(r) && !e.AllowedWhileDraining && !client.IsForwardedRequest(r) && state.Draining() {
+			err := response.Unavailable(fmt.Errorf("Cluster member is draining")).Render(w)
+			if err != nil {
+				state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		// Return Unavailable Error (503) for reads over the network if the member is still syncing after joining the
+		// cluster or restarting, if configured to. Internal endpoints, and endpoints allowed before initialization, are still allowed.
+		if r.Method == http.MethodGet && state.RejectReadsWhileSyncing && r.RemoteAddr != "@" && version != string(internalTypes.InternalEndpoint) && !e.AllowedBeforeInit && !client.IsForwardedRequest(r) && state.Syncing() {
+			err := response.Unavailable(fmt.Errorf("Cluster member is syncing with the cluster")).Render(w)
+			if err != nil {
+				state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
 			}
 
 			return
@@ -176,7 +380,7 @@ func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.
 			if err != nil {
 				err := response.SmartError(err).Render(w)
 				if err != nil {
-					logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+					state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
 				}
 
 				return
@@ -184,45 +388,111 @@ func HandleEndpoint(state *state.State, mux *mux.Router, version string, e rest.
 		}
 
 		// If the request is a database request, the connection should be hijacked.
-		handleRequest := handleAPIRequest
+		handleRequest := func(action rest.EndpointAction) response.Response {
+			return handleAPIRequest(e, action, state, w, r)
+		}
+
 		if e.Path == "database" {
-			handleRequest = handleDatabaseRequest
+			handleRequest = func(action rest.EndpointAction) response.Response {
+				return handleDatabaseRequest(action, state, w, r)
+			}
+		}
+
+		// Limit the size of the request body.
+		var body *limitedBody
+		if e.MaxRequestBytes > 0 {
+			if r.ContentLength > e.MaxRequestBytes {
+				err := requestTooLarge(e.MaxRequestBytes).Render(w)
+				if err != nil {
+					state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+				}
+
+				return
+			}
+
+			body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, e.MaxRequestBytes)}
+			r.Body = body
+		}
+
+		// Record the version expected by a conditional write, for handlers to retrieve with rest.ExpectedVersion.
+		conditionalRequest, err := setExpectedVersion(r)
+		if err != nil {
+			err := response.BadRequest(err).Render(w)
+			if err != nil {
+				state.Log.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+			}
+
+			return
+		}
+
+		r = conditionalRequest
+
+		if e.DisableCompression {
+			disableCompression(r)
 		}
 
 		trusted, err := access.Authenticate(state, r, state.Address().URL.Host, state.Remotes().CertificatesNative())
+		authTrusted, authErr := authenticate(state, e, r)
 		if err != nil && !errors.As(err, &access.ErrInvalidHost{}) {
 			resp = response.Forbidden(fmt.Errorf("Failed to authenticate request: %w", err))
+		} else if authErr != nil {
+			resp = unauthorized(authErr)
+		} else if !certAllowed(state, e, r) {
+			resp = response.Forbidden(fmt.Errorf("Client certificate is not allowed to access this endpoint"))
+		} else if !tryAcquire(inFlight) {
+			// Return Unavailable Error (503) if the endpoint is already handling its maximum number of requests.
+			w.Header().Set("Retry-After", "1")
+			resp = response.Unavailable(fmt.Errorf("Endpoint is handling too many concurrent requests"))
 		} else {
-			r = internalAccess.SetRequestAuthentication(r, trusted)
+			defer release(inFlight)
+
+			r = internalAccess.SetRequestAuthentication(r, trusted || authTrusted)
+
+			// Issue a consistency token for requests that may have modified state, if the client asked for one and
+			// the request succeeded.
+			if !isRead(r) && e.Path != "database" && r.Header.Get(types.ConsistencyTokenHeader) != "" {
+				w = &consistencyWriter{ResponseWriter: w, state: state, r: r}
+			}
 
 			switch r.Method {
 			case "GET":
-				resp = handleRequest(e.Get, state, w, r)
+				resp = handleRequest(e.Get)
 			case "PUT":
-				resp = handleRequest(e.Put, state, w, r)
+				resp = handleRequest(e.Put)
 			case "POST":
-				resp = handleRequest(e.Post, state, w, r)
+				resp = handleRequest(e.Post)
 			case "DELETE":
-				resp = handleRequest(e.Delete, state, w, r)
+				resp = handleRequest(e.Delete)
 			case "PATCH":
-				resp = handleRequest(e.Patch, state, w, r)
+				resp = handleRequest(e.Patch)
 			default:
 				resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
 			}
 		}
 
+		// Return Request Entity Too Large (413) if the handler read past the request body limit.
+		if body != nil && body.exceeded {
+			resp = requestTooLarge(e.MaxRequestBytes)
+		}
+
 		// Handle errors.
 		if e.Path != "database" {
 			err := resp.Render(w)
 			if err != nil {
 				err := response.InternalError(err).Render(w)
 				if err != nil {
-					logger.Error("Failed writing error for HTTP response", logger.Ctx{"url": url, "error": err})
+					state.Log.Error("Failed writing error for HTTP response", logger.Ctx{"url": url, "error": err})
 				}
 			}
 		}
 	})
 
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	route := mux.Handle(url, handler)
+
 	// If the endpoint has a canonical name then record it so it can be used to build URLS
 	// and accessed in the context of the request by the handler function.
 	if e.Name != "" {
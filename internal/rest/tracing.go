@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing returns middleware that starts a span with the given tracer for each request, named after the path template
+// of the endpoint. If the caller propagated a trace with a traceparent header, the span continues that trace.
+// The span is set on the request context, so that requests sent to other cluster members with it are child spans.
+func Tracing(tracer trace.Tracer) func(http.Handler) http.Handler {
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			route := mux.CurrentRoute(r)
+			if route != nil {
+				template, err := route.GetPathTemplate()
+				if err == nil {
+					path = template
+				}
+			}
+
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+path,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", path),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
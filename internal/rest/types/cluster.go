@@ -17,14 +17,21 @@ type ClusterMember struct {
 	Status                MemberStatus          `json:"status" yaml:"status"`
 	Extensions            extensions.Extensions `json:"extensions" yaml:"extensions"`
 	Secret                string                `json:"secret" yaml:"secret"`
+
+	// RequestedRole is the dqlite role last assigned to the cluster member by an operator, if any. The effective
+	// role is Role, as dqlite may since have reassigned it until the next heartbeat round assigns it again.
+	RequestedRole string `json:"requested_role" yaml:"requested_role"`
+
+	// ReplicaOnly is true if the cluster member only ever holds the dqlite spare role and rejects writes.
+	ReplicaOnly bool `json:"replica_only" yaml:"replica_only"`
+
+	// FailureDomain and Tags describe the placement of the cluster member, such as its rack or availability zone.
+	FailureDomain uint64            `json:"failure_domain" yaml:"failure_domain"`
+	Tags          map[string]string `json:"tags" yaml:"tags"`
 }
 
 // ClusterMemberLocal represents local information about a new cluster member.
-type ClusterMemberLocal struct {
-	Name        string                `json:"name" yaml:"name"`
-	Address     types.AddrPort        `json:"address" yaml:"address"`
-	Certificate types.X509Certificate `json:"certificate" yaml:"certificate"`
-}
+type ClusterMemberLocal = types.ClusterMemberLocal
 
 // MemberStatus represents the online status of a cluster member.
 type MemberStatus string
@@ -42,9 +49,52 @@ const (
 	// MemberNotFound should be the MemberStatus when the node was not found in dqlite.
 	MemberNotFound MemberStatus = "NOT FOUND"
 
+	// MemberDraining should be the MemberStatus when the node is draining ahead of its removal.
+	MemberDraining MemberStatus = "DRAINING"
+
 	// MemberUpgrading should be the MemberStatus if the system is awaiting or performing a schema upgrade.
 	MemberUpgrading MemberStatus = "UPGRADING"
 
 	// MemberNeedsUpgrade should be the MemberStatus if the system needs to receive a schema upgrade to be compatible with other cluster members.
 	MemberNeedsUpgrade MemberStatus = "NEEDS UPGRADE"
 )
+
+// ClusterMemberVersionPost represents the request for the schema versions and API extensions of a cluster member.
+// Secret is the secret of a pending join token, which must be presented to query an untrusted cluster member.
+type ClusterMemberVersionPost struct {
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+// ClusterMemberVersion represents the schema versions and API extensions of a cluster member.
+type ClusterMemberVersion struct {
+	SchemaInternalVersion uint64                `json:"schema_internal_version" yaml:"schema_internal_version"`
+	SchemaExternalVersion uint64                `json:"schema_external_version" yaml:"schema_external_version"`
+	Extensions            extensions.Extensions `json:"extensions"              yaml:"extensions"`
+}
+
+// RoleAssignment represents the arguments for assigning a dqlite role to a cluster member.
+type RoleAssignment struct {
+	// Name is the name of the cluster member.
+	Name string `json:"name" yaml:"name"`
+
+	// Role is the dqlite role to assign, one of "voter", "stand-by", or "spare".
+	Role string `json:"role" yaml:"role"`
+}
+
+// HeartbeatPause represents the arguments for pausing heartbeats.
+type HeartbeatPause struct {
+	// Duration is how long to pause heartbeats for. If 0, heartbeats are resumed.
+	Duration time.Duration `json:"duration" yaml:"duration"`
+}
+
+// QuorumRecovery represents the arguments for recovering the dqlite cluster configuration after quorum loss.
+type QuorumRecovery struct {
+	// Members are the names of the surviving cluster members, which become the only voters.
+	Members []string `json:"members" yaml:"members"`
+}
+
+// LeaderTransfer represents the arguments for transferring dqlite leadership to another cluster member.
+type LeaderTransfer struct {
+	// Target is the name of the cluster member to transfer leadership to. If empty, a voter is chosen automatically.
+	Target string `json:"target" yaml:"target"`
+}
@@ -11,4 +11,17 @@ type Control struct {
 	JoinToken  string            `json:"join_token" yaml:"join_token"`
 	Address    types.AddrPort    `json:"address" yaml:"address"`
 	Name       string            `json:"name" yaml:"name"`
+
+	// Interface is the name of a network interface whose address should be used with the port of Address.
+	Interface string `json:"interface" yaml:"interface"`
+}
+
+// SocketGroup represents the group that owns the control socket.
+type SocketGroup struct {
+	Group string `json:"group" yaml:"group"`
+}
+
+// JoinCheck represents the arguments used to check compatibility with a cluster before joining it.
+type JoinCheck struct {
+	JoinToken string `json:"join_token" yaml:"join_token"`
 }
@@ -0,0 +1,5 @@
+package types
+
+// DatabaseDumpErrorTrailer is the trailer set on a database dump response if the dump failed after it started to be
+// streamed, in which case the dump is incomplete.
+const DatabaseDumpErrorTrailer = "X-Microcluster-Dump-Error"
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"time"
+)
+
 // HeartbeatInfo represents information about the cluster sent out by the leader of the cluster to other members.
 // If BeginRound is set, a new heartbeat will initiate.
 type HeartbeatInfo struct {
@@ -7,4 +11,16 @@ type HeartbeatInfo struct {
 	MaxSchemaInternal uint64                   `json:"max_schema_internal" yaml:"max_schema_internal"`
 	MaxSchemaExternal uint64                   `json:"max_schema_external" yaml:"max_schema_external"`
 	ClusterMembers    map[string]ClusterMember `json:"cluster_members" yaml:"cluster_members"`
+
+	// Payloads are the latest heartbeat payloads of the cluster members known to the leader, keyed by member name.
+	Payloads map[string][]byte `json:"payloads,omitempty" yaml:"payloads,omitempty"`
+}
+
+// HeartbeatResponse is the response of a cluster member to a heartbeat sent by the leader.
+type HeartbeatResponse struct {
+	// Time is the wall clock time of the cluster member when it handled the heartbeat.
+	Time time.Time `json:"time" yaml:"time"`
+
+	// Payload is the heartbeat payload of the cluster member, if any.
+	Payload []byte `json:"payload,omitempty" yaml:"payload,omitempty"`
 }
@@ -7,6 +7,9 @@ const (
 	// OnStart is run after the daemon is started.
 	OnStart HookType = "on-start"
 
+	// OnDatabaseReady is run after the database is open and ready for queries.
+	OnDatabaseReady HookType = "on-database-ready"
+
 	// PreBootstrap is run before the daemon is initialized and bootstrapped.
 	PreBootstrap HookType = "pre-bootstrap"
 
@@ -27,11 +30,34 @@ const (
 	// PostRemove is run on all other peers after one is removed from the cluster.
 	PostRemove HookType = "post-remove"
 
+	// PreNewMember is run on the dqlite leader when a new cluster member asks to join, before it is added to the
+	// cluster. Returning an error rejects the join.
+	PreNewMember HookType = "pre-new-member"
+
 	// OnNewMember is run on each peer after a new cluster member has joined and executed their 'PreJoin' hook.
 	OnNewMember HookType = "on-new-member"
 
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat HookType = "on-heartbeat"
+
+	// HeartbeatPayload is run on each cluster member during a heartbeat round, to return the payload to share with
+	// the other cluster members.
+	HeartbeatPayload HookType = "heartbeat-payload"
+
+	// OnHeartbeatPayloads is run on each cluster member with the payloads shared by the cluster members.
+	OnHeartbeatPayloads HookType = "on-heartbeat-payloads"
+
+	// OnConfigChange is run on each cluster member after the cluster-wide configuration has changed.
+	OnConfigChange HookType = "on-config-change"
+
+	// OnLeaderChange is run on a cluster member when it gains or loses dqlite leadership.
+	OnLeaderChange HookType = "on-leader-change"
+
+	// PreShutdown is run on a cluster member when it begins shutting down.
+	PreShutdown HookType = "pre-shutdown"
+
+	// OnLowDisk is run on a cluster member when its database directory is low on disk space.
+	OnLowDisk HookType = "on-low-disk"
 )
 
 // HookRemoveMemberOptions holds configuration pertaining to the PreRemove and PostRemove hooks.
@@ -45,3 +71,12 @@ type HookNewMemberOptions struct {
 	// Name is the name of the new cluster member that joined the cluster, triggering this hook.
 	Name string `json:"name" yaml:"name"`
 }
+
+// HookConfigChangeOptions holds configuration pertaining to the OnConfigChange hook.
+type HookConfigChangeOptions struct {
+	// OldConfig is the cluster-wide configuration before the change.
+	OldConfig map[string]string `json:"old_config" yaml:"old_config"`
+
+	// NewConfig is the cluster-wide configuration after the change.
+	NewConfig map[string]string `json:"new_config" yaml:"new_config"`
+}
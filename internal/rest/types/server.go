@@ -21,3 +21,6 @@ const (
 	// ControlEndpoint - all endpoints available on the local unix socket.
 	ControlEndpoint types.EndpointPrefix = "cluster/control"
 )
+
+// TracerName is the name of the OpenTelemetry tracer used for the spans of the daemon and its clients.
+const TracerName = "github.com/canonical/microcluster"
@@ -8,6 +8,9 @@ type SQLDump struct {
 // SQLQuery represents a SQL query.
 type SQLQuery struct {
 	Query string `json:"query" yaml:"query"`
+
+	// ReadOnly restricts the query to SELECT statements.
+	ReadOnly bool `json:"read_only" yaml:"read_only"`
 }
 
 // SQLBatch represents a batch of SQL results.
@@ -21,4 +24,7 @@ type SQLResult struct {
 	Columns      []string `json:"columns" yaml:"columns"`
 	Rows         [][]any  `json:"rows" yaml:"rows"`
 	RowsAffected int64    `json:"rows_affected" yaml:"rows_affected"`
+
+	// Truncated is set if not all rows were returned, because the result exceeded the size limit.
+	Truncated bool `json:"truncated" yaml:"truncated"`
 }
@@ -21,6 +21,9 @@ type TokenResponse struct {
 	// ClusterKey is the private key used across the cluster.
 	ClusterKey string `json:"cluster_key" yaml:"cluster_key"`
 
+	// ClusterCA is the PEM encoded CA that signed the cluster certificate, if it was not self-signed.
+	ClusterCA string `json:"cluster_ca" yaml:"cluster_ca"`
+
 	// ClusterMembers is the full list of cluster members that are currently present and available in the cluster.
 	// The joiner supplies this list to dqlite so that it can start its database.
 	ClusterMembers []ClusterMemberLocal `json:"cluster_members" yaml:"cluster_members"`
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// PublishEvent emits an event of the given type from the local cluster member to any subscribers of its event stream.
+func (s *State) PublishEvent(eventType types.EventType, message string, metadata map[string]string) {
+	if s.Events == nil {
+		return
+	}
+
+	s.Events.Publish(types.Event{
+		Type:     eventType,
+		Time:     time.Now(),
+		Member:   s.Name(),
+		Message:  message,
+		Metadata: metadata,
+	})
+}
+
+// SubscribeEvents returns a channel of the events of the given types emitted by the local cluster member as they
+// occur, or of all types if none are given. Member events are derived from SubscribeMembers, without the initial
+// snapshot. Events are dropped rather than delaying the daemon if the subscriber falls behind. The channel is closed
+// once the given context is cancelled or the daemon shuts down.
+func (s *State) SubscribeEvents(ctx context.Context, eventTypes ...types.EventType) <-chan types.Event {
+	ctx, cancel := context.WithCancel(ctx)
+	events := make(chan types.Event)
+
+	var published <-chan types.Event
+	if s.Events != nil {
+		published = s.Events.Subscribe(ctx, eventTypes...)
+	}
+
+	var members <-chan types.MemberEvent
+	if len(eventTypes) == 0 || slices.Contains(eventTypes, types.EventMember) {
+		members = s.SubscribeMembers(ctx)
+	}
+
+	go func() {
+		defer close(events)
+		defer cancel()
+
+		for {
+			var event types.Event
+			select {
+			case e, ok := <-published:
+				if !ok {
+					return
+				}
+
+				event = e
+			case m, ok := <-members:
+				if !ok {
+					return
+				}
+
+				if m.Snapshot {
+					continue
+				}
+
+				event = memberEvent(s.Name(), m)
+			case <-ctx.Done():
+				return
+			case <-s.Context.Done():
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			case <-s.Context.Done():
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// memberEvent converts a change to the membership of the cluster into an event.
+func memberEvent(localName string, m types.MemberEvent) types.Event {
+	return types.Event{
+		Type:    types.EventMember,
+		Time:    time.Now(),
+		Member:  localName,
+		Message: fmt.Sprintf("Cluster member %q %s", m.Name, m.Type),
+		Metadata: map[string]string{
+			"event":         string(m.Type),
+			"name":          m.Name,
+			"address":       m.Address.String(),
+			"role":          m.Role,
+			"previous_role": m.PreviousRole,
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package state
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// EvictMember removes the cluster member with the given name from the cluster, through the dqlite leader as with any
+// other removal.
+//
+// Unless force is true, the member must be reachable so that it can be drained, run its PreRemove hook and reset
+// itself. If force is true, the member is evicted without its participation, so that a permanently offline member can
+// still be removed. It is only asked to run its PreRemove hook and reset itself on a best-effort basis, for a bounded
+// time, and the PostRemove hook is run on all remaining members with force set. The local member can't be evicted.
+func (s *State) EvictMember(ctx context.Context, name string, force bool) error {
+	_, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	if force && name == s.Name() {
+		return api.StatusErrorf(http.StatusBadRequest, "Cannot evict the local cluster member %q", name)
+	}
+
+	c, err := s.Leader()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteClusterMember(ctx, name, force)
+}
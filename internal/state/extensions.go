@@ -0,0 +1,132 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// extensionCacheTTL is how long the API extensions advertised by a cluster member are cached, so that members that
+// have been upgraded are noticed.
+const extensionCacheTTL = time.Minute
+
+// ExtensionCache caches the API extensions advertised by other cluster members.
+type ExtensionCache struct {
+	mu      sync.Mutex
+	entries map[string]extensionCacheEntry
+}
+
+// extensionCacheEntry is the API extensions advertised by a cluster member at an address.
+type extensionCacheEntry struct {
+	address    string
+	extensions types.APIExtensions
+	fetched    time.Time
+}
+
+// NewExtensionCache returns an empty cache of the API extensions of cluster members.
+func NewExtensionCache() *ExtensionCache {
+	return &ExtensionCache{entries: map[string]extensionCacheEntry{}}
+}
+
+// get returns the cached API extensions of the named cluster member, if they were fetched from the given address
+// recently enough.
+func (c *ExtensionCache) get(name string, address string) (types.APIExtensions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[name]
+	if !ok || entry.address != address || time.Since(entry.fetched) > extensionCacheTTL {
+		return types.APIExtensions{}, false
+	}
+
+	return entry.extensions, true
+}
+
+// set caches the API extensions of the named cluster member, fetched from the given address.
+func (c *ExtensionCache) set(name string, address string, extensions types.APIExtensions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[name] = extensionCacheEntry{address: address, extensions: extensions, fetched: time.Now()}
+}
+
+// MemberExtensions returns the API extensions advertised by the cluster member with the given name on its core API
+// listener. The extensions of other members are cached for a minute, so that they can be checked before each call to a
+// member during a rolling upgrade.
+func (s *State) MemberExtensions(ctx context.Context, name string) (types.APIExtensions, error) {
+	if name == s.Name() {
+		internal, external := s.Extensions.Split()
+
+		return types.APIExtensions{Internal: internal, External: external, Version: s.Extensions.Version()}, nil
+	}
+
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return types.APIExtensions{}, api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	address := remote.Address.String()
+	extensions, ok := s.ExtensionCache.get(name, address)
+	if ok {
+		return extensions, nil
+	}
+
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return types.APIExtensions{}, err
+	}
+
+	c, err := s.ClientPool.Get(remote.URL(), s.ServerCert(), publicKey, false)
+	if err != nil {
+		return types.APIExtensions{}, err
+	}
+
+	apiExtensions, err := c.GetAPIExtensions(ctx)
+	if err != nil {
+		return types.APIExtensions{}, fmt.Errorf("Failed to get API extensions of cluster member %q: %w", name, err)
+	}
+
+	s.ExtensionCache.set(name, address, *apiExtensions)
+
+	return *apiExtensions, nil
+}
+
+// AllMemberExtensions returns the API extensions advertised by every cluster member, keyed by name, as returned by
+// MemberExtensions. If the extensions of any member can't be fetched, the extensions of the other members are returned
+// along with an error.
+func (s *State) AllMemberExtensions(ctx context.Context) (map[string]types.APIExtensions, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+
+	allExtensions := map[string]types.APIExtensions{}
+	for name := range s.Remotes().RemotesByName() {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			extensions, err := s.MemberExtensions(ctx, name)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+
+			allExtensions[name] = extensions
+		}(name)
+	}
+
+	wg.Wait()
+
+	return allExtensions, errors.Join(errs...)
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/cluster"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// memberPollInterval is how often the cluster membership is checked for changes on behalf of each subscriber.
+const memberPollInterval = 5 * time.Second
+
+// memberEventBuffer is the number of events buffered for each subscriber before polling waits on it.
+const memberEventBuffer = 64
+
+// SubscribeMembers returns a channel of changes to the membership of the cluster, starting with a MemberAdded event
+// for each current cluster member marked as part of the initial snapshot. The members table and the dqlite roles
+// reported by the leader are polled for changes by a goroutine dedicated to the subscriber, so a slow consumer only
+// delays its own events. The channel is closed once
+// the given context is cancelled or the daemon shuts down.
+func (s *State) SubscribeMembers(ctx context.Context) <-chan types.MemberEvent {
+	events := make(chan types.MemberEvent, memberEventBuffer)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(memberPollInterval)
+		defer ticker.Stop()
+
+		// Members are nil until the first successful poll, which produces the snapshot.
+		var members map[string]types.MemberEvent
+		for {
+			current, err := s.currentMembers(ctx)
+			if err != nil {
+				s.Log.Debug("Failed to get cluster members for subscriber", logger.Ctx{"error": err})
+			} else {
+				for _, event := range diffMembers(members, current) {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					case <-s.Context.Done():
+						return
+					}
+				}
+
+				members = current
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-s.Context.Done():
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// currentMembers returns the cluster members recorded in the database, keyed by name, with their roles as reported by
+// the dqlite leader. Members that are not yet part of the dqlite cluster keep the role recorded in the database.
+func (s *State) currentMembers(ctx context.Context) (map[string]types.MemberEvent, error) {
+	err := s.Database.IsOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbMembers []cluster.InternalClusterMember
+	var dqliteAddresses map[string]string
+	err = s.Database.IdempotentTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		dbMembers, err = cluster.GetInternalClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		dqliteAddresses, err = cluster.GetDqliteAddresses(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := s.DqliteMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		roles[node.Address.String()] = node.Role
+	}
+
+	members := make(map[string]types.MemberEvent, len(dbMembers))
+	for _, member := range dbMembers {
+		addr, err := types.ParseAddrPort(member.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		dqliteAddress, ok := dqliteAddresses[member.Name]
+		if !ok {
+			dqliteAddress = member.Address
+		}
+
+		role, ok := roles[dqliteAddress]
+		if !ok {
+			role = string(member.Role)
+		}
+
+		members[member.Name] = types.MemberEvent{Name: member.Name, Address: addr, Role: role}
+	}
+
+	return members, nil
+}
+
+// diffMembers returns the events that turn the old membership into the new one, ordered by member name.
+// If the old membership is nil, the new membership is returned as a snapshot.
+func diffMembers(oldMembers map[string]types.MemberEvent, newMembers map[string]types.MemberEvent) []types.MemberEvent {
+	events := []types.MemberEvent{}
+	for name, member := range newMembers {
+		oldMember, ok := oldMembers[name]
+		if !ok {
+			member.Type = types.MemberAdded
+			member.Snapshot = oldMembers == nil
+			events = append(events, member)
+		} else if oldMember.Role != member.Role {
+			member.Type = types.MemberRoleChanged
+			member.PreviousRole = oldMember.Role
+			events = append(events, member)
+		}
+	}
+
+	for name, member := range oldMembers {
+		_, ok := newMembers[name]
+		if !ok {
+			member.Type = types.MemberRemoved
+			events = append(events, member)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
+
+	return events
+}
@@ -2,23 +2,39 @@ package state
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
 	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/microcluster/client"
+	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/internal/db"
 	"github.com/canonical/microcluster/internal/endpoints"
+	"github.com/canonical/microcluster/internal/events"
 	"github.com/canonical/microcluster/internal/extensions"
+	"github.com/canonical/microcluster/internal/operations"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/internal/trust"
+	"github.com/canonical/microcluster/rest/types"
 )
 
 // State is a gateway to the stateful components of the microcluster daemon.
 type State struct {
-	// Context.
+	// Context is cancelled when the daemon begins shutting down. Prefer ShutdownCtx to obtain it.
 	Context context.Context
 
 	// Ready channel.
@@ -30,12 +46,20 @@ type State struct {
 	// File structure.
 	OS *sys.OS
 
+	// WatchPath runs the given hook on every file written, created, or removed under the given path, which must be
+	// within the state directory. Relative paths are taken relative to the state directory, and missing directories
+	// are created. Hooks stop running once the daemon's listeners are stopped.
+	WatchPath func(path string, hook func(path string, event fsnotify.Op) error) error
+
 	// Listen Address.
 	Address func() *api.URL
 
 	// Name of the cluster member.
 	Name func() string
 
+	// Project is the name of the go project that started the daemon, used to namespace its database statements.
+	Project func() string
+
 	// Server.
 	Endpoints *endpoints.Endpoints
 
@@ -55,10 +79,90 @@ type State struct {
 	StartAPI func(bootstrap bool, initConfig map[string]string, newConfig *trust.Location, joinAddresses ...string) error
 
 	// Stop fully stops the daemon, its database, and all listeners.
-	Stop func() (exit func(), stopErr error)
+	// Unless force is set, leadership is first handed off and the PreShutdown hook is run.
+	Stop func(force bool) (exit func(), stopErr error)
+
+	// RemoveSelf removes the local cluster member from the cluster, and then stops the daemon.
+	// If force is true, leadership is not handed off, and the local state is removed even if the rest of the cluster is unreachable.
+	RemoveSelf func(ctx context.Context, force bool) error
+
+	// Operations returns the registry of long-running operations on this cluster member.
+	Operations func() *operations.Operations
+
+	// RegisterWorker runs the worker in a new goroutine with the shutdown context of the daemon. The daemon waits for
+	// the worker to return after the context is cancelled, before stopping the database. Workers registered after
+	// shutdown has started are not run.
+	RegisterWorker func(worker func(ctx context.Context))
+
+	// Draining returns whether this cluster member is draining ahead of its removal.
+	Draining func() bool
+
+	// Syncing returns whether this cluster member has recently joined the cluster or restarted, and is still catching
+	// up with the leader.
+	Syncing func() bool
+
+	// LowDisk returns whether the free space in the database directory is below the configured threshold.
+	LowDisk func() bool
+
+	// Drain marks this cluster member as draining, so that it rejects new writes and fails health checks,
+	// and then waits for its running operations to complete.
+	Drain func(ctx context.Context) error
+
+	// Undrain has this cluster member accept new writes and pass health checks again.
+	Undrain func()
+
+	// DescribeEndpoints describes the REST endpoints served by this cluster member, including those of extension servers.
+	DescribeEndpoints func() []types.EndpointInfo
+
+	// DiagnosticsConfig returns the effective runtime configuration and database settings of this cluster member, such
+	// as its listen addresses, schema version, registered hooks and dqlite snapshot parameters.
+	DiagnosticsConfig func() types.Diagnostics
 
 	// Runtime extensions.
 	Extensions extensions.Extensions
+
+	// ClientPool caches clients to other cluster members so that their connections can be reused.
+	ClientPool *internalClient.Pool
+
+	// ExtensionCache caches the API extensions advertised by other cluster members, for MemberExtensions.
+	ExtensionCache *ExtensionCache
+
+	// Log is the logger of the daemon.
+	Log logger.Logger
+
+	// AllowRemoteSQL allows SQL queries against the database from other cluster members, rather than only over the
+	// unix socket.
+	AllowRemoteSQL bool
+
+	// NetworkDisabled is true if the API is served over the unix socket only, in which case the member can't be part
+	// of a cluster with other members.
+	NetworkDisabled bool
+
+	// ReplicaOnly is true if the member only ever holds the dqlite spare role and rejects writes to the public API.
+	ReplicaOnly bool
+
+	// RejectReadsWhileSyncing is true if read requests to the public API over the network are rejected while the
+	// member is syncing.
+	RejectReadsWhileSyncing bool
+
+	// Events distributes the events emitted by the daemon to the subscribers of its event stream.
+	Events *events.Bus
+
+	// ConfigSchema is the schema of the cluster-wide configuration, enforced by GetConfig and SetConfig.
+	// It is nil if the application has not declared any configuration keys.
+	ConfigSchema ConfigSchema
+}
+
+// ConfigSchema validates the keys of the cluster-wide configuration. It is implemented by config.Registry.
+type ConfigSchema interface {
+	// Registered returns whether the key has been declared.
+	Registered(key string) bool
+
+	// Default returns the default value of the key, or a Not Found error if the key has not been declared.
+	Default(key string) (string, error)
+
+	// Validate checks the value against the schema of the key, returning a descriptive error if it is invalid.
+	Validate(key string, value string) error
 }
 
 // StopListeners stops the network listeners and the fsnotify listener.
@@ -73,12 +177,57 @@ var PreRemoveHook func(state *State, force bool) error
 // OnHeartbeatHook is a post-action hook that is run on the leader after a successful heartbeat round.
 var OnHeartbeatHook func(state *State) error
 
+// HeartbeatPayloadHook returns the payload that a cluster member attaches to heartbeats.
+var HeartbeatPayloadHook func(state *State) ([]byte, error)
+
+// OnHeartbeatPayloadsHook is a post-action hook that is run on all cluster members when they receive the heartbeat
+// payloads of the cluster members.
+var OnHeartbeatPayloadsHook func(state *State, payloads map[string][]byte) error
+
+// PreNewMemberHook is a pre-action hook that is run on the dqlite leader before a new cluster member is added to the
+// cluster. Returning an error rejects the join.
+var PreNewMemberHook func(ctx context.Context, state *State, candidate types.ClusterMemberLocal) error
+
 // OnNewMemberHook is a post-action hook that is run on all cluster members when a new cluster member joins the cluster.
 var OnNewMemberHook func(state *State) error
 
+// OnConfigChangeHook is a post-action hook that is run on all cluster members when the cluster-wide configuration changes.
+var OnConfigChangeHook func(ctx context.Context, state *State, oldConfig map[string]string, newConfig map[string]string) error
+
+// PreShutdownHook is a pre-action hook that is run on a cluster member when it begins shutting down.
+var PreShutdownHook func(ctx context.Context, state *State) error
+
+// OnLeaderChangeHook is a post-action hook that is run on a cluster member when it gains or loses dqlite leadership.
+var OnLeaderChangeHook func(ctx context.Context, state *State, isLeader bool) error
+
 // ReloadClusterCert reloads the cluster keypair from the state directory.
 var ReloadClusterCert func() error
 
+// RefreshTrustStore reloads the trust store from the state directory.
+var RefreshTrustStore func() error
+
+// ExportTrustStore writes the trust store to the writer as a bundle signed with the cluster certificate.
+var ExportTrustStore func(w io.Writer) error
+
+// ImportTrustStore replaces the trust store with a bundle written by ExportTrustStore and signed with the certificate
+// with the given fingerprint, or the cluster certificate if empty, and returns the number of remotes imported.
+var ImportTrustStore func(r io.Reader, fingerprint string) (int, error)
+
+// RunNamedHook runs the hook with the given name, one of the hook types, with the given arguments.
+var RunNamedHook func(ctx context.Context, state *State, hook string, args types.HookArgs) error
+
+// SetControlSocketGroup changes the group that owns the control socket.
+var SetControlSocketGroup func(group string) error
+
+// SetMemberName records a new name for the local cluster member in its daemon configuration.
+var SetMemberName func(name string) error
+
+// ShutdownCtx returns the context that is cancelled when the daemon begins shutting down. Long-lived work started by
+// extensions should stop when it is done, and can be registered with RegisterWorker to delay shutdown until it has.
+func (s *State) ShutdownCtx() context.Context {
+	return s.Context
+}
+
 // Cluster returns a client for every member of a cluster, except
 // this one.
 // All requests made by the client will have the UserAgentNotifier header set
@@ -105,8 +254,8 @@ func (s *State) Cluster(isNotification bool) (client.Cluster, error) {
 			return nil, err
 		}
 
-		url := api.NewURL().Scheme("https").Host(clusterMember.Address.String())
-		c, err := internalClient.New(*url, s.ServerCert(), publicKey, isNotification)
+		url := api.NewURL().Scheme(sys.Scheme()).Host(clusterMember.Address.String())
+		c, err := s.ClientPool.Get(*url, s.ServerCert(), publicKey, isNotification)
 		if err != nil {
 			return nil, err
 		}
@@ -137,11 +286,837 @@ func (s *State) Leader() (*client.Client, error) {
 		return nil, err
 	}
 
-	url := api.NewURL().Scheme("https").Host(leaderInfo.Address)
-	c, err := internalClient.New(*url, s.ServerCert(), publicKey, false)
+	url := api.NewURL().Scheme(sys.Scheme()).Host(leaderInfo.Address)
+	c, err := s.ClientPool.Get(*url, s.ServerCert(), publicKey, false)
 	if err != nil {
 		return nil, err
 	}
 
 	return &client.Client{Client: *c}, nil
 }
+
+// Failover executes the given query on one cluster member at a time until it succeeds, as with client.Cluster.Failover.
+// The dqlite leader is tried first if it is known, followed by the other members of the trust store in a random order.
+// The query must be idempotent, and at most maxAttempts members are tried, or all of them if maxAttempts is not
+// positive.
+func (s *State) Failover(ctx context.Context, maxAttempts int, query func(context.Context, *client.Client) error) error {
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := s.Remotes().Cluster(s.ClientPool, false, s.ServerCert(), publicKey)
+	if err != nil {
+		return err
+	}
+
+	rand.Shuffle(len(cluster), func(i, j int) { cluster[i], cluster[j] = cluster[j], cluster[i] })
+
+	// Prefer the leader, but don't fail over if it can't be determined, as that is the case failover exists for.
+	leaderAddress, err := s.LeaderAddress(ctx)
+	if err == nil {
+		for i, c := range cluster {
+			if c.URL().URL.Host == leaderAddress.String() {
+				cluster[0], cluster[i] = cluster[i], cluster[0]
+				break
+			}
+		}
+	}
+
+	return cluster.Failover(ctx, maxAttempts, query)
+}
+
+// LeaderAddress returns the address of the current dqlite leader, as reported by dqlite.
+func (s *State) LeaderAddress(ctx context.Context) (types.AddrPort, error) {
+	leaderClient, err := s.Database.Leader(ctx)
+	if err != nil {
+		return types.AddrPort{}, err
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	leaderInfo, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return types.AddrPort{}, err
+	}
+
+	return types.ParseAddrPort(leaderInfo.Address)
+}
+
+// IsLeader returns whether the local cluster member is the current dqlite leader. An error is returned if the leader
+// cannot be determined, for example while the database is unavailable.
+func (s *State) IsLeader(ctx context.Context) (bool, error) {
+	err := s.Database.IsOpen(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	leaderAddress, err := s.LeaderAddress(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get dqlite leader address: %w", err)
+	}
+
+	return leaderAddress.String() == s.Address().URL.Host, nil
+}
+
+// DqliteMembers returns all nodes of the dqlite cluster and their roles, as reported by the dqlite leader.
+func (s *State) DqliteMembers(ctx context.Context) ([]types.DqliteMember, error) {
+	leaderClient, err := s.Database.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	nodes, err := s.Database.Cluster(ctx, leaderClient)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]types.DqliteMember, 0, len(nodes))
+	for _, node := range nodes {
+		addr, err := types.ParseAddrPort(node.Address)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse address of dqlite node %d: %w", node.ID, err)
+		}
+
+		members = append(members, types.DqliteMember{ID: node.ID, Address: addr, Role: node.Role.String()})
+	}
+
+	return members, nil
+}
+
+// ClusterRoles returns the number of voters and stand-bys targeted by dqlite, and the number of nodes that currently
+// have each role.
+func (s *State) ClusterRoles(ctx context.Context) (*types.ClusterRoles, error) {
+	members, err := s.DqliteMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := &types.ClusterRoles{}
+	roles.TargetVoters, roles.TargetStandBys = s.Database.TargetRoles()
+	for _, member := range members {
+		switch member.Role {
+		case dqliteClient.Voter.String():
+			roles.Voters++
+		case dqliteClient.StandBy.String():
+			roles.StandBys++
+		case dqliteClient.Spare.String():
+			roles.Spares++
+		}
+	}
+
+	return roles, nil
+}
+
+// PauseHeartbeats suspends heartbeat rounds on every cluster member for the given duration, for example during
+// maintenance that briefly partitions the network, so that heartbeat-based failure detection and the OnHeartbeat hook
+// don't fire spuriously. Heartbeats resume automatically once the duration has elapsed on each member's clock, or
+// when resumed with ResumeHeartbeats. The duration must not exceed one hour. Cluster members that can't be reached
+// are not paused, and an error is returned once the others have been.
+func (s *State) PauseHeartbeats(ctx context.Context, d time.Duration) error {
+	if d < 0 || d > db.MaxHeartbeatPause {
+		return api.StatusErrorf(http.StatusBadRequest, "Heartbeat pause must be between 0 and %s", db.MaxHeartbeatPause)
+	}
+
+	s.Database.PauseHeartbeats(d)
+
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := s.Remotes().Cluster(s.ClientPool, true, s.ServerCert(), publicKey)
+	if err != nil {
+		return err
+	}
+
+	return cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+		if c.URL().URL.Host == s.Address().URL.Host {
+			return nil
+		}
+
+		return internalClient.PauseHeartbeats(ctx, &c.Client, d)
+	})
+}
+
+// ResumeHeartbeats resumes heartbeat rounds on every cluster member, after they were paused with PauseHeartbeats.
+func (s *State) ResumeHeartbeats(ctx context.Context) error {
+	return s.PauseHeartbeats(ctx, 0)
+}
+
+// ClusterHealth summarizes the health of the cluster as seen by the dqlite leader, which alone knows when it last sent
+// a heartbeat to each voter. If this member is not the leader, the request is forwarded to the leader, and only whether
+// this member is syncing is reported from the local member. If no leader can be reached, the health is reported from
+// the local dqlite node store as with LocalClusterHealth.
+func (s *State) ClusterHealth(ctx context.Context) (*types.ClusterHealth, error) {
+	err := s.Database.IsOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	leaderAddress, err := s.LeaderAddress(leaderCtx)
+	if err != nil || leaderAddress.String() == s.Address().URL.Host {
+		return s.LocalClusterHealth(ctx)
+	}
+
+	c, err := s.Leader()
+	if err == nil {
+		c.SetClusterNotification()
+
+		var health *types.ClusterHealth
+		health, err = c.GetClusterHealth(ctx)
+		if err == nil {
+			health.Syncing = s.Syncing()
+
+			return health, nil
+		}
+	}
+
+	s.Log.Warn("Failed to get cluster health from the dqlite leader", logger.Ctx{"leader": leaderAddress.String(), "error": err})
+
+	return s.LocalClusterHealth(ctx)
+}
+
+// ReplicationStatus reports how this cluster member is keeping up with the dqlite leader. As the dqlite client does not
+// expose raft indices, it reports when the member last received a heartbeat from the leader rather than its log lag.
+func (s *State) ReplicationStatus(ctx context.Context) (*types.ReplicationStatus, error) {
+	isLeader, err := s.IsLeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.DqliteMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &types.ReplicationStatus{Leader: isLeader, Syncing: s.Syncing()}
+	status.HeartbeatReceived, _ = s.Database.HeartbeatReceived()
+	for _, member := range members {
+		if member.Address.String() == s.Address().URL.Host {
+			status.Role = member.Role
+			break
+		}
+	}
+
+	return status, nil
+}
+
+// LocalClusterHealth summarizes the health of the cluster from the local dqlite node store and the heartbeats sent by
+// this member, measured on its monotonic clock. A voter is counted as reachable if it is the leader, or if this member
+// is the leader and the voter responded to one of its heartbeats within the last two heartbeat rounds. Voters that this
+// member has not sent a heartbeat to since it was elected, or since heartbeats were last paused, are of unknown
+// reachability, as are all voters other than the leader if this member is not the leader. If no leader can be
+// reached, only the local member is counted as reachable and quorum is not met.
+func (s *State) LocalClusterHealth(ctx context.Context) (*types.ClusterHealth, error) {
+	err := s.Database.IsOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := s.Database.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &types.ClusterHealth{Members: len(nodes)}
+	health.HeartbeatsPausedUntil, health.HeartbeatsPaused = s.Database.HeartbeatsPausedUntil()
+	health.Syncing = s.Syncing()
+
+	leaderCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	leaderAddress, err := s.LeaderAddress(leaderCtx)
+	hasLeader := err == nil
+	if hasLeader {
+		remote := s.Remotes().RemoteByAddress(leaderAddress)
+		if remote != nil {
+			health.Leader = remote.Name
+		}
+	} else {
+		s.Log.Warn("Failed to get dqlite leader for cluster health", logger.Ctx{"error": err})
+	}
+
+	isLeader := hasLeader && leaderAddress.String() == s.Address().URL.Host
+	lastPaused := s.Database.HeartbeatsLastPaused()
+
+	// Heartbeat rounds are at most twice the heartbeat timeout apart, so allow for one missed round.
+	window := 4 * internalClient.HeartbeatTimeout * time.Second
+	for _, node := range nodes {
+		if node.Role != dqliteClient.Voter {
+			continue
+		}
+
+		health.Voters++
+		if !hasLeader {
+			if node.Address == s.Address().URL.Host {
+				health.ReachableVoters++
+			}
+
+			continue
+		}
+
+		if node.Address == leaderAddress.String() {
+			health.ReachableVoters++
+			continue
+		}
+
+		lastSent, sent := s.Database.HeartbeatSent(node.Address)
+		switch {
+		case !isLeader || !sent || !lastSent.After(lastPaused):
+			health.UnknownVoters++
+		case time.Since(lastSent) < window:
+			health.ReachableVoters++
+		}
+	}
+
+	quorum := health.Voters/2 + 1
+	health.QuorumMet = hasLeader && health.ReachableVoters+health.UnknownVoters >= quorum
+	health.QuorumAtRisk = health.QuorumMet && health.ReachableVoters+health.UnknownVoters == quorum
+
+	return health, nil
+}
+
+// CheckJoin compares the schema versions and API extensions of this cluster member with those of the cluster members
+// at the join addresses of the given join token, without modifying any local or remote state. As when joining, each
+// cluster member must present the cluster certificate named by the token.
+func (s *State) CheckJoin(ctx context.Context, token internalTypes.Token) (*types.JoinCheck, error) {
+	if len(token.JoinAddresses) == 0 {
+		return nil, fmt.Errorf("No join addresses given")
+	}
+
+	localInternal, localExternal, _ := s.Database.Schema().Version()
+	report := &types.JoinCheck{Members: make([]types.JoinCheckMember, 0, len(token.JoinAddresses))}
+	anyReachable := false
+	anyMismatch := false
+	for _, addr := range token.JoinAddresses {
+		member := types.JoinCheckMember{Address: addr}
+		version, err := s.joinAddressVersion(ctx, addr, token)
+		if err != nil {
+			member.Error = err.Error()
+			report.Members = append(report.Members, member)
+			continue
+		}
+
+		member.Reachable = true
+		anyReachable = true
+
+		missing, extra := s.Extensions.Diff(version.Extensions)
+		if localInternal != version.SchemaInternalVersion || localExternal != version.SchemaExternalVersion || len(missing) > 0 || len(extra) > 0 {
+			anyMismatch = true
+			member.Mismatch = &types.SchemaVersionMismatch{
+				LocalSchemaInternal:   localInternal,
+				LocalSchemaExternal:   localExternal,
+				ClusterSchemaInternal: version.SchemaInternalVersion,
+				ClusterSchemaExternal: version.SchemaExternalVersion,
+				MissingExtensions:     missing,
+				ExtraExtensions:       extra,
+			}
+		}
+
+		report.Members = append(report.Members, member)
+	}
+
+	report.Compatible = anyReachable && !anyMismatch
+
+	return report, nil
+}
+
+// joinAddressVersion returns the schema versions and API extensions of the cluster member at the given address, after
+// verifying its certificate against the fingerprint in the join token.
+func (s *State) joinAddressVersion(ctx context.Context, addr types.AddrPort, token internalTypes.Token) (*internalTypes.ClusterMemberVersion, error) {
+	url := api.NewURL().Scheme(sys.Scheme()).Host(addr.String())
+	cert, err := sys.RemoteCertificate(url.String())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get certificate of cluster member %q: %w", url.URL.Host, err)
+	}
+
+	// In plaintext mode there is no certificate to verify the token against.
+	if cert != nil && shared.CertFingerprint(cert) != token.Fingerprint {
+		return nil, fmt.Errorf("Cluster certificate token does not match that of cluster member %q", url.URL.Host)
+	}
+
+	c, err := internalClient.New(*url, s.ServerCert(), cert, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetClusterMemberVersion(ctx, token.Secret)
+}
+
+// SetClusterConfig sets the given keys of the cluster-wide configuration, removing any keys with an empty value.
+// The change is applied by the dqlite leader, which then runs the OnConfigChange hook on every cluster member.
+func (s *State) SetClusterConfig(ctx context.Context, config map[string]string) error {
+	c, err := s.Leader()
+	if err != nil {
+		return err
+	}
+
+	return c.UpdateClusterConfig(ctx, config)
+}
+
+// GetConfig returns the value of a declared key of the cluster-wide configuration, or its default if it has not been set.
+// It returns a Not Found error if the key has not been declared in the configuration schema.
+func (s *State) GetConfig(ctx context.Context, key string) (string, error) {
+	if s.ConfigSchema == nil {
+		return "", api.StatusErrorf(http.StatusNotFound, "Unknown configuration key %q", key)
+	}
+
+	value, err := s.ConfigSchema.Default(key)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Database.IdempotentTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		config, err := cluster.GetConfig(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		current, ok := config[key]
+		if ok {
+			value = current
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// SetConfig sets a declared key of the cluster-wide configuration. An empty value resets the key to its default.
+// The value is validated against the configuration schema before it is sent to the dqlite leader, which persists it
+// and runs the OnConfigChange hook on every cluster member.
+func (s *State) SetConfig(ctx context.Context, key string, value string) error {
+	if s.ConfigSchema == nil {
+		return api.StatusErrorf(http.StatusNotFound, "Unknown configuration key %q", key)
+	}
+
+	err := s.ConfigSchema.Validate(key, value)
+	if err != nil {
+		return err
+	}
+
+	return s.SetClusterConfig(ctx, map[string]string{key: value})
+}
+
+// RecoverFromQuorumLoss forcibly rewrites the dqlite cluster configuration of this cluster member so that only the
+// named surviving members remain, all as voters. It is a last resort for a cluster that has permanently lost the
+// majority of its voters and can no longer elect a leader. The local member must be one of the survivors.
+//
+// The daemon must have been started in recovery mode, so that dqlite is not running, otherwise a Conflict error is
+// returned. Run it only on the surviving member with the most recent data. It writes the recovered database to the
+// recovery_db.tar.gz tarball in its state directory, which must be copied into the state directory of every other
+// surviving member before any member is restarted normally. Each member replaces its database with the tarball when
+// it starts, and then removes it. The lost members remain in the cluster records and trust store until they are
+// removed with force.
+//
+// WARNING: Any changes that were committed by the lost voters but not yet replicated to the recovered member are lost
+// permanently, and if a lost member comes back online it may diverge from the recovered cluster. Back up the
+// database directory of every surviving member first.
+func (s *State) RecoverFromQuorumLoss(ctx context.Context, survivingMembers []string) error {
+	if !slices.Contains(survivingMembers, s.Name()) {
+		return api.StatusErrorf(http.StatusBadRequest, "Local cluster member %q must be one of the surviving members", s.Name())
+	}
+
+	remotes := s.Remotes().RemotesByName()
+	addresses := make([]string, 0, len(survivingMembers))
+	for _, name := range survivingMembers {
+		remote, ok := remotes[name]
+		if !ok {
+			return api.StatusErrorf(http.StatusNotFound, "No cluster member found with name %q", name)
+		}
+
+		addresses = append(addresses, remote.Address.String())
+	}
+
+	return s.Database.Recover(ctx, addresses)
+}
+
+// TransferLeadership transfers dqlite leadership from the current leader to the voter with the given name.
+// If targetName is empty, the reachable voter with the most recent heartbeat is chosen.
+func (s *State) TransferLeadership(ctx context.Context, targetName string) error {
+	c, err := s.Leader()
+	if err != nil {
+		return err
+	}
+
+	return c.TransferLeadership(ctx, internalTypes.LeaderTransfer{Target: targetName})
+}
+
+// CompareAndSwap runs the write in a database transaction, but only if the version of the resource returned by current,
+// read in the same transaction, matches the expected version. An expected version of "*" matches any version other
+// than the empty string, which current should return if the resource does not exist. On a mismatch, the write is not
+// run and a Precondition Failed (412) error is returned.
+//
+// The expected version is usually taken from the If-Match header of the request with rest.ExpectedVersion.
+func (s *State) CompareAndSwap(ctx context.Context, expected string, current func(ctx context.Context, tx *sql.Tx) (string, error), write func(ctx context.Context, tx *sql.Tx) error) error {
+	return s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		version, err := current(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if expected == "*" && version == "" {
+			return api.StatusErrorf(http.StatusPreconditionFailed, "Resource does not exist")
+		}
+
+		if expected != "*" && version != expected {
+			return api.StatusErrorf(http.StatusPreconditionFailed, "Resource version %q does not match the expected version %q", version, expected)
+		}
+
+		return write(ctx, tx)
+	})
+}
+
+// DrainMember marks the cluster member with the given name as draining in the cluster, and then has it stop accepting
+// new writes and wait for its running operations to complete, so that it can be safely removed.
+func (s *State) DrainMember(ctx context.Context, name string) error {
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.SetClusterMemberDraining(ctx, tx, name, true)
+	})
+	if err != nil {
+		return err
+	}
+
+	if name == s.Name() {
+		return s.Drain(ctx)
+	}
+
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	// Forward the request so that it is accepted by a member that is already draining.
+	c, err := s.ClientPool.Get(remote.URL(), s.ServerCert(), publicKey, true)
+	if err != nil {
+		return err
+	}
+
+	return c.DrainLocalMember(ctx)
+}
+
+// UndrainMember clears the draining status of the cluster member with the given name in the cluster, and then has it
+// accept new writes again.
+func (s *State) UndrainMember(ctx context.Context, name string) error {
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return cluster.SetClusterMemberDraining(ctx, tx, name, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	if name == s.Name() {
+		s.Undrain()
+		return nil
+	}
+
+	publicKey, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return err
+	}
+
+	c, err := s.ClientPool.Get(remote.URL(), s.ServerCert(), publicKey, true)
+	if err != nil {
+		return err
+	}
+
+	return c.UndrainLocalMember(ctx)
+}
+
+// SetMemberRole assigns the dqlite role "voter", "stand-by", or "spare" to the cluster member with the given name,
+// through the dqlite leader. The role is recorded as requested for the member, though dqlite may later reassign it to
+// keep the target number of voters and stand-bys. The last voter and the leader can't be demoted.
+func (s *State) SetMemberRole(ctx context.Context, name string, role string) error {
+	c, err := s.Leader()
+	if err != nil {
+		return err
+	}
+
+	return c.AssignRole(ctx, internalTypes.RoleAssignment{Name: name, Role: role})
+}
+
+// SchemaStatus returns the schema versions and API extensions supported by this cluster member.
+// Once every cluster member reports the same status, the cluster has converged on it.
+func (s *State) SchemaStatus() types.SchemaStatus {
+	schemaInternal, schemaExternal, _ := s.Database.Schema().Version()
+
+	return types.SchemaStatus{
+		SchemaInternal:   schemaInternal,
+		SchemaExternal:   schemaExternal,
+		SchemaExtensions: s.Database.Schema().ExtensionVersions(),
+		APIExtensions:    s.Extensions,
+	}
+}
+
+// MemberHistory returns the recent heartbeat outcomes of the cluster member with the given name, oldest first. As
+// heartbeats are sent by the dqlite leader, outcomes are only recorded while this cluster member is the leader, and
+// are kept in memory until it restarts.
+func (s *State) MemberHistory(name string) ([]types.HeartbeatOutcome, error) {
+	_, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	return s.Database.HeartbeatHistory(name), nil
+}
+
+// InitInfo returns how and when this cluster member was bootstrapped or joined the cluster.
+func (s *State) InitInfo() (*types.InitInfo, error) {
+	data, err := os.ReadFile(s.OS.InitInfoPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, api.StatusErrorf(http.StatusNotFound, "No initialization info found")
+		}
+
+		return nil, fmt.Errorf("Failed to read initialization info: %w", err)
+	}
+
+	info := &types.InitInfo{}
+	err = yaml.Unmarshal(data, info)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse initialization info from yaml: %w", err)
+	}
+
+	return info, nil
+}
+
+// SetSocketGroup changes the group that owns the control socket, without recreating its listener. If the group is
+// empty, the group of the daemon process is used. The change lasts until the daemon restarts.
+func (s *State) SetSocketGroup(ctx context.Context, group string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = SetControlSocketGroup(group)
+	if err != nil {
+		return fmt.Errorf("Failed to set control socket group: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshTrust reloads the trust store from the state directory, for example after its files were edited by hand,
+// and drops any cached clients to other cluster members. It returns the number of remotes in the reloaded trust store.
+func (s *State) RefreshTrust(ctx context.Context) (int, error) {
+	err := ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	err = RefreshTrustStore()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to refresh trust store: %w", err)
+	}
+
+	// Cached clients may have been set up for remotes that are no longer trusted.
+	s.ClientPool.Clear()
+
+	return s.Remotes().Count(), nil
+}
+
+// PromoteCertificate records the additional certificate with the given fingerprint as the certificate of the cluster
+// member with the given name, such as once the member has switched to its rotated certificate. The previous
+// certificate is still accepted until it is retired with RetireCertificate. The change is recorded in the database,
+// and reaches the trust store of the other cluster members with the next heartbeat.
+func (s *State) PromoteCertificate(ctx context.Context, name string, fingerprint string) error {
+	return s.updateCertificates(ctx, name, func(ctx context.Context, tx *sql.Tx) (types.X509Certificate, []types.X509Certificate, error) {
+		return cluster.PromoteCertificate(ctx, tx, name, fingerprint)
+	})
+}
+
+// RetireCertificate stops accepting the additional certificate with the given fingerprint for the cluster member with
+// the given name, such as its previous certificate once rotation has completed. The change is recorded in the
+// database, and reaches the trust store of the other cluster members with the next heartbeat.
+func (s *State) RetireCertificate(ctx context.Context, name string, fingerprint string) error {
+	return s.updateCertificates(ctx, name, func(ctx context.Context, tx *sql.Tx) (types.X509Certificate, []types.X509Certificate, error) {
+		return cluster.RetireCertificate(ctx, tx, name, fingerprint)
+	})
+}
+
+// updateCertificates applies the given change to the certificates of the cluster member with the given name in the
+// database, and records the resulting certificates in the local trust store.
+func (s *State) updateCertificates(ctx context.Context, name string, update func(ctx context.Context, tx *sql.Tx) (types.X509Certificate, []types.X509Certificate, error)) error {
+	var certificate types.X509Certificate
+	var additional []types.X509Certificate
+	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		certificate, additional, err = update(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.Remotes().SetCertificates(s.OS.TrustDir, "", name, certificate, additional)
+	if err != nil {
+		return fmt.Errorf("Failed to update trust store: %w", err)
+	}
+
+	// Cached clients may have been set up with a certificate that is no longer accepted.
+	s.ClientPool.Clear()
+
+	return nil
+}
+
+// ExportTrust writes the trust store of the local cluster member to the writer as a bundle signed with the cluster
+// certificate, which can be restored on any cluster member with ImportTrust.
+func (s *State) ExportTrust(ctx context.Context, w io.Writer) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	err = ExportTrustStore(w)
+	if err != nil {
+		return fmt.Errorf("Failed to export trust store: %w", err)
+	}
+
+	return nil
+}
+
+// RunHook runs the hook with the given name, such as "post-join" or "on-new-member", on the local cluster member with
+// the given arguments, for example to reconcile state after a hook failed. The error of the hook is returned as is.
+func (s *State) RunHook(ctx context.Context, hook string, args types.HookArgs) error {
+	return RunNamedHook(ctx, s, hook, args)
+}
+
+// ImportTrust replaces the trust store of the local cluster member with a bundle written by ExportTrust. The bundle
+// must be signed with the certificate with the given fingerprint, such as that of the cluster certificate on another
+// cluster member when restoring a rebuilt member. If the fingerprint is empty, the bundle must be signed with the local
+// cluster certificate. Every certificate in the bundle must be currently valid, and any entry for the local cluster
+// member must match its address. It returns the number of remotes imported.
+func (s *State) ImportTrust(ctx context.Context, r io.Reader, fingerprint string) (int, error) {
+	err := ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := ImportTrustStore(r, fingerprint)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to import trust store: %w", err)
+	}
+
+	// Cached clients may have been set up for remotes that are no longer trusted.
+	s.ClientPool.Clear()
+
+	return count, nil
+}
+
+// InitConfig returns a copy of the init configuration that this cluster member was bootstrapped or joined with.
+func (s *State) InitConfig() (map[string]string, error) {
+	var config map[string]string
+	err := s.Database.IdempotentTransaction(s.Context, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		config, err = cluster.GetInitConfig(ctx, tx, s.Name())
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// RenameMember renames this cluster member across the cluster.
+// The new name is recorded in the database, the local trust store, and the daemon configuration right away,
+// and other cluster members update their trust stores on the next heartbeat.
+func (s *State) RenameMember(ctx context.Context, newName string) error {
+	err := trust.ValidateName(newName)
+	if err != nil {
+		return api.StatusErrorf(http.StatusBadRequest, "%w", err)
+	}
+
+	oldName := s.Name()
+	if newName == oldName {
+		return nil
+	}
+
+	var members []cluster.InternalClusterMember
+	err = s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		exists, err := cluster.InternalClusterMemberExists(ctx, tx, newName)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return trust.ErrNameInUse(newName)
+		}
+
+		member, err := cluster.GetInternalClusterMember(ctx, tx, oldName)
+		if err != nil {
+			return err
+		}
+
+		member.Name = newName
+		err = cluster.UpdateInternalClusterMember(ctx, tx, oldName, *member)
+		if err != nil {
+			return err
+		}
+
+		members, err = cluster.GetInternalClusterMembers(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to rename cluster member %q: %w", oldName, err)
+	}
+
+	clusterMembers := make([]internalTypes.ClusterMember, 0, len(members))
+	for _, member := range members {
+		clusterMember, err := member.ToAPI()
+		if err != nil {
+			return err
+		}
+
+		clusterMembers = append(clusterMembers, *clusterMember)
+	}
+
+	err = s.Remotes().Replace(s.OS.TrustDir, "", clusterMembers...)
+	if err != nil {
+		return fmt.Errorf("Failed to update trust store: %w", err)
+	}
+
+	return SetMemberName(newName)
+}
+
+// DatabaseDump writes a consistent SQL dump of the database contents to the given writer.
+// It can be run on any cluster member, and does not block concurrent writes to the database.
+//
+// To restore a dump, bootstrap a fresh cluster with the same application version, so that the schema versions match
+// those recorded at the top of the dump. Then execute the whole dump within one transaction, for
+// example from the PostBootstrap hook:
+//
+//	err := s.Database.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+//		_, err := tx.ExecContext(ctx, string(dump))
+//		return err
+//	})
+//
+// Existing rows in each dumped table are replaced. Cluster members and join tokens are not included in the dump,
+// so rows that reference cluster members must refer to members of the new cluster, and the remaining members must
+// join the new cluster with new tokens.
+func (s *State) DatabaseDump(ctx context.Context, w io.Writer) error {
+	return s.Database.Dump(ctx, w)
+}
@@ -0,0 +1,18 @@
+package sys
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// FreeSpace returns the number of bytes available to unprivileged users on the filesystem of the given directory.
+func FreeSpace(dir string) (uint64, error) {
+	var stat unix.Statfs_t
+	err := unix.Statfs(dir, &stat)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get filesystem information of %q: %w", dir, err)
+	}
+
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
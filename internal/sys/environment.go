@@ -12,4 +12,8 @@ const (
 
 	// SocketGroup is the configurable group of the socket.
 	SocketGroup = "SOCKET_GROUP"
+
+	// ControlSocket is the configurable address of the control socket. Addresses starting with "@" are abstract unix
+	// sockets.
+	ControlSocket = "CONTROL_SOCKET"
 )
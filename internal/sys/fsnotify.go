@@ -21,6 +21,7 @@ type Watcher struct {
 
 	watching map[string]func(string, fsnotify.Op) error
 	root     string
+	closed   bool
 }
 
 // NewWatcher returns a watcher listening for fsnotify events down the given dir.
@@ -85,13 +86,34 @@ func (w *Watcher) handleEvents(ctx context.Context) {
 			}
 
 			return
-		case event := <-w.Events:
+		case event, ok := <-w.Events:
+			if !ok {
+				// The watcher was closed, so stop running hooks.
+				return
+			}
+
+			// Watch directories created under the root, so that files added to them later are also seen.
+			if event.Op&fsnotify.Create != 0 {
+				stat, err := os.Lstat(event.Name)
+				if err == nil && stat.IsDir() {
+					err = w.watchDir(event.Name)
+					if err != nil {
+						logger.Error("Failed to watch new directory", logger.Ctx{"path": event.Name, "error": err})
+					}
+				}
+			}
+
 			// Only handle write/remove events.
 			if event.Op&fsnotify.Write == 0 && event.Op&fsnotify.Remove == 0 && event.Op&fsnotify.Create == 0 {
 				continue
 			}
 
 			w.mu.Lock()
+			if w.closed {
+				w.mu.Unlock()
+				return
+			}
+
 			for path, f := range w.watching {
 				// Only handle watched events.
 				if !strings.HasPrefix(event.Name, path) {
@@ -135,3 +157,49 @@ func (w *Watcher) Watch(path string, fileExt string, f func(path string, event f
 
 	w.watching[path] = fileExtHook
 }
+
+// WatchPath adds a hook to be executed on write/create/remove events on any file under the given path.
+// The path must be within the watcher's root, and is created if it does not exist.
+// Hooks are run sequentially, and stop running once the watcher is closed.
+// A hook must not call WatchPath itself.
+func (w *Watcher) WatchPath(path string, f func(path string, event fsnotify.Op) error) error {
+	path = filepath.Clean(path)
+	if path != w.root && !strings.HasPrefix(path, w.root+string(filepath.Separator)) {
+		return fmt.Errorf("Path %q is not within watcher root path %q", path, w.root)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("Filesystem watcher is closed")
+	}
+
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		return fmt.Errorf("Failed to create path %q: %w", path, err)
+	}
+
+	err = w.watchDir(path)
+	if err != nil {
+		return err
+	}
+
+	w.watching[path] = f
+
+	return nil
+}
+
+// Close stops the watcher. Hooks are no longer run after it returns.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	return w.Watcher.Close()
+}
@@ -0,0 +1,67 @@
+package sys
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// InterfaceAddress returns the first global unicast address of the named network interface.
+// IPv4 addresses are preferred over IPv6 addresses.
+func InterfaceAddress(name string) (netip.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("Failed to find network interface %q: %w", name, err)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("Failed to get addresses of network interface %q: %w", name, err)
+	}
+
+	var ipv6 netip.Addr
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		ip, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok || !ip.IsGlobalUnicast() {
+			continue
+		}
+
+		ip = ip.Unmap()
+		if ip.Is4() {
+			return ip, nil
+		}
+
+		if !ipv6.IsValid() {
+			ipv6 = ip
+		}
+	}
+
+	if ipv6.IsValid() {
+		return ipv6, nil
+	}
+
+	return netip.Addr{}, fmt.Errorf("Network interface %q has no global unicast address", name)
+}
+
+// WaitInterfaceAddress retries InterfaceAddress until the named network interface has an address, or the context is cancelled.
+func WaitInterfaceAddress(ctx context.Context, name string) (netip.Addr, error) {
+	for {
+		addr, err := InterfaceAddress(name)
+		if err == nil {
+			return addr, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return netip.Addr{}, fmt.Errorf("Timed out waiting for an address on network interface %q: %w", name, err)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -3,8 +3,10 @@ package sys
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -17,10 +19,23 @@ type OS struct {
 	TrustDir    string
 	LogFile     string
 	SocketGroup string
+
+	// ControlSocketAddress is the address of the control socket, if not the default control.socket file in the state
+	// directory. An address starting with "@" is a socket in the Linux abstract namespace, which has no file.
+	ControlSocketAddress string
+}
+
+// IsAbstractSocket returns whether the unix socket address is in the Linux abstract namespace.
+func IsAbstractSocket(address string) bool {
+	return strings.HasPrefix(address, "@")
 }
 
 // DefaultOS returns a fresh uninitialized OS instance with default values.
-func DefaultOS(stateDir string, socketGroup string, createDir bool) (*OS, error) {
+// If databaseDir is set, the database is stored there instead of in the state directory, for example to place it on
+// a dedicated disk. It must be an existing, writable directory.
+// If controlSocket is set, the control socket is bound to it instead of the state directory. It must be an absolute
+// path, or an abstract socket address starting with "@".
+func DefaultOS(stateDir string, databaseDir string, controlSocket string, socketGroup string, createDir bool) (*OS, error) {
 	if stateDir == "" {
 		stateDir = os.Getenv(StateDir)
 	}
@@ -29,14 +44,36 @@ func DefaultOS(stateDir string, socketGroup string, createDir bool) (*OS, error)
 		socketGroup = os.Getenv(SocketGroup)
 	}
 
+	if controlSocket == "" {
+		controlSocket = os.Getenv(ControlSocket)
+	}
+
+	if controlSocket != "" && !IsAbstractSocket(controlSocket) && !filepath.IsAbs(controlSocket) {
+		return nil, fmt.Errorf("Control socket %q must be an absolute path or an abstract socket address", controlSocket)
+	}
+
 	// TODO: Configurable log file path.
 
+	customDatabaseDir := databaseDir != ""
+	if !customDatabaseDir {
+		databaseDir = filepath.Join(stateDir, "database")
+	}
+
 	os := &OS{
 		StateDir:    stateDir,
-		DatabaseDir: filepath.Join(stateDir, "database"),
+		DatabaseDir: databaseDir,
 		TrustDir:    filepath.Join(stateDir, "truststore"),
 		LogFile:     "",
 		SocketGroup: socketGroup,
+
+		ControlSocketAddress: controlSocket,
+	}
+
+	if customDatabaseDir {
+		err := validateDatabaseDir(databaseDir)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	err := os.init(createDir)
@@ -47,6 +84,79 @@ func DefaultOS(stateDir string, socketGroup string, createDir bool) (*OS, error)
 	return os, nil
 }
 
+// CheckDatabaseLocation returns an error if the database directory is not the default directory within the state
+// directory, but the default directory holds a database, as the daemon would otherwise start with an empty database.
+func (s *OS) CheckDatabaseLocation() error {
+	defaultDir := filepath.Join(s.StateDir, "database")
+	if filepath.Clean(s.DatabaseDir) == defaultDir {
+		return nil
+	}
+
+	if !shared.PathExists(filepath.Join(defaultDir, "info.yaml")) {
+		return nil
+	}
+
+	return fmt.Errorf("Found an existing database in %q, which must be moved to the database directory %q before starting", defaultDir, s.DatabaseDir)
+}
+
+// RemoveState removes the state directory, along with the contents of the database directory if it is outside of the
+// state directory, so that no database of the cluster member is left behind. A custom database directory itself is
+// kept, as it must exist for the daemon to start again.
+func (s *OS) RemoveState() error {
+	err := os.RemoveAll(s.StateDir)
+	if err != nil {
+		return fmt.Errorf("Failed to remove the state directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(s.DatabaseDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to read the database directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		err := os.RemoveAll(filepath.Join(s.DatabaseDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("Failed to remove %q from the database directory: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// validateDatabaseDir checks that the given database directory exists and is writable.
+func validateDatabaseDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("Database directory %q must be an absolute path", dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("Failed to get database directory information: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("Database directory %q is not a directory", dir)
+	}
+
+	f, err := os.CreateTemp(dir, ".write-check-")
+	if err != nil {
+		return fmt.Errorf("Database directory %q is not writable: %w", dir, err)
+	}
+
+	_ = f.Close()
+
+	err = os.Remove(f.Name())
+	if err != nil {
+		return fmt.Errorf("Failed to clean up write check in database directory %q: %w", dir, err)
+	}
+
+	return nil
+}
+
 func (s *OS) init(createDir bool) error {
 	dirs := []struct {
 		path string
@@ -88,6 +198,19 @@ func (s *OS) init(createDir bool) error {
 // accessible.
 func (s *OS) IsControlSocketPresent() (bool, error) {
 	socketPath := s.ControlSocketPath()
+
+	// Abstract sockets have no file, so they are only present while something is listening on them.
+	if IsAbstractSocket(socketPath) {
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			return false, nil
+		}
+
+		_ = conn.Close()
+
+		return true, nil
+	}
+
 	_, err := os.Stat(socketPath)
 
 	if err == nil {
@@ -101,13 +224,17 @@ func (s *OS) IsControlSocketPresent() (bool, error) {
 	return false, err
 }
 
-// ControlSocket returns the full path to the control.socket file that this daemon is listening on.
+// ControlSocket returns the URL of the control socket that this daemon is listening on.
 func (s *OS) ControlSocket() api.URL {
 	return *api.NewURL().Scheme("http").Host(s.ControlSocketPath())
 }
 
-// ControlSocketPath returns the filesystem path to the control socket.
+// ControlSocketPath returns the filesystem path to the control socket, or its address if it is an abstract socket.
 func (s *OS) ControlSocketPath() string {
+	if s.ControlSocketAddress != "" {
+		return s.ControlSocketAddress
+	}
+
 	return filepath.Join(s.StateDir, "control.socket")
 }
 
@@ -116,6 +243,11 @@ func (s *OS) DatabasePath() string {
 	return filepath.Join(s.DatabaseDir, "db.bin")
 }
 
+// InitInfoPath returns the path of the file recording how and when this cluster member was initialized.
+func (s *OS) InitInfoPath() string {
+	return filepath.Join(s.StateDir, "init.yaml")
+}
+
 // ServerCert gets the local server certificate from the state directory.
 func (s *OS) ServerCert() (*shared.CertInfo, error) {
 	if !shared.PathExists(filepath.Join(s.StateDir, "server.crt")) {
@@ -0,0 +1,63 @@
+package sys
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type osSuite struct {
+	suite.Suite
+}
+
+func TestOSSuite(t *testing.T) {
+	suite.Run(t, new(osSuite))
+}
+
+// Ensures removing the state of a cluster member also removes the database, whether it is in the default database
+// directory or a custom one outside of the state directory.
+func (s *osSuite) Test_removeState() {
+	tests := []struct {
+		name        string
+		databaseDir func(stateDir string) string
+		keepsDir    bool
+	}{
+		{
+			name:        "Default database directory",
+			databaseDir: func(stateDir string) string { return "" },
+		},
+		{
+			name:        "Custom database directory",
+			databaseDir: func(stateDir string) string { return s.T().TempDir() },
+			keepsDir:    true,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		stateDir := filepath.Join(s.T().TempDir(), "state")
+		osInfo, err := DefaultOS(stateDir, test.databaseDir(stateDir), "", "", true)
+		s.Require().NoError(err)
+
+		for _, name := range []string{"info.yaml", "cluster.yaml", "0000000000000001-0000000000000002"} {
+			s.Require().NoError(os.WriteFile(filepath.Join(osInfo.DatabaseDir, name), []byte("data"), 0600))
+		}
+
+		s.Require().NoError(os.Mkdir(filepath.Join(osInfo.DatabaseDir, "snapshots"), 0700))
+
+		s.NoError(osInfo.RemoveState())
+		s.NoDirExists(stateDir)
+
+		if !test.keepsDir {
+			s.NoDirExists(osInfo.DatabaseDir)
+			continue
+		}
+
+		entries, err := os.ReadDir(osInfo.DatabaseDir)
+		s.NoError(err)
+		s.Empty(entries)
+	}
+}
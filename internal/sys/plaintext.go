@@ -0,0 +1,36 @@
+package sys
+
+import (
+	"crypto/x509"
+	"sync/atomic"
+
+	"github.com/canonical/lxd/shared"
+)
+
+// plaintext is set if cluster members communicate over plain HTTP rather than HTTPS.
+var plaintext atomic.Bool
+
+// Plaintext returns whether cluster members communicate over plain HTTP rather than HTTPS.
+// This is only ever the case in test builds, after a call to EnablePlaintext.
+func Plaintext() bool {
+	return plaintext.Load()
+}
+
+// Scheme returns the URL scheme to use for connections to cluster members.
+func Scheme() string {
+	if Plaintext() {
+		return "http"
+	}
+
+	return "https"
+}
+
+// RemoteCertificate returns the certificate presented by the cluster member at the given URL.
+// In plaintext mode, no certificate is presented, so nil is returned.
+func RemoteCertificate(url string) (*x509.Certificate, error) {
+	if Plaintext() {
+		return nil, nil
+	}
+
+	return shared.GetRemoteCertificate(url, "")
+}
@@ -0,0 +1,13 @@
+//go:build !microcluster_plaintext
+
+package sys
+
+import (
+	"fmt"
+)
+
+// EnablePlaintext switches all listeners and clients of cluster members in this process to plain HTTP.
+// It is only available in builds with the microcluster_plaintext tag, and is meant for tests only.
+func EnablePlaintext() error {
+	return fmt.Errorf("Plaintext mode is only available in builds with the microcluster_plaintext tag")
+}
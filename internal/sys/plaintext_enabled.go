@@ -0,0 +1,11 @@
+//go:build microcluster_plaintext
+
+package sys
+
+// EnablePlaintext switches all listeners and clients of cluster members in this process to plain HTTP.
+// It is only available in builds with the microcluster_plaintext tag, and is meant for tests only.
+func EnablePlaintext() error {
+	plaintext.Store(true)
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package trust
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"gopkg.in/yaml.v2"
+
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// bundle is an exported trust store, signed with the cluster certificate.
+type bundle struct {
+	// Remotes is the YAML encoded list of remotes that the signature covers.
+	Remotes string `yaml:"remotes"`
+
+	// Signature is the base64 encoded signature of Remotes.
+	Signature string `yaml:"signature"`
+
+	// Certificate is the certificate whose private key made the signature.
+	Certificate types.X509Certificate `yaml:"certificate"`
+}
+
+// Export writes all remotes in the truststore to the writer as a bundle signed with the given certificate, such as
+// the cluster certificate, so that they can be restored with Import.
+func (ts *Store) Export(w io.Writer, cert *shared.CertInfo) error {
+	remotesByName := ts.Remotes().RemotesByName()
+	remotes := make([]Remote, 0, len(remotesByName))
+	for _, remote := range remotesByName {
+		remotes = append(remotes, remote)
+	}
+
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i].Name < remotes[j].Name
+	})
+
+	payload, err := yaml.Marshal(remotes)
+	if err != nil {
+		return fmt.Errorf("Failed to encode remotes: %w", err)
+	}
+
+	signature, err := signPayload(cert, payload)
+	if err != nil {
+		return err
+	}
+
+	x509Cert, err := cert.PublicKeyX509()
+	if err != nil {
+		return fmt.Errorf("Failed to parse certificate: %w", err)
+	}
+
+	b := bundle{
+		Remotes:     string(payload),
+		Signature:   base64.StdEncoding.EncodeToString(signature),
+		Certificate: types.X509Certificate{Certificate: x509Cert},
+	}
+
+	bytes, err := yaml.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("Failed to encode trust store bundle: %w", err)
+	}
+
+	_, err = w.Write(bytes)
+	if err != nil {
+		return fmt.Errorf("Failed to write trust store bundle: %w", err)
+	}
+
+	return nil
+}
+
+// Import replaces the remotes in the truststore with those of a bundle written by Export, after verifying its
+// signature against the certificate carried in the bundle, which must have the given fingerprint, and the validity of
+// each remote's certificate. The entry for the local member, if any, must match its address, and is kept if the bundle
+// has none. It returns the number of remotes imported.
+func (ts *Store) Import(r io.Reader, fingerprint string, dir string, local Location) (int, error) {
+	bytes, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read trust store bundle: %w", err)
+	}
+
+	b := bundle{}
+	err = yaml.Unmarshal(bytes, &b)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse trust store bundle: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(b.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to decode trust store bundle signature: %w", err)
+	}
+
+	if b.Certificate.Certificate == nil {
+		return 0, fmt.Errorf("Trust store bundle has no certificate")
+	}
+
+	if shared.CertFingerprint(b.Certificate.Certificate) != fingerprint {
+		return 0, fmt.Errorf("Trust store bundle was signed with certificate %q, not %q", shared.CertFingerprint(b.Certificate.Certificate), fingerprint)
+	}
+
+	err = verifyPayload(b.Certificate.Certificate, []byte(b.Remotes), signature)
+	if err != nil {
+		return 0, err
+	}
+
+	remotes := []Remote{}
+	err = yaml.Unmarshal([]byte(b.Remotes), &remotes)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse remotes of trust store bundle: %w", err)
+	}
+
+	now := time.Now()
+	hasLocal := false
+	members := make([]internalTypes.ClusterMember, 0, len(remotes)+1)
+	for _, remote := range remotes {
+		err := ValidateName(remote.Name)
+		if err != nil {
+			return 0, err
+		}
+
+		if remote.Certificate.Certificate == nil {
+			return 0, fmt.Errorf("Remote %q has no certificate", remote.Name)
+		}
+
+		if now.Before(remote.Certificate.NotBefore) || now.After(remote.Certificate.NotAfter) {
+			return 0, fmt.Errorf("Certificate of remote %q is not valid at the current time", remote.Name)
+		}
+
+		if remote.Name == local.Name {
+			if remote.Address.String() != local.Address.String() {
+				return 0, fmt.Errorf("Remote %q has address %q, which does not match the address %q of the local member", remote.Name, remote.Address.String(), local.Address.String())
+			}
+
+			hasLocal = true
+		}
+
+		members = append(members, internalTypes.ClusterMember{
+			ClusterMemberLocal: internalTypes.ClusterMemberLocal{
+				Name:          remote.Name,
+				Address:       remote.Address,
+				Certificate:   remote.Certificate,
+				DqliteAddress: remote.DqliteAddress,
+			},
+		})
+	}
+
+	// Keep the entry for the local member if the bundle does not have one.
+	localRemote, ok := ts.Remotes().RemotesByName()[local.Name]
+	if !hasLocal && ok {
+		members = append(members, internalTypes.ClusterMember{
+			ClusterMemberLocal: internalTypes.ClusterMemberLocal{
+				Name:          localRemote.Name,
+				Address:       localRemote.Address,
+				Certificate:   localRemote.Certificate,
+				DqliteAddress: localRemote.DqliteAddress,
+			},
+		})
+	}
+
+	err = ts.Remotes().Replace(dir, "import", members...)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to replace remotes: %w", err)
+	}
+
+	return len(members), nil
+}
+
+// signPayload signs the payload with the private key of the certificate.
+func signPayload(cert *shared.CertInfo, payload []byte) ([]byte, error) {
+	signer, ok := cert.KeyPair().PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("Private key of certificate %q cannot be used for signing", cert.Fingerprint())
+	}
+
+	// Ed25519 signs the message itself rather than a digest of it.
+	_, ok = signer.(ed25519.PrivateKey)
+	if ok {
+		return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(payload)
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign trust store bundle: %w", err)
+	}
+
+	return signature, nil
+}
+
+// verifyPayload checks that the payload was signed with the private key of the certificate.
+func verifyPayload(x509Cert *x509.Certificate, payload []byte, signature []byte) error {
+	var algorithm x509.SignatureAlgorithm
+	switch x509Cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case ed25519.PublicKey:
+		algorithm = x509.PureEd25519
+	default:
+		return fmt.Errorf("Unsupported public key type %T", x509Cert.PublicKey)
+	}
+
+	err := x509Cert.CheckSignature(algorithm, payload, signature)
+	if err != nil {
+		return fmt.Errorf("Trust store bundle signature does not match the certificate: %w", err)
+	}
+
+	return nil
+}
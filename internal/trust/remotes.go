@@ -5,20 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/validate"
 	"github.com/google/renameio"
 	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/microcluster/client"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/rest/types"
 )
 
@@ -26,18 +30,31 @@ import (
 type Remotes struct {
 	data     map[string]Remote
 	updateMu sync.RWMutex
+
+	audit func(event types.TrustAuditEvent) // Optional hook receiving every remote added or removed.
 }
 
 // Remote represents a yaml file with credentials to be read by the daemon.
 type Remote struct {
 	Location    `yaml:",inline"`
 	Certificate types.X509Certificate `yaml:"certificate"`
+
+	// AdditionalCertificates are also accepted as the certificate of the remote, such as its new certificate while it
+	// is being rotated. They are kept until the remote is recorded with one of them as its certificate.
+	AdditionalCertificates []types.X509Certificate `yaml:"additional_certificates,omitempty"`
+
+	// DqliteAddress is the address at which the remote accepts dqlite connections, if it differs from its address.
+	DqliteAddress string `yaml:"dqlite_address,omitempty"`
 }
 
 // Location represents configurable identifying information about a remote.
 type Location struct {
-	Name    string         `yaml:"name"`
-	Address types.AddrPort `yaml:"address"`
+	Name    string         `json:"name"    yaml:"name"`
+	Address types.AddrPort `json:"address" yaml:"address"`
+
+	// Interface is the name of the network interface whose address should be used as the address of the remote.
+	// If set, the IP of Address is re-resolved from the interface each time the network API starts.
+	Interface string `json:"interface,omitempty" yaml:"interface,omitempty"`
 }
 
 // Load reads any yaml files in the given directory and parses them into a set of Remotes.
@@ -68,7 +85,7 @@ func (r *Remotes) Load(dir string) error {
 			return fmt.Errorf("Unable to parse yaml for %q: %w", fileName, err)
 		}
 
-		if remote.Certificate.Certificate == nil {
+		if !remote.hasCertificates() {
 			return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
 		}
 
@@ -88,77 +105,126 @@ func (r *Remotes) Load(dir string) error {
 	return nil
 }
 
+// ValidateName validates that the given cluster member name is a valid fully qualified domain name,
+// so that it is safe for use in file paths and as a TLS server name.
+func ValidateName(name string) error {
+	// Validate length
+	if len(name) < 1 || len(name) > 255 {
+		return fmt.Errorf("Invalid cluster member name %q: Name must be 1-255 characters long", name)
+	}
+
+	hostnames := strings.Split(name, ".")
+	for _, h := range hostnames {
+		err := validate.IsHostname(h)
+		if err != nil {
+			return fmt.Errorf("Invalid cluster member name %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ErrNameInUse returns an error indicating that a cluster member with the given name already exists.
+func ErrNameInUse(name string) error {
+	return api.StatusErrorf(http.StatusConflict, "A cluster member with name %q already exists, a different name must be given to the new member when it is initialized", name)
+}
+
 // Add adds a new local cluster member record for the remotes.
-func (r *Remotes) Add(dir string, remotes ...Remote) error {
+// The source is the address of the client whose request caused the change, if any, and is recorded in audit events.
+func (r *Remotes) Add(dir string, source string, remotes ...Remote) error {
+	events, err := r.add(dir, source, remotes...)
+	r.emit(events)
+
+	return err
+}
+
+func (r *Remotes) add(dir string, source string, remotes ...Remote) ([]types.TrustAuditEvent, error) {
 	r.updateMu.Lock()
 	defer r.updateMu.Unlock()
 
+	events := make([]types.TrustAuditEvent, 0, len(remotes))
 	for _, remote := range remotes {
-		if remote.Certificate.Certificate == nil {
-			return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
+		err := ValidateName(remote.Name)
+		if err != nil {
+			return events, err
+		}
+
+		if !remote.hasCertificates() {
+			return events, fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
 		}
 
 		_, ok := r.data[remote.Name]
 		if ok {
-			return fmt.Errorf("A remote with name %q already exists", remote.Name)
+			return events, ErrNameInUse(remote.Name)
 		}
 
 		bytes, err := yaml.Marshal(remote)
 		if err != nil {
-			return fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+			return events, fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
 		}
 
 		path := filepath.Join(dir, fmt.Sprintf("%s.yaml", remote.Name))
 		_, err = os.Stat(path)
 		if err == nil {
-			return fmt.Errorf("Remote at %q already exists", path)
+			return events, fmt.Errorf("Remote at %q already exists", path)
 		}
 
 		if !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("Failed to check remote path %q: %w", path, err)
+			return events, fmt.Errorf("Failed to check remote path %q: %w", path, err)
 		}
 
 		err = renameio.WriteFile(path, bytes, 0644)
 		if err != nil {
-			return fmt.Errorf("Failed to write %q: %w", path, err)
+			return events, fmt.Errorf("Failed to write %q: %w", path, err)
 		}
 
 		// Add the remote manually so we can use it right away without waiting for inotify.
 		r.data[remote.Name] = remote
+		events = append(events, auditEvent(types.TrustAuditAdd, remote, source))
 	}
 
-	return nil
+	return events, nil
 }
 
 // Replace replaces the in-memory and locally stored remotes with the given list from the database.
-func (r *Remotes) Replace(dir string, newRemotes ...internalTypes.ClusterMember) error {
+// The source is the address of the client whose request caused the change, if any, and is recorded in audit events.
+func (r *Remotes) Replace(dir string, source string, newRemotes ...internalTypes.ClusterMember) error {
+	events, err := r.replace(dir, source, newRemotes...)
+	r.emit(events)
+
+	return err
+}
+
+func (r *Remotes) replace(dir string, source string, newRemotes ...internalTypes.ClusterMember) ([]types.TrustAuditEvent, error) {
 	r.updateMu.Lock()
 	defer r.updateMu.Unlock()
 
 	if len(newRemotes) == 0 {
-		return fmt.Errorf("Received empty remotes")
+		return nil, fmt.Errorf("Received empty remotes")
 	}
 
 	remoteData := map[string]Remote{}
 	for _, remote := range newRemotes {
 		newRemote := Remote{
-			Location:    Location{Name: remote.Name, Address: remote.Address},
-			Certificate: remote.Certificate,
+			Location:               Location{Name: remote.Name, Address: remote.Address},
+			Certificate:            remote.Certificate,
+			AdditionalCertificates: remote.AdditionalCertificates,
+			DqliteAddress:          remote.DqliteAddress,
 		}
 
-		if remote.Certificate.Certificate == nil {
-			return fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
+		if !newRemote.hasCertificates() {
+			return nil, fmt.Errorf("Failed to parse local record %q. Found empty certificate", remote.Name)
 		}
 
 		bytes, err := yaml.Marshal(newRemote)
 		if err != nil {
-			return fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+			return nil, fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
 		}
 
 		remotePath := filepath.Join(dir, fmt.Sprintf("%s.yaml", remote.Name))
 		err = renameio.WriteFile(remotePath, bytes, 0644)
 		if err != nil {
-			return fmt.Errorf("Failed to write %q: %w", remotePath, err)
+			return nil, fmt.Errorf("Failed to write %q: %w", remotePath, err)
 		}
 
 		remoteData[remote.Name] = newRemote
@@ -166,7 +232,7 @@ func (r *Remotes) Replace(dir string, newRemotes ...internalTypes.ClusterMember)
 
 	allEntries, err := os.ReadDir(dir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Remove any outdated entries.
@@ -178,18 +244,177 @@ func (r *Remotes) Replace(dir string, newRemotes ...internalTypes.ClusterMember)
 			remotePath := filepath.Join(dir, fmt.Sprintf("%s.yaml", name))
 			err = os.Remove(remotePath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
 	if len(remoteData) == 0 {
-		return fmt.Errorf("Failed to parse new remotes")
+		return nil, fmt.Errorf("Failed to parse new remotes")
+	}
+
+	// Record every certificate of a remote that is no longer accepted, or is now accepted.
+	events := []types.TrustAuditEvent{}
+	for name, oldRemote := range r.data {
+		newRemote := remoteData[name]
+		for _, cert := range oldRemote.Certificates() {
+			if !newRemote.HasCertificate(shared.CertFingerprint(cert.Certificate)) {
+				events = append(events, auditEvent(types.TrustAuditRemove, Remote{Location: oldRemote.Location, Certificate: cert}, source))
+			}
+		}
+	}
+
+	for name, newRemote := range remoteData {
+		oldRemote := r.data[name]
+		for _, cert := range newRemote.Certificates() {
+			if !oldRemote.HasCertificate(shared.CertFingerprint(cert.Certificate)) {
+				events = append(events, auditEvent(types.TrustAuditAdd, Remote{Location: newRemote.Location, Certificate: cert}, source))
+			}
+		}
 	}
 
 	r.data = remoteData
 
-	return nil
+	return events, nil
+}
+
+// AddCertificate adds another certificate that is accepted for the existing remote with the given name, so that
+// its certificate can be rotated without a period in which either the old or new certificate is rejected.
+// The certificate is only kept until the remotes are next replaced with the list from the database, so it must also be
+// recorded there with cluster.AddAdditionalCertificate.
+// The source is the address of the client whose request caused the change, if any, and is recorded in audit events.
+func (r *Remotes) AddCertificate(dir string, source string, name string, cert types.X509Certificate) error {
+	event, err := r.addCertificate(dir, source, name, cert)
+	if event != nil {
+		r.emit([]types.TrustAuditEvent{*event})
+	}
+
+	return err
+}
+
+func (r *Remotes) addCertificate(dir string, source string, name string, cert types.X509Certificate) (*types.TrustAuditEvent, error) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	if cert.Certificate == nil {
+		return nil, fmt.Errorf("Failed to add certificate to remote %q. Found empty certificate", name)
+	}
+
+	remote, ok := r.data[name]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	if remote.HasCertificate(shared.CertFingerprint(cert.Certificate)) {
+		return nil, nil
+	}
+
+	remote.AdditionalCertificates = append(remote.AdditionalCertificates, cert)
+	bytes, err := yaml.Marshal(remote)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s.yaml", remote.Name))
+	err = renameio.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write %q: %w", path, err)
+	}
+
+	r.data[remote.Name] = remote
+	event := auditEvent(types.TrustAuditAdd, Remote{Location: remote.Location, Certificate: cert}, source)
+
+	return &event, nil
+}
+
+// SetCertificates records the certificate and additional certificates that are accepted for the existing remote with
+// the given name, such as after one was promoted or retired in the database.
+// The source is the address of the client whose request caused the change, if any, and is recorded in audit events.
+func (r *Remotes) SetCertificates(dir string, source string, name string, certificate types.X509Certificate, additional []types.X509Certificate) error {
+	events, err := r.setCertificates(dir, source, name, certificate, additional)
+	r.emit(events)
+
+	return err
+}
+
+func (r *Remotes) setCertificates(dir string, source string, name string, certificate types.X509Certificate, additional []types.X509Certificate) ([]types.TrustAuditEvent, error) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	oldRemote, ok := r.data[name]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "No remote exists with the given name %q", name)
+	}
+
+	remote := oldRemote
+	remote.Certificate = certificate
+	remote.AdditionalCertificates = additional
+	if !remote.hasCertificates() {
+		return nil, fmt.Errorf("Failed to set certificates of remote %q. Found empty certificate", name)
+	}
+
+	bytes, err := yaml.Marshal(remote)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse remote %q to yaml: %w", remote.Name, err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s.yaml", remote.Name))
+	err = renameio.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write %q: %w", path, err)
+	}
+
+	r.data[remote.Name] = remote
+
+	events := []types.TrustAuditEvent{}
+	for _, cert := range oldRemote.Certificates() {
+		if !remote.HasCertificate(shared.CertFingerprint(cert.Certificate)) {
+			events = append(events, auditEvent(types.TrustAuditRemove, Remote{Location: remote.Location, Certificate: cert}, source))
+		}
+	}
+
+	for _, cert := range remote.Certificates() {
+		if !oldRemote.HasCertificate(shared.CertFingerprint(cert.Certificate)) {
+			events = append(events, auditEvent(types.TrustAuditAdd, Remote{Location: remote.Location, Certificate: cert}, source))
+		}
+	}
+
+	return events, nil
+}
+
+// SetAuditHook sets a hook that receives an event for every remote added to or removed from the remotes.
+func (r *Remotes) SetAuditHook(audit func(event types.TrustAuditEvent)) {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	r.audit = audit
+}
+
+// emit sends the given events to the audit hook, if one is set.
+func (r *Remotes) emit(events []types.TrustAuditEvent) {
+	r.updateMu.RLock()
+	audit := r.audit
+	r.updateMu.RUnlock()
+
+	if audit == nil {
+		return
+	}
+
+	for _, event := range events {
+		audit(event)
+	}
+}
+
+// auditEvent returns an audit event for the given operation on the remote.
+func auditEvent(operation types.TrustAuditOperation, remote Remote, source string) types.TrustAuditEvent {
+	return types.TrustAuditEvent{
+		Operation:   operation,
+		Time:        time.Now().UTC(),
+		Name:        remote.Name,
+		Address:     remote.Address,
+		Fingerprint: shared.CertFingerprint(remote.Certificate.Certificate),
+		Source:      source,
+	}
 }
 
 // SelectRandom returns a random remote.
@@ -219,11 +444,12 @@ func (r *Remotes) Addresses() map[string]types.AddrPort {
 }
 
 // Cluster returns a set of clients for every remote, which can be concurrently queried.
-func (r *Remotes) Cluster(isNotification bool, serverCert *shared.CertInfo, publicKey *x509.Certificate) (client.Cluster, error) {
-	cluster := make(client.Cluster, 0, r.Count()-1)
+// Clients reuse the connections of the given pool, if it is not nil.
+func (r *Remotes) Cluster(pool *internalClient.Pool, isNotification bool, serverCert *shared.CertInfo, publicKey *x509.Certificate) (client.Cluster, error) {
+	cluster := make(client.Cluster, 0, r.Count())
 	for _, addr := range r.Addresses() {
-		url := api.NewURL().Scheme("https").Host(addr.String())
-		c, err := internalClient.New(*url, serverCert, publicKey, isNotification)
+		url := api.NewURL().Scheme(sys.Scheme()).Host(addr.String())
+		c, err := pool.Get(*url, serverCert, publicKey, isNotification)
 		if err != nil {
 			return nil, err
 		}
@@ -254,7 +480,7 @@ func (r *Remotes) RemoteByCertificateFingerprint(fingerprint string) *Remote {
 	defer r.updateMu.RUnlock()
 
 	for _, remote := range r.data {
-		if fingerprint == shared.CertFingerprint(remote.Certificate.Certificate) {
+		if remote.HasCertificate(fingerprint) {
 			return &remote
 		}
 	}
@@ -269,7 +495,9 @@ func (r *Remotes) Certificates() map[string]types.X509Certificate {
 
 	certMap := map[string]types.X509Certificate{}
 	for _, remote := range r.data {
-		certMap[shared.CertFingerprint(remote.Certificate.Certificate)] = remote.Certificate
+		for _, cert := range remote.Certificates() {
+			certMap[shared.CertFingerprint(cert.Certificate)] = cert
+		}
 	}
 
 	return certMap
@@ -282,7 +510,9 @@ func (r *Remotes) CertificatesNative() map[string]x509.Certificate {
 
 	certMap := map[string]x509.Certificate{}
 	for _, remote := range r.data {
-		certMap[shared.CertFingerprint(remote.Certificate.Certificate)] = *remote.Certificate.Certificate
+		for _, cert := range remote.Certificates() {
+			certMap[shared.CertFingerprint(cert.Certificate)] = *cert.Certificate
+		}
 	}
 
 	return certMap
@@ -311,5 +541,32 @@ func (r *Remotes) RemotesByName() map[string]Remote {
 
 // URL returns the parsed URL of the Remote.
 func (r *Remote) URL() api.URL {
-	return *api.NewURL().Scheme("https").Host(r.Address.String())
+	return *api.NewURL().Scheme(sys.Scheme()).Host(r.Address.String())
+}
+
+// Certificates returns every certificate accepted for the remote, starting with its recorded certificate.
+func (r *Remote) Certificates() []types.X509Certificate {
+	return append([]types.X509Certificate{r.Certificate}, r.AdditionalCertificates...)
+}
+
+// HasCertificate returns whether a certificate with the given fingerprint is accepted for the remote.
+func (r *Remote) HasCertificate(fingerprint string) bool {
+	for _, cert := range r.Certificates() {
+		if cert.Certificate != nil && fingerprint == shared.CertFingerprint(cert.Certificate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasCertificates returns whether the remote has a certificate, and none of its additional certificates are empty.
+func (r *Remote) hasCertificates() bool {
+	for _, cert := range r.Certificates() {
+		if cert.Certificate == nil {
+			return false
+		}
+	}
+
+	return true
 }
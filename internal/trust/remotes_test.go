@@ -0,0 +1,142 @@
+package trust
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/suite"
+
+	internalTypes "github.com/canonical/microcluster/internal/rest/types"
+	"github.com/canonical/microcluster/rest/types"
+)
+
+type remotesSuite struct {
+	suite.Suite
+}
+
+func TestRemotesSuite(t *testing.T) {
+	suite.Run(t, new(remotesSuite))
+}
+
+// newTestCert returns a new self-signed certificate.
+func newTestCert() (types.X509Certificate, error) {
+	cert, key, err := shared.GenerateMemCert(true, false)
+	if err != nil {
+		return types.X509Certificate{}, err
+	}
+
+	certInfo, err := shared.KeyPairFromRaw(cert, key)
+	if err != nil {
+		return types.X509Certificate{}, err
+	}
+
+	x509Cert, err := certInfo.PublicKeyX509()
+	if err != nil {
+		return types.X509Certificate{}, err
+	}
+
+	return types.X509Certificate{Certificate: x509Cert}, nil
+}
+
+// testLocation returns the location of a remote with the given name and an address on a port unique to the name.
+func testLocation(name string, port uint16) Location {
+	return Location{Name: name, Address: types.AddrPort{AddrPort: netip.AddrPortFrom(netip.MustParseAddr("10.0.0.1"), port)}}
+}
+
+// fingerprint returns the fingerprint of the certificate.
+func fingerprint(cert types.X509Certificate) string {
+	return shared.CertFingerprint(cert.Certificate)
+}
+
+// Ensures a remote is found by any of its certificates, and each of them is accepted while it is being rotated.
+func (s *remotesSuite) Test_multipleCertificates() {
+	certs := make([]types.X509Certificate, 4)
+	for i := range certs {
+		var err error
+		certs[i], err = newTestCert()
+		s.Require().NoError(err)
+	}
+
+	dir := s.T().TempDir()
+	remotes := &Remotes{data: map[string]Remote{}}
+	err := remotes.Add(dir, "", Remote{Location: testLocation("a", 9001), Certificate: certs[0]}, Remote{Location: testLocation("b", 9002), Certificate: certs[3]})
+	s.Require().NoError(err)
+
+	// Adding a certificate to a missing remote fails, and adding an accepted one does nothing.
+	s.Error(remotes.AddCertificate(dir, "", "c", certs[1]))
+	s.NoError(remotes.AddCertificate(dir, "", "a", certs[0]))
+	s.NoError(remotes.AddCertificate(dir, "", "a", certs[1]))
+	s.Len(remotes.RemotesByName()["a"].AdditionalCertificates, 1)
+
+	for _, cert := range certs[:2] {
+		remote := remotes.RemoteByCertificateFingerprint(fingerprint(cert))
+		s.Require().NotNil(remote)
+		s.Equal("a", remote.Name)
+		s.Contains(remotes.Certificates(), fingerprint(cert))
+		s.Contains(remotes.CertificatesNative(), fingerprint(cert))
+	}
+
+	s.Nil(remotes.RemoteByCertificateFingerprint(fingerprint(certs[2])))
+	s.Len(remotes.Certificates(), 3)
+
+	// The additional certificates are kept when loaded from disk.
+	loaded := &Remotes{}
+	s.Require().NoError(loaded.Load(dir))
+	s.Equal("a", loaded.RemoteByCertificateFingerprint(fingerprint(certs[1])).Name)
+
+	// Promoting the new certificate keeps the old one accepted, and retiring the old one stops accepting it.
+	var events []types.TrustAuditEvent
+	remotes.SetAuditHook(func(event types.TrustAuditEvent) { events = append(events, event) })
+
+	s.NoError(remotes.SetCertificates(dir, "", "a", certs[1], []types.X509Certificate{certs[0]}))
+	s.Equal("a", remotes.RemoteByCertificateFingerprint(fingerprint(certs[0])).Name)
+	s.Empty(events)
+
+	s.NoError(remotes.SetCertificates(dir, "", "a", certs[1], nil))
+	s.Nil(remotes.RemoteByCertificateFingerprint(fingerprint(certs[0])))
+	s.Equal("a", remotes.RemoteByCertificateFingerprint(fingerprint(certs[1])).Name)
+	s.Require().Len(events, 1)
+	s.Equal(types.TrustAuditRemove, events[0].Operation)
+	s.Equal(fingerprint(certs[0]), events[0].Fingerprint)
+
+	s.Error(remotes.SetCertificates(dir, "", "c", certs[2], nil))
+}
+
+// Ensures replacing the remotes records the additional certificates from the given list, rather than keeping any
+// that were only accepted locally.
+func (s *remotesSuite) Test_replaceAdditionalCertificates() {
+	certs := make([]types.X509Certificate, 3)
+	for i := range certs {
+		var err error
+		certs[i], err = newTestCert()
+		s.Require().NoError(err)
+	}
+
+	dir := s.T().TempDir()
+	remotes := &Remotes{data: map[string]Remote{}}
+	err := remotes.Add(dir, "", Remote{Location: testLocation("a", 9001), Certificate: certs[0], AdditionalCertificates: []types.X509Certificate{certs[1]}})
+	s.Require().NoError(err)
+
+	var events []types.TrustAuditEvent
+	remotes.SetAuditHook(func(event types.TrustAuditEvent) { events = append(events, event) })
+
+	member := internalTypes.ClusterMember{ClusterMemberLocal: internalTypes.ClusterMemberLocal{
+		Name:                   "a",
+		Address:                testLocation("a", 9001).Address,
+		Certificate:            certs[0],
+		AdditionalCertificates: []types.X509Certificate{certs[2]},
+	}}
+
+	s.NoError(remotes.Replace(dir, "", member))
+	s.Nil(remotes.RemoteByCertificateFingerprint(fingerprint(certs[1])))
+	s.Equal("a", remotes.RemoteByCertificateFingerprint(fingerprint(certs[2])).Name)
+	s.Len(events, 2)
+
+	loaded := &Remotes{}
+	s.Require().NoError(loaded.Load(dir))
+	loadedRemote := loaded.RemotesByName()["a"]
+	s.Equal(fingerprint(certs[0]), fingerprint(loadedRemote.Certificate))
+	s.Require().Len(loadedRemote.AdditionalCertificates, 1)
+	s.Equal(fingerprint(certs[2]), fingerprint(loadedRemote.AdditionalCertificates[0]))
+}
@@ -2,19 +2,36 @@ package trust
 
 import (
 	"fmt"
+	"math/rand"
 	"sync"
+	"time"
 
+	"github.com/canonical/lxd/shared/logger"
 	"github.com/fsnotify/fsnotify"
 
 	"github.com/canonical/microcluster/internal/sys"
 )
 
+// refreshDelay is how long to wait after a change to the truststore directory before reloading it, so that a burst of
+// changes results in a single reload.
+const refreshDelay = 250 * time.Millisecond
+
+// refreshJitter is the maximum random delay added to refreshDelay, so that cluster members receiving the same burst of
+// changes do not all reload at once.
+const refreshJitter = 250 * time.Millisecond
+
 // Store represents a directory of remotes watched by the fsnotify Watcher.
 type Store struct {
 	remotesMu sync.RWMutex // Mutex for coordinating manual and fsnotify access to remotes.
 	remotes   *Remotes     // Should never be called directly, instead use Remotes().
 
 	refresh func(path string) error
+
+	refreshMu      sync.Mutex // Mutex for coordinating pending refreshes.
+	refreshPending bool       // Whether a refresh has been scheduled but not yet started.
+
+	onError  func(err error)        // Optional handler for refreshes triggered by the watcher that fail.
+	onUpdate func(remotes *Remotes) // Optional handler for refreshes that succeed.
 }
 
 // Init initializes the remotes in the truststore, seeds the rand package for selecting remotes at random, and watches
@@ -41,12 +58,22 @@ func Init(watcher *sys.Watcher, onUpdate func(oldRemotes, newRemotes Remotes) er
 			return fmt.Errorf("Unable to refresh remotes in path %q: %w", path, err)
 		}
 
+		ts.refreshMu.Lock()
+		onUpdate := ts.onUpdate
+		ts.refreshMu.Unlock()
+
+		if onUpdate != nil {
+			onUpdate(ts.remotes)
+		}
+
 		return nil
 	}
 
 	// Watch on the truststore directory for yaml updates.
 	watcher.Watch(dir, "yaml", func(path string, event fsnotify.Op) error {
-		return ts.refresh(path)
+		ts.scheduleRefresh(path)
+
+		return nil
 	})
 
 	return ts, nil
@@ -60,7 +87,56 @@ func (ts *Store) Remotes() *Remotes {
 	return ts.remotes
 }
 
+// SetErrorHandler sets a function to be called when reloading the truststore after a change on disk fails.
+func (ts *Store) SetErrorHandler(f func(err error)) {
+	ts.refreshMu.Lock()
+	defer ts.refreshMu.Unlock()
+
+	ts.onError = f
+}
+
+// SetUpdateHandler sets a function to be called with the remotes after the truststore is reloaded.
+func (ts *Store) SetUpdateHandler(f func(remotes *Remotes)) {
+	ts.refreshMu.Lock()
+	defer ts.refreshMu.Unlock()
+
+	ts.onUpdate = f
+}
+
 // Refresh reloads the truststore and runs any associated hooks.
 func (ts *Store) Refresh() error {
 	return ts.refresh("*")
 }
+
+// scheduleRefresh reloads the truststore after a short, jittered delay. Any changes made before the reload starts are
+// coalesced into it, while changes made after it starts schedule another, so the remotes always end up matching the
+// files on disk.
+func (ts *Store) scheduleRefresh(path string) {
+	ts.refreshMu.Lock()
+	defer ts.refreshMu.Unlock()
+
+	if ts.refreshPending {
+		return
+	}
+
+	ts.refreshPending = true
+	delay := refreshDelay + time.Duration(rand.Int63n(int64(refreshJitter)))
+	time.AfterFunc(delay, func() {
+		ts.refreshMu.Lock()
+		ts.refreshPending = false
+		ts.refreshMu.Unlock()
+
+		err := ts.refresh(path)
+		if err != nil {
+			logger.Error("Failed to refresh truststore", logger.Ctx{"error": err})
+
+			ts.refreshMu.Lock()
+			onError := ts.onError
+			ts.refreshMu.Unlock()
+
+			if onError != nil {
+				onError(err)
+			}
+		}
+	})
+}
@@ -5,22 +5,30 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/canonical/lxd/lxd/db/schema"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sys/unix"
 
 	"github.com/canonical/microcluster/client"
 	"github.com/canonical/microcluster/cluster"
 	"github.com/canonical/microcluster/config"
 	"github.com/canonical/microcluster/internal/daemon"
+	"github.com/canonical/microcluster/internal/db/update"
+	"github.com/canonical/microcluster/internal/logging"
 	internalClient "github.com/canonical/microcluster/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/internal/rest/types"
 	"github.com/canonical/microcluster/internal/sys"
@@ -32,7 +40,8 @@ import (
 type MicroCluster struct {
 	FileSystem *sys.OS
 
-	args Args
+	args   Args
+	errors chan error
 }
 
 // Args contains options for configuring MicroCluster.
@@ -43,12 +52,179 @@ type Args struct {
 	SocketGroup string
 
 	ListenPort string
+
+	// DisableNetworkAPI serves the API over the control socket only, without any network listeners. ListenPort must
+	// be empty. Such a daemon can only bootstrap a single-member cluster, and can't join or be joined by others.
+	DisableNetworkAPI bool
+
+	// ReplicaOnly runs this cluster member as a read-only replica, which only ever holds the dqlite spare role and
+	// so never counts towards the target number of voters or stand-bys. Writes to the public API are rejected, while
+	// reads are served from the local database. Such a member can join a cluster but can't bootstrap one, and it
+	// refuses to start unless the cluster has enough other members to reach the target number of voters and
+	// stand-bys, as dqlite would otherwise promote it.
+	ReplicaOnly bool
+
+	// MemberName is the default name of this cluster member before it is bootstrapped or joins a cluster, instead of
+	// the hostname, or a generated name if the system has no hostname. It is ignored once the member is initialized.
+	// See state.State.RenameMember to rename a member.
+	MemberName string
 	Client     *client.Client
 	Proxy      func(*http.Request) (*url.URL, error)
 
+	// MaxRequestBytes is the default maximum size of request bodies accepted by the daemon, unless overridden by a
+	// server or endpoint. If 0, a default of 32MiB is used. If negative, request bodies are not limited.
+	MaxRequestBytes int64
+
+	// RateLimits limits the rate of requests to the public API over the network.
+	// Requests exceeding the limits receive a 429 response with a Retry-After header.
+	RateLimits types.RateLimits
+
+	// ResponseHeaders are static headers, such as Strict-Transport-Security, added to every API response.
+	// Headers set by endpoint handlers take precedence. A header with an empty value is removed from every response.
+	ResponseHeaders map[string]string
+
+	// DatabaseDir is an existing, writable directory in which to store the database, for example on a dedicated disk.
+	// If empty, the database is stored within the state directory. If set while the state directory already holds a
+	// database, the daemon refuses to start until the contents of its database directory are moved here.
+	DatabaseDir string
+
+	// ControlSocket is the address of the control socket, if not the control.socket file in the state directory.
+	// It must be an absolute path, or an address starting with "@" for a socket in the Linux abstract namespace, which
+	// leaves no stale file behind after a crash. An abstract socket has no file permissions, so it only accepts
+	// connections from processes running as root or as the daemon's user, or as a member of SocketGroup.
+	ControlSocket string
+
+	// TargetVoters and TargetStandBys are the number of dqlite voters and stand-bys that automatic role assignment
+	// targets. A number of voters of zero, or no number of stand-bys, uses the default of 3. The number of voters must
+	// be odd, and all cluster members must be started with the same values.
+	TargetVoters   int
+	TargetStandBys *int
+
+	// SnapshotThreshold is the number of raft log entries after which dqlite takes a snapshot of the database, and
+	// SnapshotTrailing is the number of entries it retains in memory after a snapshot. Zero uses dqlite's defaults of
+	// 1024 and 8192. Lower values reduce memory use on write-heavy members, but a member that falls behind is then more
+	// likely to need a full snapshot to catch up. SnapshotTrailing must be at least SnapshotThreshold.
+	SnapshotThreshold uint64
+	SnapshotTrailing  uint64
+
+	// ClockSkewThreshold is the difference between the clocks of cluster members above which a warning is logged
+	// when the leader sends a heartbeat. Zero uses the default of 5 seconds, and a negative value disables the warning.
+	ClockSkewThreshold time.Duration
+
+	// HeartbeatHistoryDepth is the number of recent heartbeat outcomes that the leader keeps in memory for each cluster
+	// member, as returned by MemberHistory. Zero uses the default of 64, and a negative value disables the history.
+	HeartbeatHistoryDepth int
+
+	// TracerProvider is an optional OpenTelemetry tracer provider used to start a span for each API request,
+	// continuing any trace propagated with a traceparent header. Requests the daemon sends to other cluster members
+	// while handling a request are child spans. If nil, requests are not traced.
+	TracerProvider trace.TracerProvider
+
+	// ClusterCertificate is an optional pre-generated cluster keypair, such as one signed by an external CA, used when
+	// bootstrapping a new cluster instead of generating a self-signed one. It must be usable for server authentication
+	// and include the CA that signed it, which is also given to joining members. It is ignored once initialized.
+	ClusterCertificate *shared.CertInfo
+
+	// LowDiskThreshold is the number of free bytes in the database directory below which the cluster member is low on
+	// disk space. While it is low, a warning is logged, the ready endpoint reports the member as unavailable, and the
+	// OnLowDisk hook is run. Zero uses the default of 256MiB, and a negative value disables the check.
+	LowDiskThreshold int64
+
+	// DiskCheckInterval is how often the free space in the database directory is checked. Zero uses the default of
+	// one minute.
+	DiskCheckInterval time.Duration
+
+	// DaemonConfigJSON writes the daemon configuration in the state directory as daemon.json rather than the default
+	// daemon.yaml. A configuration file in either format is read, and converted the next time it is written.
+	DaemonConfigJSON bool
+
+	// VerifyPeerCertificate enforces extra policy on the certificates presented by clients of the core API and
+	// extension server listeners, such as requiring a custom extension issued by a provisioning system. It runs during
+	// the TLS handshake, and an error rejects the connection with a TLS alert. Client certificates are requested but not
+	// verified against any CA, as cluster members use self-signed certificates, so verifiedChains is always nil and the
+	// function must parse and check rawCerts itself. Clients that present no certificate are not checked, and whether a
+	// certificate is trusted by the cluster is still checked for each request.
+	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+
+	// ServerTimeouts are the timeouts of the HTTP servers of the unix socket and every network listener. By default, the
+	// headers of a request must be read within 10 seconds, and idle keep-alive connections are closed after 2 minutes,
+	// while request bodies and responses are not limited so that streaming responses keep working.
+	ServerTimeouts types.ServerTimeouts
+
+	// CompressionThreshold is the size in bytes above which API responses are compressed with gzip for clients that
+	// accept it. Zero uses the default of 1KiB, and a negative value disables compression. Individual endpoints can opt
+	// out with rest.Endpoint.DisableCompression.
+	CompressionThreshold int
+
+	// RecoveryMode starts an initialized cluster member without its database, serving only the control socket, so that
+	// the cluster can be recovered from the loss of quorum with RecoverFromQuorumLoss.
+	RecoveryMode bool
+
+	// AsyncPostJoin runs the PostJoin hook in the background as an operation, so that joining the cluster completes
+	// once this member is part of it. The hook's progress and any error are reported by the operation of this member
+	// described by types.OperationPostJoin, as listed by ListOperations.
+	AsyncPostJoin bool
+
+	// JoinSyncTimeout is the longest that this member reports itself as syncing after joining a cluster or restarting,
+	// while it catches up with the leader. It has caught up once its dqlite node is ready and it has then received a
+	// heartbeat from the leader. Until then or until the timeout elapses, it reports syncing in its cluster health and
+	// fails readiness checks, and after a restart the daemon is not reported as ready. Zero disables the sync gate.
+	JoinSyncTimeout time.Duration
+
+	// RejectReadsWhileSyncing rejects reads from the public API and extension endpoints over the network while this
+	// member is syncing, so that traffic is not served by a member that has only just joined.
+	RejectReadsWhileSyncing bool
+
+	// ConfigRegistry declares the keys of the cluster-wide configuration, with their types, defaults and validation.
+	// Declared keys are read and written with state.GetConfig and state.SetConfig, and invalid values are rejected
+	// before they are persisted.
+	ConfigRegistry *config.Registry
+
+	// FailureDomain identifies the failure domain of this cluster member, such as its rack or availability zone.
+	// dqlite prefers to spread voters across distinct failure domains, so that the loss of one keeps quorum.
+	FailureDomain uint64
+
+	// Tags are free-form labels describing the placement of this cluster member, listed along with its failure domain.
+	Tags map[string]string
+
+	// AllowRemoteSQL allows SQL queries against the database from other cluster members.
+	// By default, they are only allowed over the local control socket.
+	AllowRemoteSQL bool
+
+	// TrustAuditHook receives an event whenever a cluster member is added to or removed from the local trust store.
+	TrustAuditHook func(event types.TrustAuditEvent)
+
+	// LogHandler receives the log records of the daemon, its API endpoints, and the heartbeat.
+	// If nil, these are logged through the global logger configured by Verbose and Debug.
+	LogHandler slog.Handler
+
+	// DqliteLogHandler receives dqlite's own log records, such as those about raft elections and snapshots, at or
+	// above DqliteLogLevel. If nil, dqlite logs errors only, through the standard library logger.
+	DqliteLogHandler slog.Handler
+	DqliteLogLevel   slog.Level
+
+	// Plaintext makes cluster members in this process listen and connect to each other over plain HTTP instead of
+	// HTTPS, skipping all certificate checks. It is meant for tests only, and is rejected unless the binary is built
+	// with the microcluster_plaintext tag.
+	Plaintext bool
+
+	// DialFunc, if set, is used to establish all dqlite connections to other cluster members, for example to route
+	// them through an overlay network. TLS is still negotiated on top of the returned connection.
+	DialFunc func(ctx context.Context, address string) (net.Conn, error)
+
+	// DqliteAddress, if set, is a dedicated address at which this cluster member accepts dqlite connections from the
+	// other members, such as one on an internal network, so that database replication is kept apart from API traffic.
+	// It must have a specific IP address and port that differ from the API address. Dqlite connections are then no
+	// longer accepted at the API address.
+	DqliteAddress string
+
 	extensionServers []rest.Server
+	schemaExtensions update.SchemaExtensions
 }
 
+// runtimeErrorsBuffer is the number of runtime errors kept until they are received from MicroCluster.Errors.
+const runtimeErrorsBuffer = 16
+
 // App returns an instance of MicroCluster with a newly initialized filesystem if one does not exist.
 func App(args Args) (*MicroCluster, error) {
 	if args.StateDir == "" {
@@ -58,7 +234,14 @@ func App(args Args) (*MicroCluster, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Missing absolute state directory: %w", err)
 	}
-	os, err := sys.DefaultOS(stateDir, args.SocketGroup, true)
+	if args.Plaintext {
+		err := sys.EnablePlaintext()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	os, err := sys.DefaultOS(stateDir, args.DatabaseDir, args.ControlSocket, args.SocketGroup, true)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +249,7 @@ func App(args Args) (*MicroCluster, error) {
 	return &MicroCluster{
 		FileSystem: os,
 		args:       args,
+		errors:     make(chan error, runtimeErrorsBuffer),
 	}, nil
 }
 
@@ -82,7 +266,65 @@ func (m *MicroCluster) Start(ctx context.Context, extensionsSchema []schema.Upda
 
 	// Start up a daemon with a basic control socket.
 	defer logger.Info("Daemon stopped")
-	d := daemon.NewDaemon(cluster.GetCallerProject())
+	var log logger.Logger
+	if m.args.LogHandler != nil {
+		log = logging.NewSlogLogger(m.args.LogHandler)
+	}
+
+	d := daemon.NewDaemon(cluster.GetCallerProject(), log)
+	d.SetDqliteAddress(m.args.DqliteAddress)
+	if m.args.DialFunc != nil {
+		d.SetDialFunc(m.args.DialFunc)
+	}
+
+	d.SetMemberName(m.args.MemberName)
+	d.SetMaxRequestBytes(m.args.MaxRequestBytes)
+	d.SetRateLimits(m.args.RateLimits)
+	d.SetSchemaExtensions(m.args.schemaExtensions)
+	d.SetAllowRemoteSQL(m.args.AllowRemoteSQL)
+	d.SetFailureDomain(m.args.FailureDomain, m.args.Tags)
+	d.SetDatabaseDir(m.args.DatabaseDir)
+	d.SetControlSocket(m.args.ControlSocket)
+	d.SetResponseHeaders(m.args.ResponseHeaders)
+	d.SetCompressionThreshold(m.args.CompressionThreshold)
+	d.SetServerTimeouts(m.args.ServerTimeouts)
+	if m.args.VerifyPeerCertificate != nil {
+		d.SetVerifyPeerCertificate(m.args.VerifyPeerCertificate)
+	}
+	d.SetNetworkDisabled(m.args.DisableNetworkAPI)
+	d.SetReplicaOnly(m.args.ReplicaOnly)
+	d.SetRecoveryMode(m.args.RecoveryMode)
+	d.SetAsyncPostJoin(m.args.AsyncPostJoin)
+	d.SetSyncGate(m.args.JoinSyncTimeout, m.args.RejectReadsWhileSyncing)
+
+	standBys := -1
+	if m.args.TargetStandBys != nil {
+		standBys = *m.args.TargetStandBys
+	}
+
+	d.SetTargetRoles(m.args.TargetVoters, standBys)
+
+	d.SetSnapshotParams(m.args.SnapshotThreshold, m.args.SnapshotTrailing)
+	d.SetClockSkewThreshold(m.args.ClockSkewThreshold)
+	d.SetHeartbeatHistoryDepth(m.args.HeartbeatHistoryDepth)
+	d.SetDiskSpaceCheck(m.args.LowDiskThreshold, m.args.DiskCheckInterval)
+	d.SetDaemonConfigJSON(m.args.DaemonConfigJSON)
+	if m.args.ConfigRegistry != nil {
+		d.SetConfigRegistry(m.args.ConfigRegistry)
+	}
+	if m.args.TracerProvider != nil {
+		d.SetTracerProvider(m.args.TracerProvider)
+	}
+	if m.args.ClusterCertificate != nil {
+		d.SetBootstrapClusterCert(m.args.ClusterCertificate)
+	}
+	d.SetErrorChannel(m.errors)
+	if m.args.DqliteLogHandler != nil {
+		d.SetDqliteLogFunc(logging.NewDqliteLogFunc(m.args.DqliteLogHandler, m.args.DqliteLogLevel))
+	}
+	if m.args.TrustAuditHook != nil {
+		d.SetTrustAuditHook(m.args.TrustAuditHook)
+	}
 
 	chIgnore := make(chan os.Signal, 1)
 	signal.Notify(chIgnore, unix.SIGHUP)
@@ -103,6 +345,22 @@ func (m *MicroCluster) AddServers(servers []rest.Server) {
 	m.args.extensionServers = servers
 }
 
+// Errors returns a channel that receives the non-fatal errors, of type types.RuntimeError, that the daemon started
+// with Start encounters while running, such as an API listener stopping or a heartbeat round failing. The daemon keeps
+// running after these errors, so a supervisor can decide whether to restart it. Fatal errors are returned by Start.
+// Errors are dropped while the channel is full.
+func (m *MicroCluster) Errors() <-chan error {
+	return m.errors
+}
+
+// RegisterSchema registers the schema updates of a named extension, in the order they should be applied.
+// Each extension's schema version is tracked separately, so that independently developed extensions can contribute
+// their own schema without coordinating update indices. Registered extensions are applied in the order they were
+// registered, after the schema updates given to Start. It must be called before Start.
+func (m *MicroCluster) RegisterSchema(name string, updates []schema.Update) error {
+	return m.args.schemaExtensions.Register(name, updates)
+}
+
 // Status returns basic status information about the cluster.
 func (m *MicroCluster) Status(ctx context.Context) (*internalTypes.Server, error) {
 	c, err := m.LocalClient()
@@ -180,34 +438,77 @@ func (m *MicroCluster) Ready(ctx context.Context) error {
 	return nil
 }
 
+// parseListenAddress parses an address of the form `<ip>:<port>` or `<interface>:<port>`. If the host is the name of a
+// network interface, the interface name is returned with an unspecified address, which the daemon resolves to the
+// current address of the interface.
+func parseListenAddress(address string) (types.AddrPort, string, error) {
+	addr, err := types.ParseAddrPort(address)
+	if err == nil {
+		return addr, "", nil
+	}
+
+	host, portStr, splitErr := net.SplitHostPort(address)
+	if splitErr != nil {
+		return types.AddrPort{}, "", err
+	}
+
+	_, ifaceErr := net.InterfaceByName(host)
+	if ifaceErr != nil {
+		return types.AddrPort{}, "", err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return types.AddrPort{}, "", fmt.Errorf("Invalid port %q: %w", portStr, err)
+	}
+
+	return types.AddrPort{AddrPort: netip.AddrPortFrom(netip.IPv4Unspecified(), uint16(port))}, host, nil
+}
+
 // NewCluster bootstrapps a brand new cluster with this daemon as its only member.
+// The address may be given as `<ip>:<port>`, or as `<interface>:<port>` to listen on the address of a network interface.
+// The daemon will refuse to start if the address of the interface later changes.
 func (m *MicroCluster) NewCluster(ctx context.Context, name string, address string, config map[string]string) error {
 	c, err := m.LocalClient()
 	if err != nil {
 		return err
 	}
 
-	addr, err := types.ParseAddrPort(address)
+	addr, iface, err := parseListenAddress(address)
 	if err != nil {
 		return fmt.Errorf("Received invalid address %q: %w", address, err)
 	}
 
-	return c.ControlDaemon(ctx, internalTypes.Control{Bootstrap: true, Address: addr, Name: name, InitConfig: config})
+	return c.ControlDaemon(ctx, internalTypes.Control{Bootstrap: true, Address: addr, Interface: iface, Name: name, InitConfig: config})
 }
 
 // JoinCluster joins an existing cluster with a join token supplied by an existing cluster member.
+// The address may be given as `<ip>:<port>`, or as `<interface>:<port>` to listen on the address of a network interface.
+// If the join fails for a known reason, the error wraps one of the join errors such as types.ErrAlreadyClustered.
 func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address string, token string, initConfig map[string]string) error {
 	c, err := m.LocalClient()
 	if err != nil {
 		return err
 	}
 
-	addr, err := types.ParseAddrPort(address)
+	addr, iface, err := parseListenAddress(address)
 	if err != nil {
 		return fmt.Errorf("Received invalid address %q: %w", address, err)
 	}
 
-	return c.ControlDaemon(ctx, internalTypes.Control{JoinToken: token, Address: addr, Name: name, InitConfig: initConfig})
+	return c.ControlDaemon(ctx, internalTypes.Control{JoinToken: token, Address: addr, Interface: iface, Name: name, InitConfig: initConfig})
+}
+
+// CheckJoin checks whether this daemon is compatible with the cluster it would join with the given join token,
+// without joining the cluster. The returned report lists any schema version or API extension mismatches with each
+// cluster member in the token, and whether they could be reached.
+func (m *MicroCluster) CheckJoin(ctx context.Context, token string) (*types.JoinCheck, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.CheckJoin(ctx, internalTypes.JoinCheck{JoinToken: token})
 }
 
 // NewJoinToken creates and records a new join token containing all the necessary credentials for joining a cluster.
@@ -257,6 +558,245 @@ func (m *MicroCluster) RevokeJoinToken(ctx context.Context, name string) error {
 	return nil
 }
 
+// ListOperations lists the long-running operations on the local cluster member.
+func (m *MicroCluster) ListOperations(ctx context.Context) ([]types.Operation, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetOperations(ctx)
+}
+
+// CancelOperation cancels the running operation with the given ID on the local cluster member.
+func (m *MicroCluster) CancelOperation(ctx context.Context, id string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.CancelOperation(ctx, id)
+}
+
+// DrainMember marks the cluster member with the given name as draining, so that it stops accepting new writes and
+// reports itself as unavailable to health checks, and waits for its running operations to complete. Once this
+// returns, the cluster member can be safely removed. The draining status persists across restarts until the cluster
+// member is removed or UndrainMember is called. Removing a cluster member without force also drains it first. The deadline of the context, if any, bounds how long to wait.
+func (m *MicroCluster) DrainMember(ctx context.Context, name string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.DrainClusterMember(ctx, name)
+}
+
+// UndrainMember clears the draining status of the cluster member with the given name, so that it accepts new writes
+// and passes health checks again.
+func (m *MicroCluster) UndrainMember(ctx context.Context, name string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.UndrainClusterMember(ctx, name)
+}
+
+// MemberHistory returns the recent heartbeat outcomes of the cluster member with the given name, oldest first, so that
+// a member that keeps dropping out and coming back can be told apart from one that is cleanly up or down. Outcomes are
+// recorded in memory by the dqlite leader, so the history is fetched from the current leader and starts over when
+// leadership changes.
+func (m *MicroCluster) MemberHistory(ctx context.Context, name string) ([]types.HeartbeatOutcome, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetHeartbeatHistory(ctx, name)
+}
+
+// SetSocketGroup changes the group that owns the control socket, without restarting the daemon. If the group is
+// empty, the group of the daemon process is used. The change lasts until the daemon restarts, so the SocketGroup
+// argument should also be updated for subsequent starts.
+func (m *MicroCluster) SetSocketGroup(ctx context.Context, group string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.SetSocketGroup(ctx, group)
+}
+
+// SetMemberRole assigns the dqlite role "voter", "stand-by", or "spare" to the cluster member with the given name.
+// The role is recorded as requested for the member, and the dqlite leader assigns it again on each heartbeat round if
+// dqlite has since reassigned it. The last voter and the dqlite leader can't be demoted.
+func (m *MicroCluster) SetMemberRole(ctx context.Context, name string, role string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.AssignRole(ctx, internalTypes.RoleAssignment{Name: name, Role: role})
+}
+
+// ClusterRoles returns the number of dqlite voters and stand-bys targeted by the cluster, and the number of cluster
+// members that currently have each role.
+func (m *MicroCluster) ClusterRoles(ctx context.Context) (*types.ClusterRoles, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetClusterRoles(ctx)
+}
+
+// ClusterHealth returns a summary of the health of the cluster as seen by the dqlite leader: the number of members and
+// voters, how many voters are reachable or of unknown reachability, whether quorum is met or at risk, and the current
+// leader. Voters are of unknown reachability until the leader has sent them a heartbeat since it was elected or since
+// heartbeats were last paused.
+func (m *MicroCluster) ClusterHealth(ctx context.Context) (*types.ClusterHealth, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetClusterHealth(ctx)
+}
+
+// ReplicationStatus returns how the local cluster member is keeping up with the dqlite leader. The dqlite client does
+// not expose raft indices, so rather than the lag of its log, it reports when the member last received a heartbeat
+// from the leader. The status of another cluster member can be fetched with a client targeting it.
+func (m *MicroCluster) ReplicationStatus(ctx context.Context) (*types.ReplicationStatus, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetReplicationStatus(ctx)
+}
+
+// PauseHeartbeats suspends heartbeat rounds on every cluster member for the given duration, of at most one hour, for
+// example during maintenance that briefly partitions the network. Heartbeats resume automatically afterwards.
+func (m *MicroCluster) PauseHeartbeats(ctx context.Context, d time.Duration) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return internalClient.PauseHeartbeats(ctx, &c.Client, d)
+}
+
+// ResumeHeartbeats resumes heartbeat rounds on every cluster member, after they were paused with PauseHeartbeats.
+func (m *MicroCluster) ResumeHeartbeats(ctx context.Context) error {
+	return m.PauseHeartbeats(ctx, 0)
+}
+
+// StreamEvents streams the events of the given types emitted by the local cluster member, such as membership changes,
+// hook invocations and runtime errors, or of all types if none are given. The handler is called for each event as it
+// occurs, until the context is cancelled or the handler returns an error.
+func (m *MicroCluster) StreamEvents(ctx context.Context, handler func(event types.Event) error, eventTypes ...types.EventType) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.StreamEvents(ctx, handler, eventTypes...)
+}
+
+// RunHook runs the hook with the given name, such as "post-join" or "on-new-member", on the local cluster member with
+// the given arguments, for example to reconcile state after a hook failed. The error of the hook is returned as is.
+func (m *MicroCluster) RunHook(ctx context.Context, hook string, args types.HookArgs) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.RunHook(ctx, hook, args)
+}
+
+// RecoverFromQuorumLoss rewrites the dqlite cluster configuration of the local cluster member so that only the named
+// surviving members remain, all as voters. It is a last resort for a cluster that has permanently lost the majority
+// of its voters. The local daemon must have been started with Args.RecoveryMode, and the local member must be one of
+// the survivors. Run it only on the surviving member with the most recent data, then copy the recovery_db.tar.gz
+// tarball it writes to its state directory into the state directory of every other survivor. Restart each survivor
+// normally, which replaces its database with the recovered one, and remove the lost members with force.
+//
+// WARNING: Any changes that were committed by the lost voters but not yet replicated to the recovered member are lost
+// permanently. Back up the database directory of every surviving member first.
+func (m *MicroCluster) RecoverFromQuorumLoss(ctx context.Context, survivingMembers []string) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.RecoverFromQuorumLoss(ctx, survivingMembers)
+}
+
+// Endpoints returns a description of the REST endpoints served by the local cluster member, including those of
+// extension servers, so that clients can discover its capabilities.
+func (m *MicroCluster) Endpoints(ctx context.Context) ([]types.EndpointInfo, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetEndpoints(ctx)
+}
+
+// Diagnostics returns the effective runtime configuration and database settings of the local cluster member, such as
+// its listen addresses, schema version, heartbeat interval, target roles, registered hooks and dqlite snapshot
+// parameters, for inclusion in support bundles.
+func (m *MicroCluster) Diagnostics(ctx context.Context) (*types.Diagnostics, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetDiagnostics(ctx)
+}
+
+// SchemaStatus returns the schema versions and API extensions supported by the local cluster member.
+func (m *MicroCluster) SchemaStatus(ctx context.Context) (*types.SchemaStatus, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetSchemaStatus(ctx)
+}
+
+// InitInfo returns how and when the local cluster member was bootstrapped or joined the cluster.
+func (m *MicroCluster) InitInfo(ctx context.Context) (*types.InitInfo, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetInitInfo(ctx)
+}
+
+// DatabaseDump writes a consistent SQL dump of the database contents to the given writer.
+// See state.State.DatabaseDump for how to restore the dump on a fresh cluster.
+func (m *MicroCluster) DatabaseDump(ctx context.Context, w io.Writer) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.GetDatabaseDump(ctx, w)
+}
+
+// RefreshTrustStore reloads the trust store of the local cluster member from its state directory, for example after
+// its files were edited by hand, and returns the number of remotes it contains afterwards.
+func (m *MicroCluster) RefreshTrustStore(ctx context.Context) (int, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.RefreshTrustStore(ctx)
+}
+
 // LocalClient returns a client connected to the local control socket.
 func (m *MicroCluster) LocalClient() (*client.Client, error) {
 	c := m.args.Client
@@ -301,7 +841,7 @@ func (m *MicroCluster) RemoteClient(address string) (*client.Client, error) {
 			}
 		}
 
-		url := api.NewURL().Scheme("https").Host(address)
+		url := api.NewURL().Scheme(sys.Scheme()).Host(address)
 		internalClient, err := internalClient.New(*url, serverCert, publicKey, false)
 		if err != nil {
 			return nil, err
@@ -357,3 +897,15 @@ func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internal
 
 	return "", batch, err
 }
+
+// QuerySQL executes the given SQL queries against the database through the local control socket.
+// If readOnly is set, only SELECT queries are allowed. Rows beyond the size limit of the response are omitted,
+// in which case the corresponding result is marked as truncated.
+func (m *MicroCluster) QuerySQL(ctx context.Context, query string, readOnly bool) (*internalTypes.SQLBatch, error) {
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.PostSQL(ctx, internalTypes.SQLQuery{Query: query, ReadOnly: readOnly})
+}
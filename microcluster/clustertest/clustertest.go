@@ -0,0 +1,233 @@
+// Package clustertest runs real MicroCluster daemons in-process, for integration tests of applications built on
+// MicroCluster.
+package clustertest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/schema"
+
+	"github.com/canonical/microcluster/config"
+	"github.com/canonical/microcluster/microcluster"
+	"github.com/canonical/microcluster/state"
+)
+
+// DefaultTimeout is how long starting the cluster, or stopping a member, may take unless Options.Timeout is set.
+const DefaultTimeout = 2 * time.Minute
+
+// Options configures the daemons of a test cluster.
+type Options struct {
+	// Args is the template for the arguments of each daemon. StateDir and ListenPort are set for each member.
+	Args microcluster.Args
+
+	// ExtensionsSchema, APIExtensions, and Hooks are passed to each daemon when it is started.
+	// The OnStart hook is run after the member has recorded its state.
+	ExtensionsSchema []schema.Update
+	APIExtensions    []string
+	Hooks            *config.Hooks
+
+	// InitConfig is passed to each member when it bootstraps or joins the cluster.
+	InitConfig map[string]string
+
+	// Timeout limits how long starting the cluster, or stopping each member, may take.
+	// If 0, DefaultTimeout is used.
+	Timeout time.Duration
+}
+
+// Member is a running cluster member of a test cluster.
+type Member struct {
+	Name    string
+	Address string
+	App     *microcluster.MicroCluster
+
+	state   *state.State
+	stateMu sync.Mutex
+	cancel  context.CancelFunc
+	doneCh  chan error
+}
+
+// State returns the state of the member's daemon, as passed to its hooks and API handlers.
+func (m *Member) State() *state.State {
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	return m.state
+}
+
+// Cluster is a test cluster whose members have all bootstrapped or joined it.
+type Cluster struct {
+	Members []*Member
+}
+
+// NewTestCluster starts n daemons with their own temporary state directories and free ports on 127.0.0.1, has the
+// first one bootstrap a cluster, and then has the rest join it. The daemons are stopped when the test ends.
+func NewTestCluster(t testing.TB, n int) *Cluster {
+	return NewTestClusterWithOptions(t, n, Options{})
+}
+
+// NewTestClusterWithOptions is like NewTestCluster, with the daemons configured by the given options.
+func NewTestClusterWithOptions(t testing.TB, n int, opts Options) *Cluster {
+	t.Helper()
+
+	if n < 1 {
+		t.Fatalf("Test cluster must have at least one member, got %d", n)
+	}
+
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	c := &Cluster{Members: make([]*Member, 0, n)}
+	t.Cleanup(func() { c.stop(t, opts.Timeout) })
+
+	for i := 0; i < n; i++ {
+		member, err := startMember(ctx, t, fmt.Sprintf("member%02d", i), opts)
+		if err != nil {
+			t.Fatalf("Failed to start cluster member %d: %v", i, err)
+		}
+
+		c.Members = append(c.Members, member)
+	}
+
+	bootstrap := c.Members[0]
+	err := bootstrap.App.NewCluster(ctx, bootstrap.Name, bootstrap.Address, opts.InitConfig)
+	if err != nil {
+		t.Fatalf("Failed to bootstrap cluster member %q: %v", bootstrap.Name, err)
+	}
+
+	for _, member := range c.Members[1:] {
+		token, err := bootstrap.App.NewJoinToken(ctx, member.Name)
+		if err != nil {
+			t.Fatalf("Failed to issue join token for cluster member %q: %v", member.Name, err)
+		}
+
+		err = member.App.JoinCluster(ctx, member.Name, member.Address, token, opts.InitConfig)
+		if err != nil {
+			t.Fatalf("Failed to join cluster member %q: %v", member.Name, err)
+		}
+	}
+
+	return c
+}
+
+// startMember starts a daemon with a temporary state directory, and waits until it is ready to bootstrap or join.
+func startMember(ctx context.Context, t testing.TB, name string, opts Options) (*Member, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	args := opts.Args
+	args.StateDir = t.TempDir()
+	args.ListenPort = ""
+
+	app, err := microcluster.App(args)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &Member{
+		Name:    name,
+		Address: net.JoinHostPort("127.0.0.1", port),
+		App:     app,
+		doneCh:  make(chan error, 1),
+	}
+
+	hooks := config.Hooks{}
+	if opts.Hooks != nil {
+		hooks = *opts.Hooks
+	}
+
+	onStart := hooks.OnStart
+	hooks.OnStart = func(s *state.State) error {
+		member.stateMu.Lock()
+		member.state = s
+		member.stateMu.Unlock()
+
+		if onStart != nil {
+			return onStart(s)
+		}
+
+		return nil
+	}
+
+	// The daemon runs until the test ends, so it must not inherit the deadline for starting the cluster.
+	runCtx, cancel := context.WithCancel(context.Background())
+	member.cancel = cancel
+	go func() {
+		member.doneCh <- app.Start(runCtx, opts.ExtensionsSchema, opts.APIExtensions, &hooks)
+	}()
+
+	err = app.Ready(ctx)
+	if err != nil {
+		cancel()
+
+		// Wait for the daemon to stop, so that it doesn't outlive the test.
+		select {
+		case <-member.doneCh:
+		case <-time.After(opts.Timeout):
+			t.Logf("Timed out waiting for cluster member %q to stop", name)
+		}
+
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// stop shuts down the members of the cluster in reverse order. Shutdowns are forced, so that they don't wait to
+// hand off leadership to members that are about to stop as well.
+func (c *Cluster) stop(t testing.TB, timeout time.Duration) {
+	for i := len(c.Members) - 1; i >= 0; i-- {
+		member := c.Members[i]
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		client, err := member.App.LocalClient()
+		if err == nil {
+			err = client.ShutdownDaemon(ctx, true)
+		}
+
+		if err != nil {
+			t.Logf("Failed to shut down cluster member %q, cancelling it instead: %v", member.Name, err)
+			member.cancel()
+		}
+
+		select {
+		case err := <-member.doneCh:
+			if err != nil {
+				t.Logf("Cluster member %q stopped with error: %v", member.Name, err)
+			}
+
+		case <-ctx.Done():
+			t.Errorf("Timed out waiting for cluster member %q to stop", member.Name)
+		}
+
+		member.cancel()
+		cancel()
+	}
+}
+
+// freePort returns a TCP port on 127.0.0.1 that is not currently in use.
+func freePort() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("Failed to find a free port: %w", err)
+	}
+
+	defer func() { _ = listener.Close() }()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return "", err
+	}
+
+	return port, nil
+}
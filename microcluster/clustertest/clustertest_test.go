@@ -0,0 +1,47 @@
+package clustertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type clusterTestSuite struct {
+	suite.Suite
+}
+
+func TestClusterTestSuite(t *testing.T) {
+	suite.Run(t, new(clusterTestSuite))
+}
+
+// Ensures a two-member test cluster starts with both members in the cluster, and stops when the test ends.
+func (s *clusterTestSuite) Test_newTestCluster() {
+	if testing.Short() {
+		s.T().Skip("Skipping test cluster in short mode")
+	}
+
+	c := NewTestCluster(s.T(), 2)
+	s.Len(c.Members, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, member := range c.Members {
+		s.NotNil(member.State())
+
+		client, err := member.App.LocalClient()
+		s.Require().NoError(err)
+
+		members, err := client.GetClusterMembers(ctx)
+		s.Require().NoError(err)
+
+		names := make([]string, 0, len(members))
+		for _, m := range members {
+			names = append(names, m.Name)
+		}
+
+		s.ElementsMatch([]string{c.Members[0].Name, c.Members[1].Name}, names)
+	}
+}
@@ -1,17 +1,21 @@
 package access
 
 import (
+	"context"
 	"crypto/x509"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/internal/rest/access"
 	"github.com/canonical/microcluster/internal/state"
+	"github.com/canonical/microcluster/internal/sys"
 	"github.com/canonical/microcluster/rest/types"
 )
 
@@ -64,8 +68,13 @@ func Authenticate(state *state.State, r *http.Request, hostAddress string, trust
 		return false, fmt.Errorf("Invalid host address %q", hostAddress)
 	}
 
-	switch r.Host {
-	case hostAddrPort.String():
+	switch {
+	case hostMatches(r.Host, hostAddrPort):
+		// In plaintext mode there are no certificates to check, so all requests are trusted.
+		if r.TLS == nil && sys.Plaintext() {
+			return true, nil
+		}
+
 		if r.TLS != nil {
 			for _, cert := range r.TLS.PeerCertificates {
 				trusted, fingerprint := util.CheckTrustState(*cert, trustedCerts, nil, false)
@@ -82,3 +91,51 @@ func Authenticate(state *state.State, r *http.Request, hostAddress string, trust
 
 	return false, nil
 }
+
+// hostMatches returns whether the Host header of a request refers to the given address. HTTP clients strip the zone
+// of link-local IPv6 addresses from the Host header, so the zone is not compared.
+func hostMatches(host string, addrPort types.AddrPort) bool {
+	if host == addrPort.String() {
+		return true
+	}
+
+	hostAddrPort, err := types.ParseAddrPort(host)
+	if err != nil {
+		return false
+	}
+
+	return hostAddrPort.EqualIgnoringZone(addrPort)
+}
+
+// AllowCertFingerprints returns an AllowedCerts predicate that accepts only certificates with the given fingerprints.
+func AllowCertFingerprints(fingerprints ...string) func(state *state.State, cert *x509.Certificate) bool {
+	return func(state *state.State, cert *x509.Certificate) bool {
+		return shared.ValueInSlice(shared.CertFingerprint(cert), fingerprints)
+	}
+}
+
+// AllowClusterMembers returns an AllowedCerts predicate that accepts only the certificates of the cluster members
+// with the given names.
+func AllowClusterMembers(names ...string) func(state *state.State, cert *x509.Certificate) bool {
+	return func(state *state.State, cert *x509.Certificate) bool {
+		remote := state.Remotes().RemoteByCertificateFingerprint(shared.CertFingerprint(cert))
+
+		return remote != nil && shared.ValueInSlice(remote.Name, names)
+	}
+}
+
+// AllowLeader is an AllowedCerts predicate that accepts only the certificate of the current dqlite leader.
+func AllowLeader(state *state.State, cert *x509.Certificate) bool {
+	ctx, cancel := context.WithTimeout(state.Context, 30*time.Second)
+	defer cancel()
+
+	leaderAddress, err := state.LeaderAddress(ctx)
+	if err != nil {
+		logger.Warn("Failed to get dqlite leader address", logger.Ctx{"error": err})
+		return false
+	}
+
+	remote := state.Remotes().RemoteByCertificateFingerprint(shared.CertFingerprint(cert))
+
+	return remote != nil && remote.Address.String() == leaderAddress.String()
+}
@@ -0,0 +1,113 @@
+package access
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/state"
+)
+
+type accessSuite struct {
+	suite.Suite
+}
+
+func TestAccessSuite(t *testing.T) {
+	suite.Run(t, new(accessSuite))
+}
+
+// newTestCert returns a new self-signed certificate.
+func newTestCert() (*x509.Certificate, error) {
+	cert, key, err := shared.GenerateMemCert(true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	certInfo, err := shared.KeyPairFromRaw(cert, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return certInfo.PublicKeyX509()
+}
+
+// Ensures requests to a cluster member on a link-local IPv6 address are accepted, and trusted if they present a
+// trusted certificate, even though HTTP clients strip the zone from the Host header.
+func (s *accessSuite) Test_authenticateZonedAddress() {
+	address := "[fe80::1%eth0]:9000"
+	st := &state.State{Address: func() *api.URL { return api.NewURL().Scheme("https").Host(address) }}
+
+	trustedCert, err := newTestCert()
+	s.Require().NoError(err)
+
+	untrustedCert, err := newTestCert()
+	s.Require().NoError(err)
+
+	trustedCerts := map[string]x509.Certificate{shared.CertFingerprint(trustedCert): *trustedCert}
+
+	tests := []struct {
+		name       string
+		url        string
+		cert       *x509.Certificate
+		trusted    bool
+		invalidErr bool
+	}{
+		{
+			name: "Request without a certificate with the zone stripped from the Host header",
+			url:  "https://[fe80::1]:9000/core/internal/heartbeat",
+		},
+		{
+			name:    "Request with a trusted certificate with the zone stripped from the Host header",
+			url:     "https://[fe80::1]:9000/core/internal/heartbeat",
+			cert:    trustedCert,
+			trusted: true,
+		},
+		{
+			name:    "Request with a trusted certificate with the zone in the Host header",
+			url:     "https://[fe80::1%25eth0]:9000/core/internal/heartbeat",
+			cert:    trustedCert,
+			trusted: true,
+		},
+		{
+			name: "Request with an untrusted certificate with the zone stripped from the Host header",
+			url:  "https://[fe80::1]:9000/core/internal/heartbeat",
+			cert: untrustedCert,
+		},
+		{
+			name:       "Request with a trusted certificate to another address",
+			url:        "https://[fe80::2]:9000/core/internal/heartbeat",
+			cert:       trustedCert,
+			invalidErr: true,
+		},
+		{
+			name:       "Request with a trusted certificate to another port",
+			url:        "https://[fe80::1]:9001/core/internal/heartbeat",
+			cert:       trustedCert,
+			invalidErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		s.T().Logf("%s (case %d)", test.name, i)
+
+		r := httptest.NewRequest("POST", test.url, nil)
+		if test.cert != nil {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{test.cert}}
+		}
+
+		trusted, err := Authenticate(st, r, address, trustedCerts)
+		s.Equal(test.trusted, trusted)
+
+		if test.invalidErr {
+			s.True(errors.As(err, &ErrInvalidHost{}))
+		} else {
+			s.NoError(err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/canonical/lxd/lxd/request"
+)
+
+// RequestAddress returns the source address of the connection that the request was received on. If the request was
+// forwarded by another cluster member, this is the address of the forwarding member. Requests over the unix socket
+// have no source address, and return an error.
+func RequestAddress(r *http.Request) (netip.AddrPort, error) {
+	remoteAddr := r.RemoteAddr
+	conn, ok := r.Context().Value(request.CtxConn).(net.Conn)
+	if ok {
+		tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+		if ok {
+			addrPort := tcpAddr.AddrPort()
+
+			return netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port()), nil
+		}
+
+		remoteAddr = conn.RemoteAddr().String()
+	}
+
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("Failed to parse source address %q of request: %w", remoteAddr, err)
+	}
+
+	return netip.AddrPortFrom(addrPort.Addr().Unmap(), addrPort.Port()), nil
+}
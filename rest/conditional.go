@@ -0,0 +1,27 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/canonical/microcluster/rest/types"
+)
+
+// ExpectedVersion returns the version that the request expects the resource to have, as set by the client with the
+// If-Match header, and whether one was set.
+//
+// Conditional writes are opt-in: a handler supports them by passing the expected version to
+// state.CompareAndSwap, along with a function that reads the current version of the resource, so that the
+// write is only applied if the versions match:
+//
+//	version, ok := rest.ExpectedVersion(r)
+//	if ok {
+//		err := s.CompareAndSwap(r.Context(), version, currentVersion, write)
+//		...
+//	}
+//
+// Handlers that ignore the expected version apply writes unconditionally.
+func ExpectedVersion(r *http.Request) (string, bool) {
+	version, ok := r.Context().Value(types.CtxExpectedVersion).(string)
+
+	return version, ok
+}
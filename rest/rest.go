@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"crypto/x509"
 	"net/http"
 
 	"github.com/canonical/lxd/lxd/response"
@@ -10,6 +11,23 @@ import (
 	"github.com/canonical/microcluster/state"
 )
 
+// Authenticator authenticates requests over the network by other means than the TLS client certificate, such as a
+// token or header.
+type Authenticator interface {
+	// Authenticate returns whether the request is trusted. If it is not, the request is still trusted if its client
+	// certificate is in the trust store. If an error is returned, the request is rejected with Unauthorized (401), or
+	// Forbidden (403) if the error has that status, regardless of its client certificate.
+	Authenticate(state *state.State, r *http.Request) (bool, error)
+}
+
+// AuthenticatorFunc is a function that implements Authenticator.
+type AuthenticatorFunc func(state *state.State, r *http.Request) (bool, error)
+
+// Authenticate implements Authenticator.
+func (f AuthenticatorFunc) Authenticate(state *state.State, r *http.Request) (bool, error) {
+	return f(state, r)
+}
+
 // EndpointAlias represents an alias URL of and Endpoint in our API.
 type EndpointAlias struct {
 	Name string // Name for this alias.
@@ -37,6 +55,31 @@ type Endpoint struct {
 
 	AllowedDuringShutdown bool // Whether we should return Unavailable Error (503) if daemon is shutting down.
 	AllowedBeforeInit     bool // Whether we should return Unavailabel Error (503) if the daemon has not been initialized (is not yet part of a cluster).
+	AllowedWithoutLeader  bool // Whether we should return Unavailable Error (503) for POST, PUT, PATCH, and DELETE requests if the cluster leader can't be reached.
+	AllowedWhileDraining  bool // Whether we should return Unavailable Error (503) for POST, PUT, PATCH, and DELETE requests if the cluster member is draining.
+
+	// AllowedCerts optionally restricts the endpoint to callers whose verified TLS client certificate it accepts,
+	// returning Forbidden (403) otherwise. Requests over the unix socket are always allowed. If the request was
+	// forwarded by another cluster member, the certificate is that of the forwarding member.
+	// The daemon refuses to start in plaintext mode if any endpoint outside a unix socket only server sets it.
+	AllowedCerts func(state *state.State, cert *x509.Certificate) bool
+
+	// MaxRequestBytes overrides the maximum size of request bodies for this endpoint.
+	// If 0, the limit of the server is used. If negative, request bodies are not limited.
+	MaxRequestBytes int64
+
+	// Authenticator overrides the Authenticator of the server for this endpoint.
+	Authenticator Authenticator
+
+	// MaxConcurrent limits the number of requests to this endpoint that are handled at once, to protect expensive
+	// handlers from overload. Further requests are rejected with Unavailable (503) and a Retry-After header until a
+	// request completes. The limit is shared by the endpoint's aliases and every listener it is served on. If 0 or
+	// negative, requests are not limited.
+	MaxConcurrent int
+
+	// DisableCompression sends responses of this endpoint uncompressed, even if they are large enough to be compressed,
+	// for latency-sensitive handlers.
+	DisableCompression bool
 }
 
 // Resources represents all the resources served over the same path.
@@ -47,6 +90,9 @@ type Resources struct {
 
 // Server contains configuration and handlers for additional listeners to be instantiated after app startup.
 type Server struct {
+	// Name optionally identifies the server in error messages, such as when its endpoints conflict with another's.
+	Name string
+
 	// CoreAPI determines whether the the resources of the server should be served over the default cluster API.
 	CoreAPI bool
 
@@ -56,6 +102,17 @@ type Server struct {
 	// ServeUnix sets whether the resources of this endpoint should also be served over the unix socket.
 	ServeUnix bool
 
+	// UnixOnly sets whether the resources of the server should be served exclusively over the control unix socket.
+	// Such resources are never served over any network listener, so they are suitable for administrative endpoints,
+	// and access to them is controlled by the permissions of the socket. A UnixOnly server cannot be a core API
+	// server, or define an address, protocol, certificate, TLS configuration or advertised extensions.
+	UnixOnly bool
+
+	// Optional sets whether the daemon may keep running if the server's listener fails to start, for example because
+	// its address is already in use. The failure is logged and reported as a runtime error instead. Core API servers
+	// and UnixOnly servers are always required, so they cannot be optional.
+	Optional bool
+
 	// Protocol is the server protocol.
 	// Example: https
 	Protocol string
@@ -68,6 +125,30 @@ type Server struct {
 	// x509 PEM Certificate
 	Certificate *shared.CertInfo
 
+	// TLSConfig overrides the default TLS settings of the server listener, such as the minimum TLS version.
+	// If the server uses the core API, the settings apply to the core API listener.
+	TLSConfig *types.TLSConfig
+
+	// MaxRequestBytes is the maximum size of request bodies for the resources of this server, unless overridden by an endpoint.
+	// If 0, the daemon default is used. If negative, request bodies are not limited.
+	MaxRequestBytes int64
+
+	// Authenticator authenticates requests over the network to the resources of this server, in addition to the
+	// client certificate check against the trust store, unless overridden by an endpoint. Requests over the unix socket
+	// are always trusted. If nil, only the client certificate is checked.
+	Authenticator Authenticator
+
+	// Middleware wraps the handlers of this server's endpoints, for example to add authentication or logging.
+	// Middleware is applied in the given order, with the first middleware outermost. Core endpoints are unaffected.
+	Middleware []func(http.Handler) http.Handler
+
+	// AdvertisedExtensions restricts the API extensions registered by the application that are advertised by the
+	// extensions endpoint on this server's listener, for example to expose experimental extensions only on an internal
+	// port. Internal microcluster extensions are always advertised. If nil, all extensions are advertised. If the server
+	// has its own address, the extensions endpoint is also served on its listener.
+	// The restrictions of all core API servers are combined for the core API listener. The unix socket is unaffected.
+	AdvertisedExtensions []string
+
 	// Resources is the list of resources offered by this server.
 	Resources []Resources
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"math/rand"
 	"net/netip"
+	"net/url"
+	"strings"
 )
 
 // AddrPort is a wrapper for netip.AddrPort for which (json/yaml).(Marshaller/Unmarshaller) are implemented.
@@ -15,8 +17,9 @@ type AddrPort struct {
 type AddrPorts []AddrPort
 
 // ParseAddrPort parses an IPv4/IPv6 address and port string into an AddrPort.
+// The zone of a link-local IPv6 address may be URL-escaped, as in "[fe80::1%25eth0]:9000".
 func ParseAddrPort(addrPortStr string) (AddrPort, error) {
-	addrPort, err := netip.ParseAddrPort(addrPortStr)
+	addrPort, err := netip.ParseAddrPort(unescapeZone(addrPortStr))
 	if err != nil {
 		return AddrPort{}, err
 	}
@@ -24,6 +27,22 @@ func ParseAddrPort(addrPortStr string) (AddrPort, error) {
 	return AddrPort{AddrPort: addrPort}, nil
 }
 
+// unescapeZone decodes the URL-escaped zone of a bracketed IPv6 address, as zones appear in URLs.
+func unescapeZone(addrPortStr string) string {
+	start := strings.Index(addrPortStr, "%25")
+	end := strings.LastIndex(addrPortStr, "]")
+	if !strings.HasPrefix(addrPortStr, "[") || start < 0 || end < start {
+		return addrPortStr
+	}
+
+	zone, err := url.PathUnescape(addrPortStr[start:end])
+	if err != nil {
+		return addrPortStr
+	}
+
+	return addrPortStr[:start] + zone + addrPortStr[end:]
+}
+
 // ParseAddrPorts parses a list of IPv4/IPv6 address and port strings into an AddrPorts.
 func ParseAddrPorts(addrPortStrs []string) (AddrPorts, error) {
 	var err error
@@ -38,6 +57,12 @@ func ParseAddrPorts(addrPortStrs []string) (AddrPorts, error) {
 	return addrPorts, nil
 }
 
+// EqualIgnoringZone returns whether the address and port are the same as those of the other AddrPort, regardless of
+// the zone of the address. HTTP clients strip the zone from the Host header of requests, for example.
+func (a AddrPort) EqualIgnoringZone(other AddrPort) bool {
+	return a.Port() == other.Port() && a.Addr().WithZone("") == other.Addr().WithZone("")
+}
+
 // MarshalJSON implements json.Marshaler for the AddrPort type.
 func (a AddrPort) MarshalJSON() ([]byte, error) {
 	return json.Marshal(a.String())
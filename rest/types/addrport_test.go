@@ -0,0 +1,93 @@
+package types
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type addrPortSuite struct {
+	suite.Suite
+}
+
+func TestAddrPortSuite(t *testing.T) {
+	suite.Run(t, new(addrPortSuite))
+}
+
+// Ensures addresses, including link-local IPv6 addresses with zones, round-trip through URLs, JSON, and YAML.
+func (s *addrPortSuite) Test_roundTrip() {
+	tests := []struct {
+		name    string
+		address string
+		zone    string
+	}{
+		{
+			name:    "IPv4 address",
+			address: "10.0.0.1:9000",
+		},
+		{
+			name:    "IPv6 address",
+			address: "[fd00::1]:9000",
+		},
+		{
+			name:    "Link-local IPv6 address with zone",
+			address: "[fe80::1%eth0]:9000",
+			zone:    "eth0",
+		},
+		{
+			name:    "Link-local IPv6 address with URL-escaped zone",
+			address: "[fe80::1%25eth0]:9000",
+			zone:    "eth0",
+		},
+	}
+
+	for i, test := range tests {
+		s.T().Logf("%s (case %d)", test.name, i)
+
+		addrPort, err := ParseAddrPort(test.address)
+		s.NoError(err)
+		s.Equal(test.zone, addrPort.Addr().Zone())
+
+		// Addresses are written to URLs with api.NewURL().Host, and read back from the URL's host.
+		u, err := url.Parse(api.NewURL().Scheme("https").Host(addrPort.String()).Path("1.0").String())
+		s.NoError(err)
+
+		fromURL, err := ParseAddrPort(u.Host)
+		s.NoError(err)
+		s.Equal(addrPort, fromURL)
+
+		// Addresses are written to the trust store as YAML, and sent in join and heartbeat requests as JSON.
+		jsonBytes, err := json.Marshal(addrPort)
+		s.NoError(err)
+
+		fromJSON := AddrPort{}
+		s.NoError(json.Unmarshal(jsonBytes, &fromJSON))
+		s.Equal(addrPort, fromJSON)
+
+		yamlBytes, err := yaml.Marshal(addrPort)
+		s.NoError(err)
+
+		fromYAML := AddrPort{}
+		s.NoError(yaml.Unmarshal(yamlBytes, &fromYAML))
+		s.Equal(addrPort, fromYAML)
+	}
+}
+
+// Ensures addresses are compared regardless of their zone, as in the Host header of requests.
+func (s *addrPortSuite) Test_equalIgnoringZone() {
+	zoned, err := ParseAddrPort("[fe80::1%eth0]:9000")
+	s.NoError(err)
+
+	unzoned, err := ParseAddrPort("[fe80::1]:9000")
+	s.NoError(err)
+
+	otherPort, err := ParseAddrPort("[fe80::1%eth0]:9001")
+	s.NoError(err)
+
+	s.True(zoned.EqualIgnoringZone(unzoned))
+	s.False(zoned.EqualIgnoringZone(otherPort))
+}
@@ -0,0 +1,14 @@
+package types
+
+// IfMatchHeader is the header carrying the version that a conditional write expects the resource to have.
+//
+// Endpoints that support conditional writes apply the write only if the current version of the resource matches, and
+// return Precondition Failed (412) otherwise. The version is sent as a quoted entity tag, or as "*" to match any
+// existing version.
+const IfMatchHeader = "If-Match"
+
+// ctxKey is the type of request context keys set by microcluster.
+type ctxKey string
+
+// CtxExpectedVersion is the request context key holding the version parsed from the IfMatchHeader.
+const CtxExpectedVersion ctxKey = "expected-version"
@@ -0,0 +1,13 @@
+package types
+
+// ConsistencyTokenHeader is the header carrying a consistency token.
+//
+// A consistency token is a cluster-wide index that is incremented through the dqlite leader after a write, so that it
+// is ordered after the write in the replicated log. Successful requests that may modify state and include the header
+// are issued a new token in the response. A read request that includes a token waits until that index is committed.
+//
+// Database queries of cluster members are always executed by the dqlite leader, so reads already observe every
+// committed write, and the token does not make a cluster member wait for its local replica to catch up. It only
+// guarantees that the write is committed before the read is handled, at the cost of a write on the leader for each
+// request that is issued a token.
+const ConsistencyTokenHeader = "X-Microcluster-Consistency-Token"
@@ -0,0 +1,43 @@
+package types
+
+// Diagnostics reports the effective runtime configuration and database settings of a cluster member, for
+// troubleshooting and support bundles.
+type Diagnostics struct {
+	// Name is the name of the cluster member.
+	Name string `json:"name" yaml:"name"`
+
+	// Address is the listen address of the core API, or empty if the API is only served over the control socket.
+	Address string `json:"address" yaml:"address"`
+
+	// DqliteAddress is the address used for dqlite traffic, or empty if dqlite shares the core API address.
+	DqliteAddress string `json:"dqlite_address" yaml:"dqlite_address"`
+
+	// ServerAddresses are the listen addresses of the extension servers that have their own listener.
+	ServerAddresses []string `json:"server_addresses" yaml:"server_addresses"`
+
+	// ControlSocket is the path of the control socket, and SocketGroup is the group that owns it, if set.
+	ControlSocket string `json:"control_socket" yaml:"control_socket"`
+	SocketGroup   string `json:"socket_group"   yaml:"socket_group"`
+
+	// SchemaInternal and SchemaExternal are the internal and external schema versions.
+	SchemaInternal uint64 `json:"schema_internal" yaml:"schema_internal"`
+	SchemaExternal uint64 `json:"schema_external" yaml:"schema_external"`
+
+	// APIExtensions are the registered API extensions.
+	APIExtensions []string `json:"api_extensions" yaml:"api_extensions"`
+
+	// HeartbeatInterval is how often the dqlite leader begins a heartbeat round, such as "10s".
+	HeartbeatInterval string `json:"heartbeat_interval" yaml:"heartbeat_interval"`
+
+	// TargetVoters and TargetStandBys are the number of voters and stand-bys that dqlite's role assignment targets.
+	TargetVoters   int `json:"target_voters"    yaml:"target_voters"`
+	TargetStandBys int `json:"target_stand_bys" yaml:"target_stand_bys"`
+
+	// Hooks are the names of the hooks registered by the application, such as "on-start".
+	Hooks []string `json:"hooks" yaml:"hooks"`
+
+	// SnapshotThreshold is the number of raft log entries after which dqlite takes a snapshot, and SnapshotTrailing is
+	// the number of entries it retains after a snapshot.
+	SnapshotThreshold uint64 `json:"snapshot_threshold" yaml:"snapshot_threshold"`
+	SnapshotTrailing  uint64 `json:"snapshot_trailing"  yaml:"snapshot_trailing"`
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"time"
+)
+
+// DqliteMember represents a node of the dqlite cluster, as recorded by dqlite itself.
+type DqliteMember struct {
+	// ID is the dqlite node ID.
+	ID uint64 `json:"id" yaml:"id"`
+
+	// Address is the dqlite address of the node.
+	Address AddrPort `json:"address" yaml:"address"`
+
+	// Role is the dqlite role of the node, such as "voter", "stand-by", or "spare".
+	Role string `json:"role" yaml:"role"`
+}
+
+// ClusterRoles is the number of dqlite nodes the cluster targets for each role, and the number that currently have it.
+type ClusterRoles struct {
+	// TargetVoters and TargetStandBys are the configured number of voters and stand-bys.
+	TargetVoters   int `json:"target_voters"    yaml:"target_voters"`
+	TargetStandBys int `json:"target_stand_bys" yaml:"target_stand_bys"`
+
+	// Voters, StandBys, and Spares are the number of dqlite nodes that currently have each role.
+	Voters   int `json:"voters"    yaml:"voters"`
+	StandBys int `json:"stand_bys" yaml:"stand_bys"`
+	Spares   int `json:"spares"    yaml:"spares"`
+}
+
+// ClusterHealth summarizes the health of the cluster, and whether the dqlite voters have quorum.
+type ClusterHealth struct {
+	// Members is the number of dqlite nodes in the cluster.
+	Members int `json:"members" yaml:"members"`
+
+	// Voters is the number of dqlite voters, and ReachableVoters is the number of those that responded to the most
+	// recent heartbeats of the leader. UnknownVoters is the number of voters whose reachability is not yet known,
+	// because the leader has not sent them a heartbeat since it was elected or since heartbeats were last paused.
+	Voters          int `json:"voters"           yaml:"voters"`
+	ReachableVoters int `json:"reachable_voters" yaml:"reachable_voters"`
+	UnknownVoters   int `json:"unknown_voters"   yaml:"unknown_voters"`
+
+	// QuorumMet is true if a leader is elected and a majority of voters is reachable or of unknown reachability.
+	QuorumMet bool `json:"quorum_met" yaml:"quorum_met"`
+
+	// QuorumAtRisk is true if quorum is met, but would be lost if one more voter became unreachable.
+	QuorumAtRisk bool `json:"quorum_at_risk" yaml:"quorum_at_risk"`
+
+	// Leader is the name of the cluster member that is the dqlite leader, or empty if there is none.
+	Leader string `json:"leader" yaml:"leader"`
+
+	// HeartbeatsPaused is true if heartbeats, and so failure detection, are paused on the reporting cluster member
+	// until HeartbeatsPausedUntil.
+	HeartbeatsPaused      bool      `json:"heartbeats_paused"       yaml:"heartbeats_paused"`
+	HeartbeatsPausedUntil time.Time `json:"heartbeats_paused_until" yaml:"heartbeats_paused_until"`
+
+	// Syncing is true if the reporting cluster member has recently joined the cluster or restarted, and is still
+	// catching up with the leader.
+	Syncing bool `json:"syncing" yaml:"syncing"`
+}
+
+// ReplicationStatus reports how a cluster member is keeping up with the dqlite leader, as far as it can observe.
+//
+// The dqlite client does not expose the applied or committed raft index of a node, so the lag of its log behind the
+// leader is not reported. The time of the last heartbeat received from the leader is the closest available signal: a
+// follower that has not received one for several heartbeat intervals is not being kept up to date by the leader.
+type ReplicationStatus struct {
+	// Leader is true if the reporting cluster member is the dqlite leader, which has no lag.
+	Leader bool `json:"leader" yaml:"leader"`
+
+	// Role is the dqlite role of the reporting cluster member, such as "voter", "stand-by", or "spare".
+	Role string `json:"role" yaml:"role"`
+
+	// HeartbeatReceived is the time at which the reporting cluster member last received a heartbeat from the leader,
+	// on its own clock, or zero if it has not received one since it started.
+	HeartbeatReceived time.Time `json:"heartbeat_received" yaml:"heartbeat_received"`
+
+	// Syncing is true if the reporting cluster member has recently joined the cluster or restarted, and is still
+	// catching up with the leader.
+	Syncing bool `json:"syncing" yaml:"syncing"`
+}
+
+// HeartbeatOutcome is the result of a heartbeat sent by the dqlite leader to a cluster member.
+type HeartbeatOutcome struct {
+	// Time is when the heartbeat was sent, on the clock of the leader.
+	Time time.Time `json:"time" yaml:"time"`
+
+	// Success is true if the cluster member responded to the heartbeat.
+	Success bool `json:"success" yaml:"success"`
+
+	// Error is the reason the heartbeat failed, if it did.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
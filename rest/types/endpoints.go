@@ -0,0 +1,34 @@
+package types
+
+// EndpointInfo describes a REST endpoint served by a cluster member.
+type EndpointInfo struct {
+	// Path is the full path pattern of the endpoint, including its prefix.
+	Path string `json:"path" yaml:"path"`
+
+	// Name is the canonical name of the endpoint, if any.
+	Name string `json:"name" yaml:"name"`
+
+	// Aliases are the full path patterns of any aliases of the endpoint.
+	Aliases []string `json:"aliases" yaml:"aliases"`
+
+	// Methods are the HTTP methods handled by the endpoint.
+	Methods []EndpointMethod `json:"methods" yaml:"methods"`
+
+	// Address is the listen address of the extension server serving the endpoint, or empty if it is served by the
+	// core API.
+	Address string `json:"address" yaml:"address"`
+
+	// UnixOnly is true if the endpoint is served exclusively over the control unix socket.
+	UnixOnly bool `json:"unix_only" yaml:"unix_only"`
+}
+
+// EndpointMethod describes an HTTP method handled by an endpoint.
+type EndpointMethod struct {
+	// Method is the HTTP method, such as GET or POST.
+	Method string `json:"method" yaml:"method"`
+
+	// RequiresTrust is true if requests over the network must use the certificate of a trusted cluster member, because
+	// the method does not allow untrusted requests or its access handler is access.AllowAuthenticated. It is false for
+	// endpoints served only over the control socket, endpoints with an Authenticator, and other access handlers.
+	RequiresTrust bool `json:"requires_trust" yaml:"requires_trust"`
+}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"time"
+)
+
+// EventType is the kind of event emitted by a cluster member.
+type EventType string
+
+const (
+	// EventMember is emitted when the membership of the cluster changes. Its metadata describes the member event.
+	EventMember EventType = "member"
+
+	// EventHook is emitted when a hook has run. Its metadata holds the name of the hook, and any error it returned.
+	EventHook EventType = "hook"
+
+	// EventError is emitted when the daemon encounters a non-fatal runtime error.
+	EventError EventType = "error"
+)
+
+// Event is a structured event emitted by a cluster member as it occurs.
+type Event struct {
+	Type     EventType         `json:"type"     yaml:"type"`
+	Time     time.Time         `json:"time"     yaml:"time"`
+	Member   string            `json:"member"   yaml:"member"`
+	Message  string            `json:"message"  yaml:"message"`
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+}
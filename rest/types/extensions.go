@@ -0,0 +1,31 @@
+package types
+
+// APIExtensions represents the API extensions supported by a cluster member.
+type APIExtensions struct {
+	// Internal are the API extensions provided by microcluster itself.
+	Internal []string `json:"internal" yaml:"internal"`
+
+	// External are the API extensions registered by the application.
+	External []string `json:"external" yaml:"external"`
+
+	// Version is the total number of supported API extensions.
+	Version int `json:"version" yaml:"version"`
+}
+
+// HasExtension returns whether the given API extension is supported, whether it is internal or registered by the
+// application.
+func (e APIExtensions) HasExtension(extension string) bool {
+	for _, ext := range e.Internal {
+		if ext == extension {
+			return true
+		}
+	}
+
+	for _, ext := range e.External {
+		if ext == extension {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,23 @@
+package types
+
+// HookArgs holds the arguments for running a hook on demand. Only the arguments taken by the named hook are used.
+type HookArgs struct {
+	// InitConfig is passed to the bootstrap and join hooks.
+	InitConfig map[string]string `json:"init_config" yaml:"init_config"`
+
+	// Force is passed to the remove hooks.
+	Force bool `json:"force" yaml:"force"`
+
+	// OldConfig and NewConfig are passed to the config change hook.
+	OldConfig map[string]string `json:"old_config" yaml:"old_config"`
+	NewConfig map[string]string `json:"new_config" yaml:"new_config"`
+
+	// IsLeader is passed to the leader change hook.
+	IsLeader bool `json:"is_leader" yaml:"is_leader"`
+
+	// Free is passed to the low disk hook.
+	Free uint64 `json:"free" yaml:"free"`
+
+	// Candidate is passed to the new member hook that can reject a join.
+	Candidate ClusterMemberLocal `json:"candidate" yaml:"candidate"`
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"time"
+)
+
+// InitOperation is the operation with which a cluster member was initialized.
+type InitOperation string
+
+const (
+	// InitBootstrap is the InitOperation of a cluster member that bootstrapped a new cluster.
+	InitBootstrap InitOperation = "bootstrap"
+
+	// InitJoin is the InitOperation of a cluster member that joined an existing cluster.
+	InitJoin InitOperation = "join"
+)
+
+// InitInfo records how and when a cluster member was initialized.
+type InitInfo struct {
+	Operation InitOperation `json:"operation" yaml:"operation"`
+	Time      time.Time     `json:"time"      yaml:"time"`
+
+	// JoinAddresses are the addresses of the cluster members used to join the cluster, if joining.
+	JoinAddresses AddrPorts `json:"join_addresses" yaml:"join_addresses"`
+
+	// SchemaInternal and SchemaExternal are the schema versions of the cluster member at the time of initialization.
+	SchemaInternal uint64 `json:"schema_internal" yaml:"schema_internal"`
+	SchemaExternal uint64 `json:"schema_external" yaml:"schema_external"`
+
+	// APIExtensions are the API extensions supported by the cluster member at the time of initialization.
+	APIExtensions []string `json:"api_extensions" yaml:"api_extensions"`
+}
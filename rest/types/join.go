@@ -0,0 +1,140 @@
+package types
+
+import (
+	"errors"
+)
+
+// JoinCheck is a report of whether a system is compatible with the cluster it intends to join.
+type JoinCheck struct {
+	// Compatible is true if at least one join address is reachable, and no reachable cluster member reports a
+	// schema version or API extension mismatch.
+	Compatible bool `json:"compatible" yaml:"compatible"`
+
+	// Members contains the result of the check against each join address.
+	Members []JoinCheckMember `json:"members" yaml:"members"`
+}
+
+// JoinCheckMember is the result of checking the compatibility of a system with the cluster member at a join address.
+type JoinCheckMember struct {
+	Address   AddrPort `json:"address"   yaml:"address"`
+	Reachable bool     `json:"reachable" yaml:"reachable"`
+
+	// Error is the reason the cluster member could not be reached or its certificate could not be verified, if any.
+	Error string `json:"error" yaml:"error"`
+
+	// Mismatch describes the incompatibility with the cluster member, if any.
+	Mismatch *SchemaVersionMismatch `json:"mismatch" yaml:"mismatch"`
+}
+
+// Reasons a join can fail. Join errors returned by the API wrap one of these, so that callers can distinguish them
+// with errors.Is.
+var (
+	// ErrAlreadyClustered indicates that the member is already part of a cluster.
+	ErrAlreadyClustered = errors.New("This member is already part of a cluster")
+
+	// ErrJoinUnreachable indicates that none of the cluster members given by the join token could be reached.
+	ErrJoinUnreachable = errors.New("Cluster members are unreachable")
+
+	// ErrJoinNotTrusted indicates that the cluster did not accept the join token, or its certificate did not match.
+	ErrJoinNotTrusted = errors.New("Join token is not trusted by the cluster")
+
+	// ErrJoinIncompatible indicates that the schema versions or API extensions of the member don't match the cluster.
+	// A SchemaVersionMismatch is also detectable as this error.
+	ErrJoinIncompatible = errors.New("This member is incompatible with the cluster")
+
+	// ErrJoinRejected indicates that the PreNewMember hook of the cluster rejected the member.
+	ErrJoinRejected = errors.New("The cluster rejected this member")
+)
+
+// joinFailureReasons are the reason codes sent over the API for each join error.
+var joinFailureReasons = []struct {
+	reason string
+	err    error
+}{
+	{reason: "already_clustered", err: ErrAlreadyClustered},
+	{reason: "unreachable", err: ErrJoinUnreachable},
+	{reason: "not_trusted", err: ErrJoinNotTrusted},
+	{reason: "incompatible", err: ErrJoinIncompatible},
+	{reason: "rejected", err: ErrJoinRejected},
+}
+
+// JoinFailure is the metadata of an error response for a join that failed for a known reason.
+type JoinFailure struct {
+	// Reason is a code identifying the join error.
+	Reason string `json:"reason" yaml:"reason"`
+
+	// Mismatch describes the incompatibility with the cluster, if the join failed because of a schema version or API
+	// extension mismatch.
+	Mismatch *SchemaVersionMismatch `json:"mismatch,omitempty" yaml:"mismatch,omitempty"`
+}
+
+// NewJoinFailure returns the JoinFailure for the given error, and false if it doesn't wrap a known join error.
+func NewJoinFailure(err error) (JoinFailure, bool) {
+	var mismatch SchemaVersionMismatch
+	if errors.As(err, &mismatch) {
+		return JoinFailure{Reason: joinFailureReason(ErrJoinIncompatible), Mismatch: &mismatch}, true
+	}
+
+	for _, r := range joinFailureReasons {
+		if errors.Is(err, r.err) {
+			return JoinFailure{Reason: r.reason}, true
+		}
+	}
+
+	return JoinFailure{}, false
+}
+
+// JoinFailed marks the error as a join failure for the given reason, one of the join errors above.
+func JoinFailed(reason error, err error) error {
+	return JoinFailure{Reason: joinFailureReason(reason)}.Wrap(err)
+}
+
+// joinFailureReason returns the reason code of the join error.
+func joinFailureReason(reason error) string {
+	for _, r := range joinFailureReasons {
+		if r.err == reason {
+			return r.reason
+		}
+	}
+
+	return ""
+}
+
+// Err returns the join error identified by the reason code, or nil if the reason is unknown.
+func (f JoinFailure) Err() error {
+	for _, r := range joinFailureReasons {
+		if r.reason == f.Reason {
+			return r.err
+		}
+	}
+
+	return nil
+}
+
+// Wrap returns an error with the message of err that is also detectable as the join error of the failure with
+// errors.Is, and as its SchemaVersionMismatch, if any, with errors.As.
+func (f JoinFailure) Wrap(err error) error {
+	return joinFailureError{error: err, failure: f}
+}
+
+// joinFailureError is an error that also carries the reason a join failed.
+type joinFailureError struct {
+	error
+	failure JoinFailure
+}
+
+// Unwrap returns the underlying error, the join error of the failure, and any schema version mismatch.
+func (e joinFailureError) Unwrap() []error {
+	errs := []error{e.error}
+
+	reason := e.failure.Err()
+	if reason != nil {
+		errs = append(errs, reason)
+	}
+
+	if e.failure.Mismatch != nil {
+		errs = append(errs, *e.failure.Mismatch)
+	}
+
+	return errs
+}
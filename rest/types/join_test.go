@@ -0,0 +1,72 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type joinSuite struct {
+	suite.Suite
+}
+
+func TestJoinSuite(t *testing.T) {
+	suite.Run(t, new(joinSuite))
+}
+
+// Ensures join failures survive a round trip through the API metadata, keyed by the reason code.
+func (s *joinSuite) Test_joinFailureRoundTrip() {
+	mismatch := SchemaVersionMismatch{LocalSchemaInternal: 1, ClusterSchemaInternal: 2}
+
+	tests := []struct {
+		name     string
+		err      error
+		reason   error
+		mismatch bool
+	}{
+		{
+			name:   "Join error",
+			err:    JoinFailed(ErrJoinNotTrusted, fmt.Errorf("Token is unknown")),
+			reason: ErrJoinNotTrusted,
+		},
+		{
+			name:     "Schema version mismatch",
+			err:      fmt.Errorf("Failed to join: %w", mismatch),
+			reason:   ErrJoinIncompatible,
+			mismatch: true,
+		},
+		{
+			name: "Unknown error",
+			err:  fmt.Errorf("Something else"),
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Log(test.name)
+
+		failure, ok := NewJoinFailure(test.err)
+		s.Equal(test.reason != nil, ok)
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(failure)
+		s.NoError(err)
+
+		decoded := JoinFailure{}
+		s.NoError(json.Unmarshal(data, &decoded))
+
+		wrapped := decoded.Wrap(errors.New(test.err.Error()))
+		s.Equal(test.err.Error(), wrapped.Error())
+		s.ErrorIs(wrapped, test.reason)
+
+		var decodedMismatch SchemaVersionMismatch
+		s.Equal(test.mismatch, errors.As(wrapped, &decodedMismatch))
+		if test.mismatch {
+			s.Equal(mismatch, decodedMismatch)
+		}
+	}
+}
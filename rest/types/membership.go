@@ -0,0 +1,43 @@
+package types
+
+// MemberEventType is the kind of change to the membership of the cluster.
+type MemberEventType string
+
+const (
+	// MemberAdded indicates that a cluster member was added.
+	MemberAdded MemberEventType = "added"
+
+	// MemberRemoved indicates that a cluster member was removed.
+	MemberRemoved MemberEventType = "removed"
+
+	// MemberRoleChanged indicates that the role of a cluster member changed.
+	MemberRoleChanged MemberEventType = "role-changed"
+)
+
+// MemberEvent is a change to the membership of the cluster.
+type MemberEvent struct {
+	Type    MemberEventType `json:"type"    yaml:"type"`
+	Name    string          `json:"name"    yaml:"name"`
+	Address AddrPort        `json:"address" yaml:"address"`
+	Role    string          `json:"role"    yaml:"role"`
+
+	// PreviousRole is the role of the cluster member before a MemberRoleChanged event.
+	PreviousRole string `json:"previous_role" yaml:"previous_role"`
+
+	// Snapshot is true for the MemberAdded events describing the membership at the time of subscription.
+	Snapshot bool `json:"snapshot" yaml:"snapshot"`
+}
+
+// ClusterMemberLocal represents local information about a new cluster member.
+type ClusterMemberLocal struct {
+	Name        string          `json:"name" yaml:"name"`
+	Address     AddrPort        `json:"address" yaml:"address"`
+	Certificate X509Certificate `json:"certificate" yaml:"certificate"`
+
+	// DqliteAddress is the address at which the cluster member accepts dqlite connections, if it differs from Address.
+	DqliteAddress string `json:"dqlite_address,omitempty" yaml:"dqlite_address,omitempty"`
+
+	// AdditionalCertificates are also accepted as the certificate of the cluster member, such as its new certificate
+	// while it is being rotated.
+	AdditionalCertificates []X509Certificate `json:"additional_certificates,omitempty" yaml:"additional_certificates,omitempty"`
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+)
+
+// OperationStatus represents the status of a long-running cluster operation.
+type OperationStatus string
+
+const (
+	// OperationRunning is the status of an operation that has not yet completed.
+	OperationRunning OperationStatus = "Running"
+
+	// OperationSuccess is the status of an operation that completed successfully.
+	OperationSuccess OperationStatus = "Success"
+
+	// OperationFailure is the status of an operation that completed with an error.
+	OperationFailure OperationStatus = "Failure"
+
+	// OperationCancelled is the status of an operation that was cancelled before it completed.
+	OperationCancelled OperationStatus = "Cancelled"
+)
+
+// OperationPostJoin is the description of the operation that runs the PostJoin hook in the background, by which it can
+// be found among the operations of the cluster member that joined the cluster.
+const OperationPostJoin = "Running post-join hook"
+
+// Operation represents a long-running cluster operation on a cluster member.
+type Operation struct {
+	ID          string          `json:"id"          yaml:"id"`
+	Description string          `json:"description" yaml:"description"`
+	Status      OperationStatus `json:"status"      yaml:"status"`
+	CreatedAt   time.Time       `json:"created_at"  yaml:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"  yaml:"updated_at"`
+	Err         string          `json:"err"         yaml:"err"`
+}
@@ -0,0 +1,29 @@
+package types
+
+// RateLimit is a token-bucket rate limit.
+type RateLimit struct {
+	// Rate is the number of requests per second that are allowed on average. If 0, requests are not limited.
+	Rate float64
+
+	// Burst is the number of requests that are allowed at once. If 0, it defaults to the rate, rounded up.
+	Burst int
+}
+
+// RateLimits configures rate limiting of requests to the public API over the network.
+// Requests over the unix socket are never limited.
+type RateLimits struct {
+	// PerClient limits the requests of each client, identified by the fingerprint of its certificate,
+	// or by its address if it has none.
+	PerClient RateLimit
+
+	// Global limits the requests of all clients combined, except for other cluster members.
+	Global RateLimit
+
+	// Cluster limits the requests of each other cluster member. If unset, requests from cluster members are not limited.
+	Cluster RateLimit
+}
+
+// Enabled returns whether any rate limit is set.
+func (l RateLimits) Enabled() bool {
+	return l.PerClient.Rate > 0 || l.Global.Rate > 0 || l.Cluster.Rate > 0
+}
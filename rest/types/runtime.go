@@ -0,0 +1,37 @@
+package types
+
+import (
+	"fmt"
+)
+
+// RuntimeErrorSource is the component of a running daemon that encountered a non-fatal error.
+type RuntimeErrorSource string
+
+const (
+	// RuntimeErrorEndpoint is reported when an API listener stops serving unexpectedly, or an optional API listener
+	// fails to start.
+	RuntimeErrorEndpoint RuntimeErrorSource = "endpoint"
+
+	// RuntimeErrorHeartbeat is reported when a heartbeat round fails to start.
+	RuntimeErrorHeartbeat RuntimeErrorSource = "heartbeat"
+
+	// RuntimeErrorTrustStore is reported when the trust store fails to reload after a change on disk.
+	RuntimeErrorTrustStore RuntimeErrorSource = "truststore"
+)
+
+// RuntimeError is a non-fatal error encountered by a running daemon. The daemon keeps running after such an error,
+// but a supervisor may decide to restart it.
+type RuntimeError struct {
+	Source RuntimeErrorSource
+	Err    error
+}
+
+// Error implements error.
+func (e RuntimeError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e RuntimeError) Unwrap() error {
+	return e.Err
+}
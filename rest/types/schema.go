@@ -0,0 +1,86 @@
+package types
+
+import (
+	"fmt"
+)
+
+// SchemaVersionMismatch is returned when the schema versions or API extensions of a cluster member do not match
+// those of the rest of the cluster, preventing it from joining or starting up.
+type SchemaVersionMismatch struct {
+	// LocalSchemaInternal and LocalSchemaExternal are the schema versions of this cluster member.
+	LocalSchemaInternal uint64 `json:"local_schema_internal" yaml:"local_schema_internal"`
+	LocalSchemaExternal uint64 `json:"local_schema_external" yaml:"local_schema_external"`
+
+	// ClusterSchemaInternal and ClusterSchemaExternal are the highest schema versions in the cluster.
+	ClusterSchemaInternal uint64 `json:"cluster_schema_internal" yaml:"cluster_schema_internal"`
+	ClusterSchemaExternal uint64 `json:"cluster_schema_external" yaml:"cluster_schema_external"`
+
+	// MissingExtensions are API extensions supported by the cluster but not by this cluster member.
+	MissingExtensions []string `json:"missing_extensions" yaml:"missing_extensions"`
+
+	// ExtraExtensions are API extensions supported by this cluster member but not by the cluster.
+	ExtraExtensions []string `json:"extra_extensions" yaml:"extra_extensions"`
+
+	// LocalSchemaExtensions and ClusterSchemaExtensions are the versions of any named schema extensions that differ
+	// between this cluster member and the highest versions in the cluster.
+	LocalSchemaExtensions   map[string]uint64 `json:"local_schema_extensions,omitempty" yaml:"local_schema_extensions,omitempty"`
+	ClusterSchemaExtensions map[string]uint64 `json:"cluster_schema_extensions,omitempty" yaml:"cluster_schema_extensions,omitempty"`
+}
+
+// Behind returns whether this cluster member needs to be upgraded to match the rest of the cluster.
+func (e SchemaVersionMismatch) Behind() bool {
+	if e.LocalSchemaInternal != e.ClusterSchemaInternal || e.LocalSchemaExternal != e.ClusterSchemaExternal {
+		return e.LocalSchemaInternal < e.ClusterSchemaInternal || e.LocalSchemaExternal < e.ClusterSchemaExternal
+	}
+
+	for name, version := range e.ClusterSchemaExtensions {
+		if e.LocalSchemaExtensions[name] < version {
+			return true
+		}
+	}
+
+	return len(e.MissingExtensions) > 0
+}
+
+// Is reports whether the target is ErrJoinIncompatible, so that a mismatch can be detected like other join errors.
+func (e SchemaVersionMismatch) Is(target error) bool {
+	return target == ErrJoinIncompatible
+}
+
+// Error implements the error interface.
+func (e SchemaVersionMismatch) Error() string {
+	if e.LocalSchemaInternal != e.ClusterSchemaInternal || e.LocalSchemaExternal != e.ClusterSchemaExternal {
+		if e.Behind() {
+			return fmt.Sprintf("This node's schema version (internal %d, external %d) is behind the cluster (internal %d, external %d), please upgrade", e.LocalSchemaInternal, e.LocalSchemaExternal, e.ClusterSchemaInternal, e.ClusterSchemaExternal)
+		}
+
+		return fmt.Sprintf("This node's schema version (internal %d, external %d) is ahead of the cluster (internal %d, external %d)", e.LocalSchemaInternal, e.LocalSchemaExternal, e.ClusterSchemaInternal, e.ClusterSchemaExternal)
+	}
+
+	if len(e.ClusterSchemaExtensions) > 0 {
+		if e.Behind() {
+			return fmt.Sprintf("This node's schema extension versions %v are behind the cluster %v, please upgrade", e.LocalSchemaExtensions, e.ClusterSchemaExtensions)
+		}
+
+		return fmt.Sprintf("This node's schema extension versions %v are ahead of the cluster %v", e.LocalSchemaExtensions, e.ClusterSchemaExtensions)
+	}
+
+	if len(e.ExtraExtensions) == 0 {
+		return fmt.Sprintf("This node's API extensions are behind the cluster (missing %v), please upgrade", e.MissingExtensions)
+	}
+
+	return fmt.Sprintf("This node's API extensions do not match the cluster (missing %v, not supported by the cluster %v)", e.MissingExtensions, e.ExtraExtensions)
+}
+
+// SchemaStatus is the schema version and the set of API extensions supported by a cluster member.
+type SchemaStatus struct {
+	// SchemaInternal and SchemaExternal are the internal and external schema versions.
+	SchemaInternal uint64 `json:"schema_internal" yaml:"schema_internal"`
+	SchemaExternal uint64 `json:"schema_external" yaml:"schema_external"`
+
+	// SchemaExtensions are the versions of any named schema extensions.
+	SchemaExtensions map[string]uint64 `json:"schema_extensions" yaml:"schema_extensions"`
+
+	// APIExtensions are the registered API extensions.
+	APIExtensions []string `json:"api_extensions" yaml:"api_extensions"`
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultReadHeaderTimeout is the default time allowed to read the headers of a request, which protects the
+	// daemon from clients that hold connections open by sending their headers slowly.
+	DefaultReadHeaderTimeout = 10 * time.Second
+
+	// DefaultIdleTimeout is the default time that an idle keep-alive connection is kept open for its next request.
+	DefaultIdleTimeout = 2 * time.Minute
+)
+
+// ServerTimeouts are the timeouts of the HTTP servers of the daemon, applied to the unix socket and every network
+// listener. Zero fields use the defaults, and negative fields disable the timeout.
+//
+// By default, the headers of a request must be read within DefaultReadHeaderTimeout, and idle keep-alive connections
+// are closed after DefaultIdleTimeout. Reading the body and writing the response are not limited by default, as
+// request bodies may be large and some responses, such as event streams and database dumps, are long-lived.
+// Connections hijacked for dqlite are never limited.
+type ServerTimeouts struct {
+	// ReadHeaderTimeout is the time allowed to read the headers of a request.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the time allowed to read a whole request, including its body.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the time allowed to write the response, from the end of reading the request headers.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the time that an idle keep-alive connection is kept open for its next request.
+	IdleTimeout time.Duration
+}
+
+// Apply sets the timeouts of the given server, using the defaults for unset fields.
+func (t ServerTimeouts) Apply(server *http.Server) {
+	server.ReadHeaderTimeout = timeout(t.ReadHeaderTimeout, DefaultReadHeaderTimeout)
+	server.ReadTimeout = timeout(t.ReadTimeout, 0)
+	server.WriteTimeout = timeout(t.WriteTimeout, 0)
+	server.IdleTimeout = timeout(t.IdleTimeout, DefaultIdleTimeout)
+}
+
+// timeout returns the configured timeout, the default if it is zero, or zero (unlimited) if it is negative.
+func timeout(configured time.Duration, defaultTimeout time.Duration) time.Duration {
+	if configured < 0 {
+		return 0
+	}
+
+	if configured == 0 {
+		return defaultTimeout
+	}
+
+	return configured
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+)
+
+// TLSConfig overrides the default TLS settings of a network listener.
+// Any unset fields keep their default values.
+type TLSConfig struct {
+	// MinVersion is the minimum accepted TLS version, such as tls.VersionTLS13.
+	MinVersion uint16
+
+	// CipherSuites is the list of accepted cipher suites for TLS 1.2 connections. TLS 1.3 cipher suites are not configurable.
+	CipherSuites []uint16
+
+	// CurvePreferences is the list of accepted elliptic curves for the key exchange, in order of preference.
+	CurvePreferences []tls.CurveID
+}
+
+// Validate returns an error if the TLSConfig contains unknown values or an invalid combination of values.
+func (c TLSConfig) Validate() error {
+	validVersions := []uint16{0, tls.VersionTLS12, tls.VersionTLS13}
+	if !slices.Contains(validVersions, c.MinVersion) {
+		return fmt.Errorf("Unsupported minimum TLS version %q", tls.VersionName(c.MinVersion))
+	}
+
+	if len(c.CipherSuites) > 0 && c.MinVersion == tls.VersionTLS13 {
+		return fmt.Errorf("Cipher suites cannot be configured when the minimum TLS version is %q", tls.VersionName(tls.VersionTLS13))
+	}
+
+	for _, id := range c.CipherSuites {
+		supported := false
+		for _, suite := range tls.CipherSuites() {
+			if suite.ID == id && slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+				supported = true
+				break
+			}
+		}
+
+		if !supported {
+			return fmt.Errorf("Unsupported TLS cipher suite %q", tls.CipherSuiteName(id))
+		}
+	}
+
+	validCurves := []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+	for _, curve := range c.CurvePreferences {
+		if !slices.Contains(validCurves, curve) {
+			return fmt.Errorf("Unsupported TLS curve %q", curve.String())
+		}
+	}
+
+	return nil
+}
+
+// Apply overrides the fields of the given tls.Config with any fields set on the TLSConfig.
+func (c TLSConfig) Apply(config *tls.Config) {
+	if c.MinVersion != 0 {
+		config.MinVersion = c.MinVersion
+	}
+
+	if len(c.CipherSuites) > 0 {
+		config.CipherSuites = c.CipherSuites
+	}
+
+	if len(c.CurvePreferences) > 0 {
+		config.CurvePreferences = c.CurvePreferences
+	}
+}
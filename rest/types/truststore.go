@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+)
+
+// TrustAuditOperation is a kind of change to the trust store.
+type TrustAuditOperation string
+
+const (
+	// TrustAuditAdd indicates that a remote was added to the trust store.
+	TrustAuditAdd TrustAuditOperation = "add"
+
+	// TrustAuditRemove indicates that a remote was removed from the trust store.
+	TrustAuditRemove TrustAuditOperation = "remove"
+)
+
+// TrustAuditEvent records a change to the set of remotes trusted by a cluster member.
+type TrustAuditEvent struct {
+	// Operation is the kind of change.
+	Operation TrustAuditOperation
+
+	// Time is when the change was made.
+	Time time.Time
+
+	// Name, Address, and Fingerprint identify the remote that was added or removed.
+	Name        string
+	Address     AddrPort
+	Fingerprint string
+
+	// Source is the address of the client whose request caused the change.
+	// It is empty if the change was made by the cluster member itself.
+	Source string
+}
+
+// TrustStoreRefresh is the result of reloading the trust store from disk.
+type TrustStoreRefresh struct {
+	// Remotes is the number of remotes in the trust store after the refresh.
+	Remotes int `json:"remotes" yaml:"remotes"`
+}